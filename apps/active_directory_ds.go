@@ -16,6 +16,8 @@ package apps
 
 import (
 	"context"
+	"path"
+	"time"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
@@ -47,6 +49,58 @@ func (r MetricsReceiverActiveDirectoryDS) Pipelines(_ context.Context) ([]otel.R
 			),
 			otel.ModifyInstrumentationScope(r.Type(), "1.0"),
 		}},
+	}, {
+		// The pending replication synchronizations, LDAP binds by type and SYSVOL health are not
+		// collected by the active_directory_ds receiver, so they are read from the performance
+		// counters directly. The pending replication operations are already collected by it.
+		Receiver: otel.Component{
+			Type: "windowsperfcounters",
+			Config: map[string]interface{}{
+				"collection_interval": r.CollectionIntervalString(),
+				"perfcounters": []map[string]interface{}{
+					{
+						"object": "NTDS",
+						"counters": []map[string]string{
+							{"name": "DRA Pending Replication Synchronizations"},
+							{"name": "Digest Binds/sec"},
+							{"name": "External Binds/sec"},
+							{"name": "Fast Binds/sec"},
+							{"name": "Negotiated Binds/sec"},
+							{"name": "NTLM Binds/sec"},
+							{"name": "Simple Binds/sec"},
+						},
+					},
+					{
+						"object":    "DFS Replicated Folders",
+						"instances": []string{"*"},
+						"counters": []map[string]string{
+							{"name": "Conflict Space In Use"},
+							{"name": "Staging Space In Use"},
+						},
+					},
+				},
+			},
+		},
+		Processors: map[string][]otel.Component{"metrics": {
+			otel.MetricsTransform(
+				otel.RenameMetric(
+					`\NTDS\DRA Pending Replication Synchronizations`,
+					"active_directory.ds.replication.sync.pending",
+				),
+				// $ needs to be escaped because reasons.
+				// https://github.com/open-telemetry/opentelemetry-collector-contrib/tree/main/processor/metricstransformprocessor#rename-multiple-metrics-using-substitution
+				otel.CombineMetrics(
+					`^\\NTDS\\(?P<type>Digest|External|Fast|Negotiated|NTLM|Simple) Binds/sec$$`,
+					"active_directory.ds.ldap.bind.type.rate",
+				),
+				otel.CombineMetrics(
+					`^\\DFS Replicated Folders\((?P<replicated_folder>.*)\)\\(?P<area>Conflict|Staging) Space In Use$$`,
+					"active_directory.ds.sysvol.space.usage",
+				),
+				otel.AddPrefix("workload.googleapis.com"),
+			),
+			otel.ModifyInstrumentationScope(r.Type(), "1.0"),
+		}},
 	}}, nil
 }
 
@@ -80,3 +134,56 @@ func (r LoggingReceiverActiveDirectoryDS) Components(ctx context.Context, tag st
 func init() {
 	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverActiveDirectoryDS{} }, platform.Windows)
 }
+
+// LoggingReceiverActiveDirectoryDSDcdiag reads the failed dcdiag tests, which the diagnostics
+// service writes to a file in the logs directory.
+type LoggingReceiverActiveDirectoryDSDcdiag struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	CollectionInterval *time.Duration `yaml:"collection_interval,omitempty" validate:"omitempty,min=1m"`
+}
+
+const defaultDcdiagCollectionInterval = time.Hour
+
+func (r LoggingReceiverActiveDirectoryDSDcdiag) Type() string {
+	return "active_directory_ds_dcdiag"
+}
+
+// DcdiagLogsPath returns the path of the file the failed dcdiag tests are written to.
+func DcdiagLogsPath(logsDir string) string {
+	return path.Join(logsDir, "dcdiag.log")
+}
+
+// Interval returns how often dcdiag is run.
+func (r LoggingReceiverActiveDirectoryDSDcdiag) Interval() time.Duration {
+	if r.CollectionInterval == nil {
+		return defaultDcdiagCollectionInterval
+	}
+	return *r.CollectionInterval
+}
+
+func (r LoggingReceiverActiveDirectoryDSDcdiag) Components(ctx context.Context, tag string) []fluentbit.Component {
+	c := confgenerator.LoggingReceiverFilesMixin{
+		IncludePaths: []string{DcdiagLogsPath("${logs_dir}")},
+	}.Components(ctx, tag)
+	c = append(c, confgenerator.LoggingProcessorParseJson{
+		// Sample line: {"time":"2024-02-14T09:12:44.000-0800","test":"Replications","target":"DC1","result":"failed","message":"[Replications Check,DC1] A recent replication attempt failed:"}
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%Y-%m-%dT%H:%M:%S.%L%z",
+		},
+	}.Components(ctx, tag, "active_directory_ds_dcdiag")...)
+	// Only the failed tests are written.
+	severityVal := "ERROR"
+	c = append(c, confgenerator.LoggingProcessorModifyFields{
+		Fields: map[string]*confgenerator.ModifyField{
+			"severity":                 {StaticValue: &severityVal},
+			InstrumentationSourceLabel: instrumentationSourceValue(r.Type()),
+		},
+	}.Components(ctx, tag, "active_directory_ds_dcdiag")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverActiveDirectoryDSDcdiag{} }, platform.Windows)
+}
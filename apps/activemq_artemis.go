@@ -0,0 +1,227 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/secret"
+)
+
+// MetricsReceiverActivemqArtemis scrapes the Prometheus endpoint of the Artemis metrics plugin.
+// Artemis registers its MBeans under org.apache.activemq.artemis, which no JMX target system of
+// the collector queries, so the JMX receivers can't collect them.
+type MetricsReceiverActivemqArtemis struct {
+	confgenerator.ConfigComponent       `yaml:",inline"`
+	confgenerator.MetricsReceiverShared `yaml:",inline"`
+
+	Endpoint    string        `yaml:"endpoint" validate:"omitempty,hostname_port"`
+	MetricsPath string        `yaml:"metrics_path" validate:"omitempty,startswith=/"`
+	Username    string        `yaml:"username" validate:"required_with=Password"`
+	Password    secret.String `yaml:"password" validate:"required_with=Username"`
+}
+
+// The metrics plugin serves the metrics from the embedded web server of the broker, which also
+// serves the management console.
+// https://activemq.apache.org/components/artemis/documentation/latest/metrics.html
+const (
+	defaultActivemqArtemisEndpoint    = "localhost:8161"
+	defaultActivemqArtemisMetricsPath = "/metrics"
+)
+
+// activemqArtemisMetrics maps the names of the collected Prometheus metrics to their names in
+// Cloud Monitoring. Artemis exposes all of them as gauges, so cumulative ones are toggled to sums.
+var activemqArtemisMetrics = []struct {
+	prometheus, name string
+	cumulative       bool
+}{
+	{"artemis_connection_count", "activemq_artemis.connection.count", false},
+	{"artemis_address_memory_usage", "activemq_artemis.memory.usage", false},
+	{"artemis_disk_store_usage", "activemq_artemis.disk.usage", false},
+	{"artemis_routed_message_count", "activemq_artemis.address.message.routed", true},
+	{"artemis_unrouted_message_count", "activemq_artemis.address.message.unrouted", true},
+	{"artemis_message_count", "activemq_artemis.queue.message.current", false},
+	{"artemis_delivering_message_count", "activemq_artemis.queue.message.delivering", false},
+	{"artemis_messages_added", "activemq_artemis.queue.message.added", true},
+	{"artemis_messages_acknowledged", "activemq_artemis.queue.message.acknowledged", true},
+	{"artemis_messages_expired", "activemq_artemis.queue.message.expired", true},
+	{"artemis_consumer_count", "activemq_artemis.queue.consumer.count", false},
+}
+
+func (r MetricsReceiverActivemqArtemis) Type() string {
+	return "activemq_artemis"
+}
+
+func (r MetricsReceiverActivemqArtemis) Pipelines(_ context.Context) ([]otel.ReceiverPipeline, error) {
+	endpoint := r.Endpoint
+	if endpoint == "" {
+		endpoint = defaultActivemqArtemisEndpoint
+	}
+	metricsPath := r.MetricsPath
+	if metricsPath == "" {
+		metricsPath = defaultActivemqArtemisMetricsPath
+	}
+	var names []string
+	var renames []map[string]interface{}
+	for _, m := range activemqArtemisMetrics {
+		names = append(names, m.prometheus)
+		var operations []map[string]interface{}
+		if m.cumulative {
+			operations = append(operations, otel.ToggleScalarDataType)
+		}
+		renames = append(renames, otel.RenameMetric(m.prometheus, m.name, operations...))
+	}
+	scrapeConfig := map[string]interface{}{
+		"job_name":        r.Type(),
+		"scrape_interval": r.CollectionIntervalString(),
+		"metrics_path":    metricsPath,
+		"metric_relabel_configs": []map[string]interface{}{
+			{
+				"source_labels": []string{"__name__"},
+				"regex":         strings.Join(names, "|"),
+				"action":        "keep",
+			},
+		},
+		"static_configs": []map[string]interface{}{
+			{
+				"targets": []string{endpoint},
+			},
+		},
+	}
+	// Only set the basic auth if provided
+	if r.Username != "" {
+		scrapeConfig["basic_auth"] = map[string]interface{}{
+			"username": r.Username,
+			"password": r.Password.SecretValue(),
+		}
+	}
+	return []otel.ReceiverPipeline{{
+		Receiver: otel.Component{
+			Type: "prometheus",
+			Config: map[string]interface{}{
+				"config": map[string]interface{}{
+					"scrape_configs": []map[string]interface{}{scrapeConfig},
+				},
+			},
+		},
+		Processors: map[string][]otel.Component{"metrics": {
+			// remove prometheus scraping meta-metrics
+			otel.MetricsFilter("exclude", "strict",
+				"scrape_samples_post_metric_relabeling",
+				"scrape_series_added",
+				"scrape_duration_seconds",
+				"scrape_samples_scraped",
+				"up",
+			),
+			otel.MetricsTransform(append(renames,
+				otel.AddPrefix("workload.googleapis.com"),
+			)...),
+			otel.NormalizeSums(),
+			otel.ModifyInstrumentationScope(r.Type(), "1.0"),
+		}},
+	}}, nil
+}
+
+func init() {
+	confgenerator.MetricsReceiverTypes.RegisterType(func() confgenerator.MetricsReceiver { return &MetricsReceiverActivemqArtemis{} })
+}
+
+type LoggingProcessorActivemqArtemis struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorActivemqArtemis) Type() string {
+	return "activemq_artemis"
+}
+
+func (p LoggingProcessorActivemqArtemis) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Logging documentation: https://activemq.apache.org/components/artemis/documentation/latest/logging.html
+		// Sample line: 2024-02-05 10:21:54,412 INFO  [org.apache.activemq.artemis.integration.bootstrap] AMQ101000: Starting ActiveMQ Artemis Server version 2.32.0
+		// Sample line: 2024-02-05 10:21:56,027 INFO  [org.apache.activemq.artemis.core.server] AMQ221007: Server is now live
+		// Sample line: 2024-02-05 10:22:31,380 WARN  [org.apache.activemq.artemis.core.client] AMQ212037: Connection failure to /10.128.0.7:51234 has been detected: AMQ229014: Did not receive data from /10.128.0.7:51234 within the 60000ms connection TTL. [code=CONNECTION_TIMEDOUT]
+		// Sample line: 2024-02-05 10:23:02,114 ERROR [org.apache.activemq.artemis.core.server] AMQ224000: Failure in initialisation
+		//              java.io.IOException: No space left on device
+		//                  at java.base/sun.nio.ch.FileDispatcherImpl.write0(Native Method)
+		Regex: `^(?<time>\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}:\d{2},\d{3})\s+(?<level>\w+)\s+\[(?<source>[^\]]+)\]\s+(?<message>(?:(?<messageCode>AMQ\d+):)?[\s\S]*)`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%Y-%m-%d %H:%M:%S,%L",
+		},
+	}.Components(ctx, tag, uid)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				"severity": {
+					CopyFrom: "jsonPayload.level",
+					MapValues: map[string]string{
+						"TRACE": "TRACE",
+						"DEBUG": "DEBUG",
+						"INFO":  "INFO",
+						"WARN":  "WARNING",
+						"ERROR": "ERROR",
+						"FATAL": "CRITICAL",
+					},
+					MapValuesExclusive: true,
+				},
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverActivemqArtemis struct {
+	LoggingProcessorActivemqArtemis         `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverActivemqArtemis) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// Artemis has no package installers; brokers are created with `artemis create <instance dir>`.
+			"/var/lib/artemis*/log/artemis.log",
+			"/opt/artemis*/log/artemis.log",
+		}
+	}
+
+	r.MultilineRules = []confgenerator.MultilineRule{
+		{
+			StateName: "start_state",
+			NextState: "cont",
+			Regex:     `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3}`,
+		},
+		{
+			StateName: "cont",
+			NextState: "cont",
+			Regex:     `^(?!\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3})`,
+		},
+	}
+
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorActivemqArtemis.Components(ctx, tag, "activemq_artemis")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorActivemqArtemis{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverActivemqArtemis{} })
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorAlloyDBOmni struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorAlloyDBOmni) Type() string {
+	return "alloydb_omni"
+}
+
+func (p LoggingProcessorAlloyDBOmni) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	// AlloyDB Omni is PostgreSQL-compatible and writes the same server logs.
+	c := postgresqlLogComponents(ctx, tag, uid, p.Type())
+
+	// The connection events that are logged with log_connections and log_disconnections are
+	// parsed into their own fields, keeping the full message.
+	// Sample message: connection received: host=10.128.0.12 port=50214
+	// Sample message: connection authorized: user=postgres database=postgres application_name=psql
+	// Sample message: disconnection: session time: 0:00:05.163 user=postgres database=postgres host=10.128.0.12 port=50214
+	c = append(c,
+		confgenerator.LoggingProcessorParseRegex{
+			Field:       "message",
+			PreserveKey: true,
+			Regex:       `^(?<connection_event>connection received|connection authorized|disconnection):(?: session time: (?<session_time>[\d:.]+))?(?: user=(?<connection_user>\S+))?(?: database=(?<connection_database>\S+))?(?: application_name=(?<application_name>\S+))?(?: host=(?<client_host>\S+))?(?: port=(?<client_port>\d+))?`,
+			ParserShared: confgenerator.ParserShared{
+				Types: map[string]string{
+					"client_port": "integer",
+				},
+			},
+		}.Components(ctx, tag, fmt.Sprintf("%s.connection", uid))...,
+	)
+
+	return c
+}
+
+type LoggingReceiverAlloyDBOmni struct {
+	LoggingProcessorAlloyDBOmni             `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline"`
+	// The log directory is under the data directory, which is chosen when AlloyDB Omni is installed.
+	IncludePaths []string `yaml:"include_paths,omitempty" validate:"required"`
+}
+
+func (r LoggingReceiverAlloyDBOmni) Components(ctx context.Context, tag string) []fluentbit.Component {
+	r.LoggingReceiverFilesMixin.IncludePaths = r.IncludePaths
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	return append(c, r.LoggingProcessorAlloyDBOmni.Components(ctx, tag, "alloydb_omni")...)
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorAlloyDBOmni{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverAlloyDBOmni{} })
+}
@@ -0,0 +1,185 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorAuditd struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorAuditd) Type() string {
+	return "auditd"
+}
+
+const (
+	auditdMergeRecordsLuaFunction = `auditd_merge_records`
+	// auditd writes one line per record, and an event (e.g. a syscall) is made of several records
+	// that share the same audit ID. Multi-record events are terminated by an EOE record; single
+	// record events are not, so each event is held back until a record with a different audit ID
+	// or an EOE record shows up.
+	// The first record of an event provides the top-level fields. Each following record is nested
+	// under its lowercase type, except for PATH records, which are collected into "paths", and
+	// records whose only field has the same name as the type (CWD, PROCTITLE), which are flattened.
+	auditdMergeRecordsLuaScriptContents = `
+	local pending = nil
+	local pending_id = nil
+	local pending_ts = nil
+
+	-- auditd hex-encodes the value of these fields when it contains spaces, quotes or control
+	-- characters. Encoded values are written without quotes.
+	local auditd_encoded_fields = {
+	  acct = true, cmd = true, comm = true, cwd = true, data = true, exe = true,
+	  name = true, new = true, old = true, path = true, proctitle = true,
+	}
+
+	local function auditd_decode_hex(v)
+	  if (v:len() % 2 ~= 0 or not v:match("^%x+$")) then
+	    return v
+	  end
+	  local decoded = v:gsub("%x%x", function(h) return string.char(tonumber(h, 16)) end)
+	  -- The arguments in proctitle are separated by NUL characters.
+	  return (decoded:gsub("%z", " "))
+	end
+
+	local function auditd_parse_fields(s, fields, decode_args)
+	  local i = 1
+	  while true do
+	    local _, e, k = s:find("([%w_-]+)=", i)
+	    if (e == nil) then
+	      break
+	    end
+	    local q = s:sub(e + 1, e + 1)
+	    local v = nil
+	    if (q == '"' or q == "'") then
+	      local close = s:find(q, e + 2, true) or (s:len() + 1)
+	      v = s:sub(e + 2, close - 1)
+	      i = close + 1
+	      if (q == "'") then
+	        -- User space records (e.g. USER_LOGIN) wrap their own fields in msg='...'.
+	        auditd_parse_fields(v, fields, false)
+	        v = nil
+	      end
+	    else
+	      -- Enriched logs (log_format = ENRICHED) separate the translated fields with 0x1D.
+	      local close = s:find("[ \029]", e + 1) or (s:len() + 1)
+	      v = s:sub(e + 1, close - 1)
+	      i = close + 1
+	      if (auditd_encoded_fields[k] or (decode_args and k:match("^a%d+$"))) then
+	        v = auditd_decode_hex(v)
+	      end
+	    end
+	    if (v ~= nil) then
+	      fields[k] = v
+	    end
+	  end
+	  return fields
+	end
+
+	function auditd_merge_records(tag, timestamp, record)
+	  local id = record["audit_id"]
+	  if (id == nil) then
+	    return 0, timestamp, record
+	  end
+	  local rtype = record["type"]
+	  local ts = tonumber(record["audit_time"]) or timestamp
+	  -- Only the arguments of EXECVE records are strings; SYSCALL arguments are register values.
+	  local fields = auditd_parse_fields(record["message"] or "", {}, rtype == "EXECVE")
+	  record["audit_time"] = nil
+	  record["message"] = nil
+
+	  local out = nil
+	  local out_ts = nil
+	  if (pending ~= nil and (pending_id ~= id or rtype == "EOE")) then
+	    out, out_ts = pending, pending_ts
+	    pending = nil
+	  end
+
+	  if (rtype == "EOE") then
+	    -- Nothing to keep.
+	  elseif (pending == nil) then
+	    for k, v in pairs(fields) do
+	      record[k] = v
+	    end
+	    pending, pending_id, pending_ts = record, id, ts
+	  else
+	    local key = string.lower(rtype)
+	    if (key == "path") then
+	      pending["paths"] = pending["paths"] or {}
+	      table.insert(pending["paths"], fields)
+	    elseif (fields[key] ~= nil and next(fields, next(fields)) == nil) then
+	      pending[key] = fields[key]
+	    else
+	      pending[key] = fields
+	    end
+	  end
+
+	  if (out == nil) then
+	    return -1, 0, 0
+	  end
+	  return 1, out_ts, out
+	end
+	`
+)
+
+func (p LoggingProcessorAuditd) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Documentation: https://access.redhat.com/documentation/en-us/red_hat_enterprise_linux/9/html/security_hardening/auditing-the-system_security-hardening#understanding-audit-log-files_auditing-the-system
+		// Sample line: type=SYSCALL msg=audit(1707901964.123:4567): arch=c000003e syscall=59 success=yes exit=0 a0=55d0c8a0 a1=55d0c8b0 a2=55d0c8c0 a3=0 items=2 ppid=1200 pid=1234 auid=1000 uid=0 gid=0 euid=0 suid=0 fsuid=0 egid=0 sgid=0 fsgid=0 tty=pts0 ses=3 comm="ls" exe="/usr/bin/ls" key="exec"
+		// Sample line: type=EXECVE msg=audit(1707901964.123:4567): argc=2 a0="ls" a1=2D6C61202F746D70
+		// Sample line: type=PROCTITLE msg=audit(1707901964.123:4567): proctitle=6C73002D6C61002F746D70
+		// Sample line: node=vm-1 type=USER_LOGIN msg=audit(1707901965.001:4568): pid=1300 uid=0 auid=1000 ses=4 msg='op=login id=1000 exe="/usr/sbin/sshd" hostname=? addr=203.0.113.5 terminal=sshd res=success'
+		Regex: `^(?:node=(?<node>\S+) )?type=(?<type>\S+) msg=audit\((?<audit_time>\d+\.\d+):(?<audit_id>\d+)\):\s*(?<message>.*)$`,
+	}.Components(ctx, tag, uid)
+
+	// The event time is set from audit_time by the Lua script, since it is an epoch timestamp.
+	c = append(c, fluentbit.LuaFilterComponents(tag, auditdMergeRecordsLuaFunction, auditdMergeRecordsLuaScriptContents)...)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverAuditd struct {
+	LoggingProcessorAuditd                  `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverAuditd) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			"/var/log/audit/audit.log",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorAuditd.Components(ctx, tag, "auditd")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorAuditd{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverAuditd{} })
+}
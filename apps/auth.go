@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorAuth struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorAuth) Type() string {
+	return "auth"
+}
+
+func (p LoggingProcessorAuth) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Sample line: Feb 14 09:12:44 vm-1 sshd[1234]: Failed password for invalid user admin from 203.0.113.5 port 51234 ssh2
+		// Sample line: Feb 14 09:12:44 vm-1 sudo:     jdoe : TTY=pts/0 ; PWD=/home/jdoe ; USER=root ; COMMAND=/usr/bin/apt update
+		Regex: `^(?<time>\w{3}\s+\d{1,2}\s+\d{2}:\d{2}:\d{2})\s+(?<host>[^\s]+)\s+(?<program>[^\[:\s]+)(?:\[(?<pid>\d+)\])?:\s+(?<message>.*)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%b %d %H:%M:%S",
+			Types: map[string]string{
+				"pid": "integer",
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	// Security-relevant events are parsed into user, source_ip and result fields. Each regex
+	// captures the whole message again, so that the message is kept as is.
+	c = append(c,
+		confgenerator.LoggingProcessorParseRegexComplex{
+			Field: "message",
+			Parsers: []confgenerator.RegexParser{
+				{
+					// Sample line: Accepted publickey for jdoe from 10.128.0.5 port 51234 ssh2: RSA SHA256:...
+					// Sample line: Failed password for invalid user admin from 203.0.113.5 port 51234 ssh2
+					Regex: `^(?<message>(?<result>Accepted|Failed) (?<auth_method>\S+) for (?:invalid user )?(?<user>\S+) from (?<source_ip>\S+) port (?<source_port>\d+).*)$`,
+					Parser: confgenerator.ParserShared{
+						Types: map[string]string{
+							"source_port": "integer",
+						},
+					},
+				},
+				{
+					// Sample line: Invalid user admin from 203.0.113.5 port 51234
+					Regex: `^(?<message>(?<result>Invalid) user (?<user>\S*) from (?<source_ip>\S+)(?: port (?<source_port>\d+))?.*)$`,
+					Parser: confgenerator.ParserShared{
+						Types: map[string]string{
+							"source_port": "integer",
+						},
+					},
+				},
+				{
+					// Sample line: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=203.0.113.5  user=root
+					Regex: `^(?<message>pam_unix\((?<service>[^:]+):auth\): authentication (?<result>failure);.* rhost=(?<source_ip>\S*)(?:\s+user=(?<user>\S+))?.*)$`,
+				},
+				{
+					// Sample line: pam_unix(sudo:session): session opened for user root(uid=0) by jdoe(uid=1000)
+					Regex: `^(?<message>pam_unix\((?<service>[^:]+):session\): session (?<result>opened|closed) for user (?<target_user>[^\s(]+)(?:\(uid=\d+\))?(?: by (?<user>[^\s(]*))?.*)$`,
+				},
+				{
+					// Sample line: jdoe : TTY=pts/0 ; PWD=/home/jdoe ; USER=root ; COMMAND=/usr/bin/apt update
+					// Sample line: jdoe : 3 incorrect password attempts ; TTY=pts/0 ; PWD=/home/jdoe ; USER=root ; COMMAND=/usr/bin/id
+					Regex: `^(?<message>(?<user>\S+) : (?:(?<result>[^;]+?) ; )?TTY=(?<tty>\S+) ; PWD=(?<pwd>[^;]+) ; USER=(?<target_user>\S+) ; COMMAND=(?<command>.*))$`,
+				},
+				{
+					// Sample line: (to root) jdoe on pts/0
+					// Sample line: FAILED SU (to root) jdoe on pts/0
+					Regex: `^(?<message>(?:(?<result>FAILED) SU )?\(to (?<target_user>[^)]+)\) (?<user>\S+) on (?<tty>\S+))$`,
+				},
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverAuth struct {
+	LoggingProcessorAuth                    `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverAuth) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// Default log path on Ubuntu / Debian
+			"/var/log/auth.log",
+			// Default log path on CentOS / RHEL
+			"/var/log/secure",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorAuth.Components(ctx, tag, "auth")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorAuth{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverAuth{} })
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorBindQuery struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorBindQuery) Type() string {
+	return "bind_query"
+}
+
+func (p LoggingProcessorBindQuery) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Documentation: https://bind9.readthedocs.io/en/latest/reference.html#namedconf-statement-querylog
+		// The query log is written by a "queries" logging channel with "print-time yes", e.g.
+		//   channel query_log { file "/var/log/named/query.log"; print-time yes; print-category yes; print-severity yes; };
+		//   category queries { query_log; };
+		// Sample line: 14-Feb-2024 09:12:44.123 queries: info: client @0x7f3a2c0a1b70 10.128.0.5#51234 (www.example.com): query: www.example.com IN A +E(0)K (10.128.0.2)
+		// Sample line: 14-Feb-2024 09:12:44.123 client @0x7f3a2c0a1b70 10.128.0.5#51234 (www.example.com): view internal: query: www.example.com IN AAAA + (10.128.0.2)
+		// Sample line (9.9): 14-Feb-2024 09:12:44.123 client 10.128.0.5#51234 (www.example.com): query: www.example.com IN A +E (10.128.0.2)
+		Regex: `^(?<time>\d{2}-\w{3}-\d{4} \d{2}:\d{2}:\d{2}\.\d{3}) (?:queries: )?(?:(?<level>critical|error|warning|notice|info|debug(?: \d+)?): )?client (?:@\S+ )?(?<client_ip>[^#\s]+)#(?<client_port>\d+)(?: \([^)]*\))?: (?:view (?<view>[^:]+): )?query: (?<qname>\S+) (?<qclass>\S+) (?<qtype>\S+) (?<flags>\S+) \((?<server_ip>[^)]+)\)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%d-%b-%Y %H:%M:%S.%L",
+			Types: map[string]string{
+				"client_port": "integer",
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				"severity": {
+					CopyFrom: "jsonPayload.level",
+					MapValues: map[string]string{
+						"critical": "CRITICAL",
+						"error":    "ERROR",
+						"warning":  "WARNING",
+						"notice":   "NOTICE",
+						"info":     "INFO",
+					},
+					MapValuesExclusive: true,
+				},
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverBindQuery struct {
+	LoggingProcessorBindQuery               `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverBindQuery) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// Query logging has no default file; these are the conventional locations on
+			// CentOS / RHEL and Debian / Ubuntu.
+			"/var/log/named/query.log",
+			"/var/log/bind/query.log",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorBindQuery.Components(ctx, tag, "bind_query")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorBindQuery{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverBindQuery{} })
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorCloudSQLProxy struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorCloudSQLProxy) Type() string {
+	return "cloudsql_proxy"
+}
+
+func (p LoggingProcessorCloudSQLProxy) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	// The proxy writes JSON logs when it runs with --structured-logs.
+	// Documentation: https://cloud.google.com/sql/docs/mysql/sql-proxy#structured-logging
+	// Sample line: {"severity":"INFO","timestamp":"2024-03-05T17:41:13.482Z","message":"[my-project:us-central1:my-db] Listening on 127.0.0.1:5432"}
+	// Sample line: {"severity":"INFO","timestamp":"2024-03-05T17:42:01.157Z","message":"[my-project:us-central1:my-db] Accepted connection from 127.0.0.1:51834"}
+	// Sample line: {"severity":"ERROR","timestamp":"2024-03-05T17:42:09.903Z","message":"[my-project:us-central1:my-db] failed to connect to instance: dial error: context deadline exceeded"}
+	c := confgenerator.LoggingProcessorParseJson{
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "timestamp",
+			TimeFormat: "%Y-%m-%dT%H:%M:%S.%L%z",
+		},
+	}.Components(ctx, tag, uid)
+
+	// Messages about an instance are prefixed with its connection name, and the connection events
+	// are parsed into their own fields, keeping the full message.
+	c = append(c,
+		confgenerator.LoggingProcessorParseRegex{
+			Field:       "message",
+			PreserveKey: true,
+			Regex:       `^\[(?<instance>[^\]]+)\]\s+(?:(?<event>Accepted connection|client closed the connection|instance closed the connection|failed to connect to instance)(?: from (?<client_address>\S+))?)?`,
+		}.Components(ctx, tag, fmt.Sprintf("%s.instance", uid))...,
+	)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				"severity": {
+					MoveFrom: "jsonPayload.severity",
+					MapValues: map[string]string{
+						"DEBUG":   "DEBUG",
+						"INFO":    "INFO",
+						"WARNING": "WARNING",
+						"ERROR":   "ERROR",
+					},
+					MapValuesExclusive: true,
+				},
+				"jsonPayload.connection_event": {
+					MoveFrom: "jsonPayload.event",
+					MapValues: map[string]string{
+						"Accepted connection":            "accepted",
+						"client closed the connection":   "client_closed",
+						"instance closed the connection": "instance_closed",
+						"failed to connect to instance":  "failed",
+					},
+					MapValuesExclusive: true,
+				},
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverCloudSQLProxy struct {
+	LoggingProcessorCloudSQLProxy           `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline"`
+	// The proxy has no default log file; it logs to stdout, which is usually redirected to a file.
+	IncludePaths []string `yaml:"include_paths,omitempty" validate:"required"`
+}
+
+func (r LoggingReceiverCloudSQLProxy) Components(ctx context.Context, tag string) []fluentbit.Component {
+	r.LoggingReceiverFilesMixin.IncludePaths = r.IncludePaths
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	return append(c, r.LoggingProcessorCloudSQLProxy.Components(ctx, tag, "cloudsql_proxy")...)
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorCloudSQLProxy{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverCloudSQLProxy{} })
+}
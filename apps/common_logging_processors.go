@@ -32,7 +32,7 @@ func instrumentationSourceValue(processorType string) *confgenerator.ModifyField
 }
 
 func genericAccessLogParser(ctx context.Context, processorType, tag, uid string) []fluentbit.Component {
-	c := confgenerator.LoggingProcessorParseRegex{
+	return accessLogParser(ctx, confgenerator.LoggingProcessorParseRegex{
 		// Documentation:
 		// https://httpd.apache.org/docs/current/logs.html#accesslog
 		// https://docs.nginx.com/nginx/admin-guide/monitoring/logging/#setting-up-the-access-log
@@ -48,7 +48,13 @@ func genericAccessLogParser(ctx context.Context, processorType, tag, uid string)
 				// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest.FIELDS.response_size
 			},
 		},
-	}.Components(ctx, tag, uid)
+	}, processorType, tag, uid)
+}
+
+// accessLogParser parses access logs with parser and moves the captured http_request_* fields
+// to httpRequest. The parser is expected to capture the fields of the "common" format.
+func accessLogParser(ctx context.Context, parser confgenerator.LoggingProcessorParseRegex, processorType, tag, uid string) []fluentbit.Component {
+	c := parser.Components(ctx, tag, uid)
 	mf := confgenerator.LoggingProcessorModifyFields{
 		Fields: map[string]*confgenerator.ModifyField{
 			InstrumentationSourceLabel: instrumentationSourceValue(processorType),
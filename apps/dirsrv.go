@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+// Logging for 389 Directory Server, whose service and log directory are named dirsrv.
+// Documentation: https://access.redhat.com/documentation/en-us/red_hat_directory_server/12/html/configuration_and_schema_reference/assembly_log-files-reference_config-schema-reference-title
+
+type LoggingProcessorDirsrvAccess struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorDirsrvAccess) Type() string {
+	return "dirsrv_access"
+}
+
+func (p LoggingProcessorDirsrvAccess) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Sample line: [14/Feb/2024:09:12:44.123456789 +0000] conn=2 fd=64 slot=64 connection from 10.128.0.5 to 10.128.0.2
+		// Sample line: [14/Feb/2024:09:12:44.124000000 +0000] conn=2 op=1 SRCH base="dc=example,dc=com" scope=2 filter="(uid=jdoe)" attrs=ALL
+		// Sample line: [14/Feb/2024:09:12:44.126000000 +0000] conn=2 op=1 RESULT err=0 tag=101 nentries=1 wtime=0.000086 optime=0.000212 etime=0.000296
+		Regex: `^\[(?<time>[^\]]+)\] conn=(?<connection_id>\d+) (?:op=(?<operation_id>-?\d+) )?(?<message>.*)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%d/%b/%Y:%H:%M:%S.%L %z",
+			Types: map[string]string{
+				"connection_id": "integer",
+				"operation_id":  "integer",
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverDirsrvAccess struct {
+	LoggingProcessorDirsrvAccess            `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverDirsrvAccess) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// One directory per instance
+			"/var/log/dirsrv/slapd-*/access",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorDirsrvAccess.Components(ctx, tag, "dirsrv_access")...)
+	return c
+}
+
+type LoggingProcessorDirsrvError struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorDirsrvError) Type() string {
+	return "dirsrv_error"
+}
+
+func (p LoggingProcessorDirsrvError) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Sample line: [14/Feb/2024:09:12:40.331145062 +0000] - NOTICE - slapd_daemon - slapd started.  Listening on All Interfaces port 389 for LDAP requests
+		// Sample line: [14/Feb/2024:09:12:41.002341125 +0000] - ERR - NSMMReplicationPlugin - bind_and_check_pwp - agmt="cn=to-ldap-2" (ldap-2:389) - Replication bind with SIMPLE auth failed
+		// Sample line (1.3): [14/Feb/2024:09:12:40 +0000] slapd started.  Listening on All Interfaces port 389 for LDAP requests
+		Regex: `^\[(?<time>[^\]]+)\] (?:- (?<level>[A-Z]+) - (?<subsystem>[^ ]+) - )?(?<message>.*)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%d/%b/%Y:%H:%M:%S.%L %z",
+		},
+	}.Components(ctx, tag, uid)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				"severity": {
+					CopyFrom: "jsonPayload.level",
+					MapValues: map[string]string{
+						"EMERG":   "EMERGENCY",
+						"ALERT":   "ALERT",
+						"CRIT":    "CRITICAL",
+						"ERR":     "ERROR",
+						"WARNING": "WARNING",
+						"NOTICE":  "NOTICE",
+						"INFO":    "INFO",
+						"DEBUG":   "DEBUG",
+					},
+					MapValuesExclusive: true,
+				},
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverDirsrvError struct {
+	LoggingProcessorDirsrvError             `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverDirsrvError) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// One directory per instance
+			"/var/log/dirsrv/slapd-*/errors",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorDirsrvError.Components(ctx, tag, "dirsrv_error")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorDirsrvAccess{} })
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorDirsrvError{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverDirsrvAccess{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverDirsrvError{} })
+}
@@ -328,7 +328,195 @@ func (r LoggingReceiverElasticsearchGC) Components(ctx context.Context, tag stri
 	return append(c, r.LoggingProcessorElasticsearchGC.Components(ctx, tag, "elasticsearch_gc")...)
 }
 
+// elasticsearchSlowlogLuaScript turns the slow logs of the different layouts into the same
+// fields:
+//   - Elasticsearch 8 writes them in the ECS layout, with the fields prefixed with
+//     "elasticsearch.slowlog." and the time in "@timestamp".
+//   - Elasticsearch 7 and OpenSearch write them in the JSON layout, with the fields at the top.
+//   - OpenSearch writes them in the plain text layout by default, with the fields in the message,
+//     e.g. "took[1.2s], took_millis[1200], ...".
+//
+// The node of the plain text layout is moved to "node.name", as in the JSON layout. In every
+// layout, the message starts with the index, and the shard for the search slow logs.
+const elasticsearchSlowlogLuaScript = `
+local slowlog_keys = {"took", "took_millis", "total_hits", "types", "stats", "search_type", "total_shards", "source", "id", "type", "routing"}
+
+-- parse_utc returns the seconds since the epoch of an ISO 8601 time in UTC.
+local function parse_utc(s)
+  local y, mo, d, h, mi, sec, frac = string.match(s, "^(%d+)-(%d+)-(%d+)T(%d+):(%d+):(%d+)[.,]?(%d*)Z$")
+  if y == nil then
+    return nil
+  end
+  -- Days since the epoch of the date, see http://howardhinnant.github.io/date_algorithms.html.
+  y, mo, d = tonumber(y), tonumber(mo), tonumber(d)
+  if mo <= 2 then
+    y = y - 1
+  end
+  local era = math.floor(y / 400)
+  local yoe = y - era * 400
+  local doy = math.floor((153 * ((mo + 9) % 12) + 2) / 5) + d - 1
+  local doe = yoe * 365 + math.floor(yoe / 4) - math.floor(yoe / 100) + doy
+  local t = (era * 146097 + doe - 719468) * 86400 + tonumber(h) * 3600 + tonumber(mi) * 60 + tonumber(sec)
+  if frac ~= "" then
+    t = t + tonumber("0." .. frac)
+  end
+  return t
+end
+
+-- text_field returns the value of key in a message of the plain text layout. The values end
+-- with "], " and the next key, or with "]" at the end of the message.
+local function text_field(msg, key)
+  local v = string.match(msg, "[%s,]" .. key .. "%[(.-)%],%s+[%w_]+%[")
+  if v == nil then
+    v = string.match(msg, "[%s,]" .. key .. "%[(.*)%],?%s*$")
+  end
+  return v
+end
+
+function process(tag, timestamp, record)
+  local code = 2
+  local renames = {}
+  for k, _ in pairs(record) do
+    local name = string.match(k, "^elasticsearch%.slowlog%.(.+)$")
+    if name == nil then
+      local field = string.match(k, "^elasticsearch%.(.+)$")
+      if field ~= nil and (string.match(field, "^cluster%.") or string.match(field, "^node%.")) then
+        name = field
+      end
+    end
+    if k == "log.level" then
+      name = "level"
+    elseif k == "log.logger" then
+      name = "component"
+    elseif k == "node_name" then
+      name = "node.name"
+    end
+    if name ~= nil then
+      renames[k] = name
+    end
+  end
+  for k, name in pairs(renames) do
+    record[name] = record[k]
+    record[k] = nil
+  end
+  if type(record["@timestamp"]) == "string" then
+    local t = parse_utc(record["@timestamp"])
+    if t ~= nil then
+      timestamp = t
+      code = 1
+      record["@timestamp"] = nil
+    end
+  end
+
+  local msg = record["message"]
+  if type(msg) == "string" then
+    -- Search slow logs start with "[index][shard]", indexing slow logs with "[index/uuid]".
+    local index, rest = string.match(msg, "^%[([^%]]+)%](.*)$")
+    if index ~= nil then
+      local name, uuid = string.match(index, "^(.-)/(.+)$")
+      if name ~= nil then
+        index = name
+        record["index_uuid"] = uuid
+      end
+      record["index"] = index
+      local shard = string.match(rest, "^%[(%d+)%]")
+      if shard ~= nil then
+        record["shard"] = tonumber(shard)
+      end
+    end
+    if record["took"] == nil then
+      for _, key in ipairs(slowlog_keys) do
+        local v = text_field(msg, key)
+        if v ~= nil then
+          -- The JSON layout names the type of the document doc_type.
+          if key == "type" then
+            key = "doc_type"
+          end
+          record[key] = v
+        end
+      end
+    end
+  end
+  for _, key in ipairs({"took_millis", "total_shards"}) do
+    local n = tonumber(record[key])
+    if n ~= nil then
+      record[key] = n
+    end
+  end
+  return code, timestamp, record
+end
+`
+
+type LoggingProcessorElasticsearchSlowlog struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorElasticsearchSlowlog) Type() string {
+	return "elasticsearch_slowlog"
+}
+
+func (p LoggingProcessorElasticsearchSlowlog) Components(ctx context.Context, tag, uid string) []fluentbit.Component {
+	// Sample JSON line:
+	// {"type": "index_search_slowlog", "timestamp": "2024-03-11T09:12:45,101Z", "level": "WARN", "component": "i.s.s.query", "cluster.name": "elasticsearch", "node.name": "es-1", "message": "[products][0]", "took": "1.2s", "took_millis": "1204", "total_hits": "12 hits", "stats": "[]", "search_type": "QUERY_THEN_FETCH", "total_shards": "1", "source": "{\"query\":{\"match_all\":{}}}", "id": "" }
+	jsonParser, jsonParserName := confgenerator.ParserShared{
+		TimeKey:    "timestamp",
+		TimeFormat: "%Y-%m-%dT%H:%M:%S,%L%z",
+	}.Component(tag, uid+".0")
+	jsonParser.Config["Format"] = "json"
+
+	// Sample plain text line:
+	// [2024-03-11T09:12:45,101][WARN ][i.s.s.query              ] [os-1] [products][0] took[1.2s], took_millis[1204], total_hits[12 hits], stats[], search_type[QUERY_THEN_FETCH], total_shards[1], source[{"query":{"match_all":{}}}], id[],
+	textParser, textParserName := confgenerator.ParserShared{
+		TimeKey:    "timestamp",
+		TimeFormat: "%Y-%m-%dT%H:%M:%S,%L",
+	}.Component(tag, uid+".1")
+	textParser.Config["Format"] = "regex"
+	textParser.Config["Regex"] = `^\[(?<timestamp>[^\]]+)\]\[(?<level>[A-Z]+)\s*\]\[(?<component>[^\]\s]+)\s*\]\s*\[(?<node_name>[^\]]+)\]\s*(?<message>.*)$`
+
+	c := []fluentbit.Component{jsonParser, textParser}
+	c = append(c, fluentbit.ParserFilterComponents(tag, "message", []string{jsonParserName, textParserName}, false)...)
+	c = append(c, fluentbit.LuaFilterComponents(tag, "process", elasticsearchSlowlogLuaScript)...)
+
+	for _, prefix := range []string{"cluster", "node"} {
+		c = append(c,
+			confgenerator.LoggingProcessorNestWildcard{
+				Wildcard:     fmt.Sprintf("%s.*", prefix),
+				NestUnder:    prefix,
+				RemovePrefix: fmt.Sprintf("%s.", prefix),
+			}.Components(ctx, tag, uid)...,
+		)
+	}
+	c = append(c, LoggingProcessorElasticsearchJson{}.severityParser(ctx, tag, uid)...)
+	return c
+}
+
+// LoggingReceiverElasticsearchSlowlog collects the search and indexing slow logs of Elasticsearch
+// and OpenSearch, with the time that the query or the indexing took, the index and the shard,
+// and the query.
+type LoggingReceiverElasticsearchSlowlog struct {
+	LoggingProcessorElasticsearchSlowlog    `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverElasticsearchSlowlog) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		// Default slow logs of Elasticsearch and OpenSearch
+		r.IncludePaths = []string{
+			"/var/log/elasticsearch/*_index_search_slowlog.json",
+			"/var/log/elasticsearch/*_index_indexing_slowlog.json",
+			"/var/log/opensearch/*_index_search_slowlog.json",
+			"/var/log/opensearch/*_index_indexing_slowlog.json",
+			"/var/log/opensearch/*_index_search_slowlog.log",
+			"/var/log/opensearch/*_index_indexing_slowlog.log",
+		}
+	}
+
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	return append(c, r.LoggingProcessorElasticsearchSlowlog.Components(ctx, tag, "elasticsearch_slowlog")...)
+}
+
 func init() {
 	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverElasticsearchJson{} })
 	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverElasticsearchGC{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverElasticsearchSlowlog{} })
 }
@@ -0,0 +1,206 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	yaml "github.com/goccy/go-yaml"
+)
+
+// externalReceiverAPIVersions lists the plugin API versions this version of
+// the Ops Agent knows how to load. A schema file declares the version it was
+// written against; when the plugin API changes incompatibly, a new version is
+// added here and older ones are kept for as long as they can be supported.
+var externalReceiverAPIVersions = []int{1}
+
+// externalReceiverSchema is the content of the schema file that describes an
+// external receiver.
+//
+// Sample schema file:
+//
+//	api_version: 1
+//	name: sqlquery
+//	component: sqlquery
+//	signals: [metrics]
+//	properties: [driver, datasource, queries]
+type externalReceiverSchema struct {
+	// APIVersion is the plugin API version that the schema was written against.
+	APIVersion int `yaml:"api_version"`
+	// Name identifies the plugin in feature tracking and error messages.
+	Name string `yaml:"name"`
+	// Component is the type of the OTel collector receiver to instantiate. It must
+	// be one of the components that otel_raw allows, since no plugin is loaded at
+	// runtime and only these are built into the collector.
+	Component string `yaml:"component"`
+	// Signals are the kinds of data that the receiver produces. Only "metrics" is
+	// supported, like for otel_raw.
+	Signals []string `yaml:"signals"`
+	// Properties are the only receiver config keys that users may set. They must
+	// be config keys that otel_raw allows for Component.
+	Properties []string `yaml:"properties"`
+}
+
+func readExternalReceiverSchema(ctx context.Context, path string) (*externalReceiverSchema, error) {
+	data, err := confgenerator.ReadInputFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read external receiver schema file %q: %w", path, err)
+	}
+	var s externalReceiverSchema
+	if err := yaml.UnmarshalWithOptions(data, &s, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("failed to parse external receiver schema file %q: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid external receiver schema file %q: %w", path, err)
+	}
+	return &s, nil
+}
+
+func (s externalReceiverSchema) validate() error {
+	supported := false
+	for _, v := range externalReceiverAPIVersions {
+		if s.APIVersion == v {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("plugin %q requires plugin API version %d, but this version of the Ops Agent supports versions %v", s.Name, s.APIVersion, externalReceiverAPIVersions)
+	}
+	if s.Name == "" {
+		return fmt.Errorf("\"name\" is required")
+	}
+	allowedKeys, err := otelRawAllowedKeys(s.Component)
+	if err != nil {
+		return err
+	}
+	if len(s.Signals) == 0 {
+		return fmt.Errorf("\"signals\" must list [metrics]")
+	}
+	for _, signal := range s.Signals {
+		if signal != "metrics" {
+			return fmt.Errorf("signal %q must be one of [metrics]", signal)
+		}
+	}
+	allowed := map[string]bool{}
+	for _, k := range allowedKeys {
+		allowed[k] = true
+	}
+	for _, p := range s.Properties {
+		if !allowed[p] {
+			return fmt.Errorf("property %q is not allowed for component %q; must be one of %v", p, s.Component, allowedKeys)
+		}
+	}
+	return nil
+}
+
+// ReceiverExternal configures an OTel collector receiver that is built into the
+// collector but not wrapped by the Ops Agent. The receiver is described by a schema
+// file, so that teams can expose such a receiver without changing confgenerator.
+//
+// No plugin is loaded at runtime: the component and its config keys are limited to
+// the otel_raw allow-list, and the schema can only narrow them further. The
+// receiver cannot add processors, exporters or extensions, and metrics are always
+// written under the workload.googleapis.com domain.
+type ReceiverExternal struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	SchemaFile string                 `yaml:"schema_file" validate:"required"`
+	Config     map[string]interface{} `yaml:"config"`
+}
+
+func (ReceiverExternal) Type() string {
+	return "external"
+}
+
+func (r ReceiverExternal) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
+	schema, err := readExternalReceiverSchema(ctx, r.SchemaFile)
+	if err != nil {
+		return nil, err
+	}
+	allowed := map[string]bool{}
+	for _, p := range schema.Properties {
+		allowed[p] = true
+	}
+	var keys []string
+	for k := range r.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !allowed[k] {
+			return nil, fmt.Errorf("config key %q is not supported by plugin %q; supported keys are %v", k, schema.Name, schema.Properties)
+		}
+	}
+
+	pipeline := otel.ReceiverPipeline{
+		Receiver: otel.Component{
+			Type:   schema.Component,
+			Config: r.Config,
+		},
+		Processors:             map[string][]otel.Component{},
+		ExporterTypes:          map[string]otel.ExporterType{},
+		ResourceDetectionModes: map[string]otel.ResourceDetectionMode{},
+	}
+	for _, signal := range schema.Signals {
+		pipeline.ExporterTypes[signal] = otel.OTel
+		pipeline.ResourceDetectionModes[signal] = otel.SetIfMissing
+		pipeline.Processors[signal] = []otel.Component{
+			otel.MetricsTransform(
+				otel.AddPrefix("workload.googleapis.com"),
+			),
+			otel.ModifyInstrumentationScope(r.Type()+"/"+schema.Name, strconv.Itoa(schema.APIVersion)),
+		}
+	}
+	return []otel.ReceiverPipeline{pipeline}, nil
+}
+
+// ExtractFeatures returns the plugin that is in use, so that we know which
+// external receivers are popular enough to be wrapped natively.
+func (r ReceiverExternal) ExtractFeatures() ([]confgenerator.CustomFeature, error) {
+	features := []confgenerator.CustomFeature{{
+		Key:   []string{"enabled"},
+		Value: "true",
+	}}
+	schema, err := readExternalReceiverSchema(context.Background(), r.SchemaFile)
+	if err != nil {
+		// The config failed to load in this case, so there is nothing more to track.
+		return features, nil
+	}
+	features = append(features,
+		confgenerator.CustomFeature{Key: []string{"name"}, Value: schema.Name},
+		confgenerator.CustomFeature{Key: []string{"api_version"}, Value: strconv.Itoa(schema.APIVersion)},
+		confgenerator.CustomFeature{Key: []string{"config", "__length"}, Value: strconv.Itoa(len(r.Config))},
+	)
+	return features, nil
+}
+
+// ListAllFeatures returns a list of all features that the receiver supports that we track.
+func (r ReceiverExternal) ListAllFeatures() []string {
+	return []string{
+		"confgenerator.ConfigComponent.Type",
+		"name",
+		"api_version",
+		"config.__length",
+	}
+}
+
+func init() {
+	confgenerator.CombinedReceiverTypes.RegisterType(func() confgenerator.CombinedReceiver { return &ReceiverExternal{} })
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExternalReceiverSchemaValidate(t *testing.T) {
+	valid := externalReceiverSchema{
+		APIVersion: 1,
+		Name:       "sqlquery",
+		Component:  "sqlquery",
+		Signals:    []string{"metrics"},
+		Properties: []string{"driver", "datasource"},
+	}
+	for _, tc := range []struct {
+		name    string
+		modify  func(*externalReceiverSchema)
+		wantErr string
+	}{
+		{name: "valid", modify: func(*externalReceiverSchema) {}},
+		{
+			name:    "unsupported API version",
+			modify:  func(s *externalReceiverSchema) { s.APIVersion = 2 },
+			wantErr: "requires plugin API version 2",
+		},
+		{
+			name:    "component not built into the collector",
+			modify:  func(s *externalReceiverSchema) { s.Component = "my_custom_receiver" },
+			wantErr: `component "my_custom_receiver" is not built into the collector`,
+		},
+		{
+			name:    "traces",
+			modify:  func(s *externalReceiverSchema) { s.Signals = []string{"metrics", "traces"} },
+			wantErr: `signal "traces" must be one of [metrics]`,
+		},
+		{
+			name:    "property not allowed for the component",
+			modify:  func(s *externalReceiverSchema) { s.Properties = append(s.Properties, "exporters") },
+			wantErr: `property "exporters" is not allowed for component "sqlquery"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := valid
+			s.Properties = append([]string(nil), valid.Properties...)
+			tc.modify(&s)
+			err := s.validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("validate() = %v, want an error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReceiverExternalPipelines(t *testing.T) {
+	schemaFile := filepath.Join(t.TempDir(), "schema.yaml")
+	schema := "api_version: 1\nname: sqlquery\ncomponent: sqlquery\nsignals: [metrics]\nproperties: [driver, datasource]\n"
+	if err := os.WriteFile(schemaFile, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := ReceiverExternal{
+		SchemaFile: schemaFile,
+		Config:     map[string]interface{}{"driver": "postgres"},
+	}
+	pipelines, err := r.Pipelines(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipelines) != 1 || pipelines[0].Receiver.Type != "sqlquery" {
+		t.Fatalf("Pipelines() = %+v, want one sqlquery pipeline", pipelines)
+	}
+	if _, ok := pipelines[0].Processors["metrics"]; !ok {
+		t.Errorf("Pipelines() has no metrics processors")
+	}
+
+	// The schema narrows the keys that otel_raw allows for the component.
+	r.Config["queries"] = []interface{}{}
+	if _, err := r.Pipelines(context.Background()); err == nil {
+		t.Errorf("Pipelines() accepted a config key that is not in the schema")
+	}
+}
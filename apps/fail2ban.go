@@ -0,0 +1,89 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorFail2ban struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorFail2ban) Type() string {
+	return "fail2ban"
+}
+
+func (p LoggingProcessorFail2ban) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Sample line: 2024-02-14 09:12:44,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.5 - 2024-02-14 09:12:44
+		// Sample line: 2024-02-14 09:12:45,456 fail2ban.actions        [1234]: NOTICE  [sshd] Ban 203.0.113.5
+		// Sample line: 2024-02-14 09:12:40,001 fail2ban.server         [1234]: INFO    Starting Fail2ban v1.0.2
+		Regex: `^(?<time>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3}) (?<component>\S+)\s+\[(?<pid>\d+)\]: (?<level>[A-Z]+)\s+(?:\[(?<jail>[^\]]+)\] )?(?<message>(?:(?<action>Found|Ban|Unban|Restore Ban|Increase Ban) (?<source_ip>[^\s]+))?.*)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%Y-%m-%d %H:%M:%S,%L",
+			Types: map[string]string{
+				"pid": "integer",
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				"severity": {
+					CopyFrom: "jsonPayload.level",
+					MapValues: map[string]string{
+						"CRITICAL": "CRITICAL",
+						"ERROR":    "ERROR",
+						"WARNING":  "WARNING",
+						"NOTICE":   "NOTICE",
+						"INFO":     "INFO",
+						"DEBUG":    "DEBUG",
+					},
+					MapValuesExclusive: true,
+				},
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverFail2ban struct {
+	LoggingProcessorFail2ban                `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverFail2ban) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			"/var/log/fail2ban.log",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorFail2ban.Components(ctx, tag, "fail2ban")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorFail2ban{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverFail2ban{} })
+}
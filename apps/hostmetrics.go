@@ -16,6 +16,7 @@ package apps
 
 import (
 	"context"
+	"time"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
@@ -27,6 +28,11 @@ type MetricsReceiverHostmetrics struct {
 
 	confgenerator.MetricsReceiverShared `yaml:",inline"`
 
+	// PerformanceMetrics collects the pressure stall information, the hugepages and the memory of
+	// the NUMA nodes on Linux. The diagnostics service collects them, because the OTel collector
+	// has no scraper for them. It is ignored on Windows.
+	PerformanceMetrics bool `yaml:"performance_metrics,omitempty"`
+
 	disableGPUMetrics bool `yaml:"-"`
 }
 
@@ -34,6 +40,14 @@ func (r MetricsReceiverHostmetrics) Type() string {
 	return "hostmetrics"
 }
 
+func (r MetricsReceiverHostmetrics) DiagnosticsHostMetrics() (time.Duration, bool) {
+	interval, err := time.ParseDuration(r.CollectionIntervalString())
+	if err != nil {
+		interval = time.Minute
+	}
+	return interval, r.PerformanceMetrics
+}
+
 func (r MetricsReceiverHostmetrics) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
 	p := platform.FromContext(ctx)
 	processConfig := map[string]interface{}{
@@ -47,6 +61,18 @@ func (r MetricsReceiverHostmetrics) Pipelines(ctx context.Context) ([]otel.Recei
 			},
 		}
 	}
+	networkConfig := map[string]interface{}{}
+	if p.Type == platform.Linux {
+		// The conntrack table drops new connections silently once it is full.
+		networkConfig["metrics"] = map[string]interface{}{
+			"system.network.conntrack.count": map[string]interface{}{
+				"enabled": true,
+			},
+			"system.network.conntrack.max": map[string]interface{}{
+				"enabled": true,
+			},
+		}
+	}
 	transforms := []map[string]interface{}{
 		otel.RenameMetric(
 			"system.cpu.time",
@@ -283,6 +309,19 @@ func (r MetricsReceiverHostmetrics) Pipelines(ctx context.Context) ([]otel.Recei
 			),
 		)
 	}
+	if p.Type == platform.Linux {
+		transforms = append(
+			transforms,
+			otel.RenameMetric(
+				"system.network.conntrack.count",
+				"network/conntrack/count",
+			),
+			otel.RenameMetric(
+				"system.network.conntrack.max",
+				"network/conntrack/max",
+			),
+		)
+	}
 	transforms = append(transforms, otel.AddPrefix("agent.googleapis.com"))
 	pipelines := []otel.ReceiverPipeline{{
 		Receiver: otel.Component{
@@ -295,7 +334,7 @@ func (r MetricsReceiverHostmetrics) Pipelines(ctx context.Context) ([]otel.Recei
 					"memory":     struct{}{},
 					"disk":       struct{}{},
 					"filesystem": struct{}{},
-					"network":    struct{}{},
+					"network":    networkConfig,
 					"paging":     struct{}{},
 					"process":    processConfig,
 					"processes":  struct{}{},
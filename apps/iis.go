@@ -268,3 +268,108 @@ func init() {
 	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverIisAccess{} }, platform.Windows)
 	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorIisAccess{} }, platform.Windows)
 }
+
+type LoggingProcessorIisRequestTracing struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (*LoggingProcessorIisRequestTracing) Type() string {
+	return "windows_iis_request_tracing"
+}
+
+func (p *LoggingProcessorIisRequestTracing) Components(ctx context.Context, tag, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseMultilineRegex{
+		LoggingProcessorParseRegexComplex: confgenerator.LoggingProcessorParseRegexComplex{
+			Parsers: []confgenerator.RegexParser{
+				{
+					// Documentation:
+					// https://learn.microsoft.com/en-us/iis/troubleshoot/using-failed-request-tracing/troubleshooting-failed-requests-using-tracing-in-iis
+					// Each Failed Request Tracing file is a single XML document, e.g.
+					// <?xml version="1.0" encoding="UTF-8"?>
+					// <failedRequest url="http://localhost:80/app/"
+					//                siteId="1"
+					//                appPoolId="DefaultAppPool"
+					//                processId="5776"
+					//                verb="GET"
+					//                ...
+					//                failureReason="STATUS_CODE"
+					//                statusCode="500.19"
+					//                triggerStatusCode="500"
+					//                timeTaken="16"
+					//                ...>
+					//  <Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">
+					//   <System>
+					//    ...
+					//    <TimeCreated SystemTime="2022-03-10T17:26:30.529Z"/>
+					//   ...
+					// The module that failed the request is the one that set the error status.
+					Regex: `^<\?xml[\s\S]*?<failedRequest\s+url="(?<http_request_requestUrl>[^"]*)"\s+siteId="(?<site_id>[^"]*)"\s+appPoolId="(?<app_pool>[^"]*)"\s+processId="(?<process_id>[^"]*)"\s+verb="(?<http_request_requestMethod>[^"]*)"[\s\S]*?authenticationType="(?<authentication_type>[^"]*)"\s+activityId="(?<activity_id>[^"]*)"\s+failureReason="(?<failure_reason>[^"]*)"\s+statusCode="(?<http_request_status>\d+)(?:\.(?<sc_substatus>\d+))?"\s+triggerStatusCode="(?<trigger_status_code>[^"]*)"\s+timeTaken="(?<time_taken>\d+)"[\s\S]*?<TimeCreated SystemTime="(?<timestamp>[^"]+)"(?:[\s\S]*<Data Name="ModuleName">(?<module>[^<]*)</Data>(?:(?!</Event>)[\s\S])*<Opcode>MODULE_SET_RESPONSE_ERROR_STATUS</Opcode>)?`,
+					Parser: confgenerator.ParserShared{
+						TimeKey:    "timestamp",
+						TimeFormat: "%Y-%m-%dT%H:%M:%S.%L%z",
+						Types: map[string]string{
+							"http_request_status": "integer",
+							"time_taken":          "integer",
+						},
+					},
+				},
+			},
+		},
+		Rules: []confgenerator.MultilineRule{
+			{
+				StateName: "start_state",
+				NextState: "cont",
+				Regex:     `^<\?xml`,
+			},
+			{
+				StateName: "cont",
+				NextState: "cont",
+				Regex:     `^(?!<\?xml)`,
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	// Failed requests are only traced when they match a failure definition.
+	severityVal := "WARNING"
+	fields := map[string]*confgenerator.ModifyField{
+		InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+		"severity":                 {StaticValue: &severityVal},
+	}
+	for _, field := range []string{
+		"requestMethod",
+		"requestUrl",
+		"status",
+	} {
+		fields[fmt.Sprintf("httpRequest.%s", field)] = &confgenerator.ModifyField{
+			MoveFrom: fmt.Sprintf("jsonPayload.http_request_%s", field),
+		}
+	}
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: fields,
+		}.Components(ctx, tag, uid)...,
+	)
+	return c
+}
+
+type LoggingReceiverIisRequestTracing struct {
+	LoggingProcessorIisRequestTracing       `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverIisRequestTracing) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			`C:\inetpub\logs\FailedReqLogFiles\W3SVC*\fr*.xml`,
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorIisRequestTracing.Components(ctx, tag, "windows_iis_request_tracing")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverIisRequestTracing{} }, platform.Windows)
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorIisRequestTracing{} }, platform.Windows)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+const (
+	defaultJaegerThriftHTTPEndpoint    = "0.0.0.0:14268"
+	defaultJaegerThriftCompactEndpoint = "0.0.0.0:6831"
+)
+
+// ReceiverJaeger accepts spans from Jaeger clients in the Thrift format, over HTTP and over the
+// compact protocol on UDP, and sends them to Cloud Trace.
+type ReceiverJaeger struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	ThriftHTTPEndpoint    string `yaml:"thrift_http_endpoint" validate:"omitempty,hostname_port" tracking:"endpoint"`
+	ThriftCompactEndpoint string `yaml:"thrift_compact_endpoint" validate:"omitempty,hostname_port" tracking:"endpoint"`
+}
+
+func (r ReceiverJaeger) Type() string {
+	return "jaeger"
+}
+
+func (r ReceiverJaeger) TracesOnly() {}
+
+func (r ReceiverJaeger) thriftHTTPEndpoint() string {
+	if r.ThriftHTTPEndpoint == "" {
+		return defaultJaegerThriftHTTPEndpoint
+	}
+	return r.ThriftHTTPEndpoint
+}
+
+func (r ReceiverJaeger) thriftCompactEndpoint() string {
+	if r.ThriftCompactEndpoint == "" {
+		return defaultJaegerThriftCompactEndpoint
+	}
+	return r.ThriftCompactEndpoint
+}
+
+// ListenAddress returns the HTTP endpoint. The UDP endpoint is not checked, since a port that is
+// taken over UDP does not stop the collector from starting.
+func (r ReceiverJaeger) ListenAddress() (string, string, uint16) {
+	// The endpoint is validated to be a hostname_port.
+	host, port, _ := net.SplitHostPort(r.thriftHTTPEndpoint())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return "tcp", host, uint16(p)
+}
+
+func (r ReceiverJaeger) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
+	return []otel.ReceiverPipeline{{
+		ExporterTypes: map[string]otel.ExporterType{
+			"traces": otel.OTel,
+		},
+		Receiver: otel.Component{
+			Type: "jaeger",
+			Config: map[string]interface{}{
+				"protocols": map[string]interface{}{
+					"thrift_http": map[string]interface{}{
+						"endpoint": r.thriftHTTPEndpoint(),
+					},
+					"thrift_compact": map[string]interface{}{
+						"endpoint": r.thriftCompactEndpoint(),
+					},
+				},
+			},
+		},
+		Processors: map[string][]otel.Component{
+			"traces": nil,
+		},
+		ResourceDetectionModes: map[string]otel.ResourceDetectionMode{
+			"traces": otel.SetIfMissing,
+		},
+	}}, nil
+}
+
+func init() {
+	confgenerator.CombinedReceiverTypes.RegisterType(func() confgenerator.CombinedReceiver { return &ReceiverJaeger{} })
+}
@@ -16,18 +16,47 @@ package apps
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
 )
 
 type MetricsProcessorExcludeMetrics struct {
 	confgenerator.ConfigComponent `yaml:",inline"`
 
 	MetricsPattern []string `yaml:"metrics_pattern,flow"`
+	// ExcludeSeries drops the time series of the matching metrics, rather than whole metrics.
+	ExcludeSeries []MetricsSeriesMatcher `yaml:"exclude_series,omitempty" validate:"dive"`
+}
+
+// MetricsSeriesMatcher matches the time series whose labels match all of LabelMatchers.
+type MetricsSeriesMatcher struct {
+	// MetricsPattern is a glob pattern of the metrics to match; all metrics if empty.
+	MetricsPattern string `yaml:"metrics_pattern,omitempty"`
+	// LabelMatchers are label=glob or label!=glob expressions, e.g. device=/dev/loop*.
+	LabelMatchers []string `yaml:"label_matchers,flow" validate:"required,min=1,dive,label_matcher"`
+}
+
+// expression returns the OTTL condition that matches the data points of m.
+func (m MetricsSeriesMatcher) expression() ottl.Value {
+	var conditions []ottl.Value
+	if m.MetricsPattern != "" {
+		conditions = append(conditions, ottl.IsMatch(ottl.LValue{"metric.name"}, escapeDollar(globToRegex(m.MetricsPattern))))
+	}
+	for _, lm := range m.LabelMatchers {
+		parts := confgenerator.LabelMatcherRegexp.FindStringSubmatch(lm)
+		c := ottl.IsMatch(ottl.LValue{"attributes", parts[1]}, escapeDollar(globToRegex(parts[3])))
+		if parts[2] == "!=" {
+			c = ottl.Not(c)
+		}
+		conditions = append(conditions, c)
+	}
+	return ottl.And(conditions...)
 }
 
 func (p MetricsProcessorExcludeMetrics) Type() string {
@@ -35,17 +64,32 @@ func (p MetricsProcessorExcludeMetrics) Type() string {
 }
 
 func (p MetricsProcessorExcludeMetrics) Processors(_ context.Context) ([]otel.Component, error) {
-	var metricNames []string
-	for _, glob := range p.MetricsPattern {
-		// $ needs to be escaped because reasons.
-		// https://github.com/open-telemetry/opentelemetry-collector-contrib/tree/main/processor/metricstransformprocessor#rename-multiple-metrics-using-substitution
-		metricNames = append(metricNames, strings.Replace(globToRegex(glob), "$", "$$", -1))
+	var processors []otel.Component
+	if len(p.MetricsPattern) > 0 || len(p.ExcludeSeries) == 0 {
+		var metricNames []string
+		for _, glob := range p.MetricsPattern {
+			metricNames = append(metricNames, escapeDollar(globToRegex(glob)))
+		}
+		processors = append(processors, otel.MetricsFilter(
+			"exclude",
+			"regexp",
+			metricNames...,
+		))
+	}
+	if len(p.ExcludeSeries) > 0 {
+		var expressions []ottl.Value
+		for _, m := range p.ExcludeSeries {
+			expressions = append(expressions, m.expression())
+		}
+		processors = append(processors, otel.Filter("metrics", "datapoint", expressions))
 	}
-	return []otel.Component{otel.MetricsFilter(
-		"exclude",
-		"regexp",
-		metricNames...,
-	)}, nil
+	return processors, nil
+}
+
+// escapeDollar escapes $ in a regular expression, because reasons.
+// https://github.com/open-telemetry/opentelemetry-collector-contrib/tree/main/processor/metricstransformprocessor#rename-multiple-metrics-using-substitution
+func escapeDollar(pattern string) string {
+	return strings.Replace(pattern, "$", "$$", -1)
 }
 
 // globToRegex converts metrics glob patterns to regex patterns
@@ -73,6 +117,106 @@ OUTER:
 	return true
 }
 
+// MetricsProcessorAggregateMetrics aggregates away the labels of the matching metrics other than
+// KeepLabels, e.g. to collapse the per-CPU or per-device time series of a metric into one per host.
+type MetricsProcessorAggregateMetrics struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	MetricsPattern []string `yaml:"metrics_pattern,flow" validate:"required,min=1,dive,required"`
+	KeepLabels     []string `yaml:"keep_labels,omitempty,flow" validate:"dive,required"`
+	// Aggregation is how the values of the aggregated time series are combined; sum if empty.
+	Aggregation string `yaml:"aggregation,omitempty" validate:"omitempty,oneof=sum mean min max" tracking:""`
+}
+
+func (p MetricsProcessorAggregateMetrics) Type() string {
+	return "aggregate_metrics"
+}
+
+func (p MetricsProcessorAggregateMetrics) Processors(_ context.Context) ([]otel.Component, error) {
+	aggregation := p.Aggregation
+	if aggregation == "" {
+		aggregation = "sum"
+	}
+	// An empty label set aggregates all the time series of a metric into one.
+	keepLabels := append([]string{}, p.KeepLabels...)
+	var transforms []map[string]interface{}
+	for _, glob := range p.MetricsPattern {
+		transforms = append(transforms, otel.UpdateMetricRegexp(
+			escapeDollar(globToRegex(glob)),
+			otel.AggregateLabels(aggregation, keepLabels...),
+		))
+	}
+	return []otel.Component{otel.MetricsTransform(transforms...)}, nil
+}
+
+// sloMetricPrefix is the prefix of the metrics produced by MetricsProcessorSLOMetrics.
+const sloMetricPrefix = "workload.googleapis.com/slo."
+
+// SLOSourceMetric selects the metric of an application that an SLO metric is copied from.
+type SLOSourceMetric struct {
+	Metric string `yaml:"metric" validate:"required"`
+	// ResponseCodeLabel is the label of Metric that holds the response code, if any.
+	ResponseCodeLabel string `yaml:"response_code_label,omitempty"`
+}
+
+// MetricsProcessorSLOMetrics copies the request count and latency metrics of an application to
+// metrics with canonical names and labels, modeled after the request_count and total_latencies
+// metrics of Cloud Load Balancing. This lets SLOs be defined the same way for every application.
+// Latency must be a histogram; its unit is kept.
+type MetricsProcessorSLOMetrics struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	ServiceName  string           `yaml:"service_name" validate:"required"`
+	RequestCount *SLOSourceMetric `yaml:"request_count,omitempty"`
+	Latency      *SLOSourceMetric `yaml:"latency,omitempty"`
+}
+
+func (p MetricsProcessorSLOMetrics) Type() string {
+	return "slo_metrics"
+}
+
+func (p MetricsProcessorSLOMetrics) Processors(_ context.Context) ([]otel.Component, error) {
+	if p.RequestCount == nil && p.Latency == nil {
+		return nil, errors.New(`at least one of "request_count" or "latency" must be set`)
+	}
+	var transforms []map[string]interface{}
+	var queries []otel.TransformQuery
+	for _, m := range []struct {
+		name   string
+		source *SLOSourceMetric
+	}{
+		{sloMetricPrefix + "request_count", p.RequestCount},
+		{sloMetricPrefix + "latencies", p.Latency},
+	} {
+		if m.source == nil {
+			continue
+		}
+		var operations []map[string]interface{}
+		labels := []string{"service"}
+		if m.source.ResponseCodeLabel != "" {
+			operations = append(operations, otel.RenameLabel(m.source.ResponseCodeLabel, "response_code"))
+			labels = append(labels, "response_code")
+			// The class is derived after the labels are aggregated, e.g. 404 becomes 4xx.
+			queries = append(queries, otel.TransformQuery{
+				Context:   otel.Datapoint,
+				Statement: fmt.Sprintf(`set(attributes["response_code_class"], Concat([Substring(Concat([attributes["response_code"]], ""), 0, 1), "xx"], "")) where metric.name == %q and attributes["response_code"] != nil`, m.name),
+			})
+		}
+		operations = append(operations,
+			otel.AddLabel("service", p.ServiceName),
+			otel.AggregateLabels("sum", labels...),
+		)
+		transforms = append(transforms, otel.DuplicateMetric(m.source.Metric, m.name, operations...))
+	}
+	processors := []otel.Component{otel.MetricsTransform(transforms...)}
+	if len(queries) > 0 {
+		processors = append(processors, otel.TransformationMetrics(queries...))
+	}
+	return processors, nil
+}
+
 func init() {
 	confgenerator.MetricsProcessorTypes.RegisterType(func() confgenerator.MetricsProcessor { return &MetricsProcessorExcludeMetrics{} })
+	confgenerator.MetricsProcessorTypes.RegisterType(func() confgenerator.MetricsProcessor { return &MetricsProcessorSLOMetrics{} })
+	confgenerator.MetricsProcessorTypes.RegisterType(func() confgenerator.MetricsProcessor { return &MetricsProcessorAggregateMetrics{} })
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
 )
@@ -28,13 +29,27 @@ type MetricsReceiverMssql struct {
 	confgenerator.MetricsReceiverShared `yaml:",inline"`
 
 	confgenerator.VersionedReceivers `yaml:",inline"`
+
+	// AvailabilityGroups enables the Always On availability group metrics.
+	AvailabilityGroups bool `yaml:"availability_groups,omitempty"`
 }
 
 func (MetricsReceiverMssql) Type() string {
 	return "mssql"
 }
 
-func (m MetricsReceiverMssql) Pipelines(_ context.Context) ([]otel.ReceiverPipeline, error) {
+func (m MetricsReceiverMssql) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
+	pipelines, err := m.pipelines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if m.AvailabilityGroups {
+		pipelines = append(pipelines, m.availabilityGroupPipeline())
+	}
+	return pipelines, nil
+}
+
+func (m MetricsReceiverMssql) pipelines(_ context.Context) ([]otel.ReceiverPipeline, error) {
 	if m.ReceiverVersion == "2" {
 		return []otel.ReceiverPipeline{{
 			Receiver: otel.Component{
@@ -106,6 +121,106 @@ func (m MetricsReceiverMssql) Pipelines(_ context.Context) ([]otel.ReceiverPipel
 	}}, nil
 }
 
+// availabilityGroupPipeline reads the Always On availability group counters of each database
+// replica hosted by the instance. Neither the sqlserver receiver nor the v1 counters include them.
+func (m MetricsReceiverMssql) availabilityGroupPipeline() otel.ReceiverPipeline {
+	scopeVersion := "1.0"
+	if m.ReceiverVersion == "2" {
+		scopeVersion = "2.0"
+	}
+	return otel.ReceiverPipeline{
+		Receiver: otel.Component{
+			Type: "windowsperfcounters",
+			Config: map[string]interface{}{
+				"collection_interval": m.CollectionIntervalString(),
+				"perfcounters": []map[string]interface{}{
+					{
+						"object":    "SQLServer:Database Replica",
+						"instances": []string{"*"},
+						"counters": []map[string]string{
+							{"name": "Log Send Queue"},
+							{"name": "Recovery Queue"},
+							{"name": "Redo blocked/sec"},
+						},
+					},
+					{
+						"object":    "SQLServer:Availability Replica",
+						"instances": []string{"*"},
+						"counters": []map[string]string{
+							{"name": "Bytes Received from Replica/sec"},
+							{"name": "Bytes Sent to Replica/sec"},
+						},
+					},
+				},
+			},
+		},
+		Processors: map[string][]otel.Component{"metrics": {
+			otel.MetricsTransform(
+				// $ needs to be escaped because reasons.
+				// https://github.com/open-telemetry/opentelemetry-collector-contrib/tree/main/processor/metricstransformprocessor#rename-multiple-metrics-using-substitution
+				otel.CombineMetrics(
+					`^\\SQLServer:Database Replica\((?P<database>.*)\)\\Log Send Queue$$`,
+					"sqlserver.availability_group.log_send_queue.size",
+				),
+				otel.CombineMetrics(
+					`^\\SQLServer:Database Replica\((?P<database>.*)\)\\Recovery Queue$$`,
+					"sqlserver.availability_group.redo_queue.size",
+				),
+				otel.CombineMetrics(
+					`^\\SQLServer:Database Replica\((?P<database>.*)\)\\Redo blocked/sec$$`,
+					"sqlserver.availability_group.redo.blocked.rate",
+				),
+				otel.CombineMetrics(
+					`^\\SQLServer:Availability Replica\((?P<replica>.*)\)\\Bytes (?P<direction>Received|Sent) (?:from|to) Replica/sec$$`,
+					"sqlserver.availability_group.replica.network.io.rate",
+				),
+				otel.AddPrefix("workload.googleapis.com"),
+			),
+			otel.ModifyInstrumentationScope(m.Type(), scopeVersion),
+		}},
+	}
+}
+
 func init() {
 	confgenerator.MetricsReceiverTypes.RegisterType(func() confgenerator.MetricsReceiver { return &MetricsReceiverMssql{} }, platform.Windows)
 }
+
+// LoggingReceiverMssqlAvailabilityGroup reads the availability group role and state changes that
+// SQL Server writes to the Application event log.
+type LoggingReceiverMssqlAvailabilityGroup struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (r LoggingReceiverMssqlAvailabilityGroup) Type() string {
+	return "mssql_availability_group"
+}
+
+// Documentation:
+// https://learn.microsoft.com/en-us/sql/database-engine/availability-groups/windows/monitoring-of-availability-groups-sql-server
+// 1480: a database changes roles, e.g. after a failover.
+// 19406: the state of the local availability replica changed.
+// 35264, 35265: data movement for a database was suspended or resumed.
+const mssqlAvailabilityGroupEventQuery = `*[System[(EventID=1480 or EventID=19406 or EventID=35264 or EventID=35265)]]`
+
+func (r LoggingReceiverMssqlAvailabilityGroup) Components(ctx context.Context, tag string) []fluentbit.Component {
+	l := confgenerator.LoggingReceiverWindowsEventLog{
+		Channels:        []string{"Application"},
+		ReceiverVersion: "2",
+	}
+	c := l.Components(ctx, tag)
+	// The first component is the winevtlog input.
+	c[0].Config["Event_Query"] = mssqlAvailabilityGroupEventQuery
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(r.Type()),
+			},
+		}.Components(ctx, tag, "mssql_availability_group")...,
+	)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverMssqlAvailabilityGroup{} }, platform.Windows)
+}
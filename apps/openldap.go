@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorOpenLDAP struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (LoggingProcessorOpenLDAP) Type() string {
+	return "openldap"
+}
+
+func (p LoggingProcessorOpenLDAP) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// slapd logs through syslog with the LOCAL4 facility, which is usually written to its own file.
+		// Documentation: https://www.openldap.org/doc/admin26/slapdconf2.html#olcLogLevel
+		// Sample line: Feb 14 09:12:44 ldap-1 slapd[1234]: conn=1000 fd=12 ACCEPT from IP=10.128.0.5:51234 (IP=0.0.0.0:389)
+		// Sample line: Feb 14 09:12:44 ldap-1 slapd[1234]: conn=1000 op=1 SRCH base="dc=example,dc=com" scope=2 deref=0 filter="(uid=jdoe)"
+		// Sample line: Feb 14 09:12:44 ldap-1 slapd[1234]: slapd starting
+		Regex: `^(?<time>\w{3}\s+\d{1,2}\s+\d{2}:\d{2}:\d{2})\s+(?<host>[^\s]+)\s+slapd\[(?<pid>\d+)\]:\s+(?:conn=(?<connection_id>\d+)\s+(?:op=(?<operation_id>\d+)\s+)?)?(?<message>.*)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%b %d %H:%M:%S",
+			Types: map[string]string{
+				"pid":           "integer",
+				"connection_id": "integer",
+				"operation_id":  "integer",
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+			},
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverOpenLDAP struct {
+	LoggingProcessorOpenLDAP                `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverOpenLDAP) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// Common rsyslog destination for the LOCAL4 facility
+			"/var/log/slapd.log",
+			"/var/log/openldap/slapd.log",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorOpenLDAP.Components(ctx, tag, "openldap")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorOpenLDAP{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverOpenLDAP{} })
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// otelRawAllowedComponents is the vetted allow-list of upstream OTel receivers that are
+// built into the collector but not wrapped by a dedicated receiver type, mapped to the
+// config keys that users are allowed to set on them.
+// collection_interval is always set from the Ops Agent's own field.
+//
+// The collector is built in the opentelemetry-operations-collector submodule, which is not
+// changed here. A component can only be added to this list once that build includes it;
+// TestOTelRawAllowedComponentsAreBuilt checks this against the submodule.
+var otelRawAllowedComponents = map[string][]string{
+	"filestats": {"include", "metrics", "resource_attributes"},
+	"haproxy":   {"endpoint", "metrics"},
+	"httpcheck": {"targets", "metrics"},
+	"sqlquery":  {"driver", "datasource", "queries"},
+}
+
+// otelRawAllowedKeys returns the config keys that are allowed for component, or an error
+// if the component is not in otelRawAllowedComponents.
+func otelRawAllowedKeys(component string) ([]string, error) {
+	allowedKeys, ok := otelRawAllowedComponents[component]
+	if !ok {
+		var components []string
+		for c := range otelRawAllowedComponents {
+			components = append(components, c)
+		}
+		sort.Strings(components)
+		return nil, fmt.Errorf("component %q is not built into the collector; must be one of %v", component, components)
+	}
+	return allowedKeys, nil
+}
+
+// MetricsReceiverOTelRaw passes its config through to an upstream OTel receiver that
+// has not been wrapped yet. These receivers are not supported by Google; the metrics
+// they produce are labeled as such via their instrumentation scope.
+type MetricsReceiverOTelRaw struct {
+	confgenerator.ConfigComponent       `yaml:",inline"`
+	confgenerator.MetricsReceiverShared `yaml:",inline"`
+
+	Component string                 `yaml:"component" validate:"required" tracking:""`
+	Config    map[string]interface{} `yaml:"config"`
+}
+
+func (MetricsReceiverOTelRaw) Type() string {
+	return "otel_raw"
+}
+
+// UnmarshalYAML checks the component and its config keys against the allow-list, so that
+// invalid configs are rejected when the config is validated. It also warns that the component
+// is not supported by Google. The warning is logged once when the config is validated, instead
+// of every time the pipelines are generated.
+func (r *MetricsReceiverOTelRaw) UnmarshalYAML(ctx context.Context, unmarshal func(interface{}) error) error {
+	type rawMetricsReceiverOTelRaw MetricsReceiverOTelRaw
+	if err := unmarshal((*rawMetricsReceiverOTelRaw)(r)); err != nil {
+		return err
+	}
+	allowedKeys, err := otelRawAllowedKeys(r.Component)
+	if err != nil {
+		return err
+	}
+	allowed := map[string]bool{}
+	for _, k := range allowedKeys {
+		allowed[k] = true
+	}
+	var keys []string
+	for k := range r.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !allowed[k] {
+			return fmt.Errorf("config key %q is not allowed for component %q; must be one of %v", k, r.Component, allowedKeys)
+		}
+	}
+	log.Printf("Receiver type %q with component %q is not supported by Google; use at your own risk.", r.Type(), r.Component)
+	return nil
+}
+
+func (r MetricsReceiverOTelRaw) Pipelines(_ context.Context) ([]otel.ReceiverPipeline, error) {
+	config := map[string]interface{}{
+		"collection_interval": r.CollectionIntervalString(),
+	}
+	for k, v := range r.Config {
+		config[k] = v
+	}
+	return []otel.ReceiverPipeline{{
+		Receiver: otel.Component{
+			Type:   r.Component,
+			Config: config,
+		},
+		Processors: map[string][]otel.Component{"metrics": {
+			otel.MetricsTransform(
+				otel.AddPrefix("workload.googleapis.com"),
+			),
+			// Label the metrics so that they are clearly identified as coming from an unsupported receiver.
+			otel.ModifyInstrumentationScope(r.Type()+"/"+r.Component, "unsupported"),
+		}},
+	}}, nil
+}
+
+func init() {
+	confgenerator.MetricsReceiverTypes.RegisterType(func() confgenerator.MetricsReceiver { return &MetricsReceiverOTelRaw{} })
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const otelopscolDir = "../submodules/opentelemetry-operations-collector"
+
+// otelopscolReceiverRe matches the upstream receivers that otelopscol imports in its
+// component list or lists in its builder manifest.
+var otelopscolReceiverRe = regexp.MustCompile(`opentelemetry-collector-contrib/receiver/([a-z0-9]+)receiver\b`)
+
+// otelopscolReceivers returns the upstream receivers that are built into otelopscol.
+func otelopscolReceivers(t *testing.T) map[string]bool {
+	t.Helper()
+	entries, err := os.ReadDir(otelopscolDir)
+	if err != nil || len(entries) == 0 {
+		t.Skipf("%s is not checked out; run git submodule update --init", otelopscolDir)
+	}
+	receivers := map[string]bool{}
+	err = filepath.WalkDir(otelopscolDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, "_test.go") || !(strings.HasSuffix(path, ".go") || strings.HasSuffix(path, ".yaml")) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range otelopscolReceiverRe.FindAllStringSubmatch(string(data), -1) {
+			receivers[m[1]] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receivers) == 0 {
+		t.Fatalf("found no receivers in %s", otelopscolDir)
+	}
+	return receivers
+}
+
+func TestOTelRawAllowedComponentsAreBuilt(t *testing.T) {
+	receivers := otelopscolReceivers(t)
+	for component := range otelRawAllowedComponents {
+		if !receivers[component] {
+			t.Errorf("otel_raw allows component %q, but it is not built into otelopscol", component)
+		}
+	}
+}
@@ -17,7 +17,9 @@ package apps
 import (
 	"context"
 	"fmt"
+	"net"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
@@ -43,7 +45,22 @@ func (r ReceiverOTLP) Type() string {
 	return "otlp"
 }
 
-func (ReceiverOTLP) gmpResourceProcessors(ctx context.Context) []otel.Component {
+func (r ReceiverOTLP) endpoint() string {
+	if r.GRPCEndpoint == "" {
+		return defaultGRPCEndpoint
+	}
+	return r.GRPCEndpoint
+}
+
+func (r ReceiverOTLP) ListenAddress() (string, string, uint16) {
+	// The endpoint is validated to be a hostname_port.
+	host, port, _ := net.SplitHostPort(r.endpoint())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return "tcp", host, uint16(p)
+}
+
+// gmpResourceProcessors maps the resource of pushed metrics to the GMP monitored resource.
+func gmpResourceProcessors(ctx context.Context) []otel.Component {
 	// Keep in sync with logic in confgenerator/prometheus.go
 	stmt := func(target, source, platform string) string {
 		// if cloud.provider == "gcp" && cloud.platform == "gcp_compute_engine"
@@ -85,7 +102,7 @@ func (ReceiverOTLP) gmpResourceProcessors(ctx context.Context) []otel.Component
 
 func (r ReceiverOTLP) metricsProcessors(ctx context.Context) (otel.ExporterType, otel.ResourceDetectionMode, []otel.Component) {
 	if r.MetricsMode != "googlecloudmonitoring" {
-		return otel.GMP, otel.None, r.gmpResourceProcessors(ctx)
+		return otel.GMP, otel.None, gmpResourceProcessors(ctx)
 	}
 	var knownDomainsRegexEscaped []string
 	for _, knownDomain := range knownDomains {
@@ -126,11 +143,6 @@ func (r ReceiverOTLP) metricsProcessors(ctx context.Context) (otel.ExporterType,
 }
 
 func (r ReceiverOTLP) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
-	endpoint := r.GRPCEndpoint
-	if endpoint == "" {
-		endpoint = defaultGRPCEndpoint
-	}
-
 	receiverPipelineType, metricsRDM, metricsProcessors := r.metricsProcessors(ctx)
 
 	return []otel.ReceiverPipeline{{
@@ -144,7 +156,7 @@ func (r ReceiverOTLP) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, e
 			Config: map[string]interface{}{
 				"protocols": map[string]interface{}{
 					"grpc": map[string]interface{}{
-						"endpoint": endpoint,
+						"endpoint": r.endpoint(),
 					},
 				},
 			},
@@ -0,0 +1,123 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+type LoggingProcessorPostfix struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	// CorrelateByQueueID sets the operation of each LogEntry to the queue ID of the message,
+	// so that all the entries about one message are grouped together in Cloud Logging.
+	CorrelateByQueueID bool `yaml:"correlate_by_queue_id,omitempty"`
+}
+
+func (LoggingProcessorPostfix) Type() string {
+	return "postfix"
+}
+
+func (p LoggingProcessorPostfix) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	c := confgenerator.LoggingProcessorParseRegex{
+		// Documentation: https://www.postfix.org/MAILLOG_README.html
+		// Postfix logs through syslog, or to its own file with maillog_file; both use the same format.
+		// Sample line: Feb 14 09:12:44 mail-1 postfix/smtpd[1234]: connect from unknown[10.128.0.5]
+		// Sample line: Feb 14 09:12:44 mail-1 postfix/qmgr[1201]: 4F2A31E0A2: from=<jdoe@example.com>, size=1532, nrcpt=1 (queue active)
+		// Sample line: Feb 14 09:12:45 mail-1 postfix/smtp[1240]: 4F2A31E0A2: to=<ops@example.org>, relay=mx.example.org[203.0.113.5]:25, delay=0.52, delays=0.05/0.01/0.2/0.26, dsn=2.0.0, status=sent (250 2.0.0 OK)
+		// Sample line: Feb 14 09:12:46 mail-1 postfix/smtpd[1234]: warning: hostname host-1.example.com does not resolve to address 10.128.0.7
+		// Queue IDs are either short (hexadecimal) or long (enable_long_queue_ids = yes).
+		Regex: `^(?<time>\w{3}\s+\d{1,2}\s+\d{2}:\d{2}:\d{2})\s+(?<host>[^\s]+)\s+(?<process>[^\[\s]+)\[(?<pid>\d+)\]:\s+(?:(?<queue_id>[0-9A-F]{6,12}|[0-9B-DF-HJ-NP-TV-Zb-df-hj-np-tv-z]{10,15}):\s+)?(?:(?<level>warning|error|fatal|panic):\s+)?(?<message>.*)$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%b %d %H:%M:%S",
+			Types: map[string]string{
+				"pid": "integer",
+			},
+		},
+	}.Components(ctx, tag, uid)
+
+	// Delivery attempts are parsed into their own fields, keeping the full message.
+	c = append(c,
+		confgenerator.LoggingProcessorParseRegex{
+			Field:       "message",
+			PreserveKey: true,
+			Regex:       `to=<(?<to>[^>]*)>, (?:orig_to=<(?<orig_to>[^>]*)>, )?relay=(?<relay>[^,]+), (?:conn_use=\d+, )?delay=(?<delay>[\d.]+), delays=(?<delays>[^,]+), dsn=(?<dsn>[^,]+), status=(?<status>\w+)`,
+			ParserShared: confgenerator.ParserShared{
+				Types: map[string]string{
+					"delay": "float",
+				},
+			},
+		}.Components(ctx, tag, fmt.Sprintf("%s.delivery", uid))...,
+	)
+
+	fields := map[string]*confgenerator.ModifyField{
+		"severity": {
+			CopyFrom: "jsonPayload.level",
+			MapValues: map[string]string{
+				"warning": "WARNING",
+				"error":   "ERROR",
+				"fatal":   "CRITICAL",
+				"panic":   "EMERGENCY",
+			},
+			MapValuesExclusive: true,
+		},
+		InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+	}
+	if p.CorrelateByQueueID {
+		producer := "postfix"
+		fields["operation.id"] = &confgenerator.ModifyField{
+			CopyFrom: "jsonPayload.queue_id",
+		}
+		fields["operation.producer"] = &confgenerator.ModifyField{
+			StaticValue: &producer,
+		}
+	}
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: fields,
+		}.Components(ctx, tag, uid)...,
+	)
+
+	return c
+}
+
+type LoggingReceiverPostfix struct {
+	LoggingProcessorPostfix                 `yaml:",inline"`
+	confgenerator.LoggingReceiverFilesMixin `yaml:",inline" validate:"structonly"`
+}
+
+func (r LoggingReceiverPostfix) Components(ctx context.Context, tag string) []fluentbit.Component {
+	if len(r.IncludePaths) == 0 {
+		r.IncludePaths = []string{
+			// Default log path on CentOS / RHEL / SLES
+			"/var/log/maillog",
+			// Default log path on Ubuntu / Debian
+			"/var/log/mail.log",
+		}
+	}
+	c := r.LoggingReceiverFilesMixin.Components(ctx, tag)
+	c = append(c, r.LoggingProcessorPostfix.Components(ctx, tag, "postfix")...)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingProcessorTypes.RegisterType(func() confgenerator.LoggingProcessor { return &LoggingProcessorPostfix{} })
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverPostfix{} })
+}
@@ -117,6 +117,12 @@ func (LoggingProcessorPostgresql) Type() string {
 }
 
 func (p LoggingProcessorPostgresql) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
+	return postgresqlLogComponents(ctx, tag, uid, p.Type())
+}
+
+// postgresqlLogComponents parses PostgreSQL server logs. It is shared with the receivers of
+// PostgreSQL-compatible databases, which are labeled with their own processor type.
+func postgresqlLogComponents(ctx context.Context, tag, uid, processorType string) []fluentbit.Component {
 	c := confgenerator.LoggingProcessorParseMultilineRegex{
 		LoggingProcessorParseRegexComplex: confgenerator.LoggingProcessorParseRegexComplex{
 			// Limited logging documentation: https://www.postgresql.org/docs/10/runtime-config-logging.html
@@ -193,7 +199,7 @@ func (p LoggingProcessorPostgresql) Components(ctx context.Context, tag string,
 					},
 					MapValuesExclusive: true,
 				},
-				InstrumentationSourceLabel: instrumentationSourceValue(p.Type()),
+				InstrumentationSourceLabel: instrumentationSourceValue(processorType),
 			},
 		}.Components(ctx, tag, uid)...,
 	)
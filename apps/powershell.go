@@ -0,0 +1,131 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// powerShellEventsLua extracts the command and the user of the module logging (4103) and script
+// block logging (4104) events of the PowerShell Operational channel.
+const powerShellEventsLua = `
+function process(tag, timestamp, record)
+  local inserts = record["StringInserts"]
+  if type(inserts) ~= "table" then
+    return 0, timestamp, record
+  end
+  if record["EventID"] == 4104 then
+    -- Creating Scriptblock text (%1 of %2): %3 ScriptBlock ID: %4 Path: %5
+    record["command"] = inserts[3]
+    record["script_block_id"] = inserts[4]
+    if inserts[5] ~= nil and inserts[5] ~= "" then
+      record["script_path"] = inserts[5]
+    end
+    record["user"] = record["UserID"]
+  elseif record["EventID"] == 4103 then
+    -- The context info holds "Key = Value" lines, e.g. "Command Name = Get-Process".
+    local context = inserts[1]
+    if type(context) == "string" then
+      record["command"] = context:match("Command Name = ([^\r\n]*)")
+      record["user"] = context:match("\n%s*User = ([^\r\n]*)")
+    end
+  else
+    return 0, timestamp, record
+  end
+  return 2, timestamp, record
+end
+`
+
+type LoggingReceiverPowerShell struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	// TranscriptPaths are the transcript files written by Start-Transcript or the "Turn on
+	// PowerShell Transcription" policy. Defaults to the transcripts in the documents of the users.
+	TranscriptPaths []string `yaml:"transcript_paths,omitempty" validate:"omitempty,dive,required"`
+	ExcludePaths    []string `yaml:"exclude_paths,omitempty"`
+}
+
+func (r LoggingReceiverPowerShell) Type() string {
+	return "powershell"
+}
+
+func (r LoggingReceiverPowerShell) Components(ctx context.Context, tag string) []fluentbit.Component {
+	// Script block logging writes the code of every script block that runs to the Operational
+	// channel, and module logging the pipeline execution details.
+	l := confgenerator.LoggingReceiverWindowsEventLog{
+		Channels:        []string{"Microsoft-Windows-PowerShell/Operational"},
+		ReceiverVersion: "2",
+	}
+	c := l.Components(ctx, tag)
+	c = append(c, fluentbit.LuaFilterComponents(tag, "process", powerShellEventsLua)...)
+
+	transcriptPaths := r.TranscriptPaths
+	if len(transcriptPaths) == 0 {
+		transcriptPaths = []string{`C:\Users\*\Documents\PowerShell_transcript.*.txt`}
+	}
+	// The path of the transcript tells the sessions apart.
+	recordLogFilePath := true
+	files := confgenerator.LoggingReceiverFilesMixin{
+		IncludePaths:      transcriptPaths,
+		ExcludePaths:      r.ExcludePaths,
+		RecordLogFilePath: &recordLogFilePath,
+	}
+	for _, f := range files.Components(ctx, tag) {
+		if f.Kind == "INPUT" {
+			// The event log input already uses the DB of the tag.
+			f.Config["DB"] = confgenerator.DBPath(tag) + "_transcripts"
+		}
+		c = append(c, f)
+	}
+	// Every line of a transcript is a log entry, and the lines with the command and the user of
+	// the session are parsed. Each regex captures the whole line again, so that the message is
+	// kept as is.
+	c = append(c,
+		confgenerator.LoggingProcessorParseRegexComplex{
+			Field: "message",
+			Parsers: []confgenerator.RegexParser{
+				{
+					// Sample line: PS C:\Users\jdoe> Get-Process -Name svchost
+					Regex: `^(?<message>PS (?<working_directory>[^>]*)> (?<command>.+))$`,
+				},
+				{
+					// Sample line: Username: CORP\jdoe
+					Regex: `^(?<message>Username: (?<user>.+))$`,
+				},
+				{
+					// Sample line: RunAs User: CORP\admin
+					Regex: `^(?<message>RunAs User: (?<run_as_user>.+))$`,
+				},
+			},
+		}.Components(ctx, tag, "powershell")...,
+	)
+
+	c = append(c,
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(r.Type()),
+			},
+		}.Components(ctx, tag, "powershell")...,
+	)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverPowerShell{} }, platform.Windows)
+}
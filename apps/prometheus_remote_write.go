@@ -0,0 +1,77 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// Only accept pushes from the local host by default, since remote_write has no authentication.
+const defaultRemoteWriteEndpoint = "127.0.0.1:9091"
+
+// ReceiverPrometheusRemoteWrite accepts Prometheus remote_write requests and sends the metrics
+// to Google Cloud Managed Service for Prometheus, using the agent's credentials and resource.
+type ReceiverPrometheusRemoteWrite struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	Endpoint string `yaml:"endpoint" validate:"omitempty,hostname_port" tracking:"endpoint"`
+}
+
+func (r ReceiverPrometheusRemoteWrite) Type() string {
+	return "prometheus_remote_write"
+}
+
+func (r ReceiverPrometheusRemoteWrite) endpoint() string {
+	if r.Endpoint == "" {
+		return defaultRemoteWriteEndpoint
+	}
+	return r.Endpoint
+}
+
+func (r ReceiverPrometheusRemoteWrite) ListenAddress() (string, string, uint16) {
+	// The endpoint is validated to be a hostname_port.
+	host, port, _ := net.SplitHostPort(r.endpoint())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return "tcp", host, uint16(p)
+}
+
+func (r ReceiverPrometheusRemoteWrite) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
+	return []otel.ReceiverPipeline{{
+		Receiver: otel.Component{
+			Type: "prometheusremotewrite",
+			Config: map[string]interface{}{
+				"endpoint": r.endpoint(),
+			},
+		},
+		Processors: map[string][]otel.Component{
+			"metrics": gmpResourceProcessors(ctx),
+		},
+		ExporterTypes: map[string]otel.ExporterType{
+			"metrics": otel.GMP,
+		},
+		ResourceDetectionModes: map[string]otel.ResourceDetectionMode{
+			"metrics": otel.None,
+		},
+	}}, nil
+}
+
+func init() {
+	confgenerator.MetricsReceiverTypes.RegisterType(func() confgenerator.MetricsReceiver { return &ReceiverPrometheusRemoteWrite{} })
+}
@@ -44,7 +44,9 @@ func sqlReceiverQueriesConfig(queries []sqlReceiverQuery) []map[string]interface
 				"data_type":         m.data_type,
 				"value_type":        m.value_type,
 				"attribute_columns": m.attribute_columns,
-				"static_attributes": m.static_attributes,
+			}
+			if m.static_attributes != nil {
+				metric["static_attributes"] = m.static_attributes
 			}
 			if m.data_type == "sum" {
 				metric["monotonic"] = m.monotonic
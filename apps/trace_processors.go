@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
+)
+
+// TracesProcessorExcludeSpans drops the spans whose name, or the value of one of whose attributes,
+// matches a regular expression.
+type TracesProcessorExcludeSpans struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	SpanNames  []string          `yaml:"span_names,omitempty,flow" validate:"dive,regexp"`
+	Attributes map[string]string `yaml:"attributes,omitempty" validate:"dive,keys,required,endkeys,regexp"`
+}
+
+func (p TracesProcessorExcludeSpans) Type() string {
+	return "exclude_spans"
+}
+
+func (p TracesProcessorExcludeSpans) Processors(_ context.Context) ([]otel.Component, error) {
+	if len(p.SpanNames) == 0 && len(p.Attributes) == 0 {
+		return nil, errors.New(`at least one of "span_names" or "attributes" must be set`)
+	}
+	// $ needs to be escaped so the collector does not expand it as an environment variable.
+	escape := func(pattern string) string {
+		return strings.ReplaceAll(pattern, "$", "$$")
+	}
+	var expressions []ottl.Value
+	for _, pattern := range p.SpanNames {
+		expressions = append(expressions, ottl.IsMatch(ottl.LValue{"name"}, escape(pattern)))
+	}
+	var keys []string
+	for k := range p.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		expressions = append(expressions, ottl.IsMatch(ottl.LValue{"attributes", k}, escape(p.Attributes[k])))
+	}
+	return []otel.Component{otel.Filter("traces", "span", expressions)}, nil
+}
+
+// redactedValue replaces the values redacted by TracesProcessorRedactSpanAttributes.
+const redactedValue = "REDACTED"
+
+// TracesProcessorRedactSpanAttributes replaces the values of span attributes that may hold
+// sensitive data before they are sent to Cloud Trace.
+type TracesProcessorRedactSpanAttributes struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	// Attributes are replaced as a whole, e.g. db.statement.
+	Attributes []string `yaml:"attributes,omitempty,flow" validate:"dive,required"`
+	// URLQueryAttributes hold URLs of which only the query string is replaced, e.g. http.url.
+	URLQueryAttributes []string `yaml:"url_query_attributes,omitempty,flow" validate:"dive,required"`
+}
+
+func (p TracesProcessorRedactSpanAttributes) Type() string {
+	return "redact_span_attributes"
+}
+
+func (p TracesProcessorRedactSpanAttributes) Processors(_ context.Context) ([]otel.Component, error) {
+	if len(p.Attributes) == 0 && len(p.URLQueryAttributes) == 0 {
+		return nil, errors.New(`at least one of "attributes" or "url_query_attributes" must be set`)
+	}
+	var statements ottl.Statements
+	for _, k := range p.Attributes {
+		a := ottl.LValue{"attributes", k}
+		statements = statements.Append(a.SetIf(ottl.StringLiteral(redactedValue), ottl.IsNotNil(a)))
+	}
+	for _, k := range p.URLQueryAttributes {
+		statements = statements.Append(ottl.LValue{"attributes", k}.ReplacePattern(`[?].*`, "?"+redactedValue))
+	}
+	return []otel.Component{otel.Transform("trace", "span", statements)}, nil
+}
+
+func init() {
+	confgenerator.TracesProcessorTypes.RegisterType(func() confgenerator.TracesProcessor { return &TracesProcessorExcludeSpans{} })
+	confgenerator.TracesProcessorTypes.RegisterType(func() confgenerator.TracesProcessor { return &TracesProcessorRedactSpanAttributes{} })
+}
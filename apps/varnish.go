@@ -66,9 +66,24 @@ func (LoggingProcessorVarnish) Type() string {
 }
 
 func (p LoggingProcessorVarnish) Components(ctx context.Context, tag string, uid string) []fluentbit.Component {
-	// Logging documentation: https://github.com/varnishcache/varnish-cache/blob/04455d6c3d8b2d810007239cb1cb2b740d7ec8ab/doc/sphinx/reference/varnishncsa.rst#format
-	// Sample line: 127.0.0.1 - - [02/Mar/2022:15:55:05 +0000] "GET http://localhost:8080/test HTTP/1.1" 404 273 "-" "curl/7.64.0"
-	return genericAccessLogParser(ctx, p.Type(), tag, uid)
+	return accessLogParser(ctx, confgenerator.LoggingProcessorParseRegex{
+		// Logging documentation: https://github.com/varnishcache/varnish-cache/blob/04455d6c3d8b2d810007239cb1cb2b740d7ec8ab/doc/sphinx/reference/varnishncsa.rst#format
+		// The default varnishncsa format is the combined format. The cache handling and time to first byte
+		// are parsed too when they are appended to it, e.g. with
+		// -F '%h %l %u %t "%r" %s %b "%{Referer}i" "%{User-agent}i" %{Varnish:handling}x %{Varnish:time_firstbyte}x'
+		// Sample line: 127.0.0.1 - - [02/Mar/2022:15:55:05 +0000] "GET http://localhost:8080/test HTTP/1.1" 404 273 "-" "curl/7.64.0"
+		// Sample line: 127.0.0.1 - - [14/Feb/2024:09:12:44 +0000] "GET http://localhost:8080/ HTTP/1.1" 200 615 "-" "curl/7.88.1" hit 0.000112
+		Regex: `^(?<http_request_remoteIp>[^ ]*) (?<host>[^ ]*) (?<user>[^ ]*) \[(?<time>[^\]]*)\] "(?<http_request_requestMethod>\S+)(?: +(?<http_request_requestUrl>[^\"]*?)(?: +(?<http_request_protocol>\S+))?)?" (?<http_request_status>[^ ]*) (?<http_request_responseSize>[^ ]*)(?: "(?<http_request_referer>[^\"]*)" "(?<http_request_userAgent>[^\"]*)")?(?: (?<cache_handling>hit|miss|pass|pipe|synth))?(?: (?<time_firstbyte>\d+(?:\.\d+)?))?$`,
+		ParserShared: confgenerator.ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%d/%b/%Y:%H:%M:%S %z",
+			Types: map[string]string{
+				"http_request_status": "integer",
+				// Seconds from the start of the request until the first byte was sent.
+				"time_firstbyte": "float",
+			},
+		},
+	}, p.Type(), tag, uid)
 }
 
 type LoggingReceiverVarnish struct {
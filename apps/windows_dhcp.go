@@ -0,0 +1,111 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+type MetricsReceiverWindowsDHCP struct {
+	confgenerator.ConfigComponent       `yaml:",inline"`
+	confgenerator.MetricsReceiverShared `yaml:",inline"`
+}
+
+func (r MetricsReceiverWindowsDHCP) Type() string {
+	return "windows_dhcp"
+}
+
+func (r MetricsReceiverWindowsDHCP) Pipelines(_ context.Context) ([]otel.ReceiverPipeline, error) {
+	return []otel.ReceiverPipeline{{
+		Receiver: otel.Component{
+			Type: "windowsperfcounters",
+			Config: map[string]interface{}{
+				"collection_interval": r.CollectionIntervalString(),
+				"perfcounters": []map[string]interface{}{
+					{
+						"object": "DHCP Server",
+						"counters": []map[string]string{
+							{"name": "Acks/sec"},
+							{"name": "Active Queue Length"},
+							{"name": "Declines/sec"},
+							{"name": "Discovers/sec"},
+							{"name": "Informs/sec"},
+							{"name": "Nacks/sec"},
+							{"name": "Offers/sec"},
+							{"name": "Releases/sec"},
+							{"name": "Requests/sec"},
+						},
+					},
+				},
+			},
+		},
+		Processors: map[string][]otel.Component{"metrics": {
+			otel.MetricsTransform(
+				// Acks, releases and declines together describe the lease churn.
+				// $ needs to be escaped because reasons.
+				// https://github.com/open-telemetry/opentelemetry-collector-contrib/tree/main/processor/metricstransformprocessor#rename-multiple-metrics-using-substitution
+				otel.CombineMetrics(
+					`^\\DHCP Server\\(?P<type>Acks|Declines|Discovers|Informs|Nacks|Offers|Releases|Requests)/sec$$`,
+					"windows_dhcp.message.rate",
+				),
+				otel.RenameMetric(
+					`\DHCP Server\Active Queue Length`,
+					"windows_dhcp.queue.length",
+				),
+				otel.AddPrefix("workload.googleapis.com"),
+			),
+			otel.ModifyInstrumentationScope(r.Type(), "1.0"),
+		}},
+	}}, nil
+}
+
+func init() {
+	confgenerator.MetricsReceiverTypes.RegisterType(func() confgenerator.MetricsReceiver { return &MetricsReceiverWindowsDHCP{} }, platform.Windows)
+}
+
+type LoggingReceiverWindowsDHCP struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (r LoggingReceiverWindowsDHCP) Type() string {
+	return "windows_dhcp"
+}
+
+func (r LoggingReceiverWindowsDHCP) Components(ctx context.Context, tag string) []fluentbit.Component {
+	// The scope utilization warnings (e.g. event 1376, "scope is 90 percent full") are written to the
+	// Admin channel.
+	l := confgenerator.LoggingReceiverWindowsEventLog{
+		Channels:        []string{"Microsoft-Windows-DHCP Server Events/Admin", "Microsoft-Windows-DHCP Server Events/Operational"},
+		ReceiverVersion: "2",
+	}
+
+	c := append(l.Components(ctx, tag),
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(r.Type()),
+			},
+		}.Components(ctx, tag, "windows_dhcp")...,
+	)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverWindowsDHCP{} }, platform.Windows)
+}
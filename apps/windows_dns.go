@@ -0,0 +1,133 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+type MetricsReceiverWindowsDNS struct {
+	confgenerator.ConfigComponent       `yaml:",inline"`
+	confgenerator.MetricsReceiverShared `yaml:",inline"`
+}
+
+func (r MetricsReceiverWindowsDNS) Type() string {
+	return "windows_dns"
+}
+
+func (r MetricsReceiverWindowsDNS) Pipelines(_ context.Context) ([]otel.ReceiverPipeline, error) {
+	return []otel.ReceiverPipeline{{
+		Receiver: otel.Component{
+			Type: "windowsperfcounters",
+			Config: map[string]interface{}{
+				"collection_interval": r.CollectionIntervalString(),
+				"perfcounters": []map[string]interface{}{
+					{
+						"object": "DNS",
+						"counters": []map[string]string{
+							{"name": "Dynamic Update Received"},
+							{"name": "Recursive Queries"},
+							{"name": "Recursive Query Failure"},
+							{"name": "TCP Query Received"},
+							{"name": "UDP Query Received"},
+							{"name": "Zone Transfer Failure"},
+							{"name": "Zone Transfer Success"},
+						},
+					},
+				},
+			},
+		},
+		Processors: map[string][]otel.Component{"metrics": {
+			otel.MetricsTransform(
+				// $ needs to be escaped because reasons.
+				// https://github.com/open-telemetry/opentelemetry-collector-contrib/tree/main/processor/metricstransformprocessor#rename-multiple-metrics-using-substitution
+				otel.CombineMetrics(
+					`^\\DNS\\(?P<protocol>TCP|UDP) Query Received$$`,
+					"windows_dns.query.count",
+					// change data type from double -> int64
+					otel.ToggleScalarDataType,
+				),
+				otel.RenameMetric(
+					`\DNS\Recursive Queries`,
+					"windows_dns.recursive_query.count",
+					otel.ToggleScalarDataType,
+				),
+				otel.RenameMetric(
+					`\DNS\Recursive Query Failure`,
+					"windows_dns.recursive_query.failure.count",
+					otel.ToggleScalarDataType,
+				),
+				otel.RenameMetric(
+					`\DNS\Dynamic Update Received`,
+					"windows_dns.dynamic_update.count",
+					otel.ToggleScalarDataType,
+				),
+				otel.CombineMetrics(
+					`^\\DNS\\Zone Transfer (?P<result>Failure|Success)$$`,
+					"windows_dns.zone_transfer.count",
+					otel.ToggleScalarDataType,
+				),
+				otel.AddPrefix("workload.googleapis.com"),
+			),
+			otel.CastToSum(
+				"workload.googleapis.com/windows_dns.query.count",
+				"workload.googleapis.com/windows_dns.recursive_query.count",
+				"workload.googleapis.com/windows_dns.recursive_query.failure.count",
+				"workload.googleapis.com/windows_dns.dynamic_update.count",
+				"workload.googleapis.com/windows_dns.zone_transfer.count",
+			),
+			otel.NormalizeSums(),
+			otel.ModifyInstrumentationScope(r.Type(), "1.0"),
+		}},
+	}}, nil
+}
+
+func init() {
+	confgenerator.MetricsReceiverTypes.RegisterType(func() confgenerator.MetricsReceiver { return &MetricsReceiverWindowsDNS{} }, platform.Windows)
+}
+
+type LoggingReceiverWindowsDNS struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+}
+
+func (r LoggingReceiverWindowsDNS) Type() string {
+	return "windows_dns"
+}
+
+func (r LoggingReceiverWindowsDNS) Components(ctx context.Context, tag string) []fluentbit.Component {
+	// Zone transfer and zone loading errors are written to the DNS Server channel.
+	l := confgenerator.LoggingReceiverWindowsEventLog{
+		Channels:        []string{"DNS Server", "Microsoft-Windows-DNSServer/Audit"},
+		ReceiverVersion: "2",
+	}
+
+	c := append(l.Components(ctx, tag),
+		confgenerator.LoggingProcessorModifyFields{
+			Fields: map[string]*confgenerator.ModifyField{
+				InstrumentationSourceLabel: instrumentationSourceValue(r.Type()),
+			},
+		}.Components(ctx, tag, "windows_dns")...,
+	)
+	return c
+}
+
+func init() {
+	confgenerator.LoggingReceiverTypes.RegisterType(func() confgenerator.LoggingReceiver { return &LoggingReceiverWindowsDNS{} }, platform.Windows)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apps
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+const defaultZipkinEndpoint = "0.0.0.0:9411"
+
+// ReceiverZipkin accepts spans in the Zipkin v1 and v2 JSON and Protobuf formats over HTTP, and
+// sends them to Cloud Trace.
+type ReceiverZipkin struct {
+	confgenerator.ConfigComponent `yaml:",inline"`
+
+	Endpoint string `yaml:"endpoint" validate:"omitempty,hostname_port" tracking:"endpoint"`
+}
+
+func (r ReceiverZipkin) Type() string {
+	return "zipkin"
+}
+
+func (r ReceiverZipkin) TracesOnly() {}
+
+func (r ReceiverZipkin) endpoint() string {
+	if r.Endpoint == "" {
+		return defaultZipkinEndpoint
+	}
+	return r.Endpoint
+}
+
+func (r ReceiverZipkin) ListenAddress() (string, string, uint16) {
+	// The endpoint is validated to be a hostname_port.
+	host, port, _ := net.SplitHostPort(r.endpoint())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return "tcp", host, uint16(p)
+}
+
+func (r ReceiverZipkin) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
+	return []otel.ReceiverPipeline{{
+		ExporterTypes: map[string]otel.ExporterType{
+			"traces": otel.OTel,
+		},
+		Receiver: otel.Component{
+			Type: "zipkin",
+			Config: map[string]interface{}{
+				"endpoint": r.endpoint(),
+			},
+		},
+		Processors: map[string][]otel.Component{
+			"traces": nil,
+		},
+		ResourceDetectionModes: map[string]otel.ResourceDetectionMode{
+			"traces": otel.SetIfMissing,
+		},
+	}}, nil
+}
+
+func init() {
+	confgenerator.CombinedReceiverTypes.RegisterType(func() confgenerator.CombinedReceiver { return &ReceiverZipkin{} })
+}
@@ -20,9 +20,17 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/GoogleCloudPlatform/ops-agent/apps"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/readiness"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -33,12 +41,18 @@ func getLogFileRotation(config *confgenerator.UnifiedConfig) confgenerator.LogFi
 	return *config.Global.DefaultLogFileRotation
 }
 
-func run(logFilename, configurationPath string, cmd *exec.Cmd) error {
+// run runs cmd until it exits. When w is not nil, w kills cmd if it hangs and stops it if it
+// exceeds its watermarks.
+func run(logFilename, configurationPath string, cmd *exec.Cmd, w *watchdog) error {
 	ucConfig, err := confgenerator.MergeConfFiles(context.Background(), configurationPath, apps.BuiltInConfStructs)
 	if err != nil {
 		return err
 	}
 	config := getLogFileRotation(ucConfig)
+	if n := ucConfig.Global.GetGoMaxProcs(); n > 0 && os.Getenv("GOMAXPROCS") == "" {
+		// Set by global.performance_profile. The variable only affects Go subagents.
+		cmd.Env = append(os.Environ(), "GOMAXPROCS="+strconv.Itoa(n))
+	}
 
 	if logFilename != "" && config.GetEnabled() {
 		logger := lumberjack.Logger{
@@ -63,15 +77,100 @@ func run(logFilename, configurationPath string, cmd *exec.Cmd) error {
 	} else {
 		cmd.Stdout = os.Stdout
 	}
+	started := notifyStarted
+	if w != nil {
+		cmd.Stdout = w.Writer(cmd.Stdout)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		started = func() {
+			notifyStarted()
+			go w.Watch(ctx, cmd)
+		}
+	}
 	cmd.Stderr = cmd.Stdout
-	if err := runCommand(cmd); err != nil {
+	if err := runCommand(cmd, started); err != nil {
 		return err
 	}
 	return nil
 }
 
+// runWithRestarts runs the command in args, and restarts it with an exponential backoff each
+// time the watchdog kills or stops it. Other exits are left to the service manager.
+func runWithRestarts(ctx context.Context, args []string, w *watchdog, logger logs.StructuredLogger) (*exec.Cmd, error) {
+	for {
+		cmd := exec.Command(args[0], args[1:]...)
+		start := time.Now()
+		err := run(*logPathFlag, *configurationPathFlag, cmd, w)
+		if w == nil || ctx.Err() != nil {
+			return cmd, err
+		}
+		reason := w.Reason()
+		if reason == "" {
+			return cmd, err
+		}
+		backoff := w.NextBackoff(time.Since(start))
+		msg, code := "Restarting a subagent that stopped responding", "SubagentRestarted"
+		if w.WatermarkExceeded() {
+			msg, code = "Restarting a subagent that exceeded its resource watermark", "SubagentWatermarkExceeded"
+		}
+		logger.Warnw(msg,
+			"code", code,
+			"subagent", filepath.Base(args[0]),
+			"reason", reason,
+			"restart_count", w.restarts,
+			"backoff", backoff.String())
+		reportRestart(filepath.Base(args[0]), msg, reason, w.restarts)
+		select {
+		case <-ctx.Done():
+			return cmd, err
+		case <-time.After(backoff):
+		}
+	}
+}
+
 var logPathFlag = flag.String("log_path", "", "The name of the file to log to. If empty, logs to stdout")
 var configurationPathFlag = flag.String("config_path", "", "The path to the user specified agent config")
+var watchdogTimeoutFlag = flag.Duration("watchdog_timeout", 0, "Restart the command when it makes no progress for this long. Zero disables the watchdog")
+var memoryWatermarkFlag = flag.Uint64("memory_watermark_mb", 0, "Restart the command gracefully when its resident memory exceeds this many MiB. Zero disables the watermark. Only supported on Linux")
+var cpuWatermarkFlag = flag.Float64("cpu_watermark", 0, "Restart the command gracefully when it uses more than this many CPU cores for 5 minutes. Zero disables the watermark. Only supported on Linux")
+var subagentFlag = flag.String("subagent", "", "The service of the user specified agent config (logging or metrics) whose watermarks apply to the command, unless they are set by flags. If empty, only the flags are used")
+var healthURLFlag = flag.String("health_url", "", "A URL the command serves while it is healthy, e.g. its metrics endpoint. If empty, any output counts as progress")
+var logsDirFlag = flag.String("logs_dir", "", "The directory of the health checks log, which restarts are logged to. If empty, logs to stderr")
+var ingestionProbeFlag = flag.String("ingestion_probe", "", "Report in the systemd status of the service once the command exported for the first time, checked with this probe (fluentbit or otel). If empty, ingestion is not reported")
+var ingestionTimeoutFlag = flag.Duration("ingestion_timeout", 5*time.Minute, "Log a warning when the command has not exported after this long")
+
+// notifyStarted notifies systemd that the service is ready, once the command started. Whether the
+// command ingests is reported separately by reportIngestion, so that a subagent without anything
+// to export doesn't block the startup.
+func notifyStarted() {
+	if err := readiness.Notify("READY=1"); err != nil {
+		log.Printf("Failed to notify systemd: %v", err)
+	}
+}
+
+// reportIngestion sets the systemd status of the service once the command exported for the first
+// time, and logs a warning if it did not export before timeout.
+func reportIngestion(ctx context.Context, probe readiness.Probe, timeout time.Duration, logger logs.StructuredLogger) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := readiness.Wait(waitCtx, time.Second, probe); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warnw("The subagent did not export before the ingestion timeout",
+			"code", "SubagentNotIngesting",
+			"subagent", filepath.Base(flag.Arg(0)),
+			"timeout", timeout.String(),
+			"error", err.Error())
+		// Keep waiting, so that the status is set once the subagent exports.
+		if err := readiness.Wait(ctx, time.Second, probe); err != nil {
+			return
+		}
+	}
+	if err := readiness.Notify("STATUS=Ingesting"); err != nil {
+		log.Printf("Failed to notify systemd: %v", err)
+	}
+}
 
 func main() {
 	flag.Parse()
@@ -80,8 +179,36 @@ func main() {
 		flag.Usage()
 		log.Fatal("Command to run must be passed in as first argument")
 	}
-	cmd := exec.Command(flag.Args()[0], flag.Args()[1:]...)
-	err := run(*logPathFlag, *configurationPathFlag, cmd)
+	var w *watchdog
+	wm := watermarks{memory: *memoryWatermarkFlag << 20, cpu: *cpuWatermarkFlag}
+	if *subagentFlag != "" {
+		uc, err := confgenerator.MergeConfFiles(context.Background(), *configurationPathFlag, apps.BuiltInConfStructs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := wm.setDefaults(uc, *subagentFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *watchdogTimeoutFlag > 0 || wm.enabled() {
+		w = newWatchdog(*watchdogTimeoutFlag, *healthURLFlag)
+		w.watermarks = wm
+	}
+	var logger logs.StructuredLogger = logs.Default()
+	if *logsDirFlag != "" {
+		logger = healthchecks.CreateHealthChecksLogger(*logsDirFlag)
+	}
+	// The subprocess receives the signals too; this only stops the restarts.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *ingestionProbeFlag != "" {
+		probe, ok := readiness.Probes[*ingestionProbeFlag]
+		if !ok {
+			log.Fatalf("Unknown ingestion probe %q", *ingestionProbeFlag)
+		}
+		go reportIngestion(ctx, probe, *ingestionTimeoutFlag, logger)
+	}
+	cmd, err := runWithRestarts(ctx, flag.Args(), w, logger)
 	if err != nil {
 		log.Print(err)
 	}
@@ -17,32 +17,65 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 )
 
 const MaximumWaitForProcessStart = 5 * time.Second
 
-func handleSignals(cmd *exec.Cmd) {
-	// Relay signals that should be passed down to the subprocess we are wrapping.
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGCONT)
-	for {
-		sig := <-sigs
+// signalForwarder relays the signals that the wrapper receives to the subprocess that is currently
+// running. A single forwarder is shared by all the restarts of the subprocess, so that signals are
+// never sent to a subprocess that already exited and whose PID might have been reused.
+type signalForwarder struct {
+	mu      sync.Mutex
+	process *os.Process
+	// waitForProcess is how long a signal that arrives between two runs of the subprocess is held
+	// before it is dropped.
+	waitForProcess time.Duration
+}
+
+var (
+	forwarder      = &signalForwarder{waitForProcess: MaximumWaitForProcessStart}
+	startForwarder sync.Once
+)
+
+// setProcess sets the subprocess that signals are relayed to, or nil when no subprocess is running.
+func (f *signalForwarder) setProcess(p *os.Process) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.process = p
+}
+
+// signal relays sig to the running subprocess and reports whether there was one.
+func (f *signalForwarder) signal(sig os.Signal) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.process == nil {
+		return false
+	}
+	f.process.Signal(sig)
+	return true
+}
+
+// forward relays the signals received on sigs until sigs is closed.
+func (f *signalForwarder) forward(sigs <-chan os.Signal) {
+	for sig := range sigs {
 		start := time.Now()
-		// It is possible that we receive a signal before the code for `cmd.Run()` set cmd.Process.
-		// In this case we wait up to MaximumWaitForProcessStart before giving up relaying the signal.
-		for {
-			if cmd.Process != nil {
-				cmd.Process.Signal(sig)
-				break
-			} else if time.Until(start.Add(MaximumWaitForProcessStart)) <= 0 {
-				// We waited MaximumWaitForProcessStart and the subprocess did not start. Give up on relaying the signal.
-				log.Printf("Failed to relay signal %v to subprocess as it has not started yet", sig)
+		// It is possible that we receive a signal before the subprocess started, or while it is
+		// being restarted. In this case we wait up to waitForProcess before giving up relaying the
+		// signal.
+		for !f.signal(sig) {
+			if time.Since(start) >= f.waitForProcess {
+				log.Printf("Failed to relay signal %v to subprocess as it is not running", sig)
 				break
 			}
 			time.Sleep(50 * time.Millisecond)
@@ -50,10 +83,85 @@ func handleSignals(cmd *exec.Cmd) {
 	}
 }
 
-func runCommand(cmd *exec.Cmd) error {
+// reportRestart does nothing on Linux, where restarts are only logged to the health checks log.
+func reportRestart(subagent, msg, reason string, restarts int) {}
+
+// clockTicksPerSecond is USER_HZ, the unit of the CPU times in /proc, which is 100 on every
+// architecture that the agent supports.
+const clockTicksPerSecond = 100
+
+func readProcessUsage(pid int) (processUsage, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return processUsage{}, err
+	}
+	return parseProcStat(data, uint64(os.Getpagesize()))
+}
+
+// parseProcStat parses /proc/<pid>/stat. See proc(5).
+func parseProcStat(data []byte, pageSize uint64) (processUsage, error) {
+	// The command name in the second field may contain spaces and parentheses.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return processUsage{}, fmt.Errorf("invalid stat: %q", data)
+	}
+	// fields[0] is the third field, the state.
+	fields := bytes.Fields(data[i+1:])
+	if len(fields) < 22 {
+		return processUsage{}, fmt.Errorf("invalid stat: %q", data)
+	}
+	field := func(n int) (uint64, error) {
+		return strconv.ParseUint(string(fields[n-3]), 10, 64)
+	}
+	utime, err := field(14)
+	if err != nil {
+		return processUsage{}, err
+	}
+	stime, err := field(15)
+	if err != nil {
+		return processUsage{}, err
+	}
+	rss, err := field(24)
+	if err != nil {
+		return processUsage{}, err
+	}
+	return processUsage{
+		rss:     rss * pageSize,
+		cpuTime: time.Duration(utime+stime) * time.Second / clockTicksPerSecond,
+	}, nil
+}
+
+// stopGracefully sends SIGTERM to p, which makes Fluent Bit flush its buffers, and kills p if it
+// did not exit after stopGracePeriod. ctx is done when p exited.
+func stopGracefully(ctx context.Context, p *os.Process) {
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		p.Kill()
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(stopGracePeriod):
+		p.Kill()
+	}
+}
+
+func runCommand(cmd *exec.Cmd, started func()) error {
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Pdeathsig: syscall.SIGKILL,
 	}
-	go handleSignals(cmd)
-	return cmd.Run()
+	startForwarder.Do(func() {
+		// Relay signals that should be passed down to the subprocess we are wrapping. SIGHUP is
+		// sent by the systemd ExecReload of the service to hot reload the subagent config.
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGCONT, syscall.SIGHUP)
+		go forwarder.forward(sigs)
+	})
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	forwarder.setProcess(cmd.Process)
+	started()
+	err := cmd.Wait()
+	forwarder.setProcess(nil)
+	return err
 }
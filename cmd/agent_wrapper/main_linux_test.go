@@ -17,12 +17,64 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"testing"
+	"time"
 )
 
 // Get a command that will write the given number of bytes
 func getCommand(writeBytes int) *exec.Cmd {
 	return exec.Command("/bin/sh", "-c", fmt.Sprintf("echo %s", strings.Repeat("a", writeBytes-1)))
 }
+
+func TestParseProcStat(t *testing.T) {
+	stat := "1234 (fluent-bit (x)) S 1 1234 1234 0 -1 4194560 1000 0 0 0 250 150 0 0 20 0 4 0 100 123456789 2048 18446744073709551615\n"
+	got, err := parseProcStat([]byte(stat), 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := processUsage{rss: 2048 * 4096, cpuTime: 4 * time.Second}
+	if got != want {
+		t.Errorf("parseProcStat() = %+v, want %+v", got, want)
+	}
+	if _, err := parseProcStat([]byte("1234 (fluent-bit) S 1"), 4096); err == nil {
+		t.Errorf("parseProcStat() of a truncated stat succeeded")
+	}
+}
+
+func TestSignalForwarder(t *testing.T) {
+	f := &signalForwarder{waitForProcess: 100 * time.Millisecond}
+	sigs := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		f.forward(sigs)
+		close(done)
+	}()
+
+	// A signal that arrives while no subprocess is running is dropped.
+	sigs <- syscall.SIGTERM
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	f.setProcess(cmd.Process)
+	sigs <- syscall.SIGTERM
+	err := cmd.Wait()
+	f.setProcess(nil)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Sys().(syscall.WaitStatus).Signal() != syscall.SIGTERM {
+		t.Errorf("Wait() = %v, want the subprocess to be terminated by SIGTERM", err)
+	}
+	if f.signal(syscall.SIGTERM) {
+		t.Errorf("signal() relayed a signal to a subprocess that exited")
+	}
+
+	close(sigs)
+	<-done
+}
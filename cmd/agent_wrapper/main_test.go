@@ -268,7 +268,7 @@ func TestAgentWrapper(t *testing.T) {
 			}
 
 			// Run command to print specific amount of bytes
-			if err := run(ts.getLogFile(), ts.getConfigPath(), getCommand(tc.bytesWritten)); err != nil {
+			if err := run(ts.getLogFile(), ts.getConfigPath(), getCommand(tc.bytesWritten), nil); err != nil {
 				t.Fatal(err)
 			}
 			time.Sleep(100 * time.Millisecond)
@@ -18,9 +18,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"os"
 	"os/exec"
 	"unsafe"
 
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
 	"golang.org/x/sys/windows"
 )
 
@@ -55,12 +60,41 @@ func configureJob() (*windows.Handle, error) {
 	return &jobHandle, nil
 }
 
-func runCommand(cmd *exec.Cmd) error {
+// reportRestart writes a restart of the subagent to the operational event log, so that it can be
+// alerted on with Windows tooling.
+func reportRestart(subagent, msg, reason string, restarts int) {
+	l, err := logs.OpenOperationalLog()
+	if err != nil {
+		log.Printf("Failed to open the %s event log: %v", logs.OperationalLogName, err)
+		return
+	}
+	defer l.Close()
+	l.Warning(logs.SubagentRestartedEventID, msg,
+		"subagent", subagent,
+		"reason", reason,
+		"restart_count", restarts)
+}
+
+// readProcessUsage is not supported on Windows, so the watermarks are never exceeded.
+func readProcessUsage(pid int) (processUsage, error) {
+	return processUsage{}, errors.ErrUnsupported
+}
+
+// stopGracefully kills p, since console processes can't be signaled on Windows.
+func stopGracefully(ctx context.Context, p *os.Process) {
+	p.Kill()
+}
+
+func runCommand(cmd *exec.Cmd, started func()) error {
 	handle, err := configureJob()
 	if err != nil {
 		return err
 	}
 	defer windows.CloseHandle(*handle)
 
-	return cmd.Run()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	started()
+	return cmd.Wait()
 }
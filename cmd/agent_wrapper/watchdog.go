@@ -0,0 +1,203 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	initialRestartBackoff = time.Second
+	maxRestartBackoff     = 5 * time.Minute
+	// The backoff starts over once the subagent has run this long without being restarted.
+	restartBackoffResetAfter = time.Hour
+)
+
+// A watchdog kills the subagent when it stops making progress. Progress is a successful
+// response from healthURL when it is set, and otherwise any output from the subagent. A timeout
+// of zero disables the hang detection. The watchdog also stops the subagent when its resource
+// usage exceeds the watermarks.
+type watchdog struct {
+	timeout   time.Duration
+	healthURL string
+	client    *http.Client
+	now       func() time.Time
+
+	watermarks watermarks
+
+	mu                sync.Mutex
+	lastProgress      time.Time
+	reason            string
+	watermarkExceeded bool
+
+	restarts int
+	backoff  time.Duration
+}
+
+func newWatchdog(timeout time.Duration, healthURL string) *watchdog {
+	return &watchdog{
+		timeout:   timeout,
+		healthURL: healthURL,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		now:       time.Now,
+	}
+}
+
+func (w *watchdog) progress() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastProgress = w.now()
+}
+
+// Writer returns a writer that forwards to out and records the subagent output as progress.
+func (w *watchdog) Writer(out io.Writer) io.Writer {
+	if w.healthURL != "" {
+		return out
+	}
+	return progressWriter{out, w}
+}
+
+type progressWriter struct {
+	io.Writer
+	w *watchdog
+}
+
+func (pw progressWriter) Write(p []byte) (int, error) {
+	pw.w.progress()
+	return pw.Writer.Write(p)
+}
+
+func (w *watchdog) healthy(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.healthURL, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode < 300
+}
+
+// check returns why the subagent is considered hung, or "" if it is not.
+func (w *watchdog) check(ctx context.Context) string {
+	if w.healthURL != "" && w.healthy(ctx) {
+		w.progress()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.now().Sub(w.lastProgress) < w.timeout {
+		return ""
+	}
+	if w.healthURL != "" {
+		return "health endpoint " + w.healthURL + " did not respond for " + w.timeout.String()
+	}
+	return "no output for " + w.timeout.String()
+}
+
+// Watch kills cmd when it makes no progress for the watchdog timeout, and stops it gracefully
+// when it exceeds the watermarks, until ctx is done. It must be called after cmd is started.
+func (w *watchdog) Watch(ctx context.Context, cmd *exec.Cmd) {
+	w.mu.Lock()
+	w.lastProgress = w.now()
+	w.reason = ""
+	w.watermarkExceeded = false
+	w.mu.Unlock()
+	w.watermarks.reset()
+
+	var hangChecks, watermarkChecks <-chan time.Time
+	if w.timeout > 0 {
+		interval := w.timeout / 4
+		if interval < time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		hangChecks = ticker.C
+	}
+	if w.watermarks.enabled() {
+		ticker := time.NewTicker(watermarkCheckInterval)
+		defer ticker.Stop()
+		watermarkChecks = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangChecks:
+			if reason := w.check(ctx); reason != "" {
+				w.stopped(reason, false)
+				cmd.Process.Kill()
+				return
+			}
+		case <-watermarkChecks:
+			usage, err := readProcessUsage(cmd.Process.Pid)
+			if err != nil {
+				continue
+			}
+			if reason := w.watermarks.check(usage, w.now()); reason != "" {
+				w.stopped(reason, true)
+				stopGracefully(ctx, cmd.Process)
+				return
+			}
+		}
+	}
+}
+
+func (w *watchdog) stopped(reason string, watermarkExceeded bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reason = reason
+	w.watermarkExceeded = watermarkExceeded
+}
+
+// Reason returns why the watchdog killed the last run of the subagent, or "" if it did not.
+func (w *watchdog) Reason() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reason
+}
+
+// WatermarkExceeded returns whether the watchdog stopped the last run of the subagent because it
+// exceeded a watermark.
+func (w *watchdog) WatermarkExceeded() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.watermarkExceeded
+}
+
+// NextBackoff returns how long to wait before restarting a subagent that ran for ranFor.
+func (w *watchdog) NextBackoff(ranFor time.Duration) time.Duration {
+	if ranFor >= restartBackoffResetAfter {
+		w.backoff = 0
+	}
+	if w.backoff == 0 {
+		w.backoff = initialRestartBackoff
+	} else {
+		w.backoff *= 2
+	}
+	if w.backoff > maxRestartBackoff {
+		w.backoff = maxRestartBackoff
+	}
+	w.restarts++
+	return w.backoff
+}
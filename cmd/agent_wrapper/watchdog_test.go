@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatchdogBackoff(t *testing.T) {
+	w := newWatchdog(time.Minute, "")
+	var got []time.Duration
+	for i := 0; i < 12; i++ {
+		got = append(got, w.NextBackoff(time.Minute))
+	}
+	want := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second,
+		64 * time.Second, 128 * time.Second, 256 * time.Second, 5 * time.Minute, 5 * time.Minute, 5 * time.Minute,
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("backoff %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if got := w.NextBackoff(2 * time.Hour); got != time.Second {
+		t.Errorf("backoff after a long run = %v, want %v", got, time.Second)
+	}
+}
+
+func TestWatchdogOutput(t *testing.T) {
+	now := time.Date(2024, 2, 14, 9, 0, 0, 0, time.UTC)
+	w := newWatchdog(time.Minute, "")
+	w.now = func() time.Time { return now }
+	out := w.Writer(io.Discard)
+
+	w.progress()
+	now = now.Add(50 * time.Second)
+	if reason := w.check(context.Background()); reason != "" {
+		t.Fatalf("check() = %q before the timeout", reason)
+	}
+	out.Write([]byte("still alive\n"))
+	now = now.Add(50 * time.Second)
+	if reason := w.check(context.Background()); reason != "" {
+		t.Fatalf("check() = %q after output", reason)
+	}
+	now = now.Add(time.Minute)
+	if reason := w.check(context.Background()); reason != "no output for 1m0s" {
+		t.Errorf("check() = %q, want a hang", reason)
+	}
+}
+
+func TestWatchdogHealthURL(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 2, 14, 9, 0, 0, 0, time.UTC)
+	w := newWatchdog(time.Minute, server.URL)
+	w.now = func() time.Time { return now }
+	w.progress()
+
+	now = now.Add(2 * time.Minute)
+	if reason := w.check(context.Background()); reason != "" {
+		t.Fatalf("check() = %q while the endpoint is healthy", reason)
+	}
+	healthy = false
+	now = now.Add(30 * time.Second)
+	if reason := w.check(context.Background()); reason != "" {
+		t.Fatalf("check() = %q before the timeout", reason)
+	}
+	now = now.Add(time.Minute)
+	if reason := w.check(context.Background()); reason == "" {
+		t.Errorf("check() did not report a hang")
+	}
+}
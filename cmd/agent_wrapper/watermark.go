@@ -0,0 +1,121 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+)
+
+const (
+	// watermarkCheckInterval is how often the resource usage of the subagent is read.
+	watermarkCheckInterval = 15 * time.Second
+	// cpuWatermarkDuration is how long the CPU usage must stay above the watermark, so that
+	// bursts, e.g. while the backlog is sent after a network outage, don't restart the subagent.
+	cpuWatermarkDuration = 5 * time.Minute
+	// stopGracePeriod is how long a subagent that exceeded a watermark has to flush its buffers
+	// and exit before it is killed.
+	stopGracePeriod = 30 * time.Second
+)
+
+// processUsage is the resource usage of a process.
+type processUsage struct {
+	// rss is the resident memory in bytes.
+	rss uint64
+	// cpuTime is the CPU time used since the process started.
+	cpuTime time.Duration
+}
+
+// watermarks restart the subagent before the OOM killer kills it and drops the chunks that it
+// holds in memory. Unlike the OOM killer, the restart stops the subagent gracefully, so it can
+// flush its buffers to the storage.
+type watermarks struct {
+	// memory is the resident memory in bytes above which the subagent is restarted.
+	// Zero disables it.
+	memory uint64
+	// cpu is the number of CPU cores above which the subagent is restarted when it uses them for
+	// cpuWatermarkDuration. Zero disables it.
+	cpu float64
+
+	last       processUsage
+	lastTime   time.Time
+	cpuAboveAt time.Time
+}
+
+func (wm *watermarks) enabled() bool {
+	return wm.memory > 0 || wm.cpu > 0
+}
+
+// setDefaults sets the watermarks that are not set yet to the watermarks of the logging or
+// metrics service in uc, depending on subagent.
+func (wm *watermarks) setDefaults(uc *confgenerator.UnifiedConfig, subagent string) error {
+	var c *confgenerator.SubagentWatermarks
+	switch subagent {
+	case "logging":
+		if uc.Logging != nil && uc.Logging.Service != nil {
+			c = uc.Logging.Service.Watermarks
+		}
+	case "metrics":
+		if uc.Metrics != nil && uc.Metrics.Service != nil {
+			c = uc.Metrics.Service.Watermarks
+		}
+	default:
+		return fmt.Errorf("unknown subagent %q", subagent)
+	}
+	if c == nil {
+		return nil
+	}
+	if wm.memory == 0 {
+		wm.memory = c.MemoryMiB << 20
+	}
+	if wm.cpu == 0 {
+		wm.cpu = c.CPUCores
+	}
+	return nil
+}
+
+// reset forgets the usage of the previous run of the subagent.
+func (wm *watermarks) reset() {
+	wm.last, wm.lastTime, wm.cpuAboveAt = processUsage{}, time.Time{}, time.Time{}
+}
+
+// check returns which watermark usage exceeds at now, or "" if it exceeds none.
+func (wm *watermarks) check(usage processUsage, now time.Time) string {
+	if wm.memory > 0 && usage.rss > wm.memory {
+		return fmt.Sprintf("resident memory of %d MiB exceeded the watermark of %d MiB", usage.rss>>20, wm.memory>>20)
+	}
+	if wm.cpu <= 0 {
+		return ""
+	}
+	last, lastTime := wm.last, wm.lastTime
+	wm.last, wm.lastTime = usage, now
+	if lastTime.IsZero() || !now.After(lastTime) {
+		return ""
+	}
+	cores := float64(usage.cpuTime-last.cpuTime) / float64(now.Sub(lastTime))
+	if cores <= wm.cpu {
+		wm.cpuAboveAt = time.Time{}
+		return ""
+	}
+	if wm.cpuAboveAt.IsZero() {
+		wm.cpuAboveAt = lastTime
+	}
+	if now.Sub(wm.cpuAboveAt) < cpuWatermarkDuration {
+		return ""
+	}
+	return fmt.Sprintf("CPU usage exceeded the watermark of %g cores for %s", wm.cpu, cpuWatermarkDuration)
+}
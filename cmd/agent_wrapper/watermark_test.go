@@ -0,0 +1,94 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+)
+
+func TestMemoryWatermark(t *testing.T) {
+	wm := watermarks{memory: 512 << 20}
+	now := time.Date(2024, 2, 14, 9, 0, 0, 0, time.UTC)
+	if reason := wm.check(processUsage{rss: 400 << 20}, now); reason != "" {
+		t.Fatalf("check() = %q below the watermark", reason)
+	}
+	want := "resident memory of 600 MiB exceeded the watermark of 512 MiB"
+	if reason := wm.check(processUsage{rss: 600 << 20}, now.Add(watermarkCheckInterval)); reason != want {
+		t.Errorf("check() = %q, want %q", reason, want)
+	}
+}
+
+func TestCPUWatermark(t *testing.T) {
+	wm := watermarks{cpu: 1.5}
+	now := time.Date(2024, 2, 14, 9, 0, 0, 0, time.UTC)
+	var usage processUsage
+	// step advances the time by the check interval, during which the subagent used cores.
+	step := func(cores float64) string {
+		now = now.Add(watermarkCheckInterval)
+		usage.cpuTime += time.Duration(cores * float64(watermarkCheckInterval))
+		return wm.check(usage, now)
+	}
+	if reason := wm.check(usage, now); reason != "" {
+		t.Fatalf("check() = %q on the first check", reason)
+	}
+	for i := 0; i < 10; i++ {
+		if reason := step(2); reason != "" {
+			t.Fatalf("check() = %q after a burst of %s", reason, time.Duration(i+1)*watermarkCheckInterval)
+		}
+	}
+	// The usage drops below the watermark, so it must exceed it for the full duration again.
+	if reason := step(1); reason != "" {
+		t.Fatalf("check() = %q below the watermark", reason)
+	}
+	checks := int(cpuWatermarkDuration / watermarkCheckInterval)
+	for i := 1; i < checks; i++ {
+		if reason := step(2); reason != "" {
+			t.Fatalf("check() = %q after %s above the watermark", reason, time.Duration(i)*watermarkCheckInterval)
+		}
+	}
+	want := "CPU usage exceeded the watermark of 1.5 cores for 5m0s"
+	if reason := step(2); reason != want {
+		t.Errorf("check() = %q, want %q", reason, want)
+	}
+}
+
+func TestWatermarksSetDefaults(t *testing.T) {
+	uc := &confgenerator.UnifiedConfig{
+		Logging: &confgenerator.Logging{Service: &confgenerator.LoggingService{
+			Watermarks: &confgenerator.SubagentWatermarks{MemoryMiB: 512, CPUCores: 1.5},
+		}},
+	}
+	// The flags take precedence over the config.
+	wm := watermarks{cpu: 2}
+	if err := wm.setDefaults(uc, "logging"); err != nil {
+		t.Fatal(err)
+	}
+	if wm.memory != 512<<20 || wm.cpu != 2 {
+		t.Errorf("setDefaults() = {memory: %d, cpu: %g}, want {memory: %d, cpu: 2}", wm.memory, wm.cpu, 512<<20)
+	}
+	wm = watermarks{}
+	if err := wm.setDefaults(uc, "metrics"); err != nil {
+		t.Fatal(err)
+	}
+	if wm.enabled() {
+		t.Errorf("setDefaults() enabled the watermarks of the metrics subagent, which has none")
+	}
+	if err := wm.setDefaults(uc, "traces"); err == nil {
+		t.Errorf("setDefaults() of an unknown subagent succeeded")
+	}
+}
@@ -15,11 +15,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
 
+	"cloud.google.com/go/compute/metadata"
 	"github.com/GoogleCloudPlatform/ops-agent/apps"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/adaptive_sampling"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/log_staleness"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/offline_buffering"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/opamp"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/prometheus_targets"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/readiness"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/self_metrics"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/tls_rotation"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
 )
 
 // getUserAndMergedConfigs if successful will return both the users original
@@ -41,6 +60,242 @@ func getUserAndMergedConfigs(ctx context.Context, userConfPath string) (*confgen
 	return userUc, mergedUc, nil
 }
 
+// opampOptions returns the options of the OpAMP client, if global.opamp enables it. restart
+// restarts the agent once a pushed config was written to userConfPath. reloadLogging, if not nil,
+// is used instead when only the logging config changed. The nonces of the pushed configs are
+// recorded in stateDir.
+func opampOptions(ctx context.Context, mergedUc *confgenerator.UnifiedConfig, userConfPath, logsDir, stateDir string, restart, reloadLogging func()) (opamp.Options, bool, error) {
+	if mergedUc.Global == nil || mergedUc.Global.OpAMP == nil {
+		return opamp.Options{}, false, nil
+	}
+	cfg := mergedUc.Global.OpAMP
+	id, err := os.Hostname()
+	if err != nil {
+		return opamp.Options{}, false, err
+	}
+	if metadata.OnGCE() {
+		if instanceID, err := metadata.InstanceIDWithContext(ctx); err == nil {
+			id = instanceID
+		}
+	}
+	opts := opamp.Options{
+		Endpoint:     cfg.Endpoint,
+		PollInterval: cfg.GetPollInterval(),
+		InstanceUID:  opamp.InstanceUID(id),
+		IdentifyingAttributes: map[string]string{
+			"service.name":    "google-cloud-ops-agent",
+			"service.version": version.Version,
+		},
+		NonIdentifyingAttributes: map[string]string{
+			"host.name": id,
+			"os.type":   runtime.GOOS,
+		},
+		// The configs are marshaled again, which redacts their secrets.
+		EffectiveConfig: func() (map[string][]byte, error) {
+			userUc, err := confgenerator.ReadUnifiedConfigFromFile(ctx, userConfPath)
+			if err != nil {
+				return nil, err
+			}
+			var userConf []byte
+			if userUc != nil {
+				userConf = []byte(userUc.String())
+			}
+			return map[string][]byte{
+				opamp.ConfigFile: userConf,
+				"merged.yaml":    []byte(mergedUc.String()),
+			}, nil
+		},
+		Health: subagentHealth,
+	}
+	if cfg.PublicKeyPath != "" {
+		key, err := opamp.ReadPublicKey(cfg.PublicKeyPath)
+		if err != nil {
+			return opamp.Options{}, false, fmt.Errorf("failed to read global.opamp.public_key_path: %w", err)
+		}
+		opts.PublicKey = key
+		opts.NoncesPath = filepath.Join(stateDir, "opamp_nonces.json")
+		// A logging only change is reloaded without restarting the diagnostics service, so the
+		// applied config is tracked here.
+		var onlyLogging bool
+		opts.Apply = func(ctx context.Context, config []byte) (bool, error) {
+			uc, err := applyConfig(ctx, config, userConfPath, logsDir)
+			if err != nil || uc == nil {
+				return false, err
+			}
+			onlyLogging = reloadLogging != nil && confgenerator.OnlyLoggingChanged(mergedUc, uc)
+			mergedUc = uc
+			return true, nil
+		}
+		opts.Restart = func() {
+			if onlyLogging {
+				reloadLogging()
+				return
+			}
+			restart()
+		}
+	}
+	return opts, true, nil
+}
+
+// adaptiveSamplingOptions returns the options of the adaptive sampling of the logs of uc, whose
+// rate file is next to the logging agent buffers.
+func adaptiveSamplingOptions(uc *confgenerator.UnifiedConfig, buffersDir string) (adaptive_sampling.Options, bool) {
+	if uc.Logging == nil || uc.Logging.Service == nil || uc.Logging.Service.AdaptiveSampling == nil {
+		return adaptive_sampling.Options{}, false
+	}
+	return adaptive_sampling.Options{
+		Path:     confgenerator.AdaptiveSamplingRatePath(filepath.Dir(buffersDir)),
+		MinRate:  uc.Logging.Service.AdaptiveSampling.MinSamplingRate,
+		Interval: time.Minute,
+		Count:    adaptive_sampling.CountRejectedRequests,
+	}, true
+}
+
+// offlineBufferingOptions returns the options of the watcher that enforces the retention of the
+// logs buffered in buffersDir, if global.offline_buffering is set.
+func offlineBufferingOptions(uc *confgenerator.UnifiedConfig, buffersDir string) (offline_buffering.Options, bool) {
+	if uc.Global == nil || uc.Global.OfflineBuffering == nil {
+		return offline_buffering.Options{}, false
+	}
+	return offline_buffering.Options{
+		BuffersDir: buffersDir,
+		Retention:  *uc.Global.OfflineBuffering.Retention,
+		Interval:   10 * time.Minute,
+		Record:     self_metrics.RecordDroppedBufferedBytes,
+	}, true
+}
+
+// tlsRotationOptions returns the options of the watcher that reloads the logging subagent with
+// reload when its TLS files change.
+func tlsRotationOptions(uc *confgenerator.UnifiedConfig, reload func()) (tls_rotation.Options, bool) {
+	paths := uc.LoggingTLSFiles()
+	return tls_rotation.Options{
+		Paths:    paths,
+		Interval: 30 * time.Second,
+		Reload:   reload,
+	}, len(paths) > 0
+}
+
+// logStalenessOptions returns the options of the log staleness watchdog for the files receivers of
+// uc with a staleness alert.
+func logStalenessOptions(uc *confgenerator.UnifiedConfig) (log_staleness.Options, bool) {
+	opts := log_staleness.Options{
+		Interval: time.Minute,
+		Record:   self_metrics.RecordStaleFiles,
+	}
+	if uc.Logging == nil {
+		return opts, false
+	}
+	var ids []string
+	for id := range uc.Logging.Receivers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		r, ok := uc.Logging.Receivers[id].(*confgenerator.LoggingReceiverFiles)
+		if !ok || r.StalenessAlert == nil {
+			continue
+		}
+		opts.Receivers = append(opts.Receivers, log_staleness.Receiver{
+			ID:           id,
+			IncludePaths: r.IncludePaths,
+			ExcludePaths: r.ExcludePaths,
+			Threshold:    *r.StalenessAlert,
+		})
+	}
+	return opts, len(opts.Receivers) > 0
+}
+
+// prometheusTargetsOptions returns the options of the watcher of the scrape jobs of the prometheus
+// receivers that are used in a metrics pipeline of uc.
+func prometheusTargetsOptions(uc *confgenerator.UnifiedConfig) (prometheus_targets.Options, bool) {
+	opts := prometheus_targets.Options{
+		URL:       fmt.Sprintf("http://127.0.0.1:%d/metrics", confgenerator.ScrapeStatusPort),
+		Intervals: 3,
+		Interval:  time.Minute,
+		Record:    self_metrics.RecordTruncatedScrapes,
+	}
+	if uc.Metrics == nil || uc.Metrics.Service == nil {
+		return opts, false
+	}
+	used := map[string]bool{}
+	for _, p := range uc.Metrics.Service.Pipelines {
+		for _, id := range p.ReceiverIDs {
+			used[id] = true
+		}
+	}
+	for id := range used {
+		r, ok := uc.Metrics.Receivers[id].(*confgenerator.PrometheusMetrics)
+		if !ok {
+			continue
+		}
+		for _, sc := range r.PromConfig.ScrapeConfigs {
+			interval := time.Duration(sc.ScrapeInterval)
+			opts.Jobs = append(opts.Jobs, prometheus_targets.Job{
+				ReceiverID:     id,
+				Name:           sc.JobName,
+				ScrapeInterval: interval,
+			})
+			if interval > 0 && interval < opts.Interval {
+				opts.Interval = interval
+			}
+		}
+	}
+	return opts, len(opts.Jobs) > 0
+}
+
+// subagentHealth reports a subagent as unhealthy until it has exported telemetry.
+func subagentHealth(ctx context.Context) map[string]error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	health := map[string]error{}
+	for name, probe := range readiness.Probes {
+		exported, err := probe.Exported(ctx, client)
+		if err == nil && !exported {
+			err = errors.New("has not exported any telemetry yet")
+		}
+		health[name] = err
+	}
+	return health
+}
+
+// applyConfig validates config and replaces the user config with it. It returns the new merged
+// config, or nil if the user config already is config.
+func applyConfig(ctx context.Context, config []byte, userConfPath, logsDir string) (*confgenerator.UnifiedConfig, error) {
+	current, err := os.ReadFile(userConfPath)
+	if err == nil && bytes.Equal(current, config) {
+		return nil, nil
+	}
+	f, err := os.CreateTemp(filepath.Dir(userConfPath), ".config-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(config)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	uc, err := confgenerator.MergeConfFiles(ctx, f.Name(), apps.BuiltInConfStructs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := uc.GenerateOtelConfig(ctx); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if _, err := uc.GenerateFluentBitConfigs(ctx, logsDir, ""); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), userConfPath); err != nil {
+		return nil, err
+	}
+	return uc, nil
+}
+
 func main() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -19,17 +19,88 @@ package main
 import (
 	"context"
 	"flag"
+	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"regexp"
 	"syscall"
+	"time"
 
+	"cloud.google.com/go/compute/metadata"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/adaptive_sampling"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/log_staleness"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/offline_buffering"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/opamp"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/preemption"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/process_events"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/prometheus_targets"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/self_metrics"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/tls_rotation"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version_check"
 )
 
 var (
-	config = flag.String("config", "/etc/google-cloud-ops-agent/config.yaml", "path to the user specified agent config")
+	config     = flag.String("config", "/etc/google-cloud-ops-agent/config.yaml", "path to the user specified agent config")
+	logsDir    = flag.String("logs", "/var/log/google-cloud-ops-agent", "path to store agent logs")
+	buffersDir = flag.String("buffers", "/var/lib/google-cloud-ops-agent/fluent-bit/buffers", "path of the logging agent buffers")
 )
 
+// processEventsOptions combines the process_events receivers of uc. Events are written if any
+// receiver wants them, since the receivers apply their own filters and rate limits to the file.
+func processEventsOptions(uc *confgenerator.UnifiedConfig, logsDir string) (process_events.Options, bool, error) {
+	opts := process_events.Options{
+		Path: confgenerator.ProcessEventsLogsPath(logsDir),
+	}
+	if uc.Logging == nil {
+		return opts, false, nil
+	}
+	for _, r := range uc.Logging.Receivers {
+		pe, ok := r.(*confgenerator.LoggingReceiverProcessEvents)
+		if !ok {
+			continue
+		}
+		var f process_events.Filter
+		for _, p := range pe.IncludeNames {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return opts, false, err
+			}
+			f.Include = append(f.Include, re)
+		}
+		for _, p := range pe.ExcludeNames {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return opts, false, err
+			}
+			f.Exclude = append(f.Exclude, re)
+		}
+		opts.Filters = append(opts.Filters, f)
+		opts.MaxEventsPerSecond += pe.EventsPerSecond()
+	}
+	return opts, len(opts.Filters) > 0, nil
+}
+
+// restartAgent restarts the agent to use a new config. The diagnostics service is restarted with
+// it, so the restart is queued instead of waited for.
+func restartAgent() {
+	if err := exec.Command("systemctl", "restart", "--no-block", "google-cloud-ops-agent.service").Run(); err != nil {
+		log.Printf("failed to restart the agent: %v", err)
+	}
+}
+
+// reloadLogging regenerates the logging subagent config and hot reloads it, which avoids the
+// ingestion gap of a restart.
+func reloadLogging() {
+	if err := exec.Command("systemctl", "reload", "--no-block", "google-cloud-ops-agent-fluent-bit.service").Run(); err != nil {
+		log.Printf("failed to reload the logging subagent: %v", err)
+	}
+}
+
 func run(ctx context.Context) error {
 	userUc, mergedUc, err := getUserAndMergedConfigs(ctx, *config)
 	if err != nil {
@@ -53,6 +124,57 @@ func run(ctx context.Context) error {
 		}
 	}()
 
+	opts, ok, err := processEventsOptions(mergedUc, *logsDir)
+	if err != nil {
+		return err
+	}
+	if ok {
+		go func() {
+			if err := process_events.Run(ctx, opts); err != nil {
+				log.Printf("failed to collect process events: %v", err)
+			}
+		}()
+	}
+
+	if metadata.OnGCE() {
+		go preemption.Watch(ctx, metadata.NewClient(nil), preemption.Options{
+			BuffersDir: *buffersDir,
+			Start:      time.Now(),
+			Flush:      self_metrics.Flush,
+		}, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	if opampOpts, ok, err := opampOptions(ctx, mergedUc, *config, *logsDir, filepath.Dir(*buffersDir), restartAgent, reloadLogging); err != nil {
+		log.Printf("failed to configure the OpAMP client: %v", err)
+	} else if ok {
+		go opamp.Run(ctx, http.DefaultClient, opampOpts, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	if opts, ok := adaptiveSamplingOptions(mergedUc, *buffersDir); ok {
+		go adaptive_sampling.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	if opts, ok := tlsRotationOptions(mergedUc, reloadLogging); ok {
+		go tls_rotation.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	if opts, ok := offlineBufferingOptions(mergedUc, *buffersDir); ok {
+		go offline_buffering.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	if opts, ok := logStalenessOptions(mergedUc); ok {
+		go log_staleness.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	if opts, ok := prometheusTargetsOptions(mergedUc); ok {
+		go prometheus_targets.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(*logsDir))
+	}
+
+	go version_check.Watch(ctx, version_check.Options{
+		Interval: 24 * time.Hour,
+		Record:   self_metrics.RecordVersionCheck,
+	}, healthchecks.CreateHealthChecksLogger(*logsDir))
+
 	err = self_metrics.CollectOpsAgentSelfMetrics(ctx, userUc, mergedUc)
 	if err != nil {
 		return err
@@ -20,9 +20,25 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
-
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/GoogleCloudPlatform/ops-agent/apps"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/adaptive_sampling"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/dcdiag"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/log_staleness"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/offline_buffering"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/opamp"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/preemption"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/prometheus_targets"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/self_metrics"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/tls_rotation"
 	"go.opentelemetry.io/otel"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/debug"
@@ -38,9 +54,11 @@ const (
 )
 
 type service struct {
-	ctx      context.Context
-	log      debug.Log
-	userConf string
+	ctx        context.Context
+	log        debug.Log
+	userConf   string
+	logsDir    string
+	buffersDir string
 }
 
 func (s *service) Handle(err error) {
@@ -109,6 +127,45 @@ func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, changes cha
 		}
 	}()
 
+	if interval, ok := dcdiagInterval(mergedUc); ok {
+		go dcdiag.Run(ctx, apps.DcdiagLogsPath(s.logsDir), interval)
+	}
+
+	if metadata.OnGCE() {
+		go preemption.Watch(ctx, metadata.NewClient(nil), preemption.Options{
+			BuffersDir: s.buffersDir,
+			Start:      time.Now(),
+			Flush:      self_metrics.Flush,
+		}, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
+	if opts, ok := adaptiveSamplingOptions(mergedUc, s.buffersDir); ok {
+		go adaptive_sampling.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
+	if opts, ok := tlsRotationOptions(mergedUc, s.restartLogging); ok {
+		go tls_rotation.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
+	if opts, ok := offlineBufferingOptions(mergedUc, s.buffersDir); ok {
+		go offline_buffering.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
+	if opts, ok := logStalenessOptions(mergedUc); ok {
+		go log_staleness.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
+	if opts, ok := prometheusTargetsOptions(mergedUc); ok {
+		go prometheus_targets.Watch(ctx, opts, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
+	opampOpts, ok, err := opampOptions(ctx, mergedUc, s.userConf, s.logsDir, filepath.Dir(s.buffersDir), s.restartAgent, nil)
+	if err != nil {
+		s.log.Error(DiagnosticsEventID, fmt.Sprintf("failed to configure the OpAMP client: %v", err))
+	} else if ok {
+		go opamp.Run(ctx, http.DefaultClient, opampOpts, healthchecks.CreateHealthChecksLogger(s.logsDir))
+	}
+
 	// Set otel error handler
 	otel.SetErrorHandler(s)
 
@@ -121,9 +178,50 @@ func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, changes cha
 	return false, ERROR_SUCCESS
 }
 
+// restartAgent restarts the agent to use a new config. The diagnostics service is stopped with
+// it, so the restart runs in a separate process.
+func (s *service) restartAgent() {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-Command", "Restart-Service google-cloud-ops-agent -Force")
+	if err := cmd.Start(); err != nil {
+		s.log.Error(DiagnosticsEventID, fmt.Sprintf("failed to restart the agent: %v", err))
+	}
+}
+
+// restartLogging restarts the logging subagent, which can't hot reload its config on Windows.
+// The other subagents keep running.
+func (s *service) restartLogging() {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-Command", "Restart-Service google-cloud-ops-agent-fluent-bit -Force")
+	if err := cmd.Start(); err != nil {
+		s.log.Error(DiagnosticsEventID, fmt.Sprintf("failed to restart the logging subagent: %v", err))
+		return
+	}
+	go cmd.Wait()
+}
+
 func (s *service) parseFlags(args []string) error {
 	s.log.Info(DiagnosticsEventID, fmt.Sprintf("args: %#v", args))
 	var fs flag.FlagSet
 	fs.StringVar(&s.userConf, "config", "", "path to the user specified agent config")
+	fs.StringVar(&s.logsDir, "logs", "", "path to store agent logs")
+	fs.StringVar(&s.buffersDir, "buffers", "", "path of the logging agent buffers")
 	return fs.Parse(args)
 }
+
+// dcdiagInterval returns the shortest collection interval of the active_directory_ds_dcdiag
+// receivers of uc, since they all read the same file.
+func dcdiagInterval(uc *confgenerator.UnifiedConfig) (time.Duration, bool) {
+	if uc.Logging == nil {
+		return 0, false
+	}
+	var interval time.Duration
+	for _, r := range uc.Logging.Receivers {
+		d, ok := r.(*apps.LoggingReceiverActiveDirectoryDSDcdiag)
+		if !ok {
+			continue
+		}
+		if interval == 0 || d.Interval() < interval {
+			interval = d.Interval()
+		}
+	}
+	return interval, interval > 0
+}
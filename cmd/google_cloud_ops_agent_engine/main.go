@@ -17,13 +17,19 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/GoogleCloudPlatform/ops-agent/apps"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks/requirements"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/readiness"
 )
 
 var (
@@ -32,27 +38,134 @@ var (
 	input        = flag.String("in", "/etc/google-cloud-ops-agent/config.yaml", "path to the user specified agent config")
 	logsDir      = flag.String("logs", "/var/log/google-cloud-ops-agent", "path to store agent logs")
 	stateDir     = flag.String("state", "/var/lib/google-cloud-ops-agent", "path to store agent state like buffers")
-	healthChecks = flag.Bool("healthchecks", false, "run health checks and exit")
+	healthChecks = flag.Bool("healthchecks", false, "run health checks against the config passed with -in and exit")
+	format       = flag.String("format", "text", "format of the -healthchecks results: text or json")
+	waitReady    = flag.Bool("wait-ready", false, "wait until every subagent has exported for the first time and exit")
+	readyTimeout = flag.Duration("wait-ready-timeout", 5*time.Minute, "how long -wait-ready waits before failing")
+	listFeatures = flag.Bool("list-features", false, "list the feature gates that can be enabled with global.experimental or EXPERIMENTAL_FEATURES and exit")
+	trigger      = flag.String("trigger", confgenerator.ConfigTriggerStart, "what makes the agent apply the config, which is recorded in the config change entry: start or reload")
+	explain      = flag.Bool("explain", false, "print the pipelines of the config passed with -in, with the steps of every pipeline in the order in which they run, and exit")
+	reload       = flag.Bool("reload", false, "apply the config passed with -in to the running agent and exit: hot reload the logging subagent if only the logging config changed since the config was applied, otherwise restart the agent")
+	// The main config file of each format has its own name, which Fluent Bit must be started
+	// with, see fluentbit.ConfigFormat.MainConfigFileName.
+	fluentBitFormat = flag.String("fluent-bit-config-format", string(fluentbit.DefaultConfigFormat), "format of the main Fluent Bit config file: yaml or classic")
 )
 
-func runHealthChecks() {
+func runHealthChecks(req healthchecks.ConfigRequirements) {
 	logger := healthchecks.CreateHealthChecksLogger(*logsDir)
 
 	defaultLogger := logs.NewSimpleLogger()
 
-	healthCheckResults := healthchecks.HealthCheckRegistryFactory().RunAllHealthChecks(logger)
+	healthCheckResults := healthchecks.HealthCheckRegistryFactory(req).WithCommandChecks(healthchecks.CommandChecksDir(*input)).RunAllHealthChecks(logger)
+	if err := healthchecks.WriteHealthCheckResults(healthCheckResults, *stateDir); err != nil {
+		log.Printf("Failed to write the health check results: %v", err)
+	}
+	if *healthChecks && *format == "json" {
+		data, err := healthchecks.MarshalHealthCheckResults(healthCheckResults, time.Now())
+		if err != nil {
+			log.Printf("Failed to encode the health check results: %v", err)
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
 	healthchecks.LogHealthCheckResults(healthCheckResults, defaultLogger)
 }
 
+// validateSubagentConfigs generates the configs for all subagents without writing them.
+func validateSubagentConfigs(ctx context.Context, uc *confgenerator.UnifiedConfig) error {
+	_, err := confgenerator.Generate(ctx, uc, confgenerator.Options{
+		Paths: confgenerator.Paths{
+			LogsDir:  *logsDir,
+			StateDir: *stateDir,
+		},
+	})
+	return err
+}
+
+// waitUntilReady waits until the subagents are ingesting, so that orchestration tools can tell
+// when the agent is actually up rather than just started.
+func waitUntilReady() error {
+	ctx, cancel := context.WithTimeout(context.Background(), *readyTimeout)
+	defer cancel()
+	return readiness.Wait(ctx, time.Second, readiness.Probes["fluentbit"], readiness.Probes["otel"])
+}
+
+// reloadAgent applies a changed config with the least disruption. A change of the logging config
+// only is hot reloaded by the logging subagent, which keeps ingesting meanwhile, while any other
+// change restarts the agent.
+func reloadAgent() error {
+	ctx := confgenerator.ContextWithFluentBitConfigFormat(context.Background(), fluentbit.ConfigFormat(*fluentBitFormat))
+	uc, err := confgenerator.MergeConfFiles(ctx, *input, apps.BuiltInConfStructs)
+	if err != nil {
+		return err
+	}
+	// Keep the running agent if the new config can't be applied.
+	if err := validateSubagentConfigs(ctx, uc); err != nil {
+		return err
+	}
+	applied, err := confgenerator.ReadAppliedConfig(ctx, *stateDir)
+	if err != nil {
+		log.Print(err)
+	}
+	switch {
+	case applied != nil && applied.Digest() == uc.Digest():
+		log.Println("The agent config didn't change")
+		return nil
+	case confgenerator.OnlyLoggingChanged(applied, uc):
+		log.Println("Only the logging config changed, reloading the logging subagent")
+		return reloadLoggingSubagent()
+	default:
+		log.Println("Restarting the agent to apply the config")
+		return restartAgent()
+	}
+}
+
 func main() {
 	flag.Parse()
+	if *format != "text" && *format != "json" {
+		log.Fatalf("-format must be text or json, got %q", *format)
+	}
+	if f := fluentbit.ConfigFormat(*fluentBitFormat); f != fluentbit.FormatYAML && f != fluentbit.FormatClassic {
+		log.Fatalf("-fluent-bit-config-format must be yaml or classic, got %q", *fluentBitFormat)
+	}
+	if *trigger != confgenerator.ConfigTriggerStart && *trigger != confgenerator.ConfigTriggerReload {
+		log.Fatalf("-trigger must be start or reload, got %q", *trigger)
+	}
+	if *listFeatures {
+		printFeatureGates()
+		return
+	}
+	if *reload {
+		if err := reloadAgent(); err != nil {
+			log.Fatalf("Failed to apply the agent config: %s", err)
+		}
+		return
+	}
+	if *waitReady {
+		if err := waitUntilReady(); err != nil {
+			log.Fatalf("The agent is not ready: %s", err)
+		}
+		log.Println("The agent is ready")
+		return
+	}
 	if err := run(); err != nil {
 		log.Fatalf("The agent config file is not valid. Detailed error: %s", err)
 	}
 }
 
+// printFeatureGates prints the registered feature gates as a table.
+func printFeatureGates() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTAGE\tDESCRIPTION")
+	for _, g := range confgenerator.FeatureGates() {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", g.Name, g.Stage, g.Description)
+	}
+	w.Flush()
+}
+
 func run() error {
-	ctx := context.Background()
+	ctx := confgenerator.ContextWithFluentBitConfigFormat(context.Background(), fluentbit.ConfigFormat(*fluentBitFormat))
 	// TODO(lingshi) Move this to a shared place across Linux and Windows.
 	uc, err := confgenerator.MergeConfFiles(ctx, *input, apps.BuiltInConfStructs)
 	if err != nil {
@@ -65,13 +178,45 @@ func run() error {
 	log.Printf("Built-in config:\n%s", apps.BuiltInConfStructs["linux"])
 	log.Printf("Merged config:\n%s", uc)
 
+	if *explain {
+		explanation, err := uc.ExplainPipelines(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Print(explanation)
+		return nil
+	}
+
 	if *service == "" {
-		runHealthChecks()
+		if *healthChecks {
+			// The config passed with -in may be a candidate that is not applied yet,
+			// so make sure that configs can be generated from it for every subagent.
+			if err := validateSubagentConfigs(ctx, uc); err != nil {
+				return err
+			}
+		}
+		applied, err := confgenerator.ReadAppliedConfig(ctx, *stateDir)
+		if err != nil {
+			log.Print(err)
+		}
+		req, err := requirements.FromConfig(ctx, uc, applied)
+		if err != nil {
+			return err
+		}
+		runHealthChecks(req)
 		log.Println("Startup checks finished")
 		if *healthChecks {
 			// If healthchecks is set, stop here
 			return nil
 		}
+		// Record which receivers and pipelines changed since the config was last applied, so that
+		// changes to the observability coverage can be audited from the ops-agent-health log.
+		if err := uc.RecordConfigChange(ctx, *stateDir, *trigger, healthchecks.CreateHealthChecksLogger(*logsDir)); err != nil {
+			log.Printf("Failed to record the config change: %v", err)
+		}
+		if err := uc.RecordVersionDrift(healthchecks.CreateHealthChecksLogger(*logsDir)); err != nil {
+			log.Printf("Version drift: %v", err)
+		}
 	}
 	return uc.GenerateFilesFromConfig(ctx, *service, *logsDir, *stateDir, *outDir)
 }
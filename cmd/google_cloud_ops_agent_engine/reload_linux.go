@@ -0,0 +1,38 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// reloadLoggingSubagent regenerates the logging subagent config and hot reloads it, without
+// waiting: the logging subagent is ordered after the agent, so its reload job would wait for the
+// job of the agent unit that this runs from.
+func reloadLoggingSubagent() error {
+	if out, err := exec.Command("systemctl", "reload", "--no-block", "google-cloud-ops-agent-fluent-bit.service").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to reload the logging subagent: %v: %s", err, out)
+	}
+	return nil
+}
+
+// restartAgent restarts the agent without waiting, because it runs from a job of the agent unit.
+func restartAgent() error {
+	if out, err := exec.Command("systemctl", "restart", "--no-block", "google-cloud-ops-agent.service").CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to restart the agent: %v: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "errors"
+
+// The Windows service regenerates the configs of all subagents when it starts, so a changed
+// config is applied by restarting the service.
+var errReloadNotSupported = errors.New("-reload is not supported on Windows, restart the google-cloud-ops-agent service instead")
+
+func reloadLoggingSubagent() error {
+	return errReloadNotSupported
+}
+
+func restartAgent() error {
+	return errReloadNotSupported
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ticksPerSecond is how often the generators produce a batch.
+const ticksPerSecond = 100
+
+// generated is how much load was generated.
+type generated struct {
+	Logs       int64 `json:"logs"`
+	OTLPPoints int64 `json:"otlp_points"`
+}
+
+// pace calls emit with batches that add up to rate per second, until ctx is done or emit fails.
+func pace(ctx context.Context, rate int, emit func(n int) error) error {
+	ticker := time.NewTicker(time.Second / ticksPerSecond)
+	defer ticker.Stop()
+	remainder := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		remainder += rate
+		n := remainder / ticksPerSecond
+		remainder %= ticksPerSecond
+		if n == 0 {
+			continue
+		}
+		if err := emit(n); err != nil {
+			return err
+		}
+	}
+}
+
+// logLine returns the seq-th synthetic log line, padded to size bytes.
+func logLine(seq int64, size int) []byte {
+	line := fmt.Sprintf("%s loadgen seq=%d ", time.Now().UTC().Format(time.RFC3339Nano), seq)
+	if len(line) < size {
+		line += strings.Repeat("x", size-len(line))
+	}
+	return []byte(line + "\n")
+}
+
+// generateLogs appends rate lines of size bytes per second to path, and returns how many it wrote.
+func generateLogs(ctx context.Context, path string, rate, size int) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	var seq int64
+	err = pace(ctx, rate, func(n int) error {
+		for i := 0; i < n; i++ {
+			if _, err := w.Write(logLine(seq, size)); err != nil {
+				return err
+			}
+			seq++
+		}
+		return w.Flush()
+	})
+	return seq, err
+}
+
+// The OTLP/HTTP JSON encoding of the metrics, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpAttribute `json:"attributes"`
+	TimeUnixNano string          `json:"timeUnixNano"`
+	AsDouble     float64         `json:"asDouble"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   map[string]string `json:"scope"`
+	Metrics []otlpMetric      `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     map[string][]otlpAttribute `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics         `json:"scopeMetrics"`
+}
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// otlpBody returns an OTLP request with n gauge points, spread over series time series starting at *next.
+func otlpBody(n, series int, next *int) otlpRequest {
+	m := otlpMetric{Name: "loadgen.gauge"}
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for i := 0; i < n; i++ {
+		s := *next % series
+		*next++
+		m.Gauge.DataPoints = append(m.Gauge.DataPoints, otlpDataPoint{
+			Attributes:   []otlpAttribute{{Key: "series", Value: map[string]string{"stringValue": strconv.Itoa(s)}}},
+			TimeUnixNano: now,
+			AsDouble:     float64(s),
+		})
+	}
+	return otlpRequest{
+		ResourceMetrics: []otlpResourceMetrics{{
+			Resource: map[string][]otlpAttribute{
+				"attributes": {{Key: "service.name", Value: map[string]string{"stringValue": "loadgen"}}},
+			},
+			ScopeMetrics: []otlpScopeMetrics{{
+				Scope:   map[string]string{"name": "loadgen"},
+				Metrics: []otlpMetric{m},
+			}},
+		}},
+	}
+}
+
+// sendOTLPMetrics sends rate gauge points per second to the OTLP/HTTP endpoint, and returns how many
+// the endpoint accepted.
+func sendOTLPMetrics(ctx context.Context, endpoint string, rate, series int) (int64, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := strings.TrimSuffix(endpoint, "/") + "/v1/metrics"
+	var sent int64
+	next := 0
+	err := pace(ctx, rate, func(n int) error {
+		body, err := json.Marshal(otlpBody(n, series, &next))
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			// Keep going; points that could not be sent are not counted as generated.
+			return nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			sent += int64(n)
+		}
+		return nil
+	})
+	return sent, err
+}
+
+// writePrometheusMetrics writes series gauges in the Prometheus text exposition format.
+func writePrometheusMetrics(w io.Writer, series int, value float64) {
+	fmt.Fprintln(w, "# HELP loadgen_gauge A synthetic gauge.")
+	fmt.Fprintln(w, "# TYPE loadgen_gauge gauge")
+	for i := 0; i < series; i++ {
+		fmt.Fprintf(w, "loadgen_gauge{series=\"%d\"} %g\n", i, value+float64(i))
+	}
+}
+
+// servePrometheusMetrics serves series synthetic gauges on addr until ctx is done.
+func servePrometheusMetrics(ctx context.Context, addr string, series int) error {
+	start := time.Now()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheusMetrics(w, series, time.Since(start).Seconds())
+	})
+	server := &http.Server{Handler: mux}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
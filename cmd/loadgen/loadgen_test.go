@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogLine(t *testing.T) {
+	line := logLine(42, 100)
+	if len(line) != 101 {
+		t.Errorf("len(logLine()) = %d, want 101", len(line))
+	}
+	if !strings.Contains(string(line), "seq=42 ") || !bytes.HasSuffix(line, []byte("x\n")) {
+		t.Errorf("unexpected line %q", line)
+	}
+}
+
+func TestPace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	total := 0
+	if err := pace(ctx, 250, func(n int) error {
+		total += n
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 250/s for half a second, allowing for a slow ticker.
+	if total < 60 || total > 130 {
+		t.Errorf("pace() emitted %d in 500ms at 250/s", total)
+	}
+}
+
+func TestOTLPBody(t *testing.T) {
+	next := 0
+	body, err := json.Marshal(otlpBody(5, 3, &next))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded otlpRequest
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	points := decoded.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Gauge.DataPoints
+	var series []string
+	for _, p := range points {
+		series = append(series, p.Attributes[0].Value["stringValue"])
+	}
+	if got := strings.Join(series, ","); got != "0,1,2,0,1" {
+		t.Errorf("series = %s, want 0,1,2,0,1", got)
+	}
+	if next != 5 {
+		t.Errorf("next = %d, want 5", next)
+	}
+}
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	var b bytes.Buffer
+	writePrometheusMetrics(&b, 2, 10)
+	want := "# HELP loadgen_gauge A synthetic gauge.\n" +
+		"# TYPE loadgen_gauge gauge\n" +
+		"loadgen_gauge{series=\"0\"} 10\n" +
+		"loadgen_gauge{series=\"1\"} 11\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	before := snapshot{"logging.output_records": 100, "metrics.sent_points": 5}
+	after := snapshot{"logging.output_records": 1100, "metrics.sent_points": 5, "logging.dropped_records": 3}
+	r := newReport(10*time.Second, generated{Logs: 1000}, before, after, nil)
+	if r.Counters["logging.output_records"] != 1000 || r.Throughput["logging.output_records"] != 100 {
+		t.Errorf("unexpected output records in %+v", r)
+	}
+	if _, ok := r.Counters["logging.dropped_records"]; ok {
+		t.Errorf("a counter missing before the run was reported: %+v", r)
+	}
+}
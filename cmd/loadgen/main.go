@@ -0,0 +1,123 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// loadgen generates synthetic logs and metrics against a locally running Ops Agent, and reports
+// how the subagents coped with them. See README.md.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+var (
+	duration = flag.Duration("duration", time.Minute, "How long to generate load for")
+
+	logFile = flag.String("log_file", "", "File to append synthetic log lines to. Configure a files receiver to tail it. If empty, no logs are generated")
+	logRate = flag.Int("log_rate", 1000, "Log lines per second")
+	logSize = flag.Int("log_size", 256, "Size of each log line in bytes, excluding the newline")
+
+	otlpEndpoint = flag.String("otlp_endpoint", "", "OTLP/HTTP endpoint to send synthetic metrics to, e.g. http://127.0.0.1:4318. If empty, no OTLP metrics are sent")
+	otlpRate     = flag.Int("otlp_rate", 1000, "OTLP metric points per second")
+	otlpSeries   = flag.Int("otlp_series", 100, "Number of distinct OTLP time series")
+
+	prometheusListen = flag.String("prometheus_listen", "", "Address to serve synthetic Prometheus metrics on, e.g. 127.0.0.1:9464. Configure a prometheus receiver to scrape it. If empty, no Prometheus metrics are served")
+	prometheusSeries = flag.Int("prometheus_series", 1000, "Number of Prometheus time series")
+
+	report     = flag.Bool("report", true, "Report the throughput, drops and resource usage of the subagents after the run")
+	reportJSON = flag.String("report_json", "", "File to write the report to as JSON, so that runs can be compared. If empty, the report is only logged")
+)
+
+func main() {
+	flag.Parse()
+	if *logFile == "" && *otlpEndpoint == "" && *prometheusListen == "" {
+		flag.Usage()
+		log.Fatal("At least one of -log_file, -otlp_endpoint and -prometheus_listen must be set")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	var before snapshot
+	var usage *usageSampler
+	if *report {
+		before = takeSnapshot(ctx)
+		usage = newUsageSampler()
+	}
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	// Each generator sets its own field, which is only read after wg.Wait.
+	var gen generated
+	if *logFile != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			gen.Logs, err = generateLogs(ctx, *logFile, *logRate, *logSize)
+			if err != nil {
+				log.Printf("Failed to generate logs: %v", err)
+			}
+		}()
+	}
+	if *otlpEndpoint != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			gen.OTLPPoints, err = sendOTLPMetrics(ctx, *otlpEndpoint, *otlpRate, *otlpSeries)
+			if err != nil {
+				log.Printf("Failed to send OTLP metrics: %v", err)
+			}
+		}()
+	}
+	if *prometheusListen != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := servePrometheusMetrics(ctx, *prometheusListen, *prometheusSeries); err != nil {
+				log.Printf("Failed to serve Prometheus metrics: %v", err)
+			}
+		}()
+	}
+	if usage != nil {
+		go usage.run(ctx)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	if !*report {
+		return
+	}
+	// Give the subagents a moment to flush what was generated last.
+	time.Sleep(5 * time.Second)
+	r := newReport(elapsed, gen, before, takeSnapshot(context.Background()), usage.result())
+	r.log()
+	if *reportJSON != "" {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*reportJSON, b, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
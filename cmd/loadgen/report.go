@@ -0,0 +1,250 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/readiness"
+	"github.com/shirou/gopsutil/process"
+)
+
+// counters are the subagent self metrics compared before and after a run.
+var counters = map[string]readiness.Probe{
+	"logging.input_records":   withMetric(readiness.Probes["fluentbit"], "fluentbit_input_records_total", nil),
+	"logging.output_records":  readiness.Probes["fluentbit"],
+	"logging.retried_records": withMetric(readiness.Probes["fluentbit"], "fluentbit_output_retried_records_total", readiness.Probes["fluentbit"].Labels),
+	"logging.dropped_records": withMetric(readiness.Probes["fluentbit"], "fluentbit_output_dropped_records_total", readiness.Probes["fluentbit"].Labels),
+	"metrics.accepted_points": withMetric(readiness.Probes["otel"], "otelcol_receiver_accepted_metric_points", nil),
+	"metrics.refused_points":  withMetric(readiness.Probes["otel"], "otelcol_receiver_refused_metric_points", nil),
+	"metrics.sent_points":     readiness.Probes["otel"],
+	"metrics.failed_points":   withMetric(readiness.Probes["otel"], "otelcol_exporter_send_failed_metric_points", nil),
+}
+
+func withMetric(p readiness.Probe, metric string, labels []string) readiness.Probe {
+	p.Metric = metric
+	p.Labels = labels
+	return p
+}
+
+// subagents are the process names of the subagents, by the name they are reported with.
+var subagents = map[string][]string{
+	"fluent-bit": {"fluent-bit", "fluent-bit.exe"},
+	"otel":       {"otelopscol", "google-cloud-metrics-agent_windows_amd64.exe"},
+}
+
+// A snapshot holds the value of each of the counters.
+type snapshot map[string]float64
+
+func takeSnapshot(ctx context.Context) snapshot {
+	client := &http.Client{Timeout: 10 * time.Second}
+	s := snapshot{}
+	for name, p := range counters {
+		v, err := p.Sum(ctx, client)
+		if err != nil {
+			// The subagent is not running or has not started its endpoint yet.
+			continue
+		}
+		s[name] = v
+	}
+	return s
+}
+
+// Usage is the resource usage of a subagent during a run.
+type Usage struct {
+	// CPUSeconds is the CPU time used during the run.
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// CPUPercent is CPUSeconds relative to the run duration; 100 is one full CPU.
+	CPUPercent float64 `json:"cpu_percent"`
+	MaxRSS     uint64  `json:"max_rss_bytes"`
+}
+
+// A usageSampler samples the CPU and RSS of the subagents every second.
+type usageSampler struct {
+	mu       sync.Mutex
+	firstCPU map[string]float64
+	lastCPU  map[string]float64
+	maxRSS   map[string]uint64
+	start    time.Time
+	end      time.Time
+}
+
+func newUsageSampler() *usageSampler {
+	return &usageSampler{
+		firstCPU: map[string]float64{},
+		lastCPU:  map[string]float64{},
+		maxRSS:   map[string]uint64{},
+	}
+}
+
+func subagentName(processName string) string {
+	for name, processNames := range subagents {
+		for _, n := range processNames {
+			if processName == n {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func (u *usageSampler) sample() {
+	procs, err := process.Processes()
+	if err != nil {
+		log.Printf("Failed to list the processes: %v", err)
+		return
+	}
+	cpu := map[string]float64{}
+	rss := map[string]uint64{}
+	for _, p := range procs {
+		processName, err := p.Name()
+		if err != nil {
+			continue
+		}
+		name := subagentName(processName)
+		if name == "" {
+			continue
+		}
+		if times, err := p.Times(); err == nil {
+			cpu[name] += times.User + times.System
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			rss[name] += mem.RSS
+		}
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	if u.start.IsZero() {
+		u.start = now
+	}
+	u.end = now
+	for name, v := range cpu {
+		if _, ok := u.firstCPU[name]; !ok {
+			u.firstCPU[name] = v
+		}
+		u.lastCPU[name] = v
+	}
+	for name, v := range rss {
+		if v > u.maxRSS[name] {
+			u.maxRSS[name] = v
+		}
+	}
+}
+
+func (u *usageSampler) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		u.sample()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *usageSampler) result() map[string]Usage {
+	u.sample()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := map[string]Usage{}
+	elapsed := u.end.Sub(u.start).Seconds()
+	for name, last := range u.lastCPU {
+		usage := Usage{
+			CPUSeconds: last - u.firstCPU[name],
+			MaxRSS:     u.maxRSS[name],
+		}
+		if elapsed > 0 {
+			usage.CPUPercent = 100 * usage.CPUSeconds / elapsed
+		}
+		out[name] = usage
+	}
+	return out
+}
+
+// A Report summarizes how the subagents coped with the generated load.
+type Report struct {
+	DurationSeconds float64   `json:"duration_seconds"`
+	Generated       generated `json:"generated"`
+	// Counters are the increase of each of the counters during the run.
+	Counters map[string]float64 `json:"counters"`
+	// Throughput is the increase of each of the counters per second.
+	Throughput map[string]float64 `json:"throughput_per_second"`
+	Usage      map[string]Usage   `json:"usage"`
+}
+
+func newReport(elapsed time.Duration, gen generated, before, after snapshot, usage map[string]Usage) Report {
+	r := Report{
+		DurationSeconds: elapsed.Seconds(),
+		Generated:       gen,
+		Counters:        map[string]float64{},
+		Throughput:      map[string]float64{},
+		Usage:           usage,
+	}
+	for name, v := range after {
+		b, ok := before[name]
+		if !ok {
+			continue
+		}
+		r.Counters[name] = v - b
+		if elapsed > 0 {
+			r.Throughput[name] = (v - b) / elapsed.Seconds()
+		}
+	}
+	return r
+}
+
+func (r Report) log() {
+	log.Printf("Generated %d log lines and %d OTLP points in %.0fs", r.Generated.Logs, r.Generated.OTLPPoints, r.DurationSeconds)
+	var names []string
+	for name := range r.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("%-24s %12.0f  %10.1f/s", name, r.Counters[name], r.Throughput[name])
+	}
+	names = names[:0]
+	for name := range r.Usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		u := r.Usage[name]
+		log.Printf("%-10s CPU %6.1f%%  max RSS %s", name, u.CPUPercent, formatBytes(u.MaxRSS))
+	}
+	if len(r.Counters) == 0 {
+		log.Printf("No subagent self metrics were found; is the agent running on this machine?")
+	}
+}
+
+func formatBytes(b uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	v := float64(b)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// collectdHostPlugins are the plugins of the Stackdriver agent whose metrics the built-in
+// hostmetrics receiver of the Ops Agent collects.
+var collectdHostPlugins = map[string]bool{
+	"cpu": true, "df": true, "disk": true, "interface": true, "load": true, "memory": true,
+	"processes": true, "swap": true, "tcpconns": true, "uptime": true, "vmem": true,
+}
+
+// collectdAgentPlugins are the plugins that the Stackdriver agent needs to run, which have no
+// equivalent.
+var collectdAgentPlugins = map[string]bool{
+	"aggregation": true, "logfile": true, "match_regex": true, "match_throttle_metadata_keys": true,
+	"stackdriver_agent": true, "syslog": true, "target_replace": true, "target_set": true,
+	"write_gcm": true, "write_log": true,
+}
+
+// collectdGlobals are the global options of collectd that don't apply to the Ops Agent.
+var collectdGlobals = map[string]bool{
+	"basedir": true, "fqdnlookup": true, "hostname": true, "interval": true, "pidfile": true,
+	"plugindir": true, "typesdb": true,
+}
+
+// collectdMigrations are the plugins with an Ops Agent receiver. migrate is called with the
+// <Plugin> section of the plugin, or an empty one if the plugin is loaded without it.
+var collectdMigrations = map[string]func(m *migration, d *directive, interval string){
+	"apache":     migrateCollectdApache,
+	"nginx":      migrateCollectdNginx,
+	"mysql":      migrateCollectdDatabases("mysql", "3306", false),
+	"postgresql": migrateCollectdDatabases("postgresql", "5432", true),
+	"redis":      migrateCollectdRedis,
+	"memcached":  migrateCollectdMemcached,
+	"mongodb":    migrateCollectdMongodb,
+}
+
+// migrateCollectd migrates the plugins of a Stackdriver agent collectd config.
+func (m *migration) migrateCollectd(root *directive) {
+	interval := root.param("interval")
+	loaded := map[string]*directive{}
+	var order []string
+	configured := map[string]bool{}
+	for _, d := range root.children {
+		name := strings.ToLower(d.name)
+		switch {
+		case name == "loadplugin":
+			plugin := strings.ToLower(d.arg)
+			if _, ok := loaded[plugin]; !ok {
+				order = append(order, plugin)
+			}
+			loaded[plugin] = d
+		case name == "plugin":
+			plugin := strings.ToLower(d.arg)
+			configured[plugin] = true
+			m.migrateCollectdPlugin(d, plugin, interval)
+		case name == "include":
+			m.unsupported(d, "included files are not migrated; migrate their directory with -collectd_dir")
+		case collectdGlobals[name]:
+		default:
+			m.unsupported(d, "option is not supported")
+		}
+	}
+	// The plugins that are loaded without a <Plugin> section use their defaults.
+	for _, plugin := range order {
+		if !configured[plugin] {
+			d := loaded[plugin]
+			m.migrateCollectdPlugin(&directive{name: d.name, arg: d.arg, file: d.file, line: d.line, params: map[string]string{}}, plugin, interval)
+		}
+	}
+}
+
+func (m *migration) migrateCollectdPlugin(d *directive, plugin, interval string) {
+	switch {
+	case collectdHostPlugins[plugin], collectdAgentPlugins[plugin]:
+	case collectdMigrations[plugin] != nil:
+		collectdMigrations[plugin](m, d, interval)
+	case plugin == "java" || plugin == "genericjmx":
+		m.unsupported(d, "JMX beans are not migrated; use the jvm receiver, or the receiver of the application, e.g. tomcat or kafka")
+	default:
+		m.unsupported(d, "plugin %q has no Ops Agent receiver", plugin)
+	}
+}
+
+// receiver returns a receiver of type t with the collection interval of collectd, in seconds.
+func collectdReceiver(t, interval string) yaml.MapSlice {
+	r := yaml.MapSlice{{Key: "type", Value: t}}
+	if interval != "" && interval != "60" {
+		r = append(r, yaml.MapItem{Key: "collection_interval", Value: interval + "s"})
+	}
+	return r
+}
+
+// instances returns the sections of the instances of a plugin, e.g. "<Instance local>", or the
+// plugin section itself if it configures a single instance.
+func instances(d *directive, section string) []*directive {
+	var out []*directive
+	for _, c := range d.children {
+		if strings.EqualFold(c.name, section) {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return []*directive{d}
+	}
+	return out
+}
+
+func migrateCollectdApache(m *migration, d *directive, interval string) {
+	for _, i := range instances(d, "instance") {
+		r := collectdReceiver("apache", interval)
+		if url := i.param("url"); url != "" {
+			r = append(r, yaml.MapItem{Key: "server_status_url", Value: url})
+		}
+		m.addMetricsReceiver("apache", r)
+	}
+}
+
+func migrateCollectdNginx(m *migration, d *directive, interval string) {
+	r := collectdReceiver("nginx", interval)
+	if url := d.param("url"); url != "" {
+		r = append(r, yaml.MapItem{Key: "stub_status_url", Value: url})
+	}
+	m.addMetricsReceiver("nginx", r)
+}
+
+// migrateCollectdDatabases returns the migration of a plugin with a <Database> section per
+// database server.
+func migrateCollectdDatabases(t, defaultPort string, databases bool) func(m *migration, d *directive, interval string) {
+	return func(m *migration, d *directive, interval string) {
+		for _, db := range instances(d, "database") {
+			r := collectdReceiver(t, interval)
+			if socket := db.param("socket"); socket != "" {
+				r = append(r, yaml.MapItem{Key: "endpoint", Value: socket})
+			} else if host := db.param("host"); host != "" {
+				r = append(r, yaml.MapItem{Key: "endpoint", Value: net.JoinHostPort(host, paramOr(db, "port", defaultPort))})
+			}
+			if user := db.param("user"); user != "" {
+				r = append(r, yaml.MapItem{Key: "username", Value: user})
+			}
+			if password := db.param("password"); password != "" {
+				r = append(r, yaml.MapItem{Key: "password", Value: password})
+			}
+			if databases && db != d && db.arg != "" {
+				r = append(r, yaml.MapItem{Key: "databases", Value: []string{db.arg}})
+			}
+			if db != d {
+				for _, q := range db.children {
+					m.unsupported(q, "custom queries are not supported")
+				}
+			}
+			m.addMetricsReceiver(t, r)
+		}
+		for _, c := range d.children {
+			if !strings.EqualFold(c.name, "database") {
+				m.unsupported(c, "custom queries are not supported")
+			}
+		}
+	}
+}
+
+func migrateCollectdRedis(m *migration, d *directive, interval string) {
+	for _, node := range instances(d, "node") {
+		r := collectdReceiver("redis", interval)
+		if host := node.param("host"); host != "" {
+			r = append(r, yaml.MapItem{Key: "address", Value: net.JoinHostPort(host, paramOr(node, "port", "6379"))})
+		}
+		if password := node.param("password"); password != "" {
+			r = append(r, yaml.MapItem{Key: "password", Value: password})
+		}
+		m.addMetricsReceiver("redis", r)
+	}
+}
+
+func migrateCollectdMemcached(m *migration, d *directive, interval string) {
+	for _, i := range instances(d, "instance") {
+		r := collectdReceiver("memcached", interval)
+		if socket := i.param("socket"); socket != "" {
+			r = append(r, yaml.MapItem{Key: "endpoint", Value: socket})
+		} else if host := i.param("host"); host != "" {
+			r = append(r, yaml.MapItem{Key: "endpoint", Value: net.JoinHostPort(host, paramOr(i, "port", "11211"))})
+		}
+		m.addMetricsReceiver("memcached", r)
+	}
+}
+
+func migrateCollectdMongodb(m *migration, d *directive, interval string) {
+	r := collectdReceiver("mongodb", interval)
+	if host := d.param("host"); host != "" {
+		r = append(r, yaml.MapItem{Key: "endpoint", Value: net.JoinHostPort(host, paramOr(d, "port", "27017"))})
+	}
+	if user := d.param("user"); user != "" {
+		r = append(r, yaml.MapItem{Key: "username", Value: user})
+	}
+	if password := d.param("password"); password != "" {
+		r = append(r, yaml.MapItem{Key: "password", Value: password})
+	}
+	m.addMetricsReceiver("mongodb", r)
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// directive is a section of a fluentd or collectd config, e.g. "<source>" or "<Plugin mysql>",
+// or the whole file.
+type directive struct {
+	name string
+	arg  string
+	file string
+	line int
+	// params are the "key value" lines of the section, with the keys in lower case, since
+	// collectd keys are case insensitive.
+	params   map[string]string
+	children []*directive
+}
+
+func (d *directive) location() string {
+	return fmt.Sprintf("%s:%d", d.file, d.line)
+}
+
+// param returns the first of keys that is set.
+func (d *directive) param(keys ...string) string {
+	for _, k := range keys {
+		if v, ok := d.params[k]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// child returns the first child section called name, or nil.
+func (d *directive) child(name string) *directive {
+	for _, c := range d.children {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseDirectives parses the sections of a fluentd or collectd config, which share the same
+// syntax: "<name arg>" opens a section, "</name>" closes it, and the other lines are "key value"
+// parameters. Both formats use "#" for comments.
+func parseDirectives(file string, data []byte) (*directive, error) {
+	root := &directive{file: file, params: map[string]string{}}
+	stack := []*directive{root}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		current := stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(line, "</") && strings.HasSuffix(line, ">"):
+			name := strings.TrimSpace(line[2 : len(line)-1])
+			if len(stack) == 1 || !strings.EqualFold(current.name, name) {
+				return nil, fmt.Errorf("%s:%d: unexpected </%s>", file, n, name)
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(line, "<") && strings.HasSuffix(line, ">"):
+			name, arg, _ := strings.Cut(strings.TrimSpace(line[1:len(line)-1]), " ")
+			d := &directive{name: name, arg: unquote(strings.TrimSpace(arg)), file: file, line: n, params: map[string]string{}}
+			current.children = append(current.children, d)
+			stack = append(stack, d)
+		default:
+			key, value, _ := strings.Cut(line, " ")
+			key = strings.ToLower(strings.TrimSpace(key))
+			if _, ok := current.params[key]; !ok {
+				current.params[key] = unquote(strings.TrimSpace(value))
+			}
+			// Keys without a section, e.g. "LoadPlugin cpu" or "@include", are kept as
+			// sections without children, so that each of them is migrated.
+			if current == root {
+				root.children = append(root.children, &directive{name: key, arg: unquote(strings.TrimSpace(value)), file: file, line: n, params: map[string]string{}})
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(stack) > 1 {
+		d := stack[len(stack)-1]
+		return nil, fmt.Errorf("%s: <%s> is not closed", d.location(), d.name)
+	}
+	return root, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,279 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// fluentdSyslogRegex is the regex of the syslog parser of fluentd for lines without a priority,
+// as written to the syslog files.
+const fluentdSyslogRegex = `^(?<time>[^ ]*\s*[^ ]* [^ ]*) (?<host>[^ ]*) (?<ident>[a-zA-Z0-9_\/\.\-]*)(?:\[(?<pid>[0-9]+)\])?(?:[^\:]*\:)? *(?<message>.*)$`
+
+// fluentdDestinations are the outputs of google-fluentd that send to Cloud Logging, which the Ops
+// Agent does by default.
+var fluentdDestinations = map[string]bool{
+	"google_cloud":          true,
+	"google_cloud_buffered": true,
+}
+
+// migrateFluentd migrates the sources and filters of a google-fluentd config.
+func (m *migration) migrateFluentd(root *directive) {
+	var filters []*directive
+	for _, d := range root.children {
+		switch d.name {
+		case "source":
+			m.migrateFluentdSource(d)
+		case "filter":
+			filters = append(filters, d)
+		case "match":
+			if t := d.param("@type", "type"); !fluentdDestinations[t] {
+				m.unsupported(d, "output %q is not supported; the Ops Agent only sends the logs to Cloud Logging", t)
+			}
+		case "system":
+			// The settings of the fluentd process don't apply to the Ops Agent.
+		case "@include":
+			m.unsupported(d, "included files are not migrated; migrate their directory with -fluentd_dir")
+		default:
+			m.unsupported(d, "directive is not supported")
+		}
+	}
+	// The filters apply to the pipelines whose tag they match, which are all known by now.
+	for _, d := range filters {
+		m.migrateFluentdFilter(d)
+	}
+}
+
+func (m *migration) migrateFluentdSource(d *directive) {
+	t := d.param("@type", "type")
+	tag := d.param("tag")
+	name := tag
+	if name == "" {
+		name = t
+	}
+	switch t {
+	case "tail":
+		var paths []string
+		for _, p := range strings.Split(d.param("path"), ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) == 0 {
+			m.unsupported(d, "tail source without a path")
+			return
+		}
+		receiver := yaml.MapSlice{
+			{Key: "type", Value: "files"},
+			{Key: "include_paths", Value: paths},
+		}
+		if exclude := d.param("exclude_path"); exclude != "" {
+			var excludePaths []string
+			if err := json.Unmarshal([]byte(exclude), &excludePaths); err != nil {
+				m.unsupported(d, "exclude_path %q is not a JSON array", exclude)
+			} else {
+				receiver = append(receiver, yaml.MapItem{Key: "exclude_paths", Value: excludePaths})
+			}
+		}
+		m.migrateFluentdTail(d, name, tag, receiver)
+	case "syslog":
+		protocol := d.param("protocol_type")
+		if transport := d.child("transport"); transport != nil {
+			protocol = transport.arg
+		}
+		if protocol == "" {
+			protocol = "udp"
+		}
+		if protocol != "udp" && protocol != "tcp" {
+			m.unsupported(d, "transport %q is not supported", protocol)
+			return
+		}
+		m.addLoggingReceiver(name, tag, yaml.MapSlice{
+			{Key: "type", Value: "syslog"},
+			{Key: "transport_protocol", Value: protocol},
+			{Key: "listen_host", Value: paramOr(d, "bind", "0.0.0.0")},
+			{Key: "listen_port", Value: portParam(d, 5140)},
+		})
+	case "forward":
+		m.addLoggingReceiver(name, tag, yaml.MapSlice{
+			{Key: "type", Value: "fluent_forward"},
+			{Key: "listen_host", Value: paramOr(d, "bind", "0.0.0.0")},
+			{Key: "listen_port", Value: portParam(d, 24224)},
+		})
+	case "systemd", "systemd_journal":
+		if d.param("matches", "filters") != "" {
+			m.unsupported(d, "the journal matches are not supported; all the journal entries are collected")
+		}
+		m.addLoggingReceiver(name, tag, yaml.MapSlice{{Key: "type", Value: "systemd_journald"}})
+	case "windows_eventlog", "windows_eventlog2":
+		var channels []string
+		for _, c := range strings.Split(paramOr(d, "channels", "application,system"), ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				channels = append(channels, c)
+			}
+		}
+		m.addLoggingReceiver(name, tag, yaml.MapSlice{
+			{Key: "type", Value: "windows_event_log"},
+			{Key: "channels", Value: channels},
+		})
+	default:
+		m.unsupported(d, "source %q is not supported", t)
+	}
+}
+
+// migrateFluentdTail adds the files receiver of a tail source with the processor of its parser.
+func (m *migration) migrateFluentdTail(d *directive, name, tag string, receiver yaml.MapSlice) {
+	parse := d
+	format := d.param("format")
+	if p := d.child("parse"); p != nil {
+		parse = p
+		format = p.param("@type", "type")
+	}
+	var processor yaml.MapSlice
+	switch {
+	case format == "" || format == "none":
+	case format == "json":
+		processor = yaml.MapSlice{{Key: "type", Value: "parse_json"}}
+	case format == "regexp" || strings.HasPrefix(format, "/"):
+		expression := format
+		if format == "regexp" {
+			expression = parse.param("expression")
+		}
+		processor = yaml.MapSlice{
+			{Key: "type", Value: "parse_regex"},
+			{Key: "regex", Value: strings.TrimSuffix(strings.TrimPrefix(expression, "/"), "/")},
+		}
+	case format == "syslog":
+		processor = yaml.MapSlice{
+			{Key: "type", Value: "parse_regex"},
+			{Key: "regex", Value: fluentdSyslogRegex},
+			{Key: "time_key", Value: "time"},
+			{Key: "time_format", Value: "%b %d %H:%M:%S"},
+		}
+	case format == "apache2":
+		// The access logs of the apache and nginx integrations are parsed the same way.
+		receiver[0].Value = "apache_access"
+	case format == "nginx":
+		receiver[0].Value = "nginx_access"
+	default:
+		m.unsupported(parse, "parser %q is not supported; the logs are collected unparsed", format)
+	}
+	p := m.addLoggingReceiver(name, tag, receiver)
+	if processor == nil {
+		return
+	}
+	if timeFormat := parse.param("time_format"); timeFormat != "" && format != "syslog" {
+		processor = append(processor,
+			yaml.MapItem{Key: "time_key", Value: paramOr(parse, "time_key", "time")},
+			yaml.MapItem{Key: "time_format", Value: timeFormat},
+		)
+	}
+	m.addLoggingProcessor(p, p.id+"_parser", processor)
+}
+
+// migrateFluentdFilter adds the grep filters as exclude_logs processors of the pipelines that
+// they match.
+func (m *migration) migrateFluentdFilter(d *directive) {
+	if t := d.param("@type", "type"); t != "grep" {
+		m.unsupported(d, "filter %q is not supported", t)
+		return
+	}
+	var expressions []string
+	for _, c := range d.children {
+		// A log is excluded if it matches an <exclude> pattern, or if it doesn't match a
+		// <regexp> pattern.
+		op := map[string]string{"exclude": "=~", "regexp": "!~"}[c.name]
+		key, pattern := c.param("key"), strings.TrimSuffix(strings.TrimPrefix(c.param("pattern"), "/"), "/")
+		if op == "" || key == "" || pattern == "" {
+			m.unsupported(c, "grep rule is not supported")
+			continue
+		}
+		expressions = append(expressions, fmt.Sprintf(`jsonPayload.%s %s %s`, key, op, quoteFilterString(pattern)))
+	}
+	if len(expressions) == 0 {
+		return
+	}
+	matched := false
+	for _, p := range m.loggingPipelines {
+		if p.tag != "" && matchFluentdTag(d.arg, p.tag) {
+			matched = true
+			m.addLoggingProcessor(p, p.id+"_grep", yaml.MapSlice{
+				{Key: "type", Value: "exclude_logs"},
+				{Key: "match_any", Value: expressions},
+			})
+		}
+	}
+	if !matched {
+		m.unsupported(d, "filter does not match the tag of any source")
+	}
+}
+
+// matchFluentdTag reports whether tag matches one of the space separated fluentd match
+// patterns, where "*" matches a tag part, "**" zero or more parts, and "{a,b}" either a or b.
+func matchFluentdTag(patterns, tag string) bool {
+	for _, pattern := range strings.Fields(patterns) {
+		var re strings.Builder
+		for i := 0; i < len(pattern); i++ {
+			switch c := pattern[i]; {
+			case strings.HasPrefix(pattern[i:], ".**"):
+				// "a.**" matches "a" as well.
+				re.WriteString(`(?:\..*)?`)
+				i += 2
+			case strings.HasPrefix(pattern[i:], "**"):
+				re.WriteString(".*")
+				i++
+			case c == '*':
+				re.WriteString(`[^.]*`)
+			case c == '{':
+				re.WriteString("(?:")
+			case c == '}':
+				re.WriteString(")")
+			case c == ',':
+				re.WriteString("|")
+			default:
+				re.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		if ok, _ := regexp.MatchString("^"+re.String()+"$", tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteFilterString quotes s as a string of the logging filter language.
+func quoteFilterString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func paramOr(d *directive, key, fallback string) string {
+	if v := d.param(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func portParam(d *directive, fallback int) int {
+	if port, err := strconv.Atoi(d.param("port")); err == nil {
+		return port
+	}
+	return fallback
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// migrate_legacy_config converts the configs of the legacy Logging agent (google-fluentd) and
+// Monitoring agent (Stackdriver collectd), or of an OpenTelemetry Collector, into a best-effort
+// equivalent Ops Agent config, and reports the directives and components that have no equivalent.
+// See README.md.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	// Register the receivers that the migrated config uses.
+	_ "github.com/GoogleCloudPlatform/ops-agent/apps"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/goccy/go-yaml"
+)
+
+var (
+	fluentdDir  = flag.String("fluentd_dir", "/etc/google-fluentd/config.d", "Directory of the google-fluentd configs to migrate. Only the *.conf files are read")
+	collectdDir = flag.String("collectd_dir", "/etc/stackdriver/collectd.d", "Directory of the Stackdriver collectd configs to migrate. Only the *.conf files are read")
+	otelFile    = flag.String("otel_config", "", "OpenTelemetry Collector config to migrate instead of the legacy configs")
+	out         = flag.String("out", "", "File to write the Ops Agent config to. If empty, it is written to stdout")
+)
+
+// confFiles returns the *.conf files of dir, or none if dir doesn't exist.
+func confFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// migrate migrates the configs of the fluentd and collectd directories.
+func migrate(fluentdDir, collectdDir string) (*migration, error) {
+	m := newMigration()
+	for _, c := range []struct {
+		dir     string
+		migrate func(*directive)
+	}{
+		{fluentdDir, m.migrateFluentd},
+		{collectdDir, m.migrateCollectd},
+	} {
+		files, err := confFiles(c.dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			root, err := parseDirectives(f, data)
+			if err != nil {
+				return nil, err
+			}
+			c.migrate(root)
+		}
+	}
+	return m, nil
+}
+
+// migrateOTelFile migrates the OpenTelemetry Collector config at path.
+func migrateOTelFile(path string) (*migration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := newMigration()
+	if err := m.migrateOTel(path, data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// validate checks that config is a valid Ops Agent config.
+func validate(config []byte) error {
+	ctx := platform.Platform{Type: platform.Linux}.TestContext(context.Background())
+	uc, err := confgenerator.UnmarshalYamlToUnifiedConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	return uc.Validate(ctx)
+}
+
+func main() {
+	flag.Parse()
+	var m *migration
+	var err error
+	if *otelFile != "" {
+		m, err = migrateOTelFile(*otelFile)
+	} else {
+		for _, dir := range []string{*fluentdDir, *collectdDir} {
+			if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+				log.Printf("%s does not exist, skipping it", dir)
+			}
+		}
+		m, err = migrate(*fluentdDir, *collectdDir)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	config, err := yaml.MarshalWithOptions(m.config(), yaml.IndentSequence(true))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := validate(config); err != nil {
+		log.Printf("The migrated config is not valid, and needs to be fixed by hand: %v", err)
+	}
+	if *out == "" {
+		os.Stdout.Write(config)
+	} else if err := os.WriteFile(*out, config, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(m.report) == 0 {
+		log.Print("Every directive was migrated")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%d directives were not migrated:\n", len(m.report))
+	for _, e := range m.report {
+		fmt.Fprintln(os.Stderr, e)
+	}
+}
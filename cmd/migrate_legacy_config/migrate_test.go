@@ -0,0 +1,120 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+var updateGolden = flag.Bool("update", false, "Update the golden config")
+
+func TestMigrate(t *testing.T) {
+	m, err := migrate("testdata/fluentd", "testdata/collectd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConfig(t, m, "testdata/config.yaml")
+
+	wantReport := []string{
+		`testdata/fluentd/app.conf:32: source: parser "multiline" is not supported; the logs are collected unparsed`,
+		`testdata/fluentd/app.conf:59: match trace: output "s3" is not supported; the Ops Agent only sends the logs to Cloud Logging`,
+		`testdata/fluentd/app.conf:48: filter app: filter "record_transformer" is not supported`,
+		`testdata/collectd/stackdriver.conf:7: Query backlog: custom queries are not supported`,
+		`testdata/collectd/stackdriver.conf:5: loadplugin statsd: plugin "statsd" has no Ops Agent receiver`,
+	}
+	var gotReport []string
+	for _, e := range m.report {
+		gotReport = append(gotReport, e.String())
+	}
+	if diff := cmp.Diff(wantReport, gotReport); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMigrateOTel(t *testing.T) {
+	m, err := migrateOTelFile("testdata/otel/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkConfig(t, m, "testdata/otel/ops_agent_config.yaml")
+
+	wantReport := []string{
+		`testdata/otel/config.yaml: extension health_check: extensions are not supported`,
+		`testdata/otel/config.yaml: exporter otlphttp: the Ops Agent only sends telemetry to Google Cloud`,
+		`testdata/otel/config.yaml: pipeline logs: the pipeline has no Google Cloud exporter, but the Ops Agent sends its telemetry to Google Cloud`,
+		`testdata/otel/config.yaml: receiver filelog: operators are not migrated; parse the logs with logging processors instead`,
+		`testdata/otel/config.yaml: receiver hostmetrics: the scrapers are not migrated; the built-in hostmetrics receiver collects the host metrics under agent.googleapis.com`,
+		`testdata/otel/config.yaml: receiver mysql: option "statement_events" is not supported`,
+		`testdata/otel/config.yaml: receiver kafkametrics: receiver type "kafkametrics" has no Ops Agent receiver`,
+		`testdata/otel/config.yaml: pipeline metrics: processor transform is not supported in a metrics pipeline`,
+		`testdata/otel/config.yaml: receiver otlp: OTLP/HTTP is not supported; the Ops Agent only receives OTLP/gRPC`,
+	}
+	var gotReport []string
+	for _, e := range m.report {
+		gotReport = append(gotReport, e.String())
+	}
+	if diff := cmp.Diff(wantReport, gotReport); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// checkConfig checks that the config of m is valid and matches the golden config at path.
+func checkConfig(t *testing.T, m *migration, path string) {
+	t.Helper()
+	got, err := yaml.MarshalWithOptions(m.config(), yaml.IndentSequence(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validate(got); err != nil {
+		t.Errorf("the migrated config is not valid: %v", err)
+	}
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("migrated config mismatch, run with -update to update it (-want +got):\n%s", diff)
+	}
+}
+
+func TestMatchFluentdTag(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, tag string
+		want         bool
+	}{
+		{"app", "app", true},
+		{"app.*", "app.access", true},
+		{"app.*", "app.access.log", false},
+		{"app.**", "app", true},
+		{"app.**", "app.access.log", true},
+		{"{app,web}.log", "web.log", true},
+		{"db web", "web", true},
+		{"db", "web", false},
+	} {
+		if got := matchFluentdTag(tc.pattern, tc.tag); got != tc.want {
+			t.Errorf("matchFluentdTag(%q, %q) = %v, want %v", tc.pattern, tc.tag, got, tc.want)
+		}
+	}
+}
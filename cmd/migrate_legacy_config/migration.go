@@ -0,0 +1,169 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// reportEntry is a directive or component of the migrated configs that has no equivalent in the
+// Ops Agent config.
+type reportEntry struct {
+	Location  string
+	Directive string
+	Reason    string
+}
+
+func (e reportEntry) String() string {
+	return fmt.Sprintf("%s: %s: %s", e.Location, e.Directive, e.Reason)
+}
+
+type pipeline struct {
+	id         string
+	receivers  []string
+	processors []string
+	// tag is the fluentd tag of the logs of the pipeline, which filters are matched against.
+	tag string
+}
+
+// migration accumulates the Ops Agent config that is equivalent to the migrated configs.
+type migration struct {
+	combinedReceivers yaml.MapSlice
+	loggingReceivers  yaml.MapSlice
+	loggingProcessors yaml.MapSlice
+	loggingPipelines  []*pipeline
+	metricsReceivers  yaml.MapSlice
+	metricsProcessors yaml.MapSlice
+	metricsPipelines  []*pipeline
+	tracesPipelines   []*pipeline
+	report            []reportEntry
+	ids               map[string]bool
+}
+
+func newMigration() *migration {
+	return &migration{ids: map[string]bool{}}
+}
+
+func (m *migration) unsupported(d *directive, reason string, args ...interface{}) {
+	name := d.name
+	if d.arg != "" {
+		name += " " + d.arg
+	}
+	m.unsupportedAt(d.location(), name, reason, args...)
+}
+
+func (m *migration) unsupportedAt(location, name, reason string, args ...interface{}) {
+	m.report = append(m.report, reportEntry{
+		Location:  location,
+		Directive: name,
+		Reason:    fmt.Sprintf(reason, args...),
+	})
+}
+
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// id returns a unique component ID based on name.
+func (m *migration) id(name string) string {
+	base := strings.Trim(invalidIDChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if base == "" {
+		base = "legacy"
+	}
+	id := base
+	for i := 2; m.ids[id]; i++ {
+		id = fmt.Sprintf("%s_%d", base, i)
+	}
+	m.ids[id] = true
+	return id
+}
+
+// addLoggingReceiver adds a receiver in a pipeline of its own, and returns the pipeline.
+func (m *migration) addLoggingReceiver(name, tag string, receiver yaml.MapSlice) *pipeline {
+	id := m.id(name)
+	m.loggingReceivers = append(m.loggingReceivers, yaml.MapItem{Key: id, Value: receiver})
+	p := &pipeline{id: id, receivers: []string{id}, tag: tag}
+	m.loggingPipelines = append(m.loggingPipelines, p)
+	return p
+}
+
+func (m *migration) addLoggingProcessor(p *pipeline, name string, processor yaml.MapSlice) {
+	id := m.id(name)
+	m.loggingProcessors = append(m.loggingProcessors, yaml.MapItem{Key: id, Value: processor})
+	p.processors = append(p.processors, id)
+}
+
+// addMetricsReceiver adds a receiver of a collectd plugin to the legacy_collectd pipeline.
+func (m *migration) addMetricsReceiver(name string, receiver yaml.MapSlice) {
+	id := m.id(name)
+	m.metricsReceivers = append(m.metricsReceivers, yaml.MapItem{Key: id, Value: receiver})
+	if len(m.metricsPipelines) == 0 {
+		m.metricsPipelines = append(m.metricsPipelines, &pipeline{id: "legacy_collectd"})
+	}
+	m.metricsPipelines[0].receivers = append(m.metricsPipelines[0].receivers, id)
+}
+
+// section returns the config of a subagent, or nil if it is empty.
+func section(receivers, processors yaml.MapSlice, pipelines []*pipeline) yaml.MapSlice {
+	var service yaml.MapSlice
+	for _, p := range pipelines {
+		value := yaml.MapSlice{{Key: "receivers", Value: p.receivers}}
+		if len(p.processors) > 0 {
+			value = append(value, yaml.MapItem{Key: "processors", Value: p.processors})
+		}
+		service = append(service, yaml.MapItem{Key: p.id, Value: value})
+	}
+	var out yaml.MapSlice
+	if len(receivers) > 0 {
+		out = append(out, yaml.MapItem{Key: "receivers", Value: receivers})
+	}
+	if len(processors) > 0 {
+		out = append(out, yaml.MapItem{Key: "processors", Value: processors})
+	}
+	if len(service) > 0 {
+		out = append(out, yaml.MapItem{Key: "service", Value: yaml.MapSlice{{Key: "pipelines", Value: service}}})
+	}
+	return out
+}
+
+// config returns the migrated Ops Agent config. The built-in receivers of the Ops Agent, i.e. the
+// syslog files and the host metrics, are kept in the default pipelines, which the legacy agents
+// collected too.
+func (m *migration) config() yaml.MapSlice {
+	var out yaml.MapSlice
+	if len(m.combinedReceivers) > 0 {
+		out = append(out, yaml.MapItem{Key: "combined", Value: yaml.MapSlice{{Key: "receivers", Value: m.combinedReceivers}}})
+	}
+	for _, s := range []struct {
+		key     string
+		section yaml.MapSlice
+	}{
+		{"logging", section(m.loggingReceivers, m.loggingProcessors, m.loggingPipelines)},
+		{"metrics", section(m.metricsReceivers, m.metricsProcessors, m.metricsPipelines)},
+		{"traces", section(nil, nil, m.tracesPipelines)},
+	} {
+		if s.section == nil && len(m.combinedReceivers) > 0 && s.key != "logging" {
+			// The combined receivers need an empty section to drop the metrics or traces that
+			// no pipeline uses.
+			s.section = yaml.MapSlice{{Key: "service", Value: yaml.MapSlice{{Key: "pipelines", Value: yaml.MapSlice{}}}}}
+		}
+		if s.section != nil {
+			out = append(out, yaml.MapItem{Key: s.key, Value: s.section})
+		}
+	}
+	return out
+}
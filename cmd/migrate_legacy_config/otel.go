@@ -0,0 +1,378 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// otelConfig is the part of an OpenTelemetry Collector config that is migrated. Components that
+// no pipeline uses are ignored, like the Collector does.
+type otelConfig struct {
+	Receivers  map[string]map[string]interface{} `yaml:"receivers"`
+	Processors map[string]map[string]interface{} `yaml:"processors"`
+	Exporters  map[string]map[string]interface{} `yaml:"exporters"`
+	Service    struct {
+		Extensions []string                `yaml:"extensions"`
+		Pipelines  map[string]otelPipeline `yaml:"pipelines"`
+	} `yaml:"service"`
+}
+
+type otelPipeline struct {
+	Receivers  []string `yaml:"receivers"`
+	Processors []string `yaml:"processors"`
+	Exporters  []string `yaml:"exporters"`
+}
+
+// otelReceiver is a receiver of the Collector config migrated to the Ops Agent config.
+type otelReceiver struct {
+	id string
+	// section is the section of the Ops Agent config that the receiver is in, i.e. "combined",
+	// "logging" or "metrics", or "" if the receiver is built in and is not added to pipelines.
+	section string
+	// otlp is the config of a combined otlp receiver, whose metrics mode depends on the exporters
+	// of its pipelines.
+	otlp yaml.MapSlice
+	// gmp is whether a metrics pipeline of the otlp receiver exports to Google Cloud Managed
+	// Service for Prometheus.
+	gmp bool
+}
+
+// otelReceiverMapping maps the options of a Collector receiver to the options of the Ops Agent
+// receiver of the same application.
+type otelReceiverMapping struct {
+	t       string
+	options map[string]string
+	// tls is whether the receiver supports the tls options.
+	tls bool
+}
+
+var otelMetricsReceivers = map[string]otelReceiverMapping{
+	"apache":     {t: "apache", options: map[string]string{"endpoint": "server_status_url"}},
+	"nginx":      {t: "nginx", options: map[string]string{"endpoint": "stub_status_url"}},
+	"memcached":  {t: "memcached", options: map[string]string{"endpoint": "endpoint"}},
+	"mysql":      {t: "mysql", options: map[string]string{"endpoint": "endpoint", "username": "username", "password": "password"}},
+	"postgresql": {t: "postgresql", options: map[string]string{"endpoint": "endpoint", "username": "username", "password": "password", "databases": "databases"}, tls: true},
+	"redis":      {t: "redis", options: map[string]string{"endpoint": "address", "password": "password"}, tls: true},
+	"mongodb":    {t: "mongodb", options: map[string]string{"username": "username", "password": "password"}, tls: true},
+}
+
+var otelTLSOptions = map[string]string{
+	"insecure": "insecure", "insecure_skip_verify": "insecure_skip_verify",
+	"ca_file": "ca_file", "cert_file": "cert_file", "key_file": "key_file",
+}
+
+// otelIgnoredProcessors are the processors whose job the Ops Agent does on its own.
+var otelIgnoredProcessors = map[string]bool{
+	"batch": true, "memory_limiter": true, "resourcedetection": true,
+}
+
+// otelMigration migrates the components of a Collector config. Each component is migrated once,
+// however many pipelines use it.
+type otelMigration struct {
+	*migration
+	file      string
+	config    otelConfig
+	receivers map[string]*otelReceiver
+	// otlpReceivers are the otlp receivers in the order they were migrated.
+	otlpReceivers []*otelReceiver
+	processors    map[string]string
+	exporters     map[string]bool
+}
+
+// migrateOTel migrates the pipelines of an OpenTelemetry Collector config.
+func (m *migration) migrateOTel(file string, data []byte) error {
+	o := &otelMigration{
+		migration:  m,
+		file:       file,
+		receivers:  map[string]*otelReceiver{},
+		processors: map[string]string{},
+		exporters:  map[string]bool{},
+	}
+	if err := yaml.Unmarshal(data, &o.config); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	for _, e := range o.config.Service.Extensions {
+		m.unsupportedAt(file, "extension "+e, "extensions are not supported")
+	}
+	var ids []string
+	for id := range o.config.Service.Pipelines {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		o.migratePipeline(id, o.config.Service.Pipelines[id])
+	}
+	for _, r := range o.otlpReceivers {
+		if r.gmp {
+			r.otlp = append(r.otlp, yaml.MapItem{Key: "metrics_mode", Value: "googlemanagedprometheus"})
+		}
+		m.combinedReceivers = append(m.combinedReceivers, yaml.MapItem{Key: r.id, Value: r.otlp})
+	}
+	return nil
+}
+
+// componentType returns the type of a component ID, e.g. "prometheus" for "prometheus/app".
+func componentType(id string) string {
+	return strings.SplitN(id, "/", 2)[0]
+}
+
+func (o *otelMigration) migratePipeline(id string, p otelPipeline) {
+	name := "pipeline " + id
+	signal := componentType(id)
+	var sections map[string]bool
+	switch signal {
+	case "logs":
+		sections = map[string]bool{"combined": true, "logging": true}
+	case "metrics":
+		sections = map[string]bool{"combined": true, "metrics": true}
+	case "traces":
+		sections = map[string]bool{"combined": true}
+	default:
+		o.unsupportedAt(o.file, name, "signal %q is not supported", signal)
+		return
+	}
+
+	gmp := false
+	exported := false
+	for _, e := range p.Exporters {
+		switch componentType(e) {
+		case "googlecloud":
+			exported = true
+		case "googlemanagedprometheus":
+			exported = true
+			gmp = true
+		default:
+			if !o.exporters[e] {
+				o.exporters[e] = true
+				o.unsupportedAt(o.file, "exporter "+e, "the Ops Agent only sends telemetry to Google Cloud")
+			}
+		}
+	}
+	if !exported {
+		o.unsupportedAt(o.file, name, "the pipeline has no Google Cloud exporter, but the Ops Agent sends its telemetry to Google Cloud")
+	}
+
+	migrated := &pipeline{id: o.id(strings.ReplaceAll(id, "/", "_"))}
+	for _, rid := range p.Receivers {
+		r := o.receiver(rid)
+		switch {
+		case r == nil:
+		case r.section == "":
+		case !sections[r.section] || (r.section == "combined" && r.otlp == nil && signal != "traces"):
+			// Only the otlp receiver of the combined receivers receives logs and metrics.
+			o.unsupportedAt(o.file, name, "receiver %s can't be used in a %s pipeline of the Ops Agent", rid, signal)
+		default:
+			if r.otlp != nil && signal == "metrics" && gmp {
+				r.gmp = true
+			}
+			migrated.receivers = append(migrated.receivers, r.id)
+		}
+	}
+	for _, pid := range p.Processors {
+		if otelIgnoredProcessors[componentType(pid)] {
+			continue
+		}
+		if signal != "metrics" || componentType(pid) != "filter" {
+			o.unsupportedAt(o.file, name, "processor %s is not supported in a %s pipeline", pid, signal)
+			continue
+		}
+		if id := o.filterProcessor(pid); id != "" {
+			migrated.processors = append(migrated.processors, id)
+		}
+	}
+	if len(migrated.receivers) == 0 {
+		return
+	}
+	switch signal {
+	case "logs":
+		o.loggingPipelines = append(o.loggingPipelines, migrated)
+	case "metrics":
+		o.metricsPipelines = append(o.metricsPipelines, migrated)
+	case "traces":
+		o.tracesPipelines = append(o.tracesPipelines, migrated)
+	}
+}
+
+// receiver returns the migrated receiver rid, or nil if it was not migrated.
+func (o *otelMigration) receiver(rid string) *otelReceiver {
+	if r, ok := o.receivers[rid]; ok {
+		return r
+	}
+	r := o.migrateReceiver(rid, o.config.Receivers[rid])
+	o.receivers[rid] = r
+	if r != nil && r.otlp != nil {
+		o.otlpReceivers = append(o.otlpReceivers, r)
+	}
+	return r
+}
+
+func (o *otelMigration) migrateReceiver(rid string, cfg map[string]interface{}) *otelReceiver {
+	name := "receiver " + rid
+	t := componentType(rid)
+	switch t {
+	case "otlp":
+		r := yaml.MapSlice{{Key: "type", Value: "otlp"}}
+		protocols, _ := cfg["protocols"].(map[string]interface{})
+		if grpc, ok := protocols["grpc"].(map[string]interface{}); ok && grpc["endpoint"] != nil {
+			r = append(r, yaml.MapItem{Key: "grpc_endpoint", Value: grpc["endpoint"]})
+		}
+		if _, ok := protocols["http"]; ok {
+			o.unsupportedAt(o.file, name, "OTLP/HTTP is not supported; the Ops Agent only receives OTLP/gRPC")
+		}
+		return &otelReceiver{id: o.id(rid), section: "combined", otlp: r}
+	case "jaeger":
+		id := o.id(rid)
+		r := yaml.MapSlice{{Key: "type", Value: "jaeger"}}
+		protocols, _ := cfg["protocols"].(map[string]interface{})
+		for _, p := range sortedKeys(protocols) {
+			protocol, _ := protocols[p].(map[string]interface{})
+			switch p {
+			case "thrift_http", "thrift_compact":
+				if protocol["endpoint"] != nil {
+					r = append(r, yaml.MapItem{Key: p + "_endpoint", Value: protocol["endpoint"]})
+				}
+			default:
+				o.unsupportedAt(o.file, name, "protocol %q is not supported", p)
+			}
+		}
+		o.combinedReceivers = append(o.combinedReceivers, yaml.MapItem{Key: id, Value: r})
+		return &otelReceiver{id: id, section: "combined"}
+	case "zipkin":
+		id := o.id(rid)
+		r := yaml.MapSlice{{Key: "type", Value: "zipkin"}}
+		if cfg["endpoint"] != nil {
+			r = append(r, yaml.MapItem{Key: "endpoint", Value: cfg["endpoint"]})
+		}
+		o.combinedReceivers = append(o.combinedReceivers, yaml.MapItem{Key: id, Value: r})
+		return &otelReceiver{id: id, section: "combined"}
+	case "prometheus":
+		id := o.id(rid)
+		o.metricsReceivers = append(o.metricsReceivers, yaml.MapItem{Key: id, Value: yaml.MapSlice{
+			{Key: "type", Value: "prometheus"},
+			{Key: "config", Value: cfg["config"]},
+		}})
+		return &otelReceiver{id: id, section: "metrics"}
+	case "hostmetrics":
+		// The built-in hostmetrics receiver is in the default pipeline already, so only its
+		// collection interval is migrated.
+		o.unsupportedAt(o.file, name, "the scrapers are not migrated; the built-in hostmetrics receiver collects the host metrics under agent.googleapis.com")
+		if interval, ok := cfg["collection_interval"]; ok && interval != "60s" && !o.ids["hostmetrics"] {
+			o.metricsReceivers = append(o.metricsReceivers, yaml.MapItem{Key: o.id("hostmetrics"), Value: yaml.MapSlice{
+				{Key: "type", Value: "hostmetrics"},
+				{Key: "collection_interval", Value: interval},
+			}})
+		}
+		return &otelReceiver{}
+	case "filelog":
+		id := o.id(rid)
+		r := yaml.MapSlice{{Key: "type", Value: "files"}}
+		for _, k := range sortedKeys(cfg) {
+			switch k {
+			case "include":
+				r = append(r, yaml.MapItem{Key: "include_paths", Value: cfg[k]})
+			case "exclude":
+				r = append(r, yaml.MapItem{Key: "exclude_paths", Value: cfg[k]})
+			case "operators":
+				o.unsupportedAt(o.file, name, "operators are not migrated; parse the logs with logging processors instead")
+			default:
+				o.unsupportedAt(o.file, name, "option %q is not supported", k)
+			}
+		}
+		o.loggingReceivers = append(o.loggingReceivers, yaml.MapItem{Key: id, Value: r})
+		return &otelReceiver{id: id, section: "logging"}
+	}
+	mapping, ok := otelMetricsReceivers[t]
+	if !ok {
+		o.unsupportedAt(o.file, name, "receiver type %q has no Ops Agent receiver", t)
+		return nil
+	}
+	id := o.id(rid)
+	r := yaml.MapSlice{{Key: "type", Value: mapping.t}}
+	for _, k := range sortedKeys(cfg) {
+		switch {
+		case k == "collection_interval":
+			r = append(r, yaml.MapItem{Key: k, Value: cfg[k]})
+		case mapping.options[k] != "":
+			r = append(r, yaml.MapItem{Key: mapping.options[k], Value: cfg[k]})
+		case k == "hosts" && t == "mongodb":
+			// The Ops Agent receiver scrapes a single host.
+			hosts, _ := cfg[k].([]interface{})
+			if len(hosts) > 1 {
+				o.unsupportedAt(o.file, name, "only the first of the hosts is migrated")
+			}
+			if len(hosts) > 0 {
+				host, _ := hosts[0].(map[string]interface{})
+				r = append(r, yaml.MapItem{Key: "endpoint", Value: host["endpoint"]})
+			}
+		case k == "tls" && mapping.tls:
+			tls, _ := cfg[k].(map[string]interface{})
+			for _, tk := range sortedKeys(tls) {
+				if otelTLSOptions[tk] == "" {
+					o.unsupportedAt(o.file, name, "option \"tls::%s\" is not supported", tk)
+					continue
+				}
+				r = append(r, yaml.MapItem{Key: otelTLSOptions[tk], Value: tls[tk]})
+			}
+		default:
+			o.unsupportedAt(o.file, name, "option %q is not supported", k)
+		}
+	}
+	o.metricsReceivers = append(o.metricsReceivers, yaml.MapItem{Key: id, Value: r})
+	return &otelReceiver{id: id, section: "metrics"}
+}
+
+// filterProcessor migrates a filter processor that excludes metrics by name to an exclude_metrics
+// processor, and returns its ID, or "" if it was not migrated.
+func (o *otelMigration) filterProcessor(pid string) string {
+	if id, ok := o.processors[pid]; ok {
+		return id
+	}
+	o.processors[pid] = ""
+	name := "processor " + pid
+	metrics, _ := o.config.Processors[pid]["metrics"].(map[string]interface{})
+	exclude, _ := metrics["exclude"].(map[string]interface{})
+	names, _ := exclude["metric_names"].([]interface{})
+	if len(metrics) != 1 || len(exclude) != 2 || exclude["match_type"] != "strict" || len(names) == 0 {
+		o.unsupportedAt(o.file, name, "only filters that exclude metrics by their metric_names with match_type strict are supported")
+		return ""
+	}
+	// The Ops Agent matches the metrics of its receivers with their domain, e.g.
+	// workload.googleapis.com/mysql.threads, and the metrics received over OTLP without it.
+	var patterns []string
+	for _, n := range names {
+		patterns = append(patterns, fmt.Sprint(n), "*/"+fmt.Sprint(n))
+	}
+	id := o.id(pid)
+	o.metricsProcessors = append(o.metricsProcessors, yaml.MapItem{Key: id, Value: yaml.MapSlice{
+		{Key: "type", Value: "exclude_metrics"},
+		{Key: "metrics_pattern", Value: patterns},
+	}})
+	o.processors[pid] = id
+	return id
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
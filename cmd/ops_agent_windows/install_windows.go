@@ -16,9 +16,11 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"syscall"
 	"time"
 
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
 	"github.com/hashicorp/go-multierror"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/eventlog"
@@ -40,6 +42,10 @@ func install() error {
 		return err
 	}
 	defer m.Disconnect()
+	if err := logs.InstallOperationalLog(); err != nil {
+		// The agent only writes to the operational log when it exists, so it works without it.
+		log.Printf("failed to install the operational event log: %v", err)
+	}
 	handles := make([]*mgr.Service, len(services))
 	for i, s := range services {
 		// Registering with the event log is required to suppress the "The description for Event ID 1 from source Google Cloud Ops Agent cannot be found" message in the logs.
@@ -124,6 +130,9 @@ func uninstall() error {
 			errs = multierror.Append(errs, err)
 		}
 	}
+	if err := logs.UninstallOperationalLog(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
 	return errs
 }
 
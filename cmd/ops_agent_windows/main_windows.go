@@ -15,13 +15,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/GoogleCloudPlatform/ops-agent/apps"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks/requirements"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
 	"github.com/kardianos/osext"
 	"golang.org/x/sys/windows/svc"
@@ -34,7 +40,9 @@ const serviceDisplayName = "Google Cloud Ops Agent"
 var (
 	installServices   = flag.Bool("install", false, "whether to install the services")
 	uninstallServices = flag.Bool("uninstall", false, "whether to uninstall the services")
-	healthChecks      = flag.Bool("healthchecks", false, "run health checks and exit")
+	healthChecks      = flag.Bool("healthchecks", false, "run health checks against the config passed with --in and exit")
+	input             = flag.String("in", "", "path to the user specified agent config, for --healthchecks; defaults to the installed config")
+	format            = flag.String("format", "text", "format of the --healthchecks results: text or json")
 )
 
 func main() {
@@ -62,7 +70,36 @@ func main() {
 			}
 			infoLog.Printf("uninstalled services")
 		} else if *healthChecks {
-			healthCheckResults := getHealthCheckResults()
+			base, err := osext.ExecutableFolder()
+			if err != nil {
+				log.Fatalf("could not determine binary path: %v", err)
+			}
+			userConf := *input
+			if userConf == "" {
+				userConf = filepath.Join(base, "../config/config.yaml")
+			}
+			ctx := context.Background()
+			uc, err := confgenerator.MergeConfFiles(ctx, userConf, apps.BuiltInConfStructs)
+			if err != nil {
+				log.Fatalf("can't parse configuration: %v", err)
+			}
+			applied, err := confgenerator.ReadAppliedConfig(ctx, filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "run"))
+			if err != nil {
+				log.Print(err)
+			}
+			req, err := requirements.FromConfig(ctx, uc, applied)
+			if err != nil {
+				log.Fatalf("can't parse configuration: %v", err)
+			}
+			healthCheckResults := getHealthCheckResults(req, userConf)
+			if *format == "json" {
+				data, err := healthchecks.MarshalHealthCheckResults(healthCheckResults, time.Now())
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(string(data))
+				return
+			}
 			healthchecks.LogHealthCheckResults(healthCheckResults, infoLog)
 			infoLog.Println("Health checks finished")
 		} else {
@@ -140,8 +177,11 @@ func initServices() error {
 			[]string{
 				"-log_path", filepath.Join(logDirectory, "logging-module.log"),
 				"-config_path", filepath.Join(base, "../config/config.yaml"),
+				"-watchdog_timeout", "10m",
+				"-health_url", fmt.Sprintf("http://127.0.0.1:%d/metrics", fluentbit.MetricsPort),
+				"-logs_dir", logDirectory,
 				filepath.Join(base, "fluent-bit.exe"),
-				"-c", filepath.Join(configOutDir, `fluentbit\fluent_bit_main.conf`),
+				"-c", filepath.Join(configOutDir, "fluentbit", fluentbit.DefaultConfigFormat.MainConfigFileName()),
 				"-R", filepath.Join(configOutDir, `fluentbit\fluent_bit_parser.conf`),
 				"--storage_path", fluentbitStoragePath,
 			},
@@ -152,6 +192,8 @@ func initServices() error {
 			filepath.Join(base, fmt.Sprintf("%s-diagnostics.exe", serviceName)),
 			[]string{
 				"-config", filepath.Join(base, "../config/config.yaml"),
+				"-logs", logDirectory,
+				"-buffers", fluentbitStoragePath,
 			},
 		},
 	}
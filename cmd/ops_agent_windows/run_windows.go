@@ -26,7 +26,9 @@ import (
 	"github.com/GoogleCloudPlatform/ops-agent/apps"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks/requirements"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/debug"
@@ -50,6 +52,7 @@ func containsString(all []string, s string) bool {
 
 type service struct {
 	log          debug.Log
+	operational  *logs.OperationalLog
 	userConf     string
 	outDirectory string
 }
@@ -68,13 +71,29 @@ func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, changes cha
 		return false, 0x00000057
 	}
 
-	if err := s.generateConfigs(ctx); err != nil {
+	// generateConfigs records the new config as applied, so read the one that the subagents
+	// may still be running with first.
+	applied, err := confgenerator.ReadAppliedConfig(ctx, filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "run"))
+	if err != nil {
+		s.log.Warning(EngineEventID, err.Error())
+	}
+	uc, err := s.generateConfigs(ctx)
+	if err != nil {
 		s.log.Error(EngineEventID, fmt.Sprintf("failed to generate config files: %v", err))
+		s.operational.Error(logs.ConfigInvalidEventID, "The agent config is not valid",
+			"config", s.userConf,
+			"error", err.Error())
 		// 2 is "file not found"
 		return false, 2
 	}
 	s.log.Info(EngineEventID, "generated configuration files")
-	s.runHealthChecks()
+	s.operational.Info(logs.ConfigAppliedEventID, "The agent config was applied",
+		"config", s.userConf)
+	req, err := requirements.FromConfig(ctx, uc, applied)
+	if err != nil {
+		s.log.Error(EngineEventID, fmt.Sprintf("failed to determine health check requirements: %v", err))
+	}
+	s.runHealthChecks(req)
 
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
 	if err := s.startSubagents(); err != nil {
@@ -82,7 +101,11 @@ func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, changes cha
 		// TODO: Ignore failures for partial startup?
 	}
 	s.log.Info(EngineEventID, "started subagents")
+	s.operational.Info(logs.AgentStartedEventID, "The Ops Agent started",
+		"version", version.Version)
 	defer func() {
+		s.operational.Info(logs.AgentStoppedEventID, "The Ops Agent stopped",
+			"version", version.Version)
 		changes <- svc.Status{State: svc.StopPending}
 	}()
 	for {
@@ -140,32 +163,58 @@ func (s *service) checkForStandaloneAgents(unified *confgenerator.UnifiedConfig)
 	return nil
 }
 
-func getHealthCheckResults() []healthchecks.HealthCheckResult {
+func getHealthCheckResults(req healthchecks.ConfigRequirements, userConf string) []healthchecks.HealthCheckResult {
 	logsDir := filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "log")
-	gceHealthChecks := healthchecks.HealthCheckRegistryFactory()
+	gceHealthChecks := healthchecks.HealthCheckRegistryFactory(req).WithCommandChecks(healthchecks.CommandChecksDir(userConf))
 	logger := healthchecks.CreateHealthChecksLogger(logsDir)
 
-	return gceHealthChecks.RunAllHealthChecks(logger)
+	healthCheckResults := gceHealthChecks.RunAllHealthChecks(logger)
+	stateDir := filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "run")
+	if err := healthchecks.WriteHealthCheckResults(healthCheckResults, stateDir); err != nil {
+		log.Printf("failed to write the health check results: %v", err)
+	}
+	return healthCheckResults
 }
 
-func (srv *service) runHealthChecks() {
-	healthCheckResults := getHealthCheckResults()
+func (srv *service) runHealthChecks(req healthchecks.ConfigRequirements) {
+	healthCheckResults := getHealthCheckResults(req, srv.userConf)
 	logger := logs.WindowsServiceLogger{EventID: EngineEventID, Logger: srv.log}
 	healthchecks.LogHealthCheckResults(healthCheckResults, logger)
+	for _, r := range healthCheckResults {
+		srv.logHealthCheckEvent(r)
+	}
 	srv.log.Info(EngineEventID, "Startup checks finished")
 }
 
-func (s *service) generateConfigs(ctx context.Context) error {
+// logHealthCheckEvent writes the result of a health check to the operational event log.
+func (srv *service) logHealthCheckEvent(r healthchecks.HealthCheckResult) {
+	result := r.Result()
+	msg := fmt.Sprintf("[%s] Result: %s", r.Name, result)
+	keysAndValues := []any{"check", r.Name, "result", result}
+	if r.Err != nil {
+		keysAndValues = append(keysAndValues, "error", r.Err.Error())
+	}
+	switch result {
+	case "PASS":
+		srv.operational.Info(logs.HealthCheckPassedEventID, msg, keysAndValues...)
+	case "WARNING":
+		srv.operational.Warning(logs.HealthCheckWarningEventID, msg, keysAndValues...)
+	default:
+		srv.operational.Error(logs.HealthCheckFailedEventID, msg, keysAndValues...)
+	}
+}
+
+func (s *service) generateConfigs(ctx context.Context) (*confgenerator.UnifiedConfig, error) {
 	// TODO(lingshi) Move this to a shared place across Linux and Windows.
 	uc, err := confgenerator.MergeConfFiles(ctx, s.userConf, apps.BuiltInConfStructs)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	s.log.Info(EngineEventID, fmt.Sprintf("Built-in config:\n%s\n", apps.BuiltInConfStructs["windows"]))
 	s.log.Info(EngineEventID, fmt.Sprintf("Merged config:\n%s\n", uc))
 	if err := s.checkForStandaloneAgents(uc); err != nil {
-		return err
+		return nil, err
 	}
 	// TODO: Add flag for passing in log/run path?
 	for _, subagent := range []string{
@@ -178,10 +227,18 @@ func (s *service) generateConfigs(ctx context.Context) error {
 			filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "log"),
 			filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "run"),
 			filepath.Join(s.outDirectory, subagent)); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	logsDir := filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "log")
+	stateDir := filepath.Join(os.Getenv("PROGRAMDATA"), dataDirectory, "run")
+	if err := uc.RecordConfigChange(ctx, stateDir, confgenerator.ConfigTriggerStart, healthchecks.CreateHealthChecksLogger(logsDir)); err != nil {
+		s.log.Warning(EngineEventID, fmt.Sprintf("failed to record the config change: %v", err))
+	}
+	if err := uc.RecordVersionDrift(healthchecks.CreateHealthChecksLogger(logsDir)); err != nil {
+		s.log.Warning(EngineEventID, err.Error())
+	}
+	return uc, nil
 }
 
 func (s *service) startSubagents() error {
@@ -234,8 +291,15 @@ func run(name string) error {
 		EventLog: elog,
 	})
 
+	// The operational log is only written to if it was installed with the services.
+	operational, err := logs.OpenOperationalLog()
+	if err != nil {
+		elog.Warning(EngineEventID, fmt.Sprintf("failed to open the %s event log: %v", logs.OperationalLogName, err))
+	}
+	defer operational.Close()
+
 	elog.Info(1, fmt.Sprintf("starting %s service", name))
-	err = svc.Run(name, &service{log: elog})
+	err = svc.Run(name, &service{log: elog, operational: operational})
 	if err != nil {
 		elog.Error(EngineEventID, fmt.Sprintf("%s service failed: %v", name, err))
 		return err
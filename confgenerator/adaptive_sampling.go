@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+// LoggingAdaptiveSampling samples the logs of the logging pipelines while the Logging API keeps
+// rejecting requests with 429 RESOURCE_EXHAUSTED, instead of buffering them until the quota
+// allows to send the backlog at once.
+//
+// The diagnostics service counts the rejected requests in the Fluent Bit self metrics, and
+// writes the sampling rate to the file at AdaptiveSamplingRatePath, which a filter at the end of
+// the logging pipelines reads. Once requests were rejected for 3 minutes in a row, the rate halves
+// for every minute of rejected requests down to MinSamplingRate, and it doubles for every minute
+// without rejected requests.
+//
+// The logs that are not sampled are dropped for every destination of their pipeline.
+type LoggingAdaptiveSampling struct {
+	// MinSamplingRate is the smallest fraction of the logs that are kept while sampling.
+	MinSamplingRate float64 `yaml:"min_sampling_rate" validate:"required,gt=0,lte=1"`
+}
+
+// AdaptiveSamplingRatePath returns the path of the file that holds the sampling rate of the logs
+// of the Fluent Bit that keeps its state in stateDir.
+func AdaptiveSamplingRatePath(stateDir string) string {
+	return path.Join(stateDir, "adaptive_sampling_rate")
+}
+
+// adaptiveSamplingRateCheckInterval is how often, in seconds, the filter reads the rate file.
+const adaptiveSamplingRateCheckInterval = 10
+
+const adaptiveSamplingLuaScript = `
+local rate_path = %q
+local min_rate = %g
+local rate = 1
+local checked = 0
+
+function sample(tag, timestamp, record)
+  local now = os.time()
+  if now - checked >= %d then
+    checked = now
+    rate = 1
+    local f = io.open(rate_path, "r")
+    if f ~= nil then
+      local r = tonumber(f:read("*l"))
+      f:close()
+      if r ~= nil and r < 1 then
+        rate = math.max(r, min_rate)
+      end
+    end
+  end
+  if rate < 1 and math.random() >= rate then
+    return -1, timestamp, record
+  end
+  return 0, timestamp, record
+end
+`
+
+// components returns the filter that samples the logs whose tag matches tagRegex.
+func (a LoggingAdaptiveSampling) components(tagRegex, ratePath string) []fluentbit.Component {
+	script := fmt.Sprintf(adaptiveSamplingLuaScript, ratePath, a.MinSamplingRate, adaptiveSamplingRateCheckInterval)
+	c := fluentbit.LuaFilterComponents("", "sample", script)
+	delete(c[0].Config, "Match")
+	c[0].Config["Match_Regex"] = fmt.Sprintf("^(%s)$", tagRegex)
+	return c
+}
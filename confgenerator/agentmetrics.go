@@ -57,6 +57,7 @@ func (r AgentSelfMetrics) MetricsSubmodulePipeline() otel.ReceiverPipeline {
 				"otelcol_process_memory_rss",
 				"grpc_client_attempt_duration",
 				"googlecloudmonitoring_point_count",
+				"otelcol_exporter_send_failed_metric_points",
 			),
 			otel.Transform("metric", "metric",
 				// create new count metric from histogram metric
@@ -69,6 +70,7 @@ func (r AgentSelfMetrics) MetricsSubmodulePipeline() otel.ReceiverPipeline {
 				"otelcol_process_memory_rss",
 				"grpc_client_attempt_duration_count",
 				"googlecloudmonitoring_point_count",
+				"otelcol_exporter_send_failed_metric_points",
 			),
 			otel.MetricsTransform(
 				otel.RenameMetric("otelcol_process_uptime", "agent/uptime",
@@ -98,6 +100,13 @@ func (r AgentSelfMetrics) MetricsSubmodulePipeline() otel.ReceiverPipeline {
 					// Remove service.version label
 					otel.AggregateLabels("sum", "status"),
 				),
+				// Points the exporters gave up on, after any retries.
+				otel.RenameMetric("otelcol_exporter_send_failed_metric_points", "agent/monitoring/failed_point_count",
+					// change data type from double -> int64
+					otel.ToggleScalarDataType,
+					otel.RenameLabel("exporter", "pipeline"),
+					otel.AggregateLabels("sum", "pipeline"),
+				),
 				otel.AddPrefix("agent.googleapis.com"),
 			),
 		}},
@@ -133,6 +142,9 @@ func (r AgentSelfMetrics) LoggingSubmodulePipeline() otel.ReceiverPipeline {
 				"fluentbit_stackdriver_requests_total",
 				"fluentbit_stackdriver_proc_records_total",
 				"fluentbit_stackdriver_retried_records_total",
+				"fluentbit_output_latency_seconds",
+				"fluentbit_output_dropped_records_total",
+				"fluentbit_output_retries_failed_total",
 			),
 			otel.MetricsTransform(
 				otel.RenameMetric("fluentbit_uptime", "agent/uptime",
@@ -160,6 +172,32 @@ func (r AgentSelfMetrics) LoggingSubmodulePipeline() otel.ReceiverPipeline {
 					otel.RenameLabel("status", "response_code"),
 					otel.AggregateLabels("sum", "response_code"),
 				),
+				// The time from when an input ingested a record until the output flushed it successfully,
+				// as a distribution.
+				otel.RenameMetric("fluentbit_output_latency_seconds", "agent/log_entry_latencies",
+					// The self metrics are not sent to Cloud Logging.
+					otel.DeleteLabelValue("output", "prometheus_exporter.0"),
+					otel.AggregateLabels("sum"),
+				),
+				otel.RenameMetric("fluentbit_output_dropped_records_total", "agent/log_entry_dropped_count",
+					// change data type from double -> int64
+					otel.ToggleScalarDataType,
+					// The self metrics are not sent to Cloud Logging.
+					otel.DeleteLabelValue("name", "prometheus_exporter.0"),
+					otel.RenameLabel("name", "pipeline"),
+					otel.RenameLabelValues("pipeline", logsOutputPipelines),
+					otel.AggregateLabels("sum", "pipeline"),
+				),
+				// Chunks whose retries were exhausted; their records are dropped too.
+				otel.RenameMetric("fluentbit_output_retries_failed_total", "agent/log_chunk_retry_failed_count",
+					// change data type from double -> int64
+					otel.ToggleScalarDataType,
+					// The self metrics are not sent to Cloud Logging.
+					otel.DeleteLabelValue("name", "prometheus_exporter.0"),
+					otel.RenameLabel("name", "pipeline"),
+					otel.RenameLabelValues("pipeline", logsOutputPipelines),
+					otel.AggregateLabels("sum", "pipeline"),
+				),
 				otel.AddPrefix("agent.googleapis.com"),
 			),
 		}},
@@ -0,0 +1,85 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// BigQuery streams the structured logs of a logging pipeline into a BigQuery table, in addition
+// to Cloud Logging, without going through a log sink.
+//
+// The fields of jsonPayload are written to the columns with the same names, and BigQuery converts
+// their values to the types of the columns. Fields without a column are ignored.
+type BigQuery struct {
+	// Table is "DATASET.TABLE" or "PROJECT.DATASET.TABLE". The project defaults to the project
+	// of the VM.
+	Table string `yaml:"table" validate:"required"`
+	// CredentialsFile is the service account key that is used to write to the table.
+	CredentialsFile string `yaml:"credentials_file" validate:"required"`
+	// SkipInvalidRows writes the valid rows of a batch even if some rows can't be converted,
+	// instead of dropping the whole batch.
+	SkipInvalidRows bool `yaml:"skip_invalid_rows,omitempty"`
+}
+
+func (b *BigQuery) validate(field string) error {
+	parts := strings.Split(b.Table, ".")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return fmt.Errorf(`%q: table %q must have the format "DATASET.TABLE" or "PROJECT.DATASET.TABLE"`, field, b.Table)
+	}
+	return nil
+}
+
+// bigQueryOutputComponent writes the records whose tag matches match to the table of b.
+func bigQueryOutputComponent(ctx context.Context, match, pipelineID string, b *BigQuery) (fluentbit.Component, error) {
+	parts := strings.Split(b.Table, ".")
+	if len(parts) == 2 {
+		resource, err := platform.FromContext(ctx).GetResource()
+		if err != nil {
+			return fluentbit.Component{}, err
+		}
+		if resource.ProjectName() == "" {
+			return fluentbit.Component{}, fmt.Errorf(`"logging.service.pipelines.%s.bigquery": the project of table %q must be specified on this platform`, pipelineID, b.Table)
+		}
+		parts = append([]string{resource.ProjectName()}, parts...)
+	}
+	skipInvalidRows := "Off"
+	if b.SkipInvalidRows {
+		skipInvalidRows = "On"
+	}
+	return fluentbit.Component{
+		Kind: "OUTPUT",
+		Config: map[string]string{
+			// https://docs.fluentbit.io/manual/pipeline/outputs/bigquery
+			"Name":                       "bigquery",
+			"Match_Regex":                fmt.Sprintf("^(%s)$", match),
+			"Alias":                      fmt.Sprintf("bigquery.%s", pipelineID),
+			"google_service_credentials": b.CredentialsFile,
+			"project_id":                 parts[0],
+			"dataset_id":                 parts[1],
+			"table_id":                   parts[2],
+			"skip_invalid_rows":          skipInvalidRows,
+			// The records also have the LogEntry fields that are set by the agent, which have no column.
+			"ignore_unknown_values": "On",
+			"Retry_Limit":           "3",
+		},
+	}, nil
+}
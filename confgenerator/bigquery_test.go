@@ -0,0 +1,63 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import "testing"
+
+func TestBigQueryValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{
+			name:   "dataset and table",
+			output: `bigquery: {table: logs.app, credentials_file: /etc/google/bigquery.json}`,
+		},
+		{
+			name:   "project, dataset and table",
+			output: `bigquery: {table: my-project.logs.app, credentials_file: /etc/google/bigquery.json, skip_invalid_rows: true}`,
+		},
+		{
+			name:    "missing credentials file",
+			output:  `bigquery: {table: logs.app}`,
+			wantErr: "credentials_file",
+		},
+		{
+			name:    "missing table",
+			output:  `bigquery: {credentials_file: /etc/google/bigquery.json}`,
+			wantErr: "table",
+		},
+		{
+			name:    "table without dataset",
+			output:  `bigquery: {table: app, credentials_file: /etc/google/bigquery.json}`,
+			wantErr: `table "app" must have the format "DATASET.TABLE" or "PROJECT.DATASET.TABLE"`,
+		},
+		{
+			name:    "table with too many parts",
+			output:  `bigquery: {table: a.b.c.d, credentials_file: /etc/google/bigquery.json}`,
+			wantErr: `table "a.b.c.d" must have the format "DATASET.TABLE" or "PROJECT.DATASET.TABLE"`,
+		},
+		{
+			name:    "table with empty dataset",
+			output:  `bigquery: {table: my-project..app, credentials_file: /etc/google/bigquery.json}`,
+			wantErr: `table "my-project..app" must have the format`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checkPipelineOutputErr(t, validateWithPipelineOutput(tc.output), tc.wantErr)
+		})
+	}
+}
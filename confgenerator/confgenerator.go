@@ -24,9 +24,11 @@ import (
 	"maps"
 	"path"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
@@ -73,6 +75,9 @@ func googleManagedPrometheusExporter(userAgent string) otel.Component {
 }
 
 func (uc *UnifiedConfig) GenerateOtelConfig(ctx context.Context) (string, error) {
+	ctx = contextWithPerformanceProfile(ctx, uc.Global)
+	ctx = contextWithResourceOverride(ctx, uc.Global)
+	profile := performanceProfileFromContext(ctx)
 	p := platform.FromContext(ctx)
 	userAgent, _ := p.UserAgent("Google-Cloud-Ops-Agent-Metrics")
 	metricVersionLabel, _ := p.VersionLabel("google-cloud-ops-agent-metrics")
@@ -82,6 +87,12 @@ func (uc *UnifiedConfig) GenerateOtelConfig(ctx context.Context) (string, error)
 	if err != nil {
 		return "", err
 	}
+	pubsubReceiverPipelines, pubsubPipelines, err := uc.generatePubSubPipelines(ctx)
+	if err != nil {
+		return "", err
+	}
+	maps.Copy(receiverPipelines, pubsubReceiverPipelines)
+	maps.Copy(pipelines, pubsubPipelines)
 
 	receiverPipelines["otel"] = AgentSelfMetrics{
 		Version: metricVersionLabel,
@@ -101,18 +112,28 @@ func (uc *UnifiedConfig) GenerateOtelConfig(ctx context.Context) (string, error)
 		ReceiverPipelineName: "fluentbit",
 	}
 
-	if uc.Metrics.Service.LogLevel == "" {
-		uc.Metrics.Service.LogLevel = "info"
+	logLevel := uc.Metrics.Service.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
 	}
+	metricsLocation := uc.Metrics.Service.Location
+	var loggingLocation string
+	if uc.Logging != nil && uc.Logging.Service != nil {
+		loggingLocation = uc.Logging.Service.Location
+	}
+	retry := uc.Metrics.Service.Retry
 	otelConfig, err := otel.ModularConfig{
-		LogLevel:          uc.Metrics.Service.LogLevel,
+		LogLevel:          logLevel,
 		ReceiverPipelines: receiverPipelines,
 		Pipelines:         pipelines,
 		Exporters: map[otel.ExporterType]otel.Component{
-			otel.System: googleCloudExporter(userAgent, false),
-			otel.OTel:   googleCloudExporter(userAgent, true),
-			otel.GMP:    googleManagedPrometheusExporter(userAgent),
+			otel.System: withRetry(profile.withExporterQueue(withRegionalEndpoints(googleCloudExporter(userAgent, false), metricsLocation, loggingLocation)), retry),
+			otel.OTel:   withRetry(profile.withExporterQueue(withRegionalEndpoints(googleCloudExporter(userAgent, true), metricsLocation, loggingLocation)), retry),
+			otel.GMP:    withRetry(profile.withExporterQueue(withRegionalEndpoints(googleManagedPrometheusExporter(userAgent), metricsLocation, loggingLocation)), retry),
 		},
+		MemoryLimiter:      memoryLimiterProcessor(uc.Metrics.Service.MemoryLimiter),
+		Batch:              profile.batchProcessor(uc.Metrics.Service.Batch),
+		ResourceAttributes: resourceAttributesProcessor(uc.Metrics.Service.ResourceAttributes),
 	}.Generate(ctx)
 	if err != nil {
 		return "", err
@@ -154,8 +175,13 @@ func (p pipelineInstance) fluentBitComponents(ctx context.Context) (fbSource, er
 		tag = fmt.Sprintf("%s.%s.%s", hashString, pipelineIdCleaned, receiverIdCleaned)
 	}
 	var components []fluentbit.Component
+	var stages []fbStage
+	addStage := func(name string, c []fluentbit.Component) {
+		components = append(components, c...)
+		stages = append(stages, fbStage{name: name, components: c})
+	}
 	receiverComponents := receiver.Components(ctx, tag)
-	components = append(components, receiverComponents...)
+	addStage(fmt.Sprintf("receiver %q (%s)", p.rID, receiver.Type()), receiverComponents)
 
 	// To match on fluent_forward records, we need to account for the addition
 	// of the existing tag (unknown during config generation) as the suffix
@@ -169,6 +195,10 @@ func (p pipelineInstance) fluentBitComponents(ctx context.Context) (fbSource, er
 	tagRegex := regexp.QuoteMeta(tag) + regexSuffix
 	tag = tag + globSuffix
 
+	if p.gcsArchive != nil {
+		addStage("gcs_archive copy", []fluentbit.Component{gcsArchiveCopyComponent(tag)})
+	}
+
 	for i, processorItem := range p.processors {
 		processor, ok := processorItem.Component.(LoggingProcessor)
 		if !ok {
@@ -178,18 +208,38 @@ func (p pipelineInstance) fluentBitComponents(ctx context.Context) (fbSource, er
 		if err := processUserDefinedMultilineParser(i, processorItem.id, receiver, processor, receiverComponents, processorComponents); err != nil {
 			return fbSource{}, err
 		}
-		components = append(components, processorComponents...)
+		addStage(fmt.Sprintf("processor %q (%s)", processorItem.id, processor.Type()), processorComponents)
+	}
+	addStage("log name", setLogNameComponents(ctx, tag, p.rID, receiver.Type(), platform.FromContext(ctx).Hostname()))
+	if fields := receiverFields(componentConfig(receiver)); len(fields.Fields) > 0 {
+		addStage("receiver labels", fields.Components(ctx, tag, "receiverfields"))
+	}
+	if p.logBucket != "" {
+		addStage("log_bucket", logBucketFields(p.logBucket).Components(ctx, tag, "logbucket"))
 	}
-	components = append(components, setLogNameComponents(ctx, tag, p.rID, receiver.Type(), platform.FromContext(ctx).Hostname())...)
 
 	// Logs ingested using the fluent_forward receiver must add the existing_tag
 	// on the record to the LogName. This is done with a Lua filter.
 	if receiver.Type() == "fluent_forward" {
-		components = append(components, fluentbit.LuaFilterComponents(tag, addLogNameLuaFunction, addLogNameLuaScriptContents)...)
+		addStage("forward tag log name", fluentbit.LuaFilterComponents(tag, addLogNameLuaFunction, addLogNameLuaScriptContents))
+	}
+	if len(p.routes) > 0 {
+		routeComponents, err := p.routes.Components(ctx, tag)
+		if err != nil {
+			return fbSource{}, err
+		}
+		addStage("route_logs", routeComponents)
 	}
 	return fbSource{
-		tagRegex:   tagRegex,
-		components: components,
+		tagRegex:      tagRegex,
+		components:    components,
+		stages:        stages,
+		compress:      p.compress,
+		pID:           p.pID,
+		syslogForward: p.syslogForward,
+		pubsub:        p.pubsub,
+		gcsArchive:    p.gcsArchive,
+		bigQuery:      p.bigQuery,
 	}, nil
 }
 
@@ -248,7 +298,31 @@ func (p pipelineInstance) otelComponents(ctx context.Context) (map[string]otel.R
 				pipeline.Processors = append(pipeline.Processors, processors...)
 			}
 		}
+		if fields := receiverFields(componentConfig(p.receiver)); len(fields.Fields) > 0 {
+			processors, err := fields.Processors(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			pipeline.Processors = append(pipeline.Processors, processors...)
+		}
+		if p.logBucket != "" {
+			processors, err := logBucketFields(p.logBucket).Processors(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			pipeline.Processors = append(pipeline.Processors, processors...)
+		}
+		if len(p.routes) > 0 {
+			processors, err := p.routes.Processors(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			pipeline.Processors = append(pipeline.Processors, processors...)
+		}
 		outP[prefix] = pipeline
+		if _, ok := p.receiver.(*PrometheusMetrics); ok {
+			outP[prefix+"_scrape_status"] = scrapeStatusPipeline(receiverPipelineName)
+		}
 	}
 	return outR, outP, nil
 }
@@ -261,25 +335,64 @@ func (uc *UnifiedConfig) generateOtelPipelines(ctx context.Context) (map[string]
 	if err != nil {
 		return nil, nil, err
 	}
-	for _, pipeline := range pipelines {
-		if pipeline.backend != backendOTel {
-			continue
+	type otelComponents struct {
+		receiverPipelines map[string]otel.ReceiverPipeline
+		pipelines         map[string]otel.Pipeline
+	}
+	generated, err := generatePipelines(pipelines, backendOTel, func(p pipelineInstance) (otelComponents, error) {
+		pipeR, pipeP, err := p.otelComponents(ctx)
+		return otelComponents{pipeR, pipeP}, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range generated {
+		maps.Copy(outR, c.receiverPipelines)
+		maps.Copy(outP, c.pipelines)
+	}
+	return outR, outP, nil
+}
+
+// generatePipelines calls generate for every pipeline with the given backend. The pipelines are
+// independent of each other, so they are generated concurrently, which matters for configs with
+// hundreds of receivers. At most one pipeline per CPU is generated at a time. The results are in
+// the order of the pipelines, and the error is the one of the first pipeline that failed, so that
+// neither depends on the scheduling.
+func generatePipelines[T any](pipelines []pipelineInstance, backend pipelineBackend, generate func(pipelineInstance) (T, error)) ([]T, error) {
+	var selected []pipelineInstance
+	for _, p := range pipelines {
+		if p.backend == backend {
+			selected = append(selected, p)
 		}
-		pipeR, pipeP, err := pipeline.otelComponents(ctx)
+	}
+	results := make([]T, len(selected))
+	errs := make([]error, len(selected))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, p := range selected {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = generate(p)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
-		maps.Copy(outR, pipeR)
-		maps.Copy(outP, pipeP)
 	}
-	return outR, outP, nil
+	return results, nil
 }
 
 // GenerateFluentBitConfigs generates configuration file(s) for Fluent Bit.
 // It returns a map of filenames to file contents.
 func (uc *UnifiedConfig) GenerateFluentBitConfigs(ctx context.Context, logsDir string, stateDir string) (map[string]string, error) {
+	ctx = contextWithResourceOverride(ctx, uc.Global)
 	userAgent, _ := platform.FromContext(ctx).UserAgent("Google-Cloud-Ops-Agent-Logging")
-	components, err := uc.generateFluentbitComponents(ctx, userAgent)
+	components, err := uc.generateFluentbitComponents(ctx, userAgent, stateDir)
 	if err != nil {
 		return nil, err
 	}
@@ -290,9 +403,27 @@ func (uc *UnifiedConfig) GenerateFluentBitConfigs(ctx context.Context, logsDir s
 			"logs_dir":    logsDir,
 		},
 		Components: components,
+		Format:     fluentBitConfigFormatFromContext(ctx),
 	}
 	return c.Generate()
 }
+
+type fluentBitConfigFormatKeyType struct{}
+
+var fluentBitConfigFormatKey = fluentBitConfigFormatKeyType{}
+
+// ContextWithFluentBitConfigFormat returns ctx with the format of the main config file that
+// GenerateFluentBitConfigs generates, which is fluentbit.DefaultConfigFormat by default.
+func ContextWithFluentBitConfigFormat(ctx context.Context, format fluentbit.ConfigFormat) context.Context {
+	return context.WithValue(ctx, fluentBitConfigFormatKey, format)
+}
+
+func fluentBitConfigFormatFromContext(ctx context.Context) fluentbit.ConfigFormat {
+	if format, ok := ctx.Value(fluentBitConfigFormatKey).(fluentbit.ConfigFormat); ok {
+		return format
+	}
+	return fluentbit.DefaultConfigFormat
+}
 func contains(s []string, str string) bool {
 	for _, v := range s {
 		if v == str {
@@ -384,46 +515,133 @@ func addGceMetadataAttributesComponents(ctx context.Context, attributes []string
 type fbSource struct {
 	tagRegex   string
 	components []fluentbit.Component
+	// stages are the steps that the components implement, in order.
+	stages        []fbStage
+	compress      string
+	pID           string
+	syslogForward *SyslogForward
+	pubsub        *PubSub
+	gcsArchive    *GCSArchive
+	bigQuery      *BigQuery
+}
+
+// fbStage is a step of a Fluent Bit logging pipeline, like a receiver or a processor, with the
+// components that implement it.
+type fbStage struct {
+	name       string
+	components []fluentbit.Component
 }
 
 // generateFluentbitComponents generates a slice of fluentbit config sections to represent l.
-func (uc *UnifiedConfig) generateFluentbitComponents(ctx context.Context, userAgent string) ([]fluentbit.Component, error) {
+func (uc *UnifiedConfig) generateFluentbitComponents(ctx context.Context, userAgent, stateDir string) ([]fluentbit.Component, error) {
+	ctx = contextWithPerformanceProfile(ctx, uc.Global)
+	ctx = contextWithLoggingLocation(ctx, uc.Logging.Service.Location)
 	l := uc.Logging
 	var out []fluentbit.Component
-	if l.Service.LogLevel == "" {
-		l.Service.LogLevel = "info"
+	logLevel := l.Service.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+	}
+	var offline *OfflineBuffering
+	if uc.Global != nil {
+		offline = uc.Global.OfflineBuffering
+	}
+	service := fluentbit.Service{LogLevel: logLevel, ParsersFiles: l.parserFiles(ctx)}
+	if offline != nil {
+		out = append(out, offline.withService(service.Component()))
+	} else {
+		out = append(out, service.Component())
 	}
-	service := fluentbit.Service{LogLevel: l.Service.LogLevel}
-	out = append(out, service.Component())
 	out = append(out, fluentbit.MetricsInputComponent())
 
 	if l != nil && l.Service != nil && !l.Service.OTelLogging {
 		// Type for sorting.
 		var sources []fbSource
-		var tags []string
+		// Pipelines with different compressions are sent by different outputs.
+		tags := map[string][]string{}
+		// Every pipeline that forwards to syslog has its own syslog output.
+		syslogTags := map[string][]string{}
+		syslogForwards := map[string]*SyslogForward{}
+		// All the pipelines that publish to Pub/Sub share a forward output.
+		var pubsubTags []string
+		// Every pipeline that archives to Cloud Storage has its own output.
+		archiveTags := map[string][]string{}
+		archives := map[string]*GCSArchive{}
+		// Every pipeline that writes to BigQuery has its own output.
+		bigQueryTags := map[string][]string{}
+		bigQueries := map[string]*BigQuery{}
 		pipelines, err := uc.Pipelines(ctx)
 		if err != nil {
 			return nil, err
 		}
-		for _, pipeline := range pipelines {
-			if pipeline.backend != backendFluentBit {
-				continue
+		generated, err := generatePipelines(pipelines, backendFluentBit, func(p pipelineInstance) (fbSource, error) {
+			return p.fluentBitComponents(ctx)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range generated {
+			sources = append(sources, source)
+			tags[source.compress] = append(tags[source.compress], source.tagRegex)
+			if source.syslogForward != nil {
+				syslogTags[source.pID] = append(syslogTags[source.pID], source.tagRegex)
+				syslogForwards[source.pID] = source.syslogForward
 			}
-			source, err := pipeline.fluentBitComponents(ctx)
-			if err != nil {
-				return nil, err
+			if source.pubsub != nil {
+				pubsubTags = append(pubsubTags, source.tagRegex)
+			}
+			if source.gcsArchive != nil {
+				archiveTags[source.pID] = append(archiveTags[source.pID], source.tagRegex)
+				archives[source.pID] = source.gcsArchive
+			}
+			if source.bigQuery != nil {
+				bigQueryTags[source.pID] = append(bigQueryTags[source.pID], source.tagRegex)
+				bigQueries[source.pID] = source.bigQuery
 			}
-			sources = append(sources, source)
-			tags = append(tags, source.tagRegex)
 		}
 		sort.Slice(sources, func(i, j int) bool { return sources[i].tagRegex < sources[j].tagRegex })
-		sort.Strings(tags)
 
 		for _, s := range sources {
 			out = append(out, s.components...)
 		}
-		if len(tags) > 0 {
-			out = append(out, stackdriverOutputComponent(ctx, strings.Join(tags, "|"), userAgent, "2G", l.Service.Compress))
+		if a := l.Service.AdaptiveSampling; a != nil && len(sources) > 0 {
+			var allTags []string
+			for _, s := range sources {
+				allTags = append(allTags, s.tagRegex)
+			}
+			out = append(out, a.components(strings.Join(allTags, "|"), AdaptiveSamplingRatePath(stateDir))...)
+		}
+		for _, compress := range sortedKeys(tags) {
+			alias := userLogsOutputAlias
+			if compress != "" && len(tags) > 1 {
+				alias = userCompressedLogsOutputAlias
+			}
+			sort.Strings(tags[compress])
+			output := stackdriverOutputComponent(ctx, strings.Join(tags[compress], "|"), alias, userAgent, "2G", compress)
+			if offline != nil {
+				output = offline.withOutput(output)
+			}
+			out = append(out, output)
+		}
+		for _, pID := range sortedKeys(syslogTags) {
+			sort.Strings(syslogTags[pID])
+			out = append(out, syslogForwardOutputComponent(strings.Join(syslogTags[pID], "|"), pID, syslogForwards[pID]))
+		}
+		if len(pubsubTags) > 0 {
+			sort.Strings(pubsubTags)
+			out = append(out, pubsubForwardOutputComponent(strings.Join(pubsubTags, "|")))
+		}
+		for _, pID := range sortedKeys(archiveTags) {
+			sort.Strings(archiveTags[pID])
+			out = append(out, gcsArchiveOutputComponent(strings.Join(archiveTags[pID], "|"), pID, archives[pID]))
+		}
+		for _, pID := range sortedKeys(bigQueryTags) {
+			sort.Strings(bigQueryTags[pID])
+			c, err := bigQueryOutputComponent(ctx, strings.Join(bigQueryTags[pID], "|"), pID, bigQueries[pID])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, c)
 		}
 		out = append(out, uc.generateSelfLogsComponents(ctx, userAgent)...)
 		out = append(out, addGceMetadataAttributesComponents(ctx, []string{
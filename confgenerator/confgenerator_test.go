@@ -369,5 +369,9 @@ func TestMain(m *testing.M) {
 	confgenerator.FindJarPath = func() (string, error) {
 		return "/path/to/executables/opentelemetry-java-contrib-jmx-metrics.jar", nil
 	}
+	// Read the parsers files referenced by the goldens from testdata.
+	confgenerator.ReadParserFile = func(path string) ([]byte, error) {
+		return os.ReadFile(filepath.Join("testdata", "parser_files", filepath.Base(path)))
+	}
 	os.Exit(m.Run())
 }
@@ -15,13 +15,16 @@
 package confgenerator
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -61,6 +64,10 @@ func (uc *UnifiedConfig) HasCombined() bool {
 	return uc.Combined != nil
 }
 
+func (uc *UnifiedConfig) HasTraces() bool {
+	return uc.Traces != nil
+}
+
 func (uc *UnifiedConfig) DeepCopy(ctx context.Context) (*UnifiedConfig, error) {
 	toYaml, err := yaml.Marshal(uc)
 	if err != nil {
@@ -122,8 +129,12 @@ func (ve validationError) Error() string {
 		return fmt.Sprintf("%q must end with %q", ve.Field(), ve.Param())
 	case "experimental":
 		return experimentalValidationErrorString(ve)
+	case "feature_gate":
+		return featureGateValidationErrorString(ve)
 	case "ip":
 		return fmt.Sprintf("%q must be an IP address", ve.Field())
+	case "max":
+		return fmt.Sprintf("%q must be a maximum of %s", ve.Field(), ve.Param())
 	case "min":
 		return fmt.Sprintf("%q must be a minimum of %s", ve.Field(), ve.Param())
 	case "multipleof_time":
@@ -140,8 +151,19 @@ func (ve validationError) Error() string {
 		return fmt.Sprintf("%q must start with %q", ve.Field(), ve.Param())
 	case "url":
 		return fmt.Sprintf("%q must be a URL", ve.Field())
+	case "version_prefix":
+		return fmt.Sprintf("%q must be a version like 2, 2.47 or 2.47.0", ve.Field())
+	case "size":
+		return fmt.Sprintf("%q must be a size like 512k or 2M", ve.Field())
+	case "location":
+		return fmt.Sprintf("%q must be a Google Cloud region like europe-west1", ve.Field())
+	case "label_matcher":
+		return fmt.Sprintf("%q must be a label matcher like device=/dev/loop* or cpu!=cpu0, not %q", ve.Field(), ve.Value())
 	case "excluded_with":
 		return fmt.Sprintf("%q cannot be set if one of [%s] is set", ve.Field(), ve.Param())
+	case "regexp":
+		_, err := regexp.Compile(ve.Value().(string))
+		return fmt.Sprintf("%q must be a regular expression: %v", ve.Field(), err)
 	case "filter":
 		_, err := filter.NewFilter(ve.Value().(string))
 		return fmt.Sprintf("%q: %v", ve.Field(), err)
@@ -205,6 +227,28 @@ func newValidator() *validator.Validate {
 		return t >= tmin
 	})
 	v.RegisterStructValidation(validatePrometheusConfig, &promconfig.Config{})
+	// version_prefix validates that the value is the leading components of an agent version
+	v.RegisterValidation("version_prefix", func(fl validator.FieldLevel) bool {
+		return versionPrefixRegexp.MatchString(fl.Field().String())
+	})
+	// size validates that the value is a buffer size like 512k or 2M
+	v.RegisterValidation("size", func(fl validator.FieldLevel) bool {
+		_, err := sizeKilobytes(fl.Field().String())
+		return err == nil
+	})
+	// location validates that the value is a Google Cloud region
+	v.RegisterValidation("location", func(fl validator.FieldLevel) bool {
+		return locationRegexp.MatchString(fl.Field().String())
+	})
+	// label_matcher validates that the value matches LabelMatcherRegexp
+	v.RegisterValidation("label_matcher", func(fl validator.FieldLevel) bool {
+		return LabelMatcherRegexp.MatchString(fl.Field().String())
+	})
+	// regexp validates that the value is a valid regular expression
+	v.RegisterValidation("regexp", func(fl validator.FieldLevel) bool {
+		_, err := regexp.Compile(fl.Field().String())
+		return err == nil
+	})
 	// filter validates that a Cloud Logging filter condition is valid
 	v.RegisterValidation("filter", func(fl validator.FieldLevel) bool {
 		_, err := filter.NewFilter(fl.Field().String())
@@ -360,12 +404,33 @@ func getFirstNonUniqueString(slice []string) string {
 }
 
 func UnmarshalYamlToUnifiedConfig(ctx context.Context, input []byte) (*UnifiedConfig, error) {
+	node, err := expandTemplates(input)
+	if err != nil {
+		return nil, err
+	}
 	config := UnifiedConfig{}
+	if node == nil {
+		// The config is empty.
+		return &config, nil
+	}
+	// Features enabled in global.experimental must be known before the rest of the config is
+	// validated, so they are read ahead of it.
+	var experimental struct {
+		Global struct {
+			Experimental []string `yaml:"experimental"`
+		} `yaml:"global"`
+	}
+	if err := yaml.NodeToValue(node, &experimental); err == nil {
+		ctx = contextWithConfigExperiments(ctx, experimental.Global.Experimental)
+	}
 	v := &validatorContext{
 		ctx: ctx,
 		v:   newValidator(),
 	}
-	if err := yaml.UnmarshalContext(ctx, input, &config, yaml.Strict(), yaml.Validator(v)); err != nil {
+	// The config is decoded from the expanded syntax tree, whose nodes keep their positions in
+	// input for the errors.
+	dec := yaml.NewDecoder(bytes.NewReader(nil), yaml.Strict(), yaml.Validator(v))
+	if err := dec.DecodeFromNodeContext(ctx, node, &config); err != nil {
 		return nil, err
 	}
 
@@ -381,6 +446,24 @@ type Component interface {
 // It is also used by itself when unmarshaling a component's configuration.
 type ConfigComponent struct {
 	Type string `yaml:"type" validate:"required" tracking:""`
+	// Labels are set as LogEntry labels on the logs of a logging receiver.
+	// They are rejected on all other components.
+	Labels map[string]string `yaml:"labels,omitempty" validate:"omitempty,dive,keys,required,endkeys"`
+	// DefaultSeverity is the severity of the logs of a logging receiver that have none.
+	// It is rejected on all other components.
+	DefaultSeverity string `yaml:"default_severity,omitempty" validate:"omitempty,oneof=DEFAULT DEBUG INFO NOTICE WARNING ERROR CRITICAL ALERT EMERGENCY"`
+}
+
+func (c ConfigComponent) configComponent() ConfigComponent {
+	return c
+}
+
+// componentConfig returns the shared configuration of a component.
+func componentConfig(c Component) ConfigComponent {
+	if cc, ok := c.(interface{ configComponent() ConfigComponent }); ok {
+		return cc.configComponent()
+	}
+	return ConfigComponent{}
 }
 
 type componentInterface interface {
@@ -449,7 +532,25 @@ func (r *componentTypeRegistry[CI, M]) UnmarshalComponentYaml(ctx context.Contex
 			r.Subagent, r.Kind, strings.Join(supportedTypes, ", "))
 	}
 	*inner = o.(CI)
-	return unmarshal(*inner)
+	if err := unmarshal(*inner); err != nil {
+		return err
+	}
+	if r.Subagent == "logging" && r.Kind == "receiver" {
+		return nil
+	}
+	cc := componentConfig(*inner)
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"labels", len(cc.Labels) > 0},
+		{"default_severity", cc.DefaultSeverity != ""},
+	} {
+		if f.set {
+			return fmt.Errorf(`%q is only supported on logging receivers, not on %s %s type %q.`, f.name, r.Subagent, r.Kind, c.Type)
+		}
+	}
+	return nil
 }
 
 // GetComponentsFromRegistry returns all components that belong to the associated registry
@@ -528,6 +629,13 @@ type LoggingNetworkReceiver interface {
 	GetListenPort() uint16
 }
 
+// NetworkListener is implemented by receivers that listen on a network address, so that
+// the health checks can verify that the address is available before the agent starts.
+type NetworkListener interface {
+	// ListenAddress returns the transport protocol ("tcp" or "udp"), host and port.
+	ListenAddress() (protocol string, host string, port uint16)
+}
+
 // GetListenPorts returns a map of receiver IDs to ports for all LoggingNetworkReceivers
 func (m *loggingReceiverMap) GetListenPorts() map[string]uint16 {
 	receiverPortMap := map[string]uint16{}
@@ -554,16 +662,52 @@ func (m *loggingProcessorMap) UnmarshalYAML(ctx context.Context, unmarshal func(
 	return LoggingProcessorTypes.unmarshalToMap(ctx, m, unmarshal)
 }
 
+// SubagentWatermarks restarts a subagent gracefully, so that it can flush its buffers, when it
+// uses more resources than these watermarks. The watermarks are only supported on Linux.
+type SubagentWatermarks struct {
+	// MemoryMiB is the resident memory above which the subagent is restarted.
+	MemoryMiB uint64 `yaml:"memory_mib,omitempty" validate:"omitempty,min=64"`
+	// CPUCores is the number of CPU cores that the subagent may use for 5 minutes before it is
+	// restarted.
+	CPUCores float64 `yaml:"cpu_cores,omitempty" validate:"omitempty,gt=0"`
+}
+
 type LoggingService struct {
-	Compress    string               `yaml:"compress,omitempty" validate:"omitempty,oneof=gzip,experimental=log_compression"`
+	Compress    string               `yaml:"compress,omitempty" validate:"omitempty,experimental=log_compression"`
 	LogLevel    string               `yaml:"log_level,omitempty" validate:"omitempty,oneof=error warn info debug trace"`
 	Pipelines   map[string]*Pipeline `validate:"dive,keys,startsnotwith=lib:"`
 	OTelLogging bool                 `yaml:"experimental_otel_logging,omitempty" validate:"omitempty,experimental=otel_logging"`
+	// Location is the Google Cloud region whose regional Cloud Logging endpoint the logs are sent
+	// to, so that they are stored in that region.
+	Location string `yaml:"location,omitempty" validate:"omitempty,location"`
+	// ErrorOnOverlappingIncludePaths turns the warning about receivers that ingest the same files into an error.
+	ErrorOnOverlappingIncludePaths bool `yaml:"error_on_overlapping_include_paths,omitempty"`
+	// AdaptiveSampling samples the logs while the Logging API rejects requests because of the
+	// quota.
+	AdaptiveSampling *LoggingAdaptiveSampling `yaml:"adaptive_sampling,omitempty"`
+	// Watermarks restart the logging subagent when it uses too much memory or CPU.
+	Watermarks *SubagentWatermarks `yaml:"watermarks,omitempty"`
 }
 
 type Pipeline struct {
 	ReceiverIDs  []string `yaml:"receivers,omitempty,flow"`
 	ProcessorIDs []string `yaml:"processors,omitempty,flow"`
+	// Compress overrides logging.service.compress for a logging pipeline.
+	Compress string `yaml:"compress,omitempty" validate:"omitempty,experimental=log_compression"`
+	// LogBucket labels the logs of a logging pipeline, so that a log sink can route them to a log
+	// bucket with its own retention.
+	LogBucket string `yaml:"log_bucket,omitempty"`
+	// SyslogForward also sends the logs of a logging pipeline to a syslog server.
+	SyslogForward *SyslogForward `yaml:"syslog_forward,omitempty"`
+	// PubSub also publishes the logs of a logging pipeline to a Pub/Sub topic.
+	PubSub *PubSub `yaml:"pubsub,omitempty"`
+	// GCSArchive also archives the raw logs of a logging pipeline in a Cloud Storage bucket.
+	GCSArchive *GCSArchive `yaml:"gcs_archive,omitempty"`
+	// BigQuery also writes the structured logs of a logging pipeline to a BigQuery table.
+	BigQuery *BigQuery `yaml:"bigquery,omitempty"`
+	// RouteLogs sends the logs of a logging pipeline that match a filter to another log name or
+	// log bucket.
+	RouteLogs []LogRoute `yaml:"route_logs,omitempty" validate:"dive"`
 	// ExporterIDs is deprecated and ignored.
 	ExporterIDs []string `yaml:"exporters,omitempty,flow"`
 }
@@ -600,6 +744,18 @@ type TracesReceiver interface {
 	OTelReceiver
 }
 
+type TracesProcessor interface {
+	OTelProcessor
+}
+
+var TracesProcessorTypes = &componentTypeRegistry[TracesProcessor, tracesProcessorMap]{
+	Subagent: "traces", Kind: "processor",
+}
+
+func (m *tracesProcessorMap) UnmarshalYAML(ctx context.Context, unmarshal func(interface{}) error) error {
+	return TracesProcessorTypes.unmarshalToMap(ctx, m, unmarshal)
+}
+
 type MetricsReceiverShared struct {
 	CollectionInterval string `yaml:"collection_interval" validate:"duration=10s"` // time.Duration format
 }
@@ -641,6 +797,9 @@ func (m MetricsReceiverSharedTLS) TLSConfig(defaultInsecure bool) map[string]int
 	if m.KeyFile != "" {
 		tls["key_file"] = m.KeyFile
 	}
+	if m.CertFile != "" || m.CAFile != "" {
+		tls["reload_interval"] = TLSReloadInterval
+	}
 
 	return tls
 }
@@ -762,6 +921,18 @@ type CombinedReceiver interface {
 	MetricsReceiver
 }
 
+// TracesOnlyReceiver is implemented by combined receivers that only receive traces. They can only
+// be used in traces pipelines.
+type TracesOnlyReceiver interface {
+	CombinedReceiver
+	TracesOnly()
+}
+
+func isTracesOnly(r CombinedReceiver) bool {
+	_, ok := r.(TracesOnlyReceiver)
+	return ok
+}
+
 var CombinedReceiverTypes = &componentTypeRegistry[CombinedReceiver, combinedReceiverMap]{
 	Subagent: "generic", Kind: "receiver",
 }
@@ -785,6 +956,34 @@ var MetricsProcessorTypes = &componentTypeRegistry[MetricsProcessor, metricsProc
 	Subagent: "metrics", Kind: "processor",
 }
 
+// LabelMatcherRegexp matches a metric label matcher, e.g. device=/dev/loop* or cpu!=cpu0. The
+// submatches are the label, the operator and the glob pattern of the value.
+// sizeRegexp matches a buffer size in bytes, kilobytes, megabytes or gigabytes, e.g. "512k".
+var sizeRegexp = regexp.MustCompile(`^([0-9]+)([kKmMgG]?)$`)
+
+// sizeKilobytes returns the number of kilobytes in the buffer size s, rounded up.
+func sizeKilobytes(s string) (int, error) {
+	m := sizeRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(m[2]) {
+	case "":
+		return (n + 1023) / 1024, nil
+	case "m":
+		return n * 1024, nil
+	case "g":
+		return n * 1024 * 1024, nil
+	}
+	return n, nil
+}
+
+var LabelMatcherRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.]*)(!?=)(.+)$`)
+
 func (m *metricsProcessorMap) UnmarshalYAML(ctx context.Context, unmarshal func(interface{}) error) error {
 	return MetricsProcessorTypes.unmarshalToMap(ctx, m, unmarshal)
 }
@@ -792,10 +991,55 @@ func (m *metricsProcessorMap) UnmarshalYAML(ctx context.Context, unmarshal func(
 type MetricsService struct {
 	LogLevel  string               `yaml:"log_level,omitempty" validate:"omitempty,oneof=error warn info debug"`
 	Pipelines map[string]*Pipeline `yaml:"pipelines" validate:"dive,keys,startsnotwith=lib:"`
-}
-
+	// ResourceAttributes are set on the resource of workload metrics, and promoted to metric labels.
+	ResourceAttributes map[string]string `yaml:"resource_attributes,omitempty" validate:"dive,required,max=1024"`
+	// Location is the Google Cloud region whose regional Cloud Monitoring endpoint the metrics are
+	// sent to.
+	Location string `yaml:"location,omitempty" validate:"omitempty,location"`
+	// MemoryLimiter bounds the memory used by the metrics agent.
+	MemoryLimiter *MetricsMemoryLimiter `yaml:"memory_limiter,omitempty"`
+	// Watermarks restart the metrics subagent when it uses too much memory or CPU.
+	Watermarks *SubagentWatermarks `yaml:"watermarks,omitempty"`
+	// Batch overrides the batching of the metrics sent by the metrics agent.
+	Batch *MetricsBatch `yaml:"batch,omitempty"`
+	// Retry configures the retries of the metrics that the metrics agent fails to send.
+	Retry *MetricsRetry `yaml:"retry,omitempty"`
+}
+
+// MetricsMemoryLimiter configures a memory_limiter processor at the start of every metrics
+// pipeline, which refuses data when the memory used by the metrics agent exceeds LimitMiB.
+type MetricsMemoryLimiter struct {
+	LimitMiB int `yaml:"limit_mib" validate:"required,min=32"`
+	// SpikeLimitMiB is the expected increase of memory between checks, and must be less than
+	// LimitMiB. The soft limit is LimitMiB - SpikeLimitMiB; 20% of LimitMiB if unset.
+	SpikeLimitMiB int `yaml:"spike_limit_mib,omitempty" validate:"omitempty,min=1"`
+}
+
+// MetricsBatch configures the batch processor at the end of every metrics pipeline. Unset fields
+// keep the values of the performance profile.
+type MetricsBatch struct {
+	SendBatchSize    int    `yaml:"send_batch_size,omitempty" validate:"omitempty,min=1"`
+	SendBatchMaxSize int    `yaml:"send_batch_max_size,omitempty" validate:"omitempty,min=1"`
+	Timeout          string `yaml:"timeout,omitempty" validate:"omitempty,duration=1ms"`
+}
+
+// MetricsRetry configures the retries of the exporters of the metrics agent. The points of a
+// request that still fails after RetryLimit retries are dropped and counted in
+// agent/monitoring/failed_point_count, so that the memory used while Cloud Monitoring rejects
+// requests, e.g. because the quota is exceeded, stays bounded.
+type MetricsRetry struct {
+	// RetryLimit is the number of retries of a failed request. Zero disables the retries.
+	RetryLimit *int `yaml:"retry_limit" validate:"required,min=0,max=20"`
+	// InitialInterval is the backoff before the first retry, which doubles with every retry up to
+	// MaxInterval. The defaults are 5s and 30s.
+	InitialInterval string `yaml:"initial_interval,omitempty" validate:"omitempty,duration=100ms"`
+	MaxInterval     string `yaml:"max_interval,omitempty" validate:"omitempty,duration=100ms"`
+}
+
+type tracesProcessorMap map[string]TracesProcessor
 type Traces struct {
-	Service *TracesService `yaml:"service"`
+	Processors tracesProcessorMap `yaml:"processors,omitempty" validate:"dive,keys,startsnotwith=lib:"`
+	Service    *TracesService     `yaml:"service"`
 }
 
 type TracesService struct {
@@ -803,8 +1047,18 @@ type TracesService struct {
 }
 
 func (uc *UnifiedConfig) Validate(ctx context.Context) error {
+	if uc.Global != nil {
+		if err := uc.Global.validatePerformanceProfile(platform.FromContext(ctx)); err != nil {
+			return err
+		}
+		if uc.Global.ResourceOverride != nil {
+			if err := uc.Global.ResourceOverride.validate("global.resource_override.labels"); err != nil {
+				return err
+			}
+		}
+	}
 	if uc.Logging != nil {
-		if err := uc.ValidateLogging(); err != nil {
+		if err := uc.ValidateLogging(ctx); err != nil {
 			return err
 		}
 	}
@@ -826,22 +1080,34 @@ func (uc *UnifiedConfig) Validate(ctx context.Context) error {
 	return nil
 }
 
-func (uc *UnifiedConfig) ValidateLogging() error {
+func (uc *UnifiedConfig) ValidateLogging(ctx context.Context) error {
 	l := uc.Logging
 	subagent := "logging"
 	if len(l.Exporters) > 0 {
 		log.Print(`The "logging.exporters" field is no longer needed and will be ignored. This does not change any functionality. Please remove it from your configuration.`)
 	}
+	for _, id := range sortedKeys(l.Receivers) {
+		if r, ok := l.Receivers[id].(*LoggingReceiverFiles); ok && r.ParserFile != "" {
+			if err := validateParserFile(r.ParserFile, r.Parser); err != nil {
+				return fmt.Errorf("logging receiver %q: %w", id, err)
+			}
+		}
+	}
 	if l.Service == nil {
 		return nil
 	}
+	if l.Service.AdaptiveSampling != nil && l.Service.OTelLogging {
+		return fmt.Errorf("logging.service.adaptive_sampling is not supported with logging.service.experimental_otel_logging")
+	}
 	validReceivers := map[string]bool{}
 	for k := range l.Receivers {
 		validReceivers[k] = true
 	}
 	if uc.Combined != nil {
-		for k := range uc.Combined.Receivers {
-			// TODO: What about combined receivers that don't support logging (none exist today)?
+		for k, r := range uc.Combined.Receivers {
+			if isTracesOnly(r) {
+				continue
+			}
 			validReceivers[k] = true
 		}
 	}
@@ -852,9 +1118,44 @@ func (uc *UnifiedConfig) ValidateLogging() error {
 	for _, k := range defaultProcessors {
 		validProcessors[k] = nil
 	}
+	if err := validateCompress("logging.service.compress", l.Service.Compress); err != nil {
+		return err
+	}
 	portTaken := map[uint16]string{} // port -> receiverId map
+	usedReceivers := map[string]bool{}
+	usesPubSub := false
 	for _, id := range sortedKeys(l.Service.Pipelines) {
 		p := l.Service.Pipelines[id]
+		if err := validateCompress(fmt.Sprintf("logging.service.pipelines.%s.compress", id), p.Compress); err != nil {
+			return err
+		}
+		if err := validateLogBucket(fmt.Sprintf("logging.service.pipelines.%s.log_bucket", id), p.LogBucket); err != nil {
+			return err
+		}
+		if p.SyslogForward != nil {
+			if err := p.SyslogForward.validate(fmt.Sprintf("logging.service.pipelines.%s.syslog_forward", id)); err != nil {
+				return err
+			}
+		}
+		if p.PubSub != nil {
+			if !featureEnabled(ctx, "pubsub_exporter") {
+				return fmt.Errorf(`"logging.service.pipelines.%s.pubsub" requires the experimental feature "pubsub_exporter"`, id)
+			}
+			if err := p.PubSub.validate(fmt.Sprintf("logging.service.pipelines.%s.pubsub", id)); err != nil {
+				return err
+			}
+			usesPubSub = true
+		}
+		if p.BigQuery != nil {
+			if err := p.BigQuery.validate(fmt.Sprintf("logging.service.pipelines.%s.bigquery", id)); err != nil {
+				return err
+			}
+		}
+		for i, r := range p.RouteLogs {
+			if err := r.validate(fmt.Sprintf("logging.service.pipelines.%s.route_logs[%d]", id, i)); err != nil {
+				return err
+			}
+		}
 		if err := validateComponentKeys(validReceivers, p.ReceiverIDs, subagent, "receiver", id); err != nil {
 			return err
 		}
@@ -871,14 +1172,21 @@ func (uc *UnifiedConfig) ValidateLogging() error {
 		if _, err := validateReceiverPorts(portTaken, l.Receivers.GetListenPorts(), p.ReceiverIDs); err != nil {
 			return err
 		}
-		if err := validateWinlogRenderAsXML(l.Receivers, p.ReceiverIDs); err != nil {
+		if err := validateWinlogReceiverVersion(l.Receivers, p.ReceiverIDs); err != nil {
 			return err
 		}
+		warnUnparsedFieldReferences(l.Receivers, l.Processors, p, id)
 		if len(p.ExporterIDs) > 0 {
 			log.Printf(`The "logging.service.pipelines.%s.exporters" field is deprecated and will be ignored. Please remove it from your configuration.`, id)
 		}
+		for _, rID := range p.ReceiverIDs {
+			usedReceivers[rID] = true
+		}
 	}
-	return nil
+	if rID, ok := portTaken[PubSubForwardPort]; ok && usesPubSub {
+		return fmt.Errorf("logging receiver %s can not listen on port %d, which the Ops Agent uses to publish logs to Pub/Sub.", rID, PubSubForwardPort)
+	}
+	return validateOverlappingIncludePaths(l.Receivers, sortedKeys(usedReceivers), l.Service.ErrorOnOverlappingIncludePaths)
 }
 
 func (uc *UnifiedConfig) ValidateCombined() error {
@@ -888,12 +1196,12 @@ func (uc *UnifiedConfig) ValidateCombined() error {
 	if c == nil {
 		return nil
 	}
-	for k, _ := range c.Receivers {
+	for _, k := range sortedKeys(c.Receivers) {
 		for _, f := range []struct {
 			name    string
 			missing bool
 		}{
-			{"metrics", m == nil},
+			{"metrics", m == nil && !isTracesOnly(c.Receivers[k])},
 			{"traces", t == nil},
 			// TODO: Add "logging" here?
 		} {
@@ -917,6 +1225,9 @@ func (uc *UnifiedConfig) MetricsReceivers() (map[string]MetricsReceiver, error)
 			if _, ok := uc.Metrics.Receivers[k]; ok {
 				return nil, fmt.Errorf("metrics receiver %q has the same name as combined receiver %q", k, k)
 			}
+			if isTracesOnly(v) {
+				continue
+			}
 			if v, ok := v.(MetricsReceiver); ok {
 				validReceivers[k] = v
 			}
@@ -937,6 +1248,50 @@ func (uc *UnifiedConfig) TracesReceivers() (map[string]TracesReceiver, error) {
 	return validReceivers, nil
 }
 
+// ReceiverListener is a network address that a configured receiver listens on.
+type ReceiverListener struct {
+	ReceiverID string
+	// Subagent is the subagent that opens the listener, "fluentbit" or "otel".
+	Subagent string
+	// Network is the transport protocol, "tcp" or "udp".
+	Network string
+	Host    string
+	Port    uint16
+}
+
+// ReceiverListeners returns the network addresses that the receivers of the config listen on.
+// A receiver that is used in several pipelines is only returned once.
+func (uc *UnifiedConfig) ReceiverListeners(ctx context.Context) ([]ReceiverListener, error) {
+	pipelines, err := uc.Pipelines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[ReceiverListener]bool{}
+	var listeners []ReceiverListener
+	for _, p := range pipelines {
+		nl, ok := p.receiver.(NetworkListener)
+		if !ok {
+			continue
+		}
+		protocol, host, port := nl.ListenAddress()
+		l := ReceiverListener{
+			ReceiverID: p.rID,
+			Subagent:   "otel",
+			Network:    protocol,
+			Host:       host,
+			Port:       port,
+		}
+		if p.backend == backendFluentBit {
+			l.Subagent = "fluentbit"
+		}
+		if !seen[l] {
+			seen[l] = true
+			listeners = append(listeners, l)
+		}
+	}
+	return listeners, nil
+}
+
 type pipelineBackend int
 
 const (
@@ -953,6 +1308,20 @@ type pipelineInstance struct {
 		Component
 	}
 	backend pipelineBackend
+	// compress is the compression of the logs sent to Cloud Logging, or "" for none.
+	compress string
+	// logBucket is the log bucket label of the logs, or "" for none.
+	logBucket string
+	// syslogForward is the syslog server that the logs are also sent to, or nil for none.
+	syslogForward *SyslogForward
+	// pubsub is the Pub/Sub topic that the logs are also published to, or nil for none.
+	pubsub *PubSub
+	// gcsArchive is the bucket that the raw logs are also archived in, or nil for none.
+	gcsArchive *GCSArchive
+	// bigQuery is the table that the logs are also written to, or nil for none.
+	bigQuery *BigQuery
+	// routes change the log name or log bucket of the matching logs.
+	routes logRoutes
 }
 
 func (pi *pipelineInstance) Types() (string, string) {
@@ -1023,12 +1392,26 @@ func (uc *UnifiedConfig) tracesPipelines(ctx context.Context) ([]pipelineInstanc
 				if !ok {
 					return nil, fmt.Errorf("traces receiver %q not found", rID)
 				}
+				var processors []struct {
+					id string
+					Component
+				}
+				for _, prID := range p.ProcessorIDs {
+					processor, ok := uc.Traces.Processors[prID]
+					if !ok {
+						return nil, fmt.Errorf("processor %q not found", prID)
+					}
+					processors = append(processors, struct {
+						id string
+						Component
+					}{prID, processor})
+				}
 				out = append(out, pipelineInstance{
 					pipelineType: "traces",
 					pID:          pID,
 					rID:          rID,
 					receiver:     receiver,
-					processors:   nil,
+					processors:   processors,
 				})
 			}
 		}
@@ -1045,7 +1428,8 @@ func (uc *UnifiedConfig) loggingPipelines(ctx context.Context) ([]pipelineInstan
 	if err != nil {
 		return nil, err
 	}
-	exp_otlp := experimentsFromContext(ctx)["otlp_logging"]
+	ctx = contextWithConfigExperiments(ctx, uc.Global.GetExperimental())
+	exp_otlp := featureEnabled(ctx, "otlp_logging")
 	exp_otel := l.Service.OTelLogging
 	var out []pipelineInstance
 	for _, pID := range sortedKeys(l.Service.Pipelines) {
@@ -1073,15 +1457,40 @@ func (uc *UnifiedConfig) loggingPipelines(ctx context.Context) ([]pipelineInstan
 				}{prID, processor})
 			}
 			instance := pipelineInstance{
-				pipelineType: "logs",
-				backend:      backendFluentBit,
-				pID:          pID,
-				rID:          rID,
-				receiver:     receiver,
-				processors:   processors,
+				pipelineType:  "logs",
+				backend:       backendFluentBit,
+				pID:           pID,
+				rID:           rID,
+				receiver:      receiver,
+				processors:    processors,
+				compress:      l.Service.Compress,
+				logBucket:     p.LogBucket,
+				syslogForward: p.SyslogForward,
+				pubsub:        p.PubSub,
+				gcsArchive:    p.GCSArchive,
+				bigQuery:      p.BigQuery,
+				routes:        p.RouteLogs,
+			}
+			if p.Compress != "" {
+				instance.compress = p.Compress
+			}
+			if instance.compress == "none" {
+				instance.compress = ""
 			}
 			if exp_otel || (receiver.Type() == "otlp" && exp_otlp) {
 				instance.backend = backendOTel
+				if p.SyslogForward != nil {
+					return nil, fmt.Errorf(`"logging.service.pipelines.%s.syslog_forward" is not supported with receiver %q, whose logs are processed by the OpenTelemetry Collector`, pID, rID)
+				}
+				if p.PubSub != nil {
+					return nil, fmt.Errorf(`"logging.service.pipelines.%s.pubsub" is not supported with receiver %q, whose logs are processed by the OpenTelemetry Collector`, pID, rID)
+				}
+				if p.GCSArchive != nil {
+					return nil, fmt.Errorf(`"logging.service.pipelines.%s.gcs_archive" is not supported with receiver %q, whose logs are processed by the OpenTelemetry Collector`, pID, rID)
+				}
+				if p.BigQuery != nil {
+					return nil, fmt.Errorf(`"logging.service.pipelines.%s.bigquery" is not supported with receiver %q, whose logs are processed by the OpenTelemetry Collector`, pID, rID)
+				}
 			}
 			out = append(out, instance)
 		}
@@ -1119,6 +1528,9 @@ func (uc *UnifiedConfig) LoggingReceivers(ctx context.Context) (map[string]Compo
 			if _, ok := out[k]; ok {
 				return nil, fmt.Errorf("logging receiver %q has the same name as combined receiver %q", k, k)
 			}
+			if isTracesOnly(v) {
+				continue
+			}
 			out[k] = v
 		}
 	}
@@ -1152,6 +1564,15 @@ func (uc *UnifiedConfig) ValidateMetrics(ctx context.Context) error {
 	if m.Service == nil {
 		return nil
 	}
+	if l := m.Service.MemoryLimiter; l != nil && l.SpikeLimitMiB >= l.LimitMiB {
+		return fmt.Errorf("metrics.service.memory_limiter.spike_limit_mib (%d) must be less than limit_mib (%d)", l.SpikeLimitMiB, l.LimitMiB)
+	}
+	if b := m.Service.Batch; b != nil && b.SendBatchMaxSize > 0 && b.SendBatchMaxSize < b.SendBatchSize {
+		return fmt.Errorf("metrics.service.batch.send_batch_max_size (%d) must not be less than send_batch_size (%d)", b.SendBatchMaxSize, b.SendBatchSize)
+	}
+	if r := m.Service.Retry; r != nil && r.maxInterval() < r.initialInterval() {
+		return fmt.Errorf("metrics.service.retry.max_interval (%s) must not be less than initial_interval (%s)", r.maxInterval(), r.initialInterval())
+	}
 	for _, id := range sortedKeys(m.Service.Pipelines) {
 		p := m.Service.Pipelines[id]
 		if err := validateComponentKeys(receivers, p.ReceiverIDs, subagent, "receiver", id); err != nil {
@@ -1179,8 +1600,29 @@ func (uc *UnifiedConfig) ValidateMetrics(ctx context.Context) error {
 		if len(p.ExporterIDs) > 0 {
 			log.Printf(`The "metrics.service.pipelines.%s.exporters" field is deprecated and will be ignored. Please remove it from your configuration.`, id)
 		}
+		if p.Compress != "" {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.compress" is not supported; compression only applies to logging pipelines`, id)
+		}
+		if p.LogBucket != "" {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.log_bucket" is not supported; log buckets only apply to logging pipelines`, id)
+		}
+		if p.SyslogForward != nil {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.syslog_forward" is not supported; syslog forwarding only applies to logging pipelines`, id)
+		}
+		if p.PubSub != nil {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.pubsub" is not supported; Pub/Sub publishing only applies to logging pipelines`, id)
+		}
+		if p.GCSArchive != nil {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.gcs_archive" is not supported; archiving only applies to logging pipelines`, id)
+		}
+		if p.BigQuery != nil {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.bigquery" is not supported; BigQuery export only applies to logging pipelines`, id)
+		}
+		if len(p.RouteLogs) > 0 {
+			return fmt.Errorf(`"metrics.service.pipelines.%s.route_logs" is not supported; routes only apply to logging pipelines`, id)
+		}
 	}
-	return nil
+	return validateResourceAttributes(m.Service.ResourceAttributes)
 }
 
 func (uc *UnifiedConfig) ValidateTraces() error {
@@ -1198,12 +1640,36 @@ func (uc *UnifiedConfig) ValidateTraces() error {
 		if err := validateComponentKeys(receivers, p.ReceiverIDs, subagent, "receiver", id); err != nil {
 			return err
 		}
-		if len(p.ProcessorIDs) > 0 {
-			return fmt.Errorf("traces pipeline %q uses processors but traces pipelines do not support processors", id)
+		if err := validateComponentKeys(t.Processors, p.ProcessorIDs, subagent, "processor", id); err != nil {
+			return err
+		}
+		if p.Compress != "" {
+			return fmt.Errorf(`"traces.service.pipelines.%s.compress" is not supported; compression only applies to logging pipelines`, id)
+		}
+		if p.LogBucket != "" {
+			return fmt.Errorf(`"traces.service.pipelines.%s.log_bucket" is not supported; log buckets only apply to logging pipelines`, id)
+		}
+		if p.SyslogForward != nil {
+			return fmt.Errorf(`"traces.service.pipelines.%s.syslog_forward" is not supported; syslog forwarding only applies to logging pipelines`, id)
+		}
+		if p.PubSub != nil {
+			return fmt.Errorf(`"traces.service.pipelines.%s.pubsub" is not supported; Pub/Sub publishing only applies to logging pipelines`, id)
+		}
+		if p.GCSArchive != nil {
+			return fmt.Errorf(`"traces.service.pipelines.%s.gcs_archive" is not supported; archiving only applies to logging pipelines`, id)
+		}
+		if p.BigQuery != nil {
+			return fmt.Errorf(`"traces.service.pipelines.%s.bigquery" is not supported; BigQuery export only applies to logging pipelines`, id)
+		}
+		if len(p.RouteLogs) > 0 {
+			return fmt.Errorf(`"traces.service.pipelines.%s.route_logs" is not supported; routes only apply to logging pipelines`, id)
 		}
 		if _, err := validateComponentTypeCounts(receivers, p.ReceiverIDs, subagent, "receiver"); err != nil {
 			return err
 		}
+		if _, err := validateComponentTypeCounts(t.Processors, p.ProcessorIDs, subagent, "processor"); err != nil {
+			return err
+		}
 
 		if len(p.ExporterIDs) > 0 {
 			log.Printf(`The "traces.service.pipelines.%s.exporters" field is deprecated and will be ignored. Please remove it from your configuration.`, id)
@@ -1291,9 +1757,9 @@ func validateReceiverPorts(taken map[uint16]string, receiverPortMap map[string]u
 	return taken, nil
 }
 
-// validateWinlogRenderAsXML validates that the correct receiver version is used when
-// render_as_xml is true.
-func validateWinlogRenderAsXML(receivers loggingReceiverMap, receiverIDs []string) error {
+// validateWinlogReceiverVersion validates that the correct receiver version is used when
+// render_as_xml or replay_on_first_start is set.
+func validateWinlogReceiverVersion(receivers loggingReceiverMap, receiverIDs []string) error {
 	var err error
 	for _, receiverID := range receiverIDs {
 		var ok bool
@@ -1312,6 +1778,12 @@ func validateWinlogRenderAsXML(receivers loggingReceiverMap, receiverIDs []strin
 				receiverID,
 			))
 		}
+		if winlogReceiver.ReplayOnFirstStart != nil && winlogReceiver.IsDefaultVersion() {
+			err = multierr.Append(err, fmt.Errorf(
+				`"logging.receivers.%s.replay_on_first_start" is not supported for the current receiver version. Please use "receiver_version: 2" or higher for this receiver`,
+				receiverID,
+			))
+		}
 	}
 	return err
 }
@@ -0,0 +1,212 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
+)
+
+const (
+	// appliedConfigFileName is the file under the state directory that holds the
+	// merged config applied by the previous run of the engine.
+	appliedConfigFileName = "applied-config.yaml"
+
+	// ConfigTriggerStart and ConfigTriggerReload are what made the agent apply a config: a start
+	// of the agent, or a reload of a subagent without restarting the agent.
+	ConfigTriggerStart  = "start"
+	ConfigTriggerReload = "reload"
+
+	configChangeCode = "ConfigChange"
+	versionDriftCode = "AgentVersionDrift"
+)
+
+// ConfigChange summarizes the difference between two merged configs.
+type ConfigChange struct {
+	Digest           string
+	PreviousDigest   string
+	AddedReceivers   []string
+	RemovedReceivers []string
+	AddedPipelines   []string
+	RemovedPipelines []string
+}
+
+// Changed returns whether the config differs from the one applied previously. The first config
+// that is applied has changed.
+func (c ConfigChange) Changed() bool {
+	return c.PreviousDigest != c.Digest
+}
+
+// Digest returns a stable hash of the merged config.
+func (uc *UnifiedConfig) Digest() string {
+	sum := sha256.Sum256([]byte(uc.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// componentIDs returns the fully-qualified IDs of all receivers and pipelines in uc,
+// e.g. "logging.receivers.syslog" and "metrics.pipelines.default_pipeline".
+func (uc *UnifiedConfig) componentIDs() (receivers, pipelines map[string]bool) {
+	receivers = map[string]bool{}
+	pipelines = map[string]bool{}
+	if uc == nil {
+		return
+	}
+	if uc.Logging != nil {
+		for id := range uc.Logging.Receivers {
+			receivers["logging.receivers."+id] = true
+		}
+		if uc.Logging.Service != nil {
+			for id := range uc.Logging.Service.Pipelines {
+				pipelines["logging.pipelines."+id] = true
+			}
+		}
+	}
+	if uc.Metrics != nil {
+		for id := range uc.Metrics.Receivers {
+			receivers["metrics.receivers."+id] = true
+		}
+		if uc.Metrics.Service != nil {
+			for id := range uc.Metrics.Service.Pipelines {
+				pipelines["metrics.pipelines."+id] = true
+			}
+		}
+	}
+	if uc.Combined != nil {
+		for id := range uc.Combined.Receivers {
+			receivers["combined.receivers."+id] = true
+		}
+	}
+	if uc.Traces != nil && uc.Traces.Service != nil {
+		for id := range uc.Traces.Service.Pipelines {
+			pipelines["traces.pipelines."+id] = true
+		}
+	}
+	return
+}
+
+// difference returns the sorted keys of a that are not in b.
+func difference(a, b map[string]bool) []string {
+	var out []string
+	for k := range a {
+		if !b[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// DiffConfigs compares the previously applied config (which may be nil) with the current one.
+func DiffConfigs(previous, current *UnifiedConfig) ConfigChange {
+	prevReceivers, prevPipelines := previous.componentIDs()
+	curReceivers, curPipelines := current.componentIDs()
+	change := ConfigChange{
+		Digest:           current.Digest(),
+		AddedReceivers:   difference(curReceivers, prevReceivers),
+		RemovedReceivers: difference(prevReceivers, curReceivers),
+		AddedPipelines:   difference(curPipelines, prevPipelines),
+		RemovedPipelines: difference(prevPipelines, curPipelines),
+	}
+	if previous != nil {
+		change.PreviousDigest = previous.Digest()
+	}
+	return change
+}
+
+// OnlyLoggingChanged returns whether current differs from previous in the logging section only,
+// so that the change can be applied by reloading the logging subagent instead of restarting the
+// whole agent.
+func OnlyLoggingChanged(previous, current *UnifiedConfig) bool {
+	if previous == nil || current == nil || previous.Digest() == current.Digest() {
+		return false
+	}
+	p, c := *previous, *current
+	p.Logging, c.Logging = nil, nil
+	return p.Digest() == c.Digest()
+}
+
+// ReadAppliedConfig returns the merged config that RecordConfigChange persisted under stateDir,
+// or nil if there is none.
+func ReadAppliedConfig(ctx context.Context, stateDir string) (*UnifiedConfig, error) {
+	path := filepath.Join(stateDir, appliedConfigFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the previously applied config %q: %w", path, err)
+	}
+	// A previous config that doesn't parse anymore (e.g. after a downgrade) is treated as missing.
+	previous, _ := UnmarshalYamlToUnifiedConfig(ctx, data)
+	return previous, nil
+}
+
+// RecordConfigChange compares uc with the config applied by the previous run of the engine,
+// emits a structured ops-agent-health entry describing the change, and persists the applied
+// config under stateDir so that the next run can compute its own diff. trigger is
+// ConfigTriggerStart or ConfigTriggerReload. A reload only applies the logging section of uc,
+// because only the logging subagent is reloaded, so the other sections stay as they were applied
+// and a later reload still sees that they changed.
+func (uc *UnifiedConfig) RecordConfigChange(ctx context.Context, stateDir, trigger string, logger logs.StructuredLogger) error {
+	previous, err := ReadAppliedConfig(ctx, stateDir)
+	if err != nil {
+		return err
+	}
+	applied := uc
+	if trigger == ConfigTriggerReload && previous != nil {
+		reloaded := *previous
+		reloaded.Logging = uc.Logging
+		applied = &reloaded
+	}
+
+	change := DiffConfigs(previous, applied)
+	logger.Infow("Ops Agent configuration applied",
+		"code", configChangeCode,
+		"trigger", trigger,
+		"changed", change.Changed(),
+		"agentVersion", version.Version,
+		"configDigest", change.Digest,
+		"previousConfigDigest", change.PreviousDigest,
+		"addedReceivers", change.AddedReceivers,
+		"removedReceivers", change.RemovedReceivers,
+		"addedPipelines", change.AddedPipelines,
+		"removedPipelines", change.RemovedPipelines,
+	)
+
+	return WriteConfigFile([]byte(applied.String()), filepath.Join(stateDir, appliedConfigFileName))
+}
+
+// RecordVersionDrift emits a structured ops-agent-health entry if the running agent doesn't match
+// global.hold_version, so that VMs that drifted from their pin can be found from the same source
+// that manages their config. It returns the drift, if any.
+func (uc *UnifiedConfig) RecordVersionDrift(logger logs.StructuredLogger) error {
+	err := uc.Global.CheckHoldVersion(version.Version)
+	if err != nil {
+		logger.Warnw(err.Error(),
+			"code", versionDriftCode,
+			"agentVersion", version.Version,
+			"holdVersion", uc.Global.HoldVersion,
+		)
+	}
+	return err
+}
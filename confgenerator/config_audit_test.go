@@ -0,0 +1,226 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package confgenerator_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func mustParseConfig(t *testing.T, input string) *confgenerator.UnifiedConfig {
+	t.Helper()
+	ctx := platform.Platform{Type: platform.Linux}.TestContext(context.Background())
+	uc, err := confgenerator.UnmarshalYamlToUnifiedConfig(ctx, []byte(input))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	return uc
+}
+
+func TestDiffConfigs(t *testing.T) {
+	previous := mustParseConfig(t, `
+logging:
+  receivers:
+    syslog:
+      type: files
+      include_paths: [/var/log/syslog]
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [syslog]
+`)
+	current := mustParseConfig(t, `
+logging:
+  receivers:
+    app:
+      type: files
+      include_paths: [/var/log/app.log]
+  service:
+    pipelines:
+      app_pipeline:
+        receivers: [app]
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [hostmetrics]
+`)
+
+	for _, tc := range []struct {
+		name            string
+		previous        *confgenerator.UnifiedConfig
+		current         *confgenerator.UnifiedConfig
+		expected        confgenerator.ConfigChange
+		expectedChanged bool
+	}{
+		{
+			name:     "first start",
+			previous: nil,
+			current:  previous,
+			expected: confgenerator.ConfigChange{
+				AddedReceivers: []string{"logging.receivers.syslog"},
+				AddedPipelines: []string{"logging.pipelines.default_pipeline"},
+			},
+			expectedChanged: true,
+		},
+		{
+			name:            "unchanged",
+			previous:        previous,
+			current:         previous,
+			expected:        confgenerator.ConfigChange{},
+			expectedChanged: false,
+		},
+		{
+			name:     "changed",
+			previous: previous,
+			current:  current,
+			expected: confgenerator.ConfigChange{
+				AddedReceivers:   []string{"logging.receivers.app", "metrics.receivers.hostmetrics"},
+				RemovedReceivers: []string{"logging.receivers.syslog"},
+				AddedPipelines:   []string{"logging.pipelines.app_pipeline", "metrics.pipelines.default_pipeline"},
+				RemovedPipelines: []string{"logging.pipelines.default_pipeline"},
+			},
+			expectedChanged: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := confgenerator.DiffConfigs(tc.previous, tc.current)
+			if got.Digest != tc.current.Digest() {
+				t.Errorf("got digest %q, want %q", got.Digest, tc.current.Digest())
+			}
+			if diff := cmp.Diff(tc.expected, got, cmpopts.IgnoreFields(confgenerator.ConfigChange{}, "Digest", "PreviousDigest"), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("DiffConfigs() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if changed := got.Changed(); changed != tc.expectedChanged {
+				t.Errorf("got changed %t, want %t", changed, tc.expectedChanged)
+			}
+		})
+	}
+}
+
+func TestCheckHoldVersion(t *testing.T) {
+	for _, tc := range []struct {
+		hold      string
+		installed string
+		drifted   bool
+	}{
+		{"", "2.47.0", false},
+		{"2", "2.47.0", false},
+		{"2.47", "2.47.1", false},
+		{"2.47.0", "2.47.0", false},
+		{"2.47", "2.48.0", true},
+		{"2.47.0", "2.47.1", true},
+		{"3", "2.47.0", true},
+		{"2.47", "latest", false},
+	} {
+		g := &confgenerator.Global{HoldVersion: tc.hold}
+		err := g.CheckHoldVersion(tc.installed)
+		if drifted := err != nil; drifted != tc.drifted {
+			t.Errorf("CheckHoldVersion(%q) with hold_version %q = %v, want drifted %v", tc.installed, tc.hold, err, tc.drifted)
+		}
+	}
+}
+
+func TestOnlyLoggingChanged(t *testing.T) {
+	base := `
+logging:
+  receivers:
+    syslog:
+      type: files
+      include_paths: [/var/log/syslog]
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [syslog]
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [hostmetrics]
+`
+	previous := mustParseConfig(t, base)
+	for _, tc := range []struct {
+		name    string
+		current string
+		want    bool
+	}{
+		{"unchanged", base, false},
+		{"logging", strings.Replace(base, "/var/log/syslog", "/var/log/messages", 1), true},
+		{"metrics", strings.Replace(base, "type: hostmetrics", "type: hostmetrics\n      collection_interval: 30s", 1), false},
+		{"logging and metrics", strings.Replace(strings.Replace(base, "/var/log/syslog", "/var/log/messages", 1), "type: hostmetrics", "type: hostmetrics\n      collection_interval: 30s", 1), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := confgenerator.OnlyLoggingChanged(previous, mustParseConfig(t, tc.current)); got != tc.want {
+				t.Errorf("OnlyLoggingChanged() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRecordConfigChangeReloadKeepsOtherSections(t *testing.T) {
+	ctx := platform.Platform{Type: platform.Linux}.TestContext(context.Background())
+	stateDir := t.TempDir()
+	logger, _ := logs.DiscardLogger()
+	base := `
+logging:
+  receivers:
+    syslog:
+      type: files
+      include_paths: [/var/log/syslog]
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [syslog]
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [hostmetrics]
+`
+	if err := mustParseConfig(t, base).RecordConfigChange(ctx, stateDir, confgenerator.ConfigTriggerStart, logger); err != nil {
+		t.Fatal(err)
+	}
+	// The logging subagent is reloaded with a config that also changes the metrics section.
+	current := mustParseConfig(t, strings.Replace(strings.Replace(base, "/var/log/syslog", "/var/log/messages", 1), "type: hostmetrics", "type: hostmetrics\n      collection_interval: 30s", 1))
+	if err := current.RecordConfigChange(ctx, stateDir, confgenerator.ConfigTriggerReload, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := confgenerator.ReadAppliedConfig(ctx, stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the logging change is applied, so a later reload still restarts the agent for the
+	// metrics change.
+	want := mustParseConfig(t, strings.Replace(base, "/var/log/syslog", "/var/log/messages", 1))
+	if diff := cmp.Diff(want.String(), applied.String()); diff != "" {
+		t.Errorf("unexpected applied config after a reload (-want +got):\n%s", diff)
+	}
+}
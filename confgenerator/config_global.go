@@ -14,9 +14,43 @@
 
 package confgenerator
 
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/resourcedetector"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// versionPrefixRegexp matches the leading components of an agent version, e.g. "2", "2.47" or
+// "2.47.0".
+var versionPrefixRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,2}$`)
+
 type Global struct {
 	DefaultSelfLogFileCollection *bool            `yaml:"default_self_log_file_collection,omitempty"`
 	DefaultLogFileRotation       *LogFileRotation `yaml:"default_self_log_file_rotation,omitempty"`
+	// SelfLogDedupWindow collapses identical error and warning self logs that repeat within the
+	// window into their first occurrence. Deduplication is disabled if unset.
+	SelfLogDedupWindow *time.Duration `yaml:"self_log_dedup_window,omitempty" validate:"omitempty,min=1s,multipleof_time=1s"`
+	// HeartbeatInterval is how often the agent writes the LogPingOpsAgent log and the
+	// agent/ops_agent/heartbeat metric. Defaults to 10m if unset.
+	HeartbeatInterval  *time.Duration `yaml:"heartbeat_interval,omitempty" validate:"omitempty,min=1m,multipleof_time=1s"`
+	PerformanceProfile string         `yaml:"performance_profile,omitempty" validate:"omitempty,oneof=low default high_throughput"`
+	OpAMP              *OpAMP         `yaml:"opamp,omitempty"`
+	// HoldVersion pins the agent to a version prefix, e.g. "2.47" holds the agent at any 2.47.x
+	// release. The agent doesn't install anything itself, it only reports when it drifted.
+	HoldVersion string `yaml:"hold_version,omitempty" validate:"omitempty,version_prefix"`
+	// Experimental enables feature gates, like the EXPERIMENTAL_FEATURES environment variable.
+	Experimental []string `yaml:"experimental,omitempty,flow" validate:"dive,feature_gate"`
+	// ResourceOverride forces the monitored resource that the telemetry is written for, for
+	// environments where it is detected incorrectly.
+	ResourceOverride *ResourceOverride `yaml:"resource_override,omitempty"`
+	// OfflineBuffering keeps the telemetry while the agent is disconnected for long periods.
+	OfflineBuffering *OfflineBuffering `yaml:"offline_buffering,omitempty"`
 }
 
 // Get whether self log collection should be enabled. Defaults to true if unset.
@@ -27,6 +61,115 @@ func (g *Global) GetDefaultSelfLogFileCollection() bool {
 	return true
 }
 
+// Get the feature gates enabled in the config.
+func (g *Global) GetExperimental() []string {
+	if g != nil {
+		return g.Experimental
+	}
+	return nil
+}
+
+// Get the window within which repeated self logs are collapsed, or 0 to ingest them all.
+func (g *Global) GetSelfLogDedupWindow() time.Duration {
+	if g != nil && g.SelfLogDedupWindow != nil {
+		return *g.SelfLogDedupWindow
+	}
+	return 0
+}
+
+// Get how often the agent reports that it is alive. Defaults to 10m if unset.
+func (g *Global) GetHeartbeatInterval() time.Duration {
+	if g != nil && g.HeartbeatInterval != nil {
+		return *g.HeartbeatInterval
+	}
+	return 10 * time.Minute
+}
+
+// Get the performance profile that tunes the subagents. Defaults to "default" if unset.
+func (g *Global) GetPerformanceProfile() string {
+	if g != nil && g.PerformanceProfile != "" {
+		return g.PerformanceProfile
+	}
+	return "default"
+}
+
+// Get the GOMAXPROCS of the OTel collector for the performance profile, or 0 to leave it unset.
+func (g *Global) GetGoMaxProcs() int {
+	return performanceProfiles[g.GetPerformanceProfile()].GoMaxProcs
+}
+
+// CheckHoldVersion returns an error if installed doesn't match the hold_version pin. Versions that
+// can't be compared, like development builds, never drift.
+func (g *Global) CheckHoldVersion(installed string) error {
+	if g == nil || g.HoldVersion == "" || !versionPrefixRegexp.MatchString(installed) {
+		return nil
+	}
+	want := strings.Split(g.HoldVersion, ".")
+	got := strings.Split(installed, ".")
+	if len(got) < len(want) || !slices.Equal(got[:len(want)], want) {
+		return fmt.Errorf("agent version %s does not match global.hold_version %s", installed, g.HoldVersion)
+	}
+	return nil
+}
+
+// OpAMP configures the OpAMP client of the diagnostics service, which reports the agent to an
+// OpAMP server and applies the configs it pushes.
+type OpAMP struct {
+	// Endpoint is the URL of the OpAMP server, which must use https, since the agent sends its
+	// config.
+	Endpoint string `yaml:"endpoint" validate:"required,url,startswith=https://"`
+	// PublicKeyPath is a PEM encoded ed25519 public key that verifies the signature of the pushed
+	// configs. Pushed configs are rejected if it is unset.
+	PublicKeyPath string `yaml:"public_key_path,omitempty"`
+	PollInterval  string `yaml:"poll_interval,omitempty" validate:"duration=10s"` // time.Duration format
+}
+
+// Get how often the OpAMP server is polled. Defaults to 30s if unset.
+func (o *OpAMP) GetPollInterval() time.Duration {
+	if d, err := time.ParseDuration(o.PollInterval); err == nil {
+		return d
+	}
+	return 30 * time.Second
+}
+
+type ResourceOverride struct {
+	Type   string            `yaml:"type" validate:"required,oneof=gce_instance generic_node generic_task"`
+	Labels map[string]string `yaml:"labels" validate:"required"`
+	// ProjectID is the project that the resource belongs to, e.g. for BigQuery tables that don't
+	// specify their project. The telemetry is written to the project of the credentials if unset.
+	ProjectID string `yaml:"project_id,omitempty"`
+}
+
+// validate checks that the labels are the ones of the resource type. field is the name of the
+// labels in the errors.
+func (r *ResourceOverride) validate(field string) error {
+	want := resourcedetector.OverrideLabels[r.Type]
+	for _, label := range sortedKeys(want) {
+		if r.Labels[label] == "" {
+			return fmt.Errorf("%s: %q is required for resource type %q", field, label, r.Type)
+		}
+	}
+	for _, label := range sortedKeys(r.Labels) {
+		if _, ok := want[label]; !ok {
+			return fmt.Errorf("%s: %q is not a label of resource type %q", field, label, r.Type)
+		}
+	}
+	return nil
+}
+
+// contextWithResourceOverride returns a context whose platform uses the resource in
+// global.resource_override, if it is set.
+func contextWithResourceOverride(ctx context.Context, g *Global) context.Context {
+	if g == nil || g.ResourceOverride == nil {
+		return ctx
+	}
+	return platform.WithResourceOverride(ctx, resourcedetector.OverrideResource{
+		Type:    g.ResourceOverride.Type,
+		Labels:  g.ResourceOverride.Labels,
+		Project: g.ResourceOverride.ProjectID,
+	})
+}
+
 type LogFileRotation struct {
 	Enabled     *bool `yaml:"enabled"`
 	MaxFileSize *int  `yaml:"max_file_size_megabytes" validate:"omitempty,gte=1"`
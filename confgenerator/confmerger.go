@@ -41,6 +41,7 @@ func MergeConfFiles(ctx context.Context, userConfPath string, builtInConfStructs
 	if overrides != nil {
 		mergeConfigs(result, overrides)
 	}
+	ctx = contextWithConfigExperiments(ctx, result.Global.GetExperimental())
 
 	if err := result.Validate(ctx); err != nil {
 		return nil, err
@@ -91,6 +92,9 @@ func mergeConfigs(original, overrides *UnifiedConfig) {
 			if overrides.Logging.Service.Compress != "" {
 				original.Logging.Service.Compress = overrides.Logging.Service.Compress
 			}
+			original.Logging.Service.Location = overrides.Logging.Service.Location
+			original.Logging.Service.ErrorOnOverlappingIncludePaths = overrides.Logging.Service.ErrorOnOverlappingIncludePaths
+			original.Logging.Service.AdaptiveSampling = overrides.Logging.Service.AdaptiveSampling
 			for name, pipeline := range overrides.Logging.Service.Pipelines {
 				// skips logging.service.pipelines.*.exporters
 				pipeline.ExporterIDs = nil
@@ -104,6 +108,41 @@ func mergeConfigs(original, overrides *UnifiedConfig) {
 					if ids := pipeline.ProcessorIDs; ids != nil {
 						original.Logging.Service.Pipelines["default_pipeline"].ProcessorIDs = ids
 					}
+
+					// overrides logging.service.pipelines.default_pipeline.compress
+					if pipeline.Compress != "" {
+						original.Logging.Service.Pipelines["default_pipeline"].Compress = pipeline.Compress
+					}
+
+					// overrides logging.service.pipelines.default_pipeline.log_bucket
+					if pipeline.LogBucket != "" {
+						original.Logging.Service.Pipelines["default_pipeline"].LogBucket = pipeline.LogBucket
+					}
+
+					// overrides logging.service.pipelines.default_pipeline.syslog_forward
+					if pipeline.SyslogForward != nil {
+						original.Logging.Service.Pipelines["default_pipeline"].SyslogForward = pipeline.SyslogForward
+					}
+
+					// overrides logging.service.pipelines.default_pipeline.pubsub
+					if pipeline.PubSub != nil {
+						original.Logging.Service.Pipelines["default_pipeline"].PubSub = pipeline.PubSub
+					}
+
+					// overrides logging.service.pipelines.default_pipeline.gcs_archive
+					if pipeline.GCSArchive != nil {
+						original.Logging.Service.Pipelines["default_pipeline"].GCSArchive = pipeline.GCSArchive
+					}
+
+					// overrides logging.service.pipelines.default_pipeline.bigquery
+					if pipeline.BigQuery != nil {
+						original.Logging.Service.Pipelines["default_pipeline"].BigQuery = pipeline.BigQuery
+					}
+
+					// overrides logging.service.pipelines.default_pipeline.route_logs
+					if len(pipeline.RouteLogs) > 0 {
+						original.Logging.Service.Pipelines["default_pipeline"].RouteLogs = pipeline.RouteLogs
+					}
 				} else {
 					// Overrides logging.service.pipelines.<non_default_pipelines>
 					original.Logging.Service.Pipelines[name] = pipeline
@@ -126,6 +165,11 @@ func mergeConfigs(original, overrides *UnifiedConfig) {
 			if overrides.Metrics.Service.LogLevel != "info" {
 				original.Metrics.Service.LogLevel = overrides.Metrics.Service.LogLevel
 			}
+			original.Metrics.Service.ResourceAttributes = overrides.Metrics.Service.ResourceAttributes
+			original.Metrics.Service.Location = overrides.Metrics.Service.Location
+			original.Metrics.Service.MemoryLimiter = overrides.Metrics.Service.MemoryLimiter
+			original.Metrics.Service.Batch = overrides.Metrics.Service.Batch
+			original.Metrics.Service.Retry = overrides.Metrics.Service.Retry
 			for name, pipeline := range overrides.Metrics.Service.Pipelines {
 				// skips metrics.service.pipelines.*.exporters
 				pipeline.ExporterIDs = nil
@@ -0,0 +1,94 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// GenerateFluentBitDryRunConfigs generates a Fluent Bit config that reads the file at inputPath
+// with the parsing of receiverID, runs the records through the processors of every pipeline
+// that uses the receiver, and prints the resulting LogEntries to stdout instead of sending
+// them to Cloud Logging. Fluent Bit exits once the whole file has been read.
+// Only receivers that tail files are supported.
+// It returns a map of filenames to file contents.
+func (uc *UnifiedConfig) GenerateFluentBitDryRunConfigs(ctx context.Context, receiverID, inputPath, projectID string) (map[string]string, error) {
+	pipelines, err := uc.Pipelines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var components []fluentbit.Component
+	var tags []string
+	for _, p := range pipelines {
+		if p.pipelineType != "logs" || p.rID != receiverID {
+			continue
+		}
+		if p.backend != backendFluentBit {
+			return nil, fmt.Errorf("logging receiver %q is processed by the OpenTelemetry Collector, which is not supported", receiverID)
+		}
+		source, err := p.fluentBitComponents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range source.components {
+			if c.Kind == "INPUT" {
+				if c.Config["Name"] != "tail" {
+					return nil, fmt.Errorf("logging receiver %q does not read files; only receivers of type files and third-party application receivers can be tested", receiverID)
+				}
+				c = dryRunTailInput(c, inputPath)
+			}
+			components = append(components, c)
+		}
+		tags = append(tags, source.tagRegex)
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("logging receiver %q is not used in any logging pipeline", receiverID)
+	}
+
+	userAgent, _ := platform.FromContext(ctx).UserAgent("Google-Cloud-Ops-Agent-Logging")
+	output := stackdriverOutputComponent(ctx, strings.Join(tags, "|"), "", userAgent, "", "")
+	// Print the LogEntries that would be sent instead of sending them.
+	output.Config["test_log_entry_format"] = "true"
+	output.Config["export_to_project_id"] = projectID
+	components = append(components, output)
+
+	return fluentbit.ModularConfig{
+		Components: components,
+	}.Generate()
+}
+
+// dryRunTailInput makes a tail input read inputPath once from the start, without keeping
+// any state between runs.
+func dryRunTailInput(c fluentbit.Component, inputPath string) fluentbit.Component {
+	config := map[string]string{}
+	for k, v := range c.Config {
+		config[k] = v
+	}
+	for _, k := range []string{"DB", "DB.locking", "Exclude_Path", "Refresh_Interval", "storage.type"} {
+		delete(config, k)
+	}
+	config["Path"] = inputPath
+	config["Read_from_Head"] = "True"
+	config["Exit_On_Eof"] = "True"
+	return fluentbit.Component{
+		Kind:   c.Kind,
+		Config: config,
+	}
+}
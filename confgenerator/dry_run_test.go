@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package confgenerator_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/shirou/gopsutil/host"
+)
+
+func TestGenerateFluentBitDryRunConfigs(t *testing.T) {
+	ctx := platform.Platform{
+		Type:     platform.Linux,
+		HostInfo: &host.InfoStat{Hostname: "hostname"},
+	}.TestContext(context.Background())
+	uc := mustParseConfig(t, `
+logging:
+  receivers:
+    app:
+      type: files
+      include_paths: [/var/log/app.log]
+    app_syslog:
+      type: syslog
+      transport_protocol: tcp
+      listen_host: 0.0.0.0
+      listen_port: 5140
+  processors:
+    parse:
+      type: parse_regex
+      regex: "^(?<level>\\w+) (?<message>.*)$"
+  service:
+    pipelines:
+      app:
+        receivers: [app, app_syslog]
+        processors: [parse]
+`)
+
+	files, err := uc.GenerateFluentBitDryRunConfigs(ctx, "app", "/tmp/input.log", "my-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := files[fluentbit.MainConfigFileName]
+	for _, want := range []string{
+		"/tmp/input.log",
+		"exit_on_eof",
+		"test_log_entry_format",
+		"key_name",
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("generated config does not contain %q:\n%s", want, main)
+		}
+	}
+	for _, unwanted := range []string{"/var/log/app.log", "db.locking", "5140"} {
+		if strings.Contains(main, unwanted) {
+			t.Errorf("generated config unexpectedly contains %q:\n%s", unwanted, main)
+		}
+	}
+
+	for _, rID := range []string{"app_syslog", "missing"} {
+		if _, err := uc.GenerateFluentBitDryRunConfigs(ctx, rID, "/tmp/input.log", "my-project"); err == nil {
+			t.Errorf("GenerateFluentBitDryRunConfigs(%q) succeeded, want error", rID)
+		}
+	}
+}
@@ -23,18 +23,56 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// FeatureStage is the maturity of a feature gate.
+type FeatureStage string
+
+const (
+	// StageAlpha features may change or be removed without notice.
+	StageAlpha FeatureStage = "alpha"
+	// StageBeta features are complete but still have to be enabled explicitly.
+	StageBeta FeatureStage = "beta"
+	// StageGA features are always enabled; listing them has no effect.
+	StageGA FeatureStage = "ga"
+)
+
+// FeatureGate is a feature that is only available once it is enabled, either with the
+// EXPERIMENTAL_FEATURES environment variable or with global.experimental in the config.
+type FeatureGate struct {
+	Name        string       `yaml:"name"`
+	Stage       FeatureStage `yaml:"stage"`
+	Description string       `yaml:"description"`
+}
+
+var featureGates = map[string]FeatureGate{}
+
+// RegisterFeatureGate adds a feature gate to the registry. It panics if the name is taken.
+func RegisterFeatureGate(name string, stage FeatureStage, description string) {
+	if _, ok := featureGates[name]; ok {
+		panic(fmt.Sprintf("feature gate %q is already registered", name))
+	}
+	featureGates[name] = FeatureGate{Name: name, Stage: stage, Description: description}
+}
+
+// FeatureGates returns all the registered feature gates, sorted by name.
+func FeatureGates() []FeatureGate {
+	var out []FeatureGate
+	for _, name := range sortedKeys(featureGates) {
+		out = append(out, featureGates[name])
+	}
+	return out
+}
+
 // requiredFeatureForType maps a component type to a feature that must
-// be enabled (via EXPERIMENTAL_FEATURES) in order to use that component
-// in an Ops Agent configuration.
-// For example, the following would require the user to define the
-// "otlp_receiver" feature flag inside EXPERIMENTAL_FEATURES in order to
-// be able to use the "otlp" combined receiver:
+// be enabled in order to use that component in an Ops Agent configuration.
+// For example, the following would require the user to enable the
+// "otlp_receiver" feature gate in order to be able to use the "otlp"
+// combined receiver:
 //
 //	"otlp": "otlp_receiver"
-//
-// N.B. There are no enforced feature flags today, so this map is
-// intentionally left empty.
-var requiredFeatureForType = map[string]string{}
+var requiredFeatureForType = map[string]string{
+	"external":       "external_receiver",
+	"flag_anomalies": "anomaly_processor",
+}
 
 var enabledExperimentalFeatures map[string]bool
 
@@ -47,6 +85,13 @@ func ParseExperimentalFeatures(features string) map[string]bool {
 }
 
 func init() {
+	RegisterFeatureGate("anomaly_processor", StageAlpha, `Enables the "flag_anomalies" logging processor, which labels the logs with anomalous numeric fields.`)
+	RegisterFeatureGate("external_receiver", StageAlpha, `Enables the "external" combined receiver, which configures a receiver built into the OTel collector from a schema file.`)
+	RegisterFeatureGate("log_compression", StageBeta, `Enables "compress" in logging.service and in logging pipelines.`)
+	RegisterFeatureGate("otel_logging", StageBeta, `Enables logging.service.experimental_otel_logging, which collects logs with the OTel collector instead of Fluent Bit.`)
+	RegisterFeatureGate("otlp_logging", StageAlpha, "Collects the logs of otlp receivers with the OTel collector.")
+	RegisterFeatureGate("pubsub_exporter", StageAlpha, `Enables "pubsub" in logging pipelines. The OTel collector build must include the fluentforward receiver, the groupbyattrs processor and the googlecloudpubsub exporter.`)
+
 	enabledExperimentalFeatures = ParseExperimentalFeatures(os.Getenv("EXPERIMENTAL_FEATURES"))
 }
 
@@ -65,9 +110,36 @@ func experimentsFromContext(ctx context.Context) map[string]bool {
 	return enabledExperimentalFeatures
 }
 
+// featureEnabled returns whether the feature gate name is enabled in ctx. GA features are
+// always enabled.
+func featureEnabled(ctx context.Context, name string) bool {
+	return featureGates[name].Stage == StageGA || experimentsFromContext(ctx)[name]
+}
+
+// contextWithConfigExperiments returns ctx with the features listed in global.experimental
+// enabled in addition to the ones that are already enabled in ctx.
+func contextWithConfigExperiments(ctx context.Context, experimental []string) context.Context {
+	if len(experimental) == 0 {
+		return ctx
+	}
+	experiments := map[string]bool{}
+	for name, enabled := range experimentsFromContext(ctx) {
+		experiments[name] = enabled
+	}
+	for _, name := range experimental {
+		experiments[name] = true
+	}
+	return ContextWithExperiments(ctx, experiments)
+}
+
 func registerExperimentalValidations(v *validator.Validate) {
 	v.RegisterValidationCtx("experimental", func(ctx context.Context, fl validator.FieldLevel) bool {
-		return fl.Field().IsZero() || experimentsFromContext(ctx)[fl.Param()]
+		return fl.Field().IsZero() || featureEnabled(ctx, fl.Param())
+	})
+	// feature_gate validates that the value is the name of a registered feature gate.
+	v.RegisterValidation("feature_gate", func(fl validator.FieldLevel) bool {
+		_, ok := featureGates[fl.Field().String()]
+		return ok
 	})
 	v.RegisterStructValidationCtx(componentValidator, ConfigComponent{})
 }
@@ -78,7 +150,7 @@ func componentValidator(ctx context.Context, sl validator.StructLevel) {
 		return
 	}
 	feature, ok := requiredFeatureForType[comp.Type]
-	if !ok || experimentsFromContext(ctx)[feature] {
+	if !ok || featureEnabled(ctx, feature) {
 		return
 	}
 	sl.ReportError(comp, "type", "Type", "experimental", comp.Type)
@@ -87,3 +159,7 @@ func componentValidator(ctx context.Context, sl validator.StructLevel) {
 func experimentalValidationErrorString(ve validationError) string {
 	return fmt.Sprintf("Experimental feature %q cannot be used with the current version of the Ops Agent", ve.Param())
 }
+
+func featureGateValidationErrorString(ve validationError) string {
+	return fmt.Sprintf("%q must be one of the feature gates [%s], not %q", ve.Field(), strings.Join(sortedKeys(featureGates), ", "), ve.Value())
+}
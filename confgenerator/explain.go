@@ -0,0 +1,132 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// ExplainPipelines describes the pipelines of the config in the order in which their steps
+// process the data: the receiver, the processors, and the exporters. For the logging pipelines
+// that are processed by Fluent Bit, every step lists the Fluent Bit inputs and filters that
+// implement it, in the order in which they run.
+func (uc *UnifiedConfig) ExplainPipelines(ctx context.Context) (string, error) {
+	ctx = contextWithPerformanceProfile(ctx, uc.Global)
+	ctx = contextWithResourceOverride(ctx, uc.Global)
+	if uc.Logging != nil && uc.Logging.Service != nil {
+		ctx = contextWithLoggingLocation(ctx, uc.Logging.Service.Location)
+	}
+	pipelines, err := uc.Pipelines(ctx)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, p := range pipelines {
+		if p.backend == backendFluentBit {
+			if err := p.explainFluentBit(ctx, &out); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := p.explainOTel(ctx, &out); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+func (p pipelineInstance) explainFluentBit(ctx context.Context, out *strings.Builder) error {
+	source, err := p.fluentBitComponents(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s pipeline %q, receiver %q (Fluent Bit):\n", p.pipelineType, p.pID, p.rID)
+	for _, stage := range source.stages {
+		fmt.Fprintf(out, "  %s\n", stage.name)
+		for _, c := range stage.components {
+			if c.Kind != "INPUT" && c.Kind != "FILTER" {
+				continue
+			}
+			fmt.Fprintf(out, "    %s\n", explainFluentBitComponent(c))
+		}
+	}
+	exporters := []string{"google_cloud_logging"}
+	if source.syslogForward != nil {
+		exporters = append(exporters, "syslog_forward")
+	}
+	if source.pubsub != nil {
+		exporters = append(exporters, "pubsub")
+	}
+	if source.gcsArchive != nil {
+		exporters = append(exporters, "gcs_archive")
+	}
+	if source.bigQuery != nil {
+		exporters = append(exporters, "bigquery")
+	}
+	fmt.Fprintf(out, "  exporters: %s\n", strings.Join(exporters, ", "))
+	return nil
+}
+
+// explainFluentBitComponent describes c with its plugin and the setting that tells it apart
+// from the other components with the same plugin.
+func explainFluentBitComponent(c fluentbit.Component) string {
+	desc := fmt.Sprintf("%s %s", c.Kind, c.Config["Name"])
+	for _, k := range []string{"call", "Parser", "Key_Name", "Rule", "Operation"} {
+		if v, ok := c.Config[k]; ok {
+			return fmt.Sprintf("%s (%s %s)", desc, k, v)
+		}
+	}
+	return desc
+}
+
+func (p pipelineInstance) explainOTel(ctx context.Context, out *strings.Builder) error {
+	receiverPipelines, pipelines, err := p.otelComponents(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(pipelines) {
+		pipeline := pipelines[name]
+		receiverPipeline := receiverPipelines[pipeline.ReceiverPipelineName]
+		fmt.Fprintf(out, "%s pipeline %q, receiver %q (OpenTelemetry Collector):\n", p.pipelineType, p.pID, p.rID)
+		fmt.Fprintf(out, "  receiver %s\n", receiverPipeline.Receiver.Type)
+		for _, c := range receiverPipeline.Processors[pipeline.Type] {
+			fmt.Fprintf(out, "  processor %s\n", c.Type)
+		}
+		for _, c := range pipeline.Processors {
+			fmt.Fprintf(out, "  processor %s\n", c.Type)
+		}
+		exporter := explainOTelExporter(receiverPipeline.ExporterTypes[pipeline.Type])
+		if pipeline.Exporter != nil {
+			exporter = pipeline.Exporter.Type
+		}
+		fmt.Fprintf(out, "  exporters: %s\n", exporter)
+	}
+	return nil
+}
+
+func explainOTelExporter(t otel.ExporterType) string {
+	switch t {
+	case otel.GMP:
+		return "googlemanagedprometheus"
+	case otel.System:
+		return "googlecloud (agent metrics)"
+	}
+	return "googlecloud"
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package confgenerator_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/shirou/gopsutil/host"
+)
+
+func TestExplainPipelines(t *testing.T) {
+	ctx := platform.Platform{
+		Type:     platform.Linux,
+		HostInfo: &host.InfoStat{Hostname: "hostname"},
+	}.TestContext(context.Background())
+	uc := mustParseConfig(t, `
+logging:
+  receivers:
+    app:
+      type: files
+      include_paths: [/var/log/app.log]
+  processors:
+    parse:
+      type: parse_json
+    drop_debug:
+      type: exclude_logs
+      match_any: [severity = "DEBUG"]
+  service:
+    pipelines:
+      app:
+        receivers: [app]
+        processors: [parse, drop_debug]
+        log_bucket: app-logs
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [hostmetrics]
+`)
+
+	got, err := uc.ExplainPipelines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The steps of the logging pipeline must be listed in the order in which they run.
+	last := -1
+	for _, want := range []string{
+		`logs pipeline "app", receiver "app" (Fluent Bit):`,
+		`receiver "app" (files)`,
+		"INPUT tail",
+		`processor "parse" (parse_json)`,
+		"FILTER lua (call parser_merge_record)",
+		`processor "drop_debug" (exclude_logs)`,
+		"log_bucket",
+		"exporters: google_cloud_logging",
+	} {
+		i := strings.Index(got, want)
+		if i < 0 {
+			t.Fatalf("explanation does not contain %q:\n%s", want, got)
+		}
+		if i < last {
+			t.Errorf("%q is out of order:\n%s", want, got)
+		}
+		last = i
+	}
+	for _, want := range []string{
+		`metrics pipeline "default_pipeline", receiver "hostmetrics" (OpenTelemetry Collector):`,
+		"receiver hostmetrics",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("explanation does not contain %q:\n%s", want, got)
+		}
+	}
+}
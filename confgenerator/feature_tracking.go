@@ -74,6 +74,37 @@ type CustomFeatures interface {
 func ExtractFeatures(uc *UnifiedConfig) ([]Feature, error) {
 	allFeatures := getOverriddenDefaultPipelines(uc)
 	allFeatures = append(allFeatures, getSelfLogCollection(uc))
+	if uc.Global != nil && uc.Global.PerformanceProfile != "" {
+		allFeatures = append(allFeatures, Feature{
+			Module: "global",
+			Kind:   "default",
+			Type:   "performance_profile",
+			Key:    []string{"performance_profile"},
+			Value:  uc.Global.PerformanceProfile,
+		})
+	}
+	if uc.Global != nil && uc.Global.OpAMP != nil {
+		allFeatures = append(allFeatures, Feature{
+			Module: "global",
+			Kind:   "default",
+			Type:   "opamp",
+			Key:    []string{"enabled"},
+			Value:  "true",
+		})
+	}
+	experimental := map[string]bool{}
+	for _, name := range uc.Global.GetExperimental() {
+		experimental[name] = true
+	}
+	for _, name := range sortedKeys(experimental) {
+		allFeatures = append(allFeatures, Feature{
+			Module: "global",
+			Kind:   "default",
+			Type:   "experimental",
+			Key:    []string{name},
+			Value:  string(featureGates[name].Stage),
+		})
+	}
 
 	var err error
 	var tempTrackedFeatures []Feature
@@ -112,6 +143,14 @@ func ExtractFeatures(uc *UnifiedConfig) ([]Feature, error) {
 		}
 		allFeatures = append(allFeatures, tempTrackedFeatures...)
 	}
+
+	if uc.HasTraces() {
+		tempTrackedFeatures, err = trackedMappedComponents("traces", "processors", uc.Traces.Processors)
+		if err != nil {
+			return nil, err
+		}
+		allFeatures = append(allFeatures, tempTrackedFeatures...)
+	}
 	return allFeatures, nil
 }
 
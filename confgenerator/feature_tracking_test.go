@@ -839,6 +839,7 @@ func TestGolden(t *testing.T) {
 	components = append(components, confgenerator.MetricsReceiverTypes.GetComponentsFromRegistry()...)
 	components = append(components, confgenerator.MetricsProcessorTypes.GetComponentsFromRegistry()...)
 	components = append(components, confgenerator.CombinedReceiverTypes.GetComponentsFromRegistry()...)
+	components = append(components, confgenerator.TracesProcessorTypes.GetComponentsFromRegistry()...)
 
 	features := getFeatures(components)
 
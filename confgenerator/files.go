@@ -20,6 +20,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 )
 
 // ReadUnifiedConfigFromFile reads the user config file and returns a UnifiedConfig.
@@ -45,32 +47,54 @@ func ReadUnifiedConfigFromFile(ctx context.Context, path string) (*UnifiedConfig
 	return uc, nil
 }
 
+// GenerateFilesFromConfig generates the config files of service and writes them to outDir. The
+// generated files are cached under stateDir, so that they are only generated again when
+// something that they depend on has changed.
 func (uc *UnifiedConfig) GenerateFilesFromConfig(ctx context.Context, service, logsDir, stateDir, outDir string) error {
-	switch service {
-	case "": // Validate-only.
+	if service == "" { // Validate-only.
 		return nil
+	}
+	files, err := uc.generateFilesCached(ctx, service, logsDir, stateDir)
+	if err != nil {
+		return err
+	}
+	for name, contents := range files {
+		if err = WriteConfigFile([]byte(contents), filepath.Join(outDir, name)); err != nil {
+			return err
+		}
+	}
+	if service == "fluentbit" {
+		// Remove the main config file of the other format, so that Fluent Bit can't be started
+		// with a stale config after the format changes.
+		for _, name := range fluentbit.MainConfigFileNames {
+			if _, ok := files[name]; ok {
+				continue
+			}
+			if err := os.Remove(filepath.Join(outDir, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale config file %q: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// generateFiles returns the config files of service.
+func (uc *UnifiedConfig) generateFiles(ctx context.Context, service, logsDir, stateDir string) (map[string]string, error) {
+	switch service {
 	case "fluentbit":
 		files, err := uc.GenerateFluentBitConfigs(ctx, logsDir, stateDir)
 		if err != nil {
-			return fmt.Errorf("can't parse configuration: %w", err)
-		}
-		for name, contents := range files {
-			if err = WriteConfigFile([]byte(contents), filepath.Join(outDir, name)); err != nil {
-				return err
-			}
+			return nil, fmt.Errorf("can't parse configuration: %w", err)
 		}
+		return files, nil
 	case "otel":
 		otelConfig, err := uc.GenerateOtelConfig(ctx)
 		if err != nil {
-			return fmt.Errorf("can't parse configuration: %w", err)
+			return nil, fmt.Errorf("can't parse configuration: %w", err)
 		}
-		if err = WriteConfigFile([]byte(otelConfig), filepath.Join(outDir, "otel.yaml")); err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("unknown service %q", service)
+		return map[string]string{"otel.yaml": otelConfig}, nil
 	}
-	return nil
+	return nil, fmt.Errorf("unknown service %q", service)
 }
 
 func WriteConfigFile(content []byte, path string) error {
@@ -100,6 +100,37 @@ func (f Filter) String() string {
 	return f.expr.String()
 }
 
+// Members returns the fields that are referenced by the filter, in the order they appear.
+// Global restrictions match any field, so they are not included.
+func (f Filter) Members() []Member {
+	var out []Member
+	var walk func(e ast.Expression)
+	walk = func(e ast.Expression) {
+		switch e := e.(type) {
+		case ast.Restriction:
+			if e.Operator != "GLOBAL" {
+				out = append(out, Member{e.LHS})
+			}
+		case *ast.Restriction:
+			walk(*e)
+		case ast.Negation:
+			walk(e.Expression)
+		case *ast.Negation:
+			walk(e.Expression)
+		case ast.Conjunction:
+			for _, c := range e {
+				walk(c)
+			}
+		case ast.Disjunction:
+			for _, d := range e {
+				walk(d)
+			}
+		}
+	}
+	walk(f.expr)
+	return out
+}
+
 // MatchesAny returns a single Filter that matches if any of the child filters match.
 func MatchesAny(filters []*Filter) *Filter {
 	d := ast.Disjunction{}
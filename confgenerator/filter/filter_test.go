@@ -161,3 +161,30 @@ func TestValidMembers(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterMembers(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []string
+	}{
+		{`severity = "hello"`, []string{"severity"}},
+		{`NOT jsonPayload.foo = 1`, []string{"jsonPayload.foo"}},
+		{`(jsonPayload.bar = "one" OR jsonPayload.bar = "two") jsonPayload.baz = "three"`, []string{"jsonPayload.bar", "jsonPayload.bar", "jsonPayload.baz"}},
+		{`labels."logging.googleapis.com/foo" = bar`, []string{`labels."logging.googleapis.com/foo"`}},
+	} {
+		test := test
+		t.Run(test.in, func(t *testing.T) {
+			filter, err := NewFilter(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range filter.Members() {
+				got = append(got, m.String())
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("incorrect members (got -/want +):\n%s", diff)
+			}
+		})
+	}
+}
@@ -63,9 +63,36 @@ func (c Component) generateSection() string {
 	return fmt.Sprintf("[%s]\n%s\n", c.Kind, strings.Join(lines, "\n"))
 }
 
+// ConfigFormat is the format of the main config file of Fluent Bit.
+type ConfigFormat string
+
+const (
+	// FormatYAML is the YAML config format.
+	FormatYAML ConfigFormat = "yaml"
+	// FormatClassic is the classic INI-like config format.
+	FormatClassic ConfigFormat = "classic"
+
+	// DefaultConfigFormat is the format that the main config file is generated in unless another
+	// one is chosen. It is YAML, because it doesn't depend on the whitespace and the order of the
+	// sections, and it supports the newer features of Fluent Bit, like processors.
+	DefaultConfigFormat = FormatYAML
+)
+
+// MainConfigFileName returns the name of the main config file in format f, which Fluent Bit
+// must be started with.
+func (f ConfigFormat) MainConfigFileName() string {
+	if f == FormatClassic {
+		return ClassicMainConfigFileName
+	}
+	return MainConfigFileName
+}
+
 type ModularConfig struct {
 	Variables  map[string]string
 	Components []Component
+	// Format is the format of the main config file. Defaults to DefaultConfigFormat. The parsers are
+	// always generated in the classic format, which is the only one that --parser accepts.
+	Format ConfigFormat
 }
 
 const (
@@ -75,10 +102,16 @@ const (
 )
 
 const (
-	MainConfigFileName   = "fluent_bit_main.conf"
-	ParserConfigFileName = "fluent_bit_parser.conf"
+	// MainConfigFileName is the name of the main config file in the YAML format.
+	MainConfigFileName = "fluent_bit_main.yaml"
+	// ClassicMainConfigFileName is the name of the main config file in the classic format.
+	ClassicMainConfigFileName = "fluent_bit_main.conf"
+	ParserConfigFileName      = "fluent_bit_parser.conf"
 )
 
+// MainConfigFileNames are the names of the main config file in all the formats.
+var MainConfigFileNames = []string{MainConfigFileName, ClassicMainConfigFileName}
+
 func outputFileComponent(name, contents string) Component {
 	return Component{
 		Kind: outputFileKind,
@@ -89,38 +122,82 @@ func outputFileComponent(name, contents string) Component {
 	}
 }
 
+// mainSectionKinds are the kinds of the sections of the main config file, in the order in which
+// they are generated.
+var mainSectionKinds = []string{"SERVICE", "INPUT", "FILTER", "OUTPUT"}
+
 func (c ModularConfig) Generate() (map[string]string, error) {
 	files := make(map[string]string)
 
-	var parts []string
-	for k, v := range c.Variables {
-		parts = append(parts, fmt.Sprintf("@SET %s=%s", k, v))
-	}
-	sort.Strings(parts)
-	// Blank line
-	parts = append(parts, "")
-
 	// TODO: Consider removing this sorting and just outputting the components in native order.
-	sectionsByKind := map[string][]string{}
+	componentsByKind := map[string][]Component{}
 	for _, c := range c.Components {
 		if c.Kind == outputFileKind {
 			files[c.Config[outputFileName]] = c.Config[outputFileContents]
 			continue
 		}
-		out := c.generateSection()
-		sectionsByKind[c.Kind] = append(sectionsByKind[c.Kind], out)
+		componentsByKind[c.Kind] = append(componentsByKind[c.Kind], c)
+	}
+	var parserParts []string
+	for _, k := range []string{"PARSER", "MULTILINE_PARSER"} {
+		for _, p := range componentsByKind[k] {
+			parserParts = append(parserParts, p.generateSection())
+		}
+		delete(componentsByKind, k)
 	}
-	parserParts := append(sectionsByKind["PARSER"], sectionsByKind["MULTILINE_PARSER"]...)
-	delete(sectionsByKind, "PARSER")
-	delete(sectionsByKind, "MULTILINE_PARSER")
-	for _, k := range []string{"SERVICE", "INPUT", "FILTER", "OUTPUT"} {
-		parts = append(parts, sectionsByKind[k]...)
-		delete(sectionsByKind, k)
+	unknown := map[string][]string{}
+	for k, components := range componentsByKind {
+		if !contains(mainSectionKinds, k) {
+			for _, c := range components {
+				unknown[k] = append(unknown[k], c.generateSection())
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown fluentbit config sections %+v", unknown)
 	}
-	if len(sectionsByKind) > 0 {
-		return nil, fmt.Errorf("unknown fluentbit config sections %+v", sectionsByKind)
+	format := c.Format
+	if format == "" {
+		format = DefaultConfigFormat
+	}
+	switch format {
+	case FormatClassic:
+		files[format.MainConfigFileName()] = c.generateClassic(componentsByKind)
+	case FormatYAML:
+		main, err := c.generateYAML(componentsByKind)
+		if err != nil {
+			return nil, err
+		}
+		files[format.MainConfigFileName()] = main
+	default:
+		return nil, fmt.Errorf("unknown fluentbit config format %q", c.Format)
 	}
-	files[MainConfigFileName] = strings.Join(parts, "\n")
 	files[ParserConfigFileName] = strings.Join(parserParts, "\n")
 	return files, nil
 }
+
+func (c ModularConfig) generateClassic(componentsByKind map[string][]Component) string {
+	var parts []string
+	for k, v := range c.Variables {
+		parts = append(parts, fmt.Sprintf("@SET %s=%s", k, v))
+	}
+	sort.Strings(parts)
+	// Blank line
+	parts = append(parts, "")
+
+	for _, k := range mainSectionKinds {
+		for _, c := range componentsByKind[k] {
+			parts = append(parts, c.generateSection())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentbit_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	yaml "github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testComponents = []fluentbit.Component{
+	{
+		Kind: "FILTER",
+		Config: map[string]string{
+			"Name":  "modify",
+			"Match": "app",
+		},
+		OrderedConfig: [][2]string{
+			{"Add", "a 1"},
+			{"Add", "b 2"},
+		},
+	},
+	{
+		Kind: "SERVICE",
+		Config: map[string]string{
+			"Flush":     "1",
+			"Log_Level": "info",
+		},
+	},
+	{
+		Kind: "INPUT",
+		Config: map[string]string{
+			"Name": "tail",
+			"Path": "/var/log/app.log",
+			"DB":   "${buffers_dir}/app",
+			"Tag":  "app",
+		},
+	},
+	{
+		Kind: "PARSER",
+		Config: map[string]string{
+			"Name":   "app.json",
+			"Format": "json",
+		},
+	},
+	{
+		Kind: "OUTPUT",
+		Config: map[string]string{
+			"Name":  "stackdriver",
+			"Match": "app",
+		},
+	},
+}
+
+// testYAML is the main config of testComponents in the YAML format. Keys that appear several
+// times are lists.
+const testYAML = `env:
+  buffers_dir: /var/lib/buffers
+service:
+  flush: "1"
+  log_level: info
+pipeline:
+  inputs:
+  - name: tail
+    db: ${buffers_dir}/app
+    path: /var/log/app.log
+    tag: app
+  filters:
+  - name: modify
+    match: app
+    add:
+    - a 1
+    - b 2
+  outputs:
+  - name: stackdriver
+    match: app
+`
+
+const testParsers = `[PARSER]
+    Format json
+    Name   app.json
+`
+
+func TestGenerate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format fluentbit.ConfigFormat
+		want   map[string]string
+	}{
+		{
+			name:   "yaml",
+			format: fluentbit.FormatYAML,
+			want: map[string]string{
+				fluentbit.MainConfigFileName:   testYAML,
+				fluentbit.ParserConfigFileName: testParsers,
+			},
+		},
+		{
+			name: "default",
+			want: map[string]string{
+				fluentbit.MainConfigFileName:   testYAML,
+				fluentbit.ParserConfigFileName: testParsers,
+			},
+		},
+		{
+			name:   "classic",
+			format: fluentbit.FormatClassic,
+			want: map[string]string{
+				fluentbit.ClassicMainConfigFileName: `@SET buffers_dir=/var/lib/buffers
+
+[SERVICE]
+    Flush     1
+    Log_Level info
+
+[INPUT]
+    DB   ${buffers_dir}/app
+    Name tail
+    Path /var/log/app.log
+    Tag  app
+
+[FILTER]
+    Match app
+    Name  modify
+    Add   a 1
+    Add   b 2
+
+[OUTPUT]
+    Match app
+    Name  stackdriver
+`,
+				fluentbit.ParserConfigFileName: testParsers,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := fluentbit.ModularConfig{
+				Variables:  map[string]string{"buffers_dir": "/var/lib/buffers"},
+				Components: testComponents,
+				Format:     tc.format,
+			}.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Generate() returned unexpected files (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateUnknownSection(t *testing.T) {
+	for _, format := range []fluentbit.ConfigFormat{fluentbit.FormatYAML, fluentbit.FormatClassic} {
+		_, err := fluentbit.ModularConfig{
+			Components: []fluentbit.Component{{Kind: "PLUGINS", Config: map[string]string{"Path": "/plugins"}}},
+			Format:     format,
+		}.Generate()
+		if err == nil {
+			t.Errorf("Generate() with format %q succeeded, want error for the unknown section", format)
+		}
+	}
+}
+
+func TestGenerateYAMLSeveralParsersFiles(t *testing.T) {
+	parsersFiles := []string{"/etc/parsers/a.conf", "/etc/parsers/b.conf", "/etc/parsers/c.conf"}
+	files, err := fluentbit.ModularConfig{
+		Components: []fluentbit.Component{
+			fluentbit.Service{LogLevel: "info", ParsersFiles: parsersFiles}.Component(),
+		},
+		Format: fluentbit.FormatYAML,
+	}.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := files[fluentbit.MainConfigFileName]
+	var got struct {
+		Service map[string]interface{} `yaml:"service"`
+	}
+	if err := yaml.UnmarshalWithOptions([]byte(main), &got, yaml.Strict()); err != nil {
+		t.Fatalf("generated config is not valid YAML: %v\n%s", err, main)
+	}
+	var want []interface{}
+	for _, f := range parsersFiles {
+		want = append(want, f)
+	}
+	if diff := cmp.Diff(want, got.Service["parsers_file"]); diff != "" {
+		t.Errorf("unexpected parsers_file (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateYAMLOrderedConfig(t *testing.T) {
+	files, err := fluentbit.ModularConfig{
+		Components: []fluentbit.Component{{
+			Kind: "FILTER",
+			OrderedConfig: [][2]string{
+				{"Name", "modify"},
+				{"Match", "test"},
+				{"Condition", "Key_value_matches message error"},
+				{"Set", "code 1"},
+				{"Set", `message "failed"`},
+			},
+		}},
+		Format: fluentbit.FormatYAML,
+	}.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Pipeline struct {
+			Filters []yaml.MapSlice `yaml:"filters"`
+		} `yaml:"pipeline"`
+	}
+	if err := yaml.UnmarshalWithOptions([]byte(files[fluentbit.MainConfigFileName]), &got, yaml.Strict(), yaml.UseOrderedMap()); err != nil {
+		t.Fatal(err)
+	}
+	want := []yaml.MapSlice{{
+		{Key: "name", Value: "modify"},
+		{Key: "match", Value: "test"},
+		{Key: "condition", Value: "Key_value_matches message error"},
+		{Key: "set", Value: []interface{}{"code 1", `message "failed"`}},
+	}}
+	if diff := cmp.Diff(want, got.Pipeline.Filters); diff != "" {
+		t.Errorf("unexpected filters (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateYAMLInterleavedOrderedConfig(t *testing.T) {
+	config := fluentbit.ModularConfig{
+		Components: []fluentbit.Component{{
+			Kind: "FILTER",
+			Config: map[string]string{
+				"Name":  "modify",
+				"Match": "test",
+			},
+			// The second rename must run after the copy, which a list of the values of each key
+			// can't express.
+			OrderedConfig: [][2]string{
+				{"Rename", "a b"},
+				{"Copy", "b c"},
+				{"Rename", "c d"},
+			},
+		}},
+	}
+	config.Format = fluentbit.FormatYAML
+	if _, err := config.Generate(); err == nil || !strings.Contains(err.Error(), `FILTER modify: key "rename" is repeated after key "copy"`) {
+		t.Errorf("Generate() = %v, want an error for the interleaved keys", err)
+	}
+	config.Format = fluentbit.FormatClassic
+	files, err := config.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	main := files[fluentbit.ClassicMainConfigFileName]
+	if i, j, k := strings.Index(main, "Rename a b"), strings.Index(main, "Copy   b c"), strings.Index(main, "Rename c d"); i < 0 || !(i < j && j < k) {
+		t.Errorf("classic config does not keep the order of the rules:\n%s", main)
+	}
+}
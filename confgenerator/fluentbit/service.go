@@ -17,9 +17,16 @@ package fluentbit
 type Service struct {
 	// Allowed log levels are: error, warn, info, debug, and trace.
 	LogLevel string
+	// ParsersFiles are parsers files maintained outside of the agent, which are loaded in addition
+	// to the generated one.
+	ParsersFiles []string
 }
 
 func (s Service) Component() Component {
+	var parsersFiles [][2]string
+	for _, f := range s.ParsersFiles {
+		parsersFiles = append(parsersFiles, [2]string{"Parsers_File", f})
+	}
 	return Component{
 		Kind: "SERVICE",
 		Config: map[string]string{
@@ -30,6 +37,9 @@ func (s Service) Component() Component {
 			"Daemon": "off",
 			// Log_File is set by Fluent Bit systemd unit (e.g. /var/log/google-cloud-ops-agent/subagents/logging-module.log).
 			"Log_Level": s.LogLevel,
+			// Reload the config on SIGHUP instead of restarting, so that inputs keep their state.
+			// https://docs.fluentbit.io/manual/administration/hot-reload
+			"Hot_Reload": "On",
 
 			// Use the legacy DNS resolver mechanism to work around b/206549605 temporarily.
 			"dns.resolver": "legacy",
@@ -48,5 +58,6 @@ func (s Service) Component() Component {
 			// we don't want them to all be loaded into the memory.
 			"storage.max_chunks_up": "128",
 		},
+		OrderedConfig: parsersFiles,
 	}
 }
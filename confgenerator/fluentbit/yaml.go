@@ -0,0 +1,139 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fluentbit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// yamlPipelineSections maps the kinds of the components of the pipeline to the sections of the
+// YAML config that hold them.
+var yamlPipelineSections = []struct {
+	kind, section string
+}{
+	{"INPUT", "inputs"},
+	{"FILTER", "filters"},
+	{"OUTPUT", "outputs"},
+}
+
+// generateYAML generates the main config file in the YAML format.
+// See https://docs.fluentbit.io/manual/administration/configuring-fluent-bit/yaml.
+func (c ModularConfig) generateYAML(componentsByKind map[string][]Component) (string, error) {
+	var doc yaml.MapSlice
+	if len(c.Variables) > 0 {
+		var env yaml.MapSlice
+		for _, k := range sortedKeys(c.Variables) {
+			env = append(env, yaml.MapItem{Key: k, Value: c.Variables[k]})
+		}
+		doc = append(doc, yaml.MapItem{Key: "env", Value: env})
+	}
+	var service yaml.MapSlice
+	for _, s := range componentsByKind["SERVICE"] {
+		properties, err := s.yamlProperties()
+		if err != nil {
+			return "", err
+		}
+		service = append(service, properties...)
+	}
+	if len(service) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "service", Value: service})
+	}
+	var pipeline yaml.MapSlice
+	for _, s := range yamlPipelineSections {
+		var plugins []yaml.MapSlice
+		for _, c := range componentsByKind[s.kind] {
+			properties, err := c.yamlProperties()
+			if err != nil {
+				return "", err
+			}
+			plugins = append(plugins, properties)
+		}
+		if len(plugins) > 0 {
+			pipeline = append(pipeline, yaml.MapItem{Key: s.section, Value: plugins})
+		}
+	}
+	if len(pipeline) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "pipeline", Value: pipeline})
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// yamlProperties returns the properties of c in the YAML format. The keys are lowercase, like in
+// the Fluent Bit documentation, and the name of the plugin comes first. Keys that appear several
+// times, like the rules of the rewrite_tag filter, are a list of their values in order, which
+// Fluent Bit reads like the repeated keys of the classic format.
+//
+// A list keeps the order of the values of one key, but not the order across keys. It is an error
+// for the keys of OrderedConfig to be interleaved, like the rules of a modify filter that renames,
+// adds and renames again, since Fluent Bit would not apply them in order.
+func (c Component) yamlProperties() (yaml.MapSlice, error) {
+	var keys []string
+	values := map[string][]string{}
+	add := func(k, v string) {
+		k = strings.ToLower(k)
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		values[k] = append(values[k], v)
+	}
+	configKeys := sortedKeys(c.Config)
+	sort.SliceStable(configKeys, func(i, j int) bool {
+		ki, kj := strings.ToLower(configKeys[i]), strings.ToLower(configKeys[j])
+		if ki == "name" || kj == "name" {
+			return ki == "name" && kj != "name"
+		}
+		return ki < kj
+	})
+	for _, k := range configKeys {
+		add(k, c.Config[k])
+	}
+	orderedKeys := map[string]bool{}
+	var last string
+	for _, line := range c.OrderedConfig {
+		k := strings.ToLower(line[0])
+		if orderedKeys[k] && k != last {
+			return nil, fmt.Errorf("%s %s: key %q is repeated after key %q, which the YAML config format can't keep in order", c.Kind, strings.Join(values["name"], ","), k, last)
+		}
+		orderedKeys[k] = true
+		last = k
+		add(line[0], line[1])
+	}
+	var properties yaml.MapSlice
+	for _, k := range keys {
+		if len(values[k]) == 1 {
+			properties = append(properties, yaml.MapItem{Key: k, Value: values[k][0]})
+		} else {
+			properties = append(properties, yaml.MapItem{Key: k, Value: values[k]})
+		}
+	}
+	return properties, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
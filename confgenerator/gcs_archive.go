@@ -0,0 +1,104 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+// gcsArchiveTagPrefix is prepended to the tags of the copies of the records that are archived.
+const gcsArchiveTagPrefix = "gcs_archive"
+
+// GCSArchive writes the raw logs of a logging pipeline to hourly gzipped objects in a Cloud
+// Storage bucket, in addition to sending the parsed logs to Cloud Logging, for cheap long-term
+// retention.
+//
+// The objects are uploaded through the XML API of Cloud Storage, which needs HMAC keys in the
+// AWS shared credentials file of the agent, e.g. /root/.aws/credentials on Linux.
+type GCSArchive struct {
+	Bucket string `yaml:"bucket" validate:"required"`
+	// Prefix is the prefix of the object names. ${pipeline_id} is replaced by the ID of the
+	// pipeline, and strftime conversions like %Y are replaced by the time of the logs.
+	// Defaults to "${pipeline_id}".
+	Prefix string `yaml:"prefix,omitempty"`
+	// Format is "raw" for the text of the logs as read by the receiver (the default), or "json"
+	// for the records as read by the receiver.
+	Format string `yaml:"format,omitempty" validate:"omitempty,oneof=json raw"`
+	// Profile is the profile of the HMAC keys in the AWS shared credentials file.
+	Profile string `yaml:"profile,omitempty"`
+}
+
+// gcsArchiveCopyComponent copies the records of tag before they are processed, so that
+// the copies can be archived as read by the receiver. Only records with a message are copied.
+func gcsArchiveCopyComponent(tag string) fluentbit.Component {
+	return fluentbit.Component{
+		Kind: "FILTER",
+		Config: map[string]string{
+			"Name":  "rewrite_tag",
+			"Match": tag,
+			"Rule":  fmt.Sprintf(`message .* %s.$TAG true`, gcsArchiveTagPrefix),
+		},
+	}
+}
+
+// gcsArchiveOutputComponent uploads the copies of the records whose tag matches match to the
+// bucket of a.
+func gcsArchiveOutputComponent(match, pipelineID string, a *GCSArchive) fluentbit.Component {
+	prefix := a.Prefix
+	if prefix == "" {
+		prefix = "${pipeline_id}"
+	}
+	prefix = strings.Trim(strings.ReplaceAll(prefix, "${pipeline_id}", pipelineID), "/")
+	format := a.Format
+	if format == "" {
+		format = "raw"
+	}
+	extension := ".log.gz"
+	if format == "json" {
+		extension = ".json.gz"
+	}
+	config := map[string]string{
+		// https://docs.fluentbit.io/manual/pipeline/outputs/s3
+		"Name":        "s3",
+		"Match_Regex": fmt.Sprintf(`^%s\.(%s)$`, gcsArchiveTagPrefix, match),
+		"Alias":       fmt.Sprintf("%s.%s", gcsArchiveTagPrefix, pipelineID),
+		"bucket":      a.Bucket,
+		// https://cloud.google.com/storage/docs/interoperability
+		"endpoint": "https://storage.googleapis.com",
+		"region":   "auto",
+		// Roll over to a new object every hour, or earlier for very busy pipelines.
+		"s3_key_format":   fmt.Sprintf("/%s/%%Y/%%m/%%d/%%H/$UUID%s", prefix, extension),
+		"upload_timeout":  "60m",
+		"total_file_size": "250M",
+		"use_put_object":  "On",
+		"compression":     "gzip",
+		"store_dir":       path.Join("${buffers_dir}", gcsArchiveTagPrefix, pipelineID),
+		"Retry_Limit":     "3",
+	}
+	if format == "raw" {
+		config["log_key"] = "message"
+	}
+	if a.Profile != "" {
+		config["profile"] = a.Profile
+	}
+	return fluentbit.Component{
+		Kind:   "OUTPUT",
+		Config: config,
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import "testing"
+
+func TestGCSArchiveValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{
+			name:   "bucket",
+			output: `gcs_archive: {bucket: my-archive}`,
+		},
+		{
+			name:   "json with prefix",
+			output: `gcs_archive: {bucket: my-archive, prefix: "logs/${pipeline_id}/%Y/%m/%d", format: json, profile: archive}`,
+		},
+		{
+			name:    "missing bucket",
+			output:  `gcs_archive: {format: raw}`,
+			wantErr: "bucket",
+		},
+		{
+			name:    "unsupported format",
+			output:  `gcs_archive: {bucket: my-archive, format: parquet}`,
+			wantErr: "format",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checkPipelineOutputErr(t, validateWithPipelineOutput(tc.output), tc.wantErr)
+		})
+	}
+}
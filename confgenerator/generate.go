@@ -0,0 +1,108 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/resourcedetector"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// Paths are the directories that the generated configs refer to.
+type Paths struct {
+	// LogsDir is where the subagents write their own logs.
+	LogsDir string
+	// StateDir is where the subagents keep their state, like buffers and file positions.
+	StateDir string
+}
+
+// Resource is a monitored resource that the telemetry is written for.
+type Resource struct {
+	// Type is one of the monitored resource types that global.resource_override supports:
+	// gce_instance, generic_node or generic_task.
+	Type string
+	// Labels are the labels of the monitored resource. All the labels of Type are required.
+	Labels map[string]string
+	// ProjectID is the project that the resource belongs to. The telemetry is written to the
+	// project of the credentials if it is empty.
+	ProjectID string
+}
+
+// Options configure Generate.
+type Options struct {
+	// Resource is the monitored resource that the telemetry is written for.
+	// If it is nil, the resource is detected like in the agent binaries, which only
+	// works on GCE. It can't be set together with global.resource_override in the config.
+	Resource *Resource
+	// Paths are required.
+	Paths Paths
+	// FluentBitConfigFormat is the format of the main Fluent Bit config file. Defaults to
+	// fluentbit.DefaultConfigFormat, like in the agent binaries. The main config file is
+	// FluentBitConfigFormat.MainConfigFileName() in GeneratedConfigs.FluentBit.
+	FluentBitConfigFormat fluentbit.ConfigFormat
+}
+
+// GeneratedConfigs are the configs of all the subagents.
+type GeneratedConfigs struct {
+	// FluentBit maps the names of the Fluent Bit config files to their contents.
+	FluentBit map[string]string
+	// Otel is the config of the OpenTelemetry Collector.
+	Otel string
+}
+
+// Generate generates the configs of all the subagents from a config that is already merged
+// with the built-in config, e.g. with MergeConfFiles and apps.BuiltInConfStructs.
+//
+// Generate is the entry point for programs that embed config generation instead of running
+// the agent binaries, like GKE on-prem and GDC Edge. It is kept backwards compatible: fields
+// may be added to Options, Paths and GeneratedConfigs, but the existing fields and the
+// signature of Generate don't change their meaning. The other exported identifiers of this
+// package are shared with the agent binaries and may change at any time.
+func Generate(ctx context.Context, uc *UnifiedConfig, opts Options) (*GeneratedConfigs, error) {
+	if opts.Paths.LogsDir == "" || opts.Paths.StateDir == "" {
+		return nil, fmt.Errorf("both the logs and the state directories are required")
+	}
+	if opts.Resource != nil {
+		if uc.Global != nil && uc.Global.ResourceOverride != nil {
+			return nil, fmt.Errorf("the resource can't be set both in the options and in global.resource_override")
+		}
+		r := ResourceOverride{Type: opts.Resource.Type, Labels: opts.Resource.Labels, ProjectID: opts.Resource.ProjectID}
+		if _, ok := resourcedetector.OverrideLabels[r.Type]; !ok {
+			return nil, fmt.Errorf("unsupported resource type %q", r.Type)
+		}
+		if err := r.validate("resource labels"); err != nil {
+			return nil, err
+		}
+		ctx = platform.WithResourceOverride(ctx, resourcedetector.OverrideResource{Type: r.Type, Labels: r.Labels, Project: r.ProjectID})
+	}
+	if opts.FluentBitConfigFormat != "" {
+		ctx = ContextWithFluentBitConfigFormat(ctx, opts.FluentBitConfigFormat)
+	}
+	fluentBit, err := uc.GenerateFluentBitConfigs(ctx, opts.Paths.LogsDir, opts.Paths.StateDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse configuration: %w", err)
+	}
+	otelConfig, err := uc.GenerateOtelConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse configuration: %w", err)
+	}
+	return &GeneratedConfigs{
+		FluentBit: fluentBit,
+		Otel:      otelConfig,
+	}, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/apps"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/shirou/gopsutil/host"
+	"gotest.tools/v3/assert"
+)
+
+// edgeResource is a resource like the ones of embedders that don't run on GCE.
+var edgeResource = &confgenerator.Resource{
+	Type: "generic_node",
+	Labels: map[string]string{
+		"location":  "test-location",
+		"namespace": "test-namespace",
+		"node_id":   "test-node",
+	},
+	ProjectID: "test-project",
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := platform.Platform{
+		Type: platform.Linux,
+		HostInfo: &host.InfoStat{
+			Hostname: "test-host",
+			OS:       "linux",
+		},
+	}.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, filepath.Join(t.TempDir(), "config.yaml"), apps.BuiltInConfStructs)
+	assert.NilError(t, err)
+
+	got, err := confgenerator.Generate(ctx, uc, confgenerator.Options{
+		Resource: edgeResource,
+		Paths: confgenerator.Paths{
+			LogsDir:  "/var/log/test",
+			StateDir: "/var/lib/test",
+		},
+	})
+	assert.NilError(t, err)
+
+	main := got.FluentBit["fluent_bit_main.yaml"]
+	assert.Assert(t, strings.Contains(main, "generic_node"), "the Fluent Bit config doesn't use the resource:\n%s", main)
+	assert.Assert(t, strings.Contains(main, "/var/lib/test"), "the Fluent Bit config doesn't use the state directory:\n%s", main)
+	assert.Assert(t, strings.Contains(got.Otel, "test-node"), "the OTel config doesn't use the resource:\n%s", got.Otel)
+}
+
+func TestGenerateClassicFluentBitConfig(t *testing.T) {
+	ctx := linuxTestPlatform.platform.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, filepath.Join(t.TempDir(), "config.yaml"), apps.BuiltInConfStructs)
+	assert.NilError(t, err)
+
+	got, err := confgenerator.Generate(ctx, uc, confgenerator.Options{
+		Resource:              edgeResource,
+		Paths:                 confgenerator.Paths{LogsDir: "/var/log/test", StateDir: "/var/lib/test"},
+		FluentBitConfigFormat: fluentbit.FormatClassic,
+	})
+	assert.NilError(t, err)
+
+	main, ok := got.FluentBit["fluent_bit_main.conf"]
+	assert.Assert(t, ok, "the classic main config is missing from %v", got.FluentBit)
+	assert.Assert(t, strings.Contains(main, "[SERVICE]"), "the main config is not in the classic format:\n%s", main)
+	_, ok = got.FluentBit["fluent_bit_main.yaml"]
+	assert.Assert(t, !ok, "the YAML main config is generated together with the classic one")
+}
+
+func TestGenerateRequiresPaths(t *testing.T) {
+	ctx := linuxTestPlatform.platform.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, filepath.Join(t.TempDir(), "config.yaml"), apps.BuiltInConfStructs)
+	assert.NilError(t, err)
+
+	_, err = confgenerator.Generate(ctx, uc, confgenerator.Options{})
+	assert.ErrorContains(t, err, "directories are required")
+}
+
+func TestGenerateRejectsTwoResources(t *testing.T) {
+	ctx := linuxTestPlatform.platform.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, filepath.Join(t.TempDir(), "config.yaml"), apps.BuiltInConfStructs)
+	assert.NilError(t, err)
+	uc.Global = &confgenerator.Global{ResourceOverride: &confgenerator.ResourceOverride{
+		Type:   "generic_node",
+		Labels: map[string]string{"location": "us-central1-a", "namespace": "edge", "node_id": "node-1"},
+	}}
+
+	_, err = confgenerator.Generate(ctx, uc, confgenerator.Options{
+		Resource: edgeResource,
+		Paths:    confgenerator.Paths{LogsDir: "/var/log/test", StateDir: "/var/lib/test"},
+	})
+	assert.ErrorContains(t, err, "global.resource_override")
+}
+
+func TestGenerateDoesNotModifyConfig(t *testing.T) {
+	ctx := linuxTestPlatform.platform.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, filepath.Join(t.TempDir(), "config.yaml"), apps.BuiltInConfStructs)
+	assert.NilError(t, err)
+	before := uc.String()
+
+	_, err = confgenerator.Generate(ctx, uc, confgenerator.Options{
+		Resource: edgeResource,
+		Paths:    confgenerator.Paths{LogsDir: "/var/log/test", StateDir: "/var/lib/test"},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, before, uc.String())
+}
+
+func TestGenerateRejectsInvalidResource(t *testing.T) {
+	ctx := linuxTestPlatform.platform.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, filepath.Join(t.TempDir(), "config.yaml"), apps.BuiltInConfStructs)
+	assert.NilError(t, err)
+
+	_, err = confgenerator.Generate(ctx, uc, confgenerator.Options{
+		Resource: &confgenerator.Resource{Type: "generic_node", Labels: map[string]string{"node_id": "test-node"}},
+		Paths:    confgenerator.Paths{LogsDir: "/var/log/test", StateDir: "/var/lib/test"},
+	})
+	assert.ErrorContains(t, err, `"location" is required`)
+}
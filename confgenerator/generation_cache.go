@@ -0,0 +1,219 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/secret"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
+	"github.com/goccy/go-yaml"
+)
+
+// generationCacheDir is the directory under the state directory that holds the files generated
+// by the previous run of the engine for every service.
+const generationCacheDir = "generated-config-cache"
+
+// A generationCacheEntry holds the files generated for a service, with the key of everything
+// that they were generated from.
+type generationCacheEntry struct {
+	Key string `json:"key"`
+	// Inputs are the files that were read while generating the files, e.g. the schemas of
+	// external receivers. The entry is only valid while they are unchanged.
+	Inputs []generationInput `json:"inputs,omitempty"`
+	Files  map[string]string `json:"files"`
+}
+
+// A generationInput is a file or glob pattern that the generated files depend on.
+type generationInput struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+}
+
+const (
+	inputKindFile = "file"
+	inputKindGlob = "glob"
+)
+
+func digestString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// inputDigest returns the digest of the current contents of the input.
+func inputDigest(kind, name string) string {
+	switch kind {
+	case inputKindFile:
+		return fileDigest(os.ReadFile(name))
+	case inputKindGlob:
+		return globDigest(filepath.Glob(name))
+	}
+	return ""
+}
+
+func fileDigest(data []byte, err error) string {
+	if os.IsNotExist(err) {
+		return "missing"
+	} else if err != nil {
+		return "error"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func globDigest(matches []string, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return digestString(strings.Join(matches, "\n"))
+}
+
+// generationInputs records the inputs that are read while the files are generated. The
+// pipelines are generated concurrently, so it is safe for concurrent use.
+type generationInputs struct {
+	mu     sync.Mutex
+	inputs []generationInput
+}
+
+type generationInputsKeyType struct{}
+
+var generationInputsKey = generationInputsKeyType{}
+
+func contextWithGenerationInputs(ctx context.Context, inputs *generationInputs) context.Context {
+	return context.WithValue(ctx, generationInputsKey, inputs)
+}
+
+// recordInput records an input of the generation in ctx, if ctx records them.
+func recordInput(ctx context.Context, input generationInput) {
+	inputs, ok := ctx.Value(generationInputsKey).(*generationInputs)
+	if !ok {
+		return
+	}
+	inputs.mu.Lock()
+	defer inputs.mu.Unlock()
+	inputs.inputs = append(inputs.inputs, input)
+}
+
+// ReadInputFile reads a file that the generated config depends on. The file is recorded as an
+// input of the generation, so that the cached config is generated again when the file changes.
+func ReadInputFile(ctx context.Context, path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	recordInput(ctx, generationInput{Kind: inputKindFile, Name: path, Digest: fileDigest(data, err)})
+	return data, err
+}
+
+// globInput returns the files that match pattern. The pattern is recorded as an input of the
+// generation, so that the cached config is generated again when the matching files change.
+func globInput(ctx context.Context, pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	recordInput(ctx, generationInput{Kind: inputKindGlob, Name: pattern, Digest: globDigest(matches, err)})
+	return matches, err
+}
+
+// generationCacheKey returns the key of everything besides the recorded inputs that the files
+// generated for service depend on: the merged config, including the secrets that it redacts,
+// the agent version, the platform, the enabled experiments, the resource, and the format of the
+// Fluent Bit config.
+func (uc *UnifiedConfig) generationCacheKey(ctx context.Context, service, logsDir, stateDir string) (string, error) {
+	config, err := yaml.MarshalWithOptions(uc, yaml.CustomMarshaler[secret.String](func(s secret.String) ([]byte, error) {
+		return []byte(digestString(s.SecretValue())), nil
+	}))
+	if err != nil {
+		return "", err
+	}
+	p := platform.FromContext(ctx)
+	var experiments []string
+	for name, enabled := range experimentsFromContext(ctx) {
+		if enabled {
+			experiments = append(experiments, name)
+		}
+	}
+	sort.Strings(experiments)
+	resource, resourceErr := platform.FromContext(contextWithResourceOverride(ctx, uc.Global)).GetResource()
+	resourceJSON, err := json.Marshal(resource)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "version: %s\n", version.Version)
+	fmt.Fprintf(&b, "service: %s\nlogs: %s\nstate: %s\n", service, logsDir, stateDir)
+	fmt.Fprintf(&b, "platform: %d %s %q %t %d\n", p.Type, p.WindowsBuildNumber, p.WinlogV1Channels, p.HasNvidiaGpu, p.NumCPU)
+	if p.HostInfo != nil {
+		fmt.Fprintf(&b, "host: %s %s %s %s\n", p.HostInfo.Hostname, p.HostInfo.OS, p.HostInfo.Platform, p.HostInfo.PlatformVersion)
+	}
+	fmt.Fprintf(&b, "experiments: %q\n", experiments)
+	fmt.Fprintf(&b, "fluent-bit config format: %s\n", fluentBitConfigFormatFromContext(ctx))
+	fmt.Fprintf(&b, "resource: %T %s %v\n", resource, resourceJSON, resourceErr)
+	fmt.Fprintf(&b, "config:\n%s", config)
+	return digestString(b.String()), nil
+}
+
+// generateFilesCached returns the files generated for service, reusing the files generated by
+// the previous run of the engine when nothing that they depend on has changed. For hosts with
+// very large configs this saves most of the time that generating the files takes on every start.
+// Failing to read or write the cache is not an error: the files are generated as usual.
+func (uc *UnifiedConfig) generateFilesCached(ctx context.Context, service, logsDir, stateDir string) (map[string]string, error) {
+	key, err := uc.generationCacheKey(ctx, service, logsDir, stateDir)
+	if err != nil {
+		return uc.generateFiles(ctx, service, logsDir, stateDir)
+	}
+	path := filepath.Join(stateDir, generationCacheDir, service+".json")
+	if files, ok := readGenerationCache(path, key); ok {
+		return files, nil
+	}
+	inputs := &generationInputs{}
+	files, err := uc.generateFiles(contextWithGenerationInputs(ctx, inputs), service, logsDir, stateDir)
+	if err != nil {
+		return nil, err
+	}
+	entry := generationCacheEntry{Key: key, Inputs: inputs.inputs, Files: files}
+	if data, err := json.Marshal(entry); err == nil {
+		// The generated files may contain secrets, so the cache is only readable by the agent.
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			_ = os.WriteFile(path, data, 0600)
+		}
+	}
+	return files, nil
+}
+
+// readGenerationCache returns the files of the cache entry at path if its key is key and its
+// inputs are unchanged.
+func readGenerationCache(path, key string) (map[string]string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry generationCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || entry.Files == nil {
+		return nil, false
+	}
+	for _, input := range entry.Inputs {
+		if inputDigest(input.Kind, input.Name) != input.Digest {
+			return nil, false
+		}
+	}
+	return entry.Files, true
+}
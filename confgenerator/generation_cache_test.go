@@ -0,0 +1,114 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFilesFromConfigCache(t *testing.T) {
+	logsDir, stateDir, outDir := t.TempDir(), t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(logsDir, "a.log"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := func(sharedKey string) string {
+		return fmt.Sprintf(`
+logging:
+  receivers:
+    app:
+      type: files
+      include_paths: [%s]
+    forward:
+      type: fluent_forward
+      shared_key: %s
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [app, forward]
+`, filepath.Join(logsDir, "*.log"), sharedKey)
+	}
+	ctx := linuxTestPlatform.platform.TestContext(context.Background())
+	generate := func(input string) string {
+		t.Helper()
+		uc := mustParseConfig(t, input)
+		if err := uc.GenerateFilesFromConfig(ctx, "fluentbit", logsDir, stateDir, outDir); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, "fluent_bit_main.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	generated := generate(config("first-key"))
+	if !strings.Contains(generated, "first-key") {
+		t.Fatalf("generated config doesn't contain the shared key:\n%s", generated)
+	}
+
+	// Replace the cached file, to tell the files that are read from the cache apart from the
+	// generated ones.
+	cachePath := filepath.Join(stateDir, "generated-config-cache", "fluentbit.json")
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	entry["files"].(map[string]interface{})["fluent_bit_main.yaml"] = "cached"
+	if data, err = json.Marshal(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cachePath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := generate(config("first-key")); got != "cached" {
+		t.Errorf("unchanged config was generated again, want it to be read from the cache")
+	}
+	// Secrets are redacted from the merged config, but changing them must still invalidate the
+	// cache.
+	if got := generate(config("second-key")); !strings.Contains(got, "second-key") {
+		t.Errorf("config with a new shared key was read from the cache:\n%s", got)
+	}
+	if got := generate(config("first-key")); got == "cached" {
+		t.Errorf("config was read from the cache of another config")
+	}
+
+	// The files that match the include paths are an input of the generation.
+	generate(config("first-key"))
+	if err := os.WriteFile(filepath.Join(logsDir, "b.log"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = os.ReadFile(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	generate(config("first-key"))
+	after, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) == string(data) {
+		t.Errorf("cache was not updated after the files matching the include paths changed")
+	}
+}
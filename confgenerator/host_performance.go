@@ -0,0 +1,47 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"time"
+)
+
+// DiagnosticsHostMetricsReceiver is a metrics receiver that has the diagnostics service collect
+// the Linux host metrics that the OTel collector has no scraper for, i.e. the usage of the
+// ephemeral ports, and optionally the performance metrics: the pressure stall information, the
+// hugepages and the memory of the NUMA nodes.
+type DiagnosticsHostMetricsReceiver interface {
+	MetricsReceiver
+	// DiagnosticsHostMetrics returns how often the host metrics are collected, and whether the
+	// performance metrics are.
+	DiagnosticsHostMetrics() (interval time.Duration, performance bool)
+}
+
+// DiagnosticsHostMetrics returns the DiagnosticsHostMetrics of the first receiver of the metrics
+// pipelines that has them, and whether there is one.
+func (uc *UnifiedConfig) DiagnosticsHostMetrics() (interval time.Duration, performance bool, ok bool) {
+	if uc.Metrics == nil || uc.Metrics.Service == nil {
+		return 0, false, false
+	}
+	for _, id := range sortedKeys(uc.Metrics.Service.Pipelines) {
+		for _, rid := range uc.Metrics.Service.Pipelines[id].ReceiverIDs {
+			if r, ok := uc.Metrics.Receivers[rid].(DiagnosticsHostMetricsReceiver); ok {
+				interval, performance := r.DiagnosticsHostMetrics()
+				return interval, performance, true
+			}
+		}
+	}
+	return 0, false, false
+}
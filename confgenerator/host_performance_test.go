@@ -0,0 +1,64 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiagnosticsHostMetrics(t *testing.T) {
+	uc := mustParseConfig(t, `
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+      collection_interval: 30s
+      performance_metrics: true
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [hostmetrics]
+`)
+	if interval, performance, ok := uc.DiagnosticsHostMetrics(); !ok || interval != 30*time.Second || !performance {
+		t.Errorf("DiagnosticsHostMetrics() = %v, %v, %v, want 30s, true, true", interval, performance, ok)
+	}
+
+	uc = mustParseConfig(t, `
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [hostmetrics]
+`)
+	if interval, performance, ok := uc.DiagnosticsHostMetrics(); !ok || interval != time.Minute || performance {
+		t.Errorf("DiagnosticsHostMetrics() = %v, %v, %v, want 1m0s, false, true", interval, performance, ok)
+	}
+
+	uc = mustParseConfig(t, `
+metrics:
+  receivers:
+    hostmetrics:
+      type: hostmetrics
+  service:
+    pipelines:
+`)
+	if _, _, ok := uc.DiagnosticsHostMetrics(); ok {
+		t.Error("DiagnosticsHostMetrics() found a receiver outside of the pipelines")
+	}
+}
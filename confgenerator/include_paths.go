@@ -0,0 +1,213 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// fileReceiver is implemented by logging receivers that tail files.
+type fileReceiver interface {
+	filePaths() (include []string, exclude []string)
+}
+
+func (r LoggingReceiverFiles) filePaths() ([]string, []string) {
+	return r.IncludePaths, r.ExcludePaths
+}
+
+func (r LoggingReceiverFilesMixin) filePaths() ([]string, []string) {
+	return r.IncludePaths, r.ExcludePaths
+}
+
+// validateOverlappingIncludePaths detects receivers whose include_paths can match the
+// same file, which makes the file get ingested (and billed) once per receiver.
+// Overlaps are logged as warnings, unless failOnOverlap is set.
+func validateOverlappingIncludePaths(receivers loggingReceiverMap, receiverIDs []string, failOnOverlap bool) error {
+	var ids []string
+	for _, id := range receiverIDs {
+		if _, ok := receivers[id].(fileReceiver); ok {
+			ids = append(ids, id)
+		}
+	}
+	for i, id1 := range ids {
+		include1, exclude1 := receivers[id1].(fileReceiver).filePaths()
+		for _, id2 := range ids[i+1:] {
+			include2, exclude2 := receivers[id2].(fileReceiver).filePaths()
+			for _, p1 := range include1 {
+				for _, p2 := range include2 {
+					overlap, ok := globOverlap(p1, p2)
+					if !ok || globExcluded(overlap, exclude1) || globExcluded(overlap, exclude2) {
+						continue
+					}
+					msg := fmt.Sprintf("logging receivers %q and %q have overlapping include_paths: %q and %q both match %q", id1, id2, p1, p2, overlap)
+					if failOnOverlap {
+						return fmt.Errorf("%s.", msg)
+					}
+					log.Printf("%s; matching files will be ingested twice. Use exclude_paths to remove the overlap.", msg)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// globExcluded reports whether the glob is fully covered by one of the exclude patterns.
+// Wildcards in the glob are matched as literal characters, which is exact for the
+// common case of exclude patterns that use the same wildcards as the include pattern.
+func globExcluded(glob string, excludes []string) bool {
+	for _, e := range excludes {
+		if ok, _ := filepath.Match(filepath.ToSlash(e), glob); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globOverlap returns a glob that only matches paths matched by both a and b, and
+// whether there are any such paths.
+// Wildcards never match a path separator, so the patterns are compared one path
+// segment at a time.
+func globOverlap(a, b string) (string, bool) {
+	segmentsA := strings.Split(filepath.ToSlash(a), "/")
+	segmentsB := strings.Split(filepath.ToSlash(b), "/")
+	if len(segmentsA) != len(segmentsB) {
+		return "", false
+	}
+	var out []string
+	for i := range segmentsA {
+		s, ok := segmentOverlap(globTokens(segmentsA[i]), globTokens(segmentsB[i]))
+		if !ok {
+			return "", false
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, "/"), true
+}
+
+// globTokens splits a glob path segment into single characters, "*", "?" and "[...]" classes.
+func globTokens(pattern string) []string {
+	var tokens []string
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		end := i
+		if runes[i] == '[' {
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == ']' {
+					end = j
+					break
+				}
+			}
+		}
+		tokens = append(tokens, string(runes[i:end+1]))
+		i = end
+	}
+	return tokens
+}
+
+// segmentOverlap finds a glob that only matches strings matched by both token sequences.
+// It prefers keeping wildcards, so that the result describes the whole overlap
+// where possible.
+func segmentOverlap(a, b []string) (string, bool) {
+	type state struct{ i, j int }
+	memo := map[state]*string{}
+	prepend := func(prefix string, rest *string) *string {
+		if rest == nil {
+			return nil
+		}
+		if prefix == "*" && strings.HasPrefix(*rest, "*") {
+			return rest
+		}
+		r := prefix + *rest
+		return &r
+	}
+	var walk func(i, j int) *string
+	walk = func(i, j int) *string {
+		if r, ok := memo[state{i, j}]; ok {
+			return r
+		}
+		var result *string
+		starA := i < len(a) && a[i] == "*"
+		starB := j < len(b) && b[j] == "*"
+		switch {
+		case i == len(a) && j == len(b):
+			empty := ""
+			result = &empty
+		case starA && starB:
+			// Whichever star ends first, the other one can keep matching.
+			result = prepend("*", walk(i+1, j))
+			if result == nil {
+				result = prepend("*", walk(i, j+1))
+			}
+		case starA:
+			// The star either absorbs the next token of b or matches nothing.
+			if j < len(b) {
+				result = prepend(b[j], walk(i, j+1))
+			}
+			if result == nil {
+				result = walk(i+1, j)
+			}
+		case starB:
+			if i < len(a) {
+				result = prepend(a[i], walk(i+1, j))
+			}
+			if result == nil {
+				result = walk(i, j+1)
+			}
+		case i < len(a) && j < len(b):
+			if t, ok := tokenOverlap(a[i], b[j]); ok {
+				result = prepend(t, walk(i+1, j+1))
+			}
+		}
+		memo[state{i, j}] = result
+		return result
+	}
+	if r := walk(0, 0); r != nil {
+		return *r, true
+	}
+	return "", false
+}
+
+// tokenOverlap returns a token that only matches characters matched by both
+// single-character tokens a and b.
+func tokenOverlap(a, b string) (string, bool) {
+	classA := len(a) > 1 && strings.HasPrefix(a, "[")
+	classB := len(b) > 1 && strings.HasPrefix(b, "[")
+	switch {
+	case a == "?":
+		return b, true
+	case b == "?":
+		return a, true
+	case !classA && !classB:
+		return a, a == b
+	case !classA:
+		ok, _ := filepath.Match(b, a)
+		return a, ok
+	case !classB:
+		ok, _ := filepath.Match(a, b)
+		return b, ok
+	}
+	// Two character classes; look for a printable character in both.
+	for c := ' '; c <= '~'; c++ {
+		okA, _ := filepath.Match(a, string(c))
+		okB, _ := filepath.Match(b, string(c))
+		if okA && okB {
+			return string(c), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"testing"
+)
+
+func TestGlobOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want string
+		ok   bool
+	}{
+		{a: "/var/log/app.log", b: "/var/log/app.log", want: "/var/log/app.log", ok: true},
+		{a: "/var/log/app.log", b: "/var/log/other.log", ok: false},
+		{a: "/var/log/*.log", b: "/var/log/app.log", want: "/var/log/app.log", ok: true},
+		{a: "/var/log/*.log", b: "/var/log/app-*.log", want: "/var/log/app-*.log", ok: true},
+		{a: "/var/log/*.log", b: "/var/log/*.txt", ok: false},
+		{a: "/var/log/*/app.log", b: "/var/log/nginx/*.log", want: "/var/log/nginx/app.log", ok: true},
+		// Wildcards never match a path separator.
+		{a: "/var/log/*", b: "/var/log/nginx/access.log", ok: false},
+		{a: "/var/log/app?.log", b: "/var/log/app[0-9].log", want: "/var/log/app[0-9].log", ok: true},
+	} {
+		got, ok := globOverlap(tc.a, tc.b)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("globOverlap(%q, %q) = (%q, %v), want (%q, %v)", tc.a, tc.b, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestSegmentOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want string
+		ok   bool
+	}{
+		{a: "app.log", b: "app.log", want: "app.log", ok: true},
+		{a: "app.log", b: "app.txt", ok: false},
+		{a: "*", b: "app.log", want: "app.log", ok: true},
+		{a: "app.log", b: "*", want: "app.log", ok: true},
+		{a: "*", b: "*", want: "*", ok: true},
+		{a: "*.log", b: "app*", want: "app*.log", ok: true},
+		{a: "a*", b: "*b", want: "a*b", ok: true},
+		{a: "*.log", b: "*.txt", ok: false},
+		{a: "app-?.log", b: "app-[ab].log", want: "app-[ab].log", ok: true},
+		{a: "", b: "*", want: "", ok: true},
+		{a: "", b: "?", ok: false},
+	} {
+		got, ok := segmentOverlap(globTokens(tc.a), globTokens(tc.b))
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("segmentOverlap(%q, %q) = (%q, %v), want (%q, %v)", tc.a, tc.b, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestTokenOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want string
+		ok   bool
+	}{
+		{a: "a", b: "a", want: "a", ok: true},
+		{a: "a", b: "b", ok: false},
+		{a: "?", b: "a", want: "a", ok: true},
+		{a: "a", b: "?", want: "a", ok: true},
+		{a: "?", b: "[0-9]", want: "[0-9]", ok: true},
+		{a: "5", b: "[0-9]", want: "5", ok: true},
+		{a: "[0-9]", b: "x", ok: false},
+		{a: "[a-f]", b: "[d-z]", want: "d", ok: true},
+		{a: "[a-c]", b: "[x-z]", ok: false},
+		{a: "[^0-9]", b: "[0-9a]", want: "a", ok: true},
+	} {
+		got, ok := tokenOverlap(tc.a, tc.b)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("tokenOverlap(%q, %q) = (%q, %v), want (%q, %v)", tc.a, tc.b, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/filter"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
+)
+
+// logNameRegex matches the log IDs that Cloud Logging accepts.
+var logNameRegex = regexp.MustCompile(`^[A-Za-z0-9/_.-]{1,512}$`)
+
+// LogRoute sends the logs of a logging pipeline that match a filter to another log name or log
+// bucket than the rest of the logs of the pipeline, e.g. to send the errors to a log that pages
+// someone.
+type LogRoute struct {
+	// Match is a filter in the Logging query language.
+	Match string `yaml:"match" validate:"required,filter"`
+	// LogName replaces the log name of the matching logs.
+	LogName string `yaml:"log_name,omitempty"`
+	// LogBucket replaces the log bucket label of the matching logs, like log_bucket does for the
+	// whole pipeline.
+	LogBucket string `yaml:"log_bucket,omitempty"`
+}
+
+func (r LogRoute) validate(field string) error {
+	if r.LogName == "" && r.LogBucket == "" {
+		return fmt.Errorf(`%q: one of "log_name" and "log_bucket" must be set`, field)
+	}
+	if r.LogName != "" && !logNameRegex.MatchString(r.LogName) {
+		return fmt.Errorf(`%q must be a log name of at most 512 letters, digits, slashes, underscores, periods and hyphens, got %q`, field+".log_name", r.LogName)
+	}
+	return validateLogBucket(field+".log_bucket", r.LogBucket)
+}
+
+// logRoutes are the routes of a logging pipeline. Every log takes the first route that it
+// matches, if any.
+type logRoutes []LogRoute
+
+// fields returns the fields that route r sets, and their values.
+func (r LogRoute) fields() map[string]string {
+	out := map[string]string{}
+	if r.LogName != "" {
+		out["logName"] = r.LogName
+	}
+	if r.LogBucket != "" {
+		out[fmt.Sprintf(`labels.%q`, logBucketLabel)] = r.LogBucket
+	}
+	return out
+}
+
+func (routes logRoutes) Components(ctx context.Context, tag string) ([]fluentbit.Component, error) {
+	filters := map[string]*filter.Filter{}
+	for i, r := range routes {
+		f, err := filter.NewFilter(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse filter %q: %w", r.Match, err)
+		}
+		filters[fmt.Sprintf("route%d", i)] = f
+	}
+	components, matches := filter.AllFluentConfig(tag, filters)
+	var lua strings.Builder
+	lua.WriteString(`
+function process(tag, timestamp, record)
+`)
+	lua.WriteString(matches)
+	for i, r := range routes {
+		if i > 0 {
+			lua.WriteString("else")
+		}
+		fmt.Fprintf(&lua, "if route%d then\n", i)
+		fields := r.fields()
+		for _, field := range sortedKeys(fields) {
+			m, err := filter.NewMember(field)
+			if err != nil {
+				return nil, err
+			}
+			ra, err := m.LuaAccessor(true)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&lua, "%s(%s);\n", ra, filter.LuaQuote(fields[field]))
+		}
+	}
+	lua.WriteString(`else
+  return 0, timestamp, record
+end
+return 2, timestamp, record
+end
+`)
+	return append(components, fluentbit.LuaFilterComponents(tag, "process", lua.String())...), nil
+}
+
+func (routes logRoutes) Processors(ctx context.Context) ([]otel.Component, error) {
+	// Find the route of every log before changing any field, so that the changes of a route
+	// can't affect which route a log takes.
+	route := ottl.LValue{"cache", "__route"}
+	var statements ottl.Statements
+	for i, r := range routes {
+		f, err := filter.NewFilter(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse filter %q: %w", r.Match, err)
+		}
+		expr, err := f.OTTLExpression()
+		if err != nil {
+			return nil, fmt.Errorf("failed to process filter %q: %w", r.Match, err)
+		}
+		statements = statements.Append(route.SetIf(ottl.IntLiteral(i), ottl.And(
+			ottl.Equals(route, ottl.Nil()),
+			expr,
+		)))
+	}
+	for i, r := range routes {
+		fields := r.fields()
+		for _, field := range sortedKeys(fields) {
+			m, err := filter.NewMember(field)
+			if err != nil {
+				return nil, err
+			}
+			accessor, err := m.OTTLAccessor()
+			if err != nil {
+				return nil, err
+			}
+			statements = statements.Append(accessor.SetIf(ottl.StringLiteral(fields[field]), ottl.Equals(route, ottl.IntLiteral(i))))
+		}
+	}
+	return []otel.Component{otel.Transform("log", "log", statements)}, nil
+}
@@ -17,7 +17,9 @@ package confgenerator
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
@@ -28,6 +30,84 @@ import (
 const InstrumentationSourceLabel = `labels."logging.googleapis.com/instrumentation_source"`
 const HttpRequestKey = "logging.googleapis.com/httpRequest"
 
+// The aliases of the stackdriver outputs, which become the pipeline label of the logging self metrics.
+const (
+	userLogsOutputAlias  = "stackdriver.user"
+	agentLogsOutputAlias = "stackdriver.agent"
+	// userCompressedLogsOutputAlias is the output of the user pipelines with "compress: gzip", when
+	// other user pipelines are not compressed.
+	userCompressedLogsOutputAlias = userLogsOutputAlias + ".gzip"
+)
+
+// UserLogsOutputAliases are the aliases of the stackdriver outputs of the user pipelines, which
+// label the Fluent Bit output self metrics with name="<alias>".
+var UserLogsOutputAliases = []string{userLogsOutputAlias, userCompressedLogsOutputAlias}
+
+// logsOutputPipelines maps the aliases of the stackdriver outputs to the pipeline label of the
+// logging self metrics.
+var logsOutputPipelines = map[string]string{
+	userLogsOutputAlias:           "user",
+	userCompressedLogsOutputAlias: "user",
+	agentLogsOutputAlias:          "agent",
+}
+
+// compressionGuidance explains when to compress the logs sent to Cloud Logging.
+const compressionGuidance = `"gzip" spends CPU on every flush to shrink text logs several times over, which pays off for high-volume pipelines on constrained egress; logs that are already compact or binary gain little from it`
+
+// validateCompress validates a compress setting of the logging service or of a pipeline.
+func validateCompress(field, compress string) error {
+	switch compress {
+	case "", "gzip", "none":
+		return nil
+	}
+	return fmt.Errorf(`%q must be one of [gzip none], got %q: %s`, field, compress, compressionGuidance)
+}
+
+// logBucketLabel is the label that holds the log bucket of a logging pipeline.
+const logBucketLabel = "agent.googleapis.com/log_bucket"
+
+// logBucketRegex matches log bucket IDs.
+var logBucketRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,100}$`)
+
+// validateLogBucket validates the log_bucket setting of a pipeline.
+func validateLogBucket(field, bucket string) error {
+	if bucket == "" || logBucketRegex.MatchString(bucket) {
+		return nil
+	}
+	return fmt.Errorf(`%q must be a log bucket ID of at most 100 letters, digits, underscores and hyphens, got %q`, field, bucket)
+}
+
+// logBucketFields sets the log bucket label on log entries. A log sink with the filter
+// labels."agent.googleapis.com/log_bucket"="<bucket>" routes them to the bucket.
+func logBucketFields(bucket string) LoggingProcessorModifyFields {
+	return LoggingProcessorModifyFields{
+		Fields: map[string]*ModifyField{
+			fmt.Sprintf(`labels.%q`, logBucketLabel): {
+				StaticValue: &bucket,
+			},
+		},
+	}
+}
+
+// receiverFields sets the labels and the default severity configured on a logging receiver on
+// its log entries.
+func receiverFields(c ConfigComponent) LoggingProcessorModifyFields {
+	fields := map[string]*ModifyField{}
+	for k, v := range c.Labels {
+		fields[fmt.Sprintf(`labels.%q`, k)] = &ModifyField{
+			StaticValue: &v,
+		}
+	}
+	if c.DefaultSeverity != "" {
+		fields["severity"] = &ModifyField{
+			DefaultValue: &c.DefaultSeverity,
+		}
+	}
+	return LoggingProcessorModifyFields{
+		Fields: fields,
+	}
+}
+
 // setLogNameComponents generates a series of components that rewrites the tag on log entries tagged `tag` to be `logName`.
 func setLogNameComponents(ctx context.Context, tag, logName, receiverType string, hostName string) []fluentbit.Component {
 	return LoggingProcessorModifyFields{
@@ -68,7 +148,8 @@ func otelSetLogNameComponents(ctx context.Context, logName, hostName string) []o
 }
 
 // stackdriverOutputComponent generates a component that outputs logs matching the regex `match` using `userAgent`.
-func stackdriverOutputComponent(ctx context.Context, match, userAgent, storageLimitSize, compress string) fluentbit.Component {
+// The output self metrics are labeled with `alias`, if set.
+func stackdriverOutputComponent(ctx context.Context, match, alias, userAgent, storageLimitSize, compress string) fluentbit.Component {
 	config := map[string]string{
 		// https://docs.fluentbit.io/manual/pipeline/outputs/stackdriver
 		"Name":              "stackdriver",
@@ -88,7 +169,7 @@ func stackdriverOutputComponent(ctx context.Context, match, userAgent, storageLi
 		// Do not force certificate validation.
 		"tls.verify": "Off",
 
-		"workers": "8",
+		"workers": strconv.Itoa(performanceProfileFromContext(ctx).OutputWorkers),
 
 		// Mute these errors until https://github.com/fluent/fluent-bit/issues/4473 is fixed.
 		"net.connect_timeout_log_error": "False",
@@ -104,6 +185,14 @@ func stackdriverOutputComponent(ctx context.Context, match, userAgent, storageLi
 		config["resource_labels"] = strings.Join(labels, ",")
 	}
 
+	if location := loggingLocationFromContext(ctx); location != "" {
+		config["cloud_logging_base_url"] = "https://" + LoggingEndpoint(location)
+	}
+
+	if alias != "" {
+		config["Alias"] = alias
+	}
+
 	if storageLimitSize != "" {
 		// Limit the maximum number of fluent-bit chunks in the filesystem for the current
 		// output logical destination.
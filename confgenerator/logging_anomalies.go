@@ -0,0 +1,145 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/filter"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// anomalyLabel is set to "true" on the logs with an anomalous value.
+const anomalyLabel = "labels.anomaly"
+
+// A LoggingProcessorFlagAnomalies flags the logs whose numeric fields are far from their recent
+// values. Every field keeps an exponentially weighted moving average and standard deviation of
+// its values, and a log is flagged when the z-score of one of its values exceeds the threshold.
+//
+// The statistics are kept in memory for every receiver of the pipeline, and are reset when the
+// agent restarts.
+type LoggingProcessorFlagAnomalies struct {
+	ConfigComponent `yaml:",inline"`
+
+	Fields []string `yaml:"fields" validate:"required,dive,field"`
+	// ZScoreThreshold is how many standard deviations from the average a value must be to be
+	// flagged. Defaults to 3.
+	ZScoreThreshold float64 `yaml:"z_score_threshold,omitempty" validate:"omitempty,gt=0"`
+	// SmoothingFactor is the weight of every new value in the statistics. Higher values adapt
+	// faster to changes. Defaults to 0.05.
+	SmoothingFactor float64 `yaml:"smoothing_factor,omitempty" validate:"omitempty,gt=0,lt=1"`
+	// MinSamples is how many values of a field are needed before its values are flagged.
+	// Defaults to 30.
+	MinSamples int `yaml:"min_samples,omitempty" validate:"omitempty,min=1"`
+}
+
+func (p LoggingProcessorFlagAnomalies) Type() string {
+	return "flag_anomalies"
+}
+
+const flagAnomaliesLuaScript = `
+local stats = {}
+
+-- update adds v to the statistics of field i, and returns whether v is anomalous.
+function update(i, v)
+  local s = stats[i]
+  if s == nil then
+    stats[i] = {n = 1, mean = v, var = 0}
+    return false
+  end
+  local anomalous = false
+  if s.n >= %d and s.var > 0 then
+    anomalous = math.abs(v - s.mean) / math.sqrt(s.var) > %g
+  end
+  local diff = v - s.mean
+  local incr = %g * diff
+  s.mean = s.mean + incr
+  s.var = (1 - %g) * (s.var + diff * incr)
+  s.n = s.n + 1
+  return anomalous
+end
+
+function process(tag, timestamp, record)
+  local anomalous = false
+%s
+  if not anomalous then
+    return 0, timestamp, record
+  end
+  %s("true")
+  return 2, timestamp, record
+end
+`
+
+func (p LoggingProcessorFlagAnomalies) Components(ctx context.Context, tag, uid string) []fluentbit.Component {
+	c, err := p.components(tag)
+	if err != nil {
+		// It shouldn't be possible to get here if the input validation is working, so treat this as a code bug.
+		panic(err)
+	}
+	return c
+}
+
+func (p LoggingProcessorFlagAnomalies) components(tag string) ([]fluentbit.Component, error) {
+	threshold := p.ZScoreThreshold
+	if threshold == 0 {
+		threshold = 3
+	}
+	alpha := p.SmoothingFactor
+	if alpha == 0 {
+		alpha = 0.05
+	}
+	minSamples := p.MinSamples
+	if minSamples == 0 {
+		minSamples = 30
+	}
+	var updates strings.Builder
+	for i, field := range p.Fields {
+		m, err := filter.NewMember(field)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse field %q: %w", field, err)
+		}
+		accessor, err := m.LuaAccessor(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert field %q to Lua accessor: %w", field, err)
+		}
+		fmt.Fprintf(&updates, `  local v%d = tonumber(%s)
+  if v%d ~= nil and update(%d, v%d) then
+    anomalous = true
+  end
+`, i, accessor, i, i, i)
+	}
+	label, err := filter.NewMember(anomalyLabel)
+	if err != nil {
+		return nil, err
+	}
+	setLabel, err := label.LuaAccessor(true)
+	if err != nil {
+		return nil, err
+	}
+	script := fmt.Sprintf(flagAnomaliesLuaScript, minSamples, threshold, alpha, alpha, strings.TrimRight(updates.String(), "\n"), setLabel)
+	return fluentbit.LuaFilterComponents(tag, "process", script), nil
+}
+
+func (p LoggingProcessorFlagAnomalies) Processors(ctx context.Context) ([]otel.Component, error) {
+	return nil, errors.New(`"flag_anomalies" is not supported with the OTel logging backend`)
+}
+
+func init() {
+	LoggingProcessorTypes.RegisterType(func() LoggingProcessor { return &LoggingProcessorFlagAnomalies{} })
+}
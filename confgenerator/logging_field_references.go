@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"log"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/filter"
+)
+
+// isMessageOnlyReceiver returns whether the records of r only contain jsonPayload.message until a
+// parser processor runs. Files receivers with a parser and syslog receivers with a format parse
+// the records themselves.
+func isMessageOnlyReceiver(r LoggingReceiver) bool {
+	switch r := r.(type) {
+	case *LoggingReceiverFiles:
+		return r.Parser == ""
+	case *LoggingReceiverSyslog:
+		return r.Format == ""
+	}
+	return false
+}
+
+// nonParserProcessorTypes are the logging processors that do not parse the record
+// into new jsonPayload fields.
+var nonParserProcessorTypes = map[string]bool{
+	"exclude_logs":    true,
+	"modify_fields":   true,
+	"parse_multiline": true,
+}
+
+// fieldReferencer is implemented by logging processors that read or write fields
+// of the log record.
+type fieldReferencer interface {
+	fieldReferences() (reads []filter.Member, writes []filter.Member)
+}
+
+func (p LoggingProcessorExcludeLogs) fieldReferences() ([]filter.Member, []filter.Member) {
+	filters, err := p.filters()
+	if err != nil {
+		return nil, nil
+	}
+	var reads []filter.Member
+	for _, f := range filters {
+		reads = append(reads, f.Members()...)
+	}
+	return reads, nil
+}
+
+func (p LoggingProcessorModifyFields) fieldReferences() ([]filter.Member, []filter.Member) {
+	var reads, writes []filter.Member
+	for _, dest := range sortedKeys(p.Fields) {
+		if m, err := filter.NewMember(dest); err == nil {
+			writes = append(writes, *m)
+		}
+		field := p.Fields[dest]
+		if field == nil {
+			continue
+		}
+		for _, src := range []string{field.MoveFrom, field.CopyFrom} {
+			if src == "" {
+				continue
+			}
+			if m, err := filter.NewMember(src); err == nil {
+				reads = append(reads, *m)
+			}
+		}
+		if field.OmitIf != "" {
+			if f, err := filter.NewFilter(field.OmitIf); err == nil {
+				reads = append(reads, f.Members()...)
+			}
+		}
+	}
+	return reads, writes
+}
+
+// warnUnparsedFieldReferences warns about processors that reference jsonPayload fields
+// before any parser in the pipeline could have set them. Such processors are silently
+// a no-op, e.g. an exclude_logs processor that comes before the parse_json processor.
+func warnUnparsedFieldReferences(receivers loggingReceiverMap, processors loggingProcessorMap, p *Pipeline, pipelineID string) {
+	if len(p.ReceiverIDs) == 0 {
+		return
+	}
+	for _, rID := range p.ReceiverIDs {
+		r, ok := receivers[rID]
+		if !ok || !isMessageOnlyReceiver(r) {
+			return
+		}
+	}
+	firstParser := ""
+	for _, pID := range p.ProcessorIDs {
+		if proc, ok := processors[pID]; !ok || !nonParserProcessorTypes[proc.Type()] {
+			firstParser = pID
+			break
+		}
+	}
+	present := map[string]bool{"message": true}
+	for _, pID := range p.ProcessorIDs {
+		if pID == firstParser {
+			return
+		}
+		fr, ok := processors[pID].(fieldReferencer)
+		if !ok {
+			continue
+		}
+		reads, writes := fr.fieldReferences()
+		warned := map[string]bool{}
+		for _, m := range reads {
+			name, ok := jsonPayloadField(m)
+			if !ok || present[name] || warned[name] {
+				continue
+			}
+			warned[name] = true
+			if firstParser != "" {
+				log.Printf(`logging processor %q from pipeline %q references %s before it is parsed, so it has no effect. Move %q after processor %q.`, pID, pipelineID, m, pID, firstParser)
+			} else {
+				log.Printf(`logging processor %q from pipeline %q references %s, but its receivers only set jsonPayload.message, so it has no effect. Add a parse_json or parse_regex processor before %q.`, pID, pipelineID, m, pID)
+			}
+		}
+		for _, m := range writes {
+			if name, ok := jsonPayloadField(m); ok {
+				present[name] = true
+			}
+		}
+	}
+}
+
+// jsonPayloadField returns the name of the top-level jsonPayload field that m refers to.
+func jsonPayloadField(m filter.Member) (string, bool) {
+	parts, err := m.Unquote()
+	if err != nil || len(parts) < 2 || parts[0] != "jsonPayload" {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestWarnUnparsedFieldReferences(t *testing.T) {
+	excludeDebug := &LoggingProcessorExcludeLogs{MatchAny: []string{`jsonPayload.level = "debug"`}}
+	for _, tc := range []struct {
+		name       string
+		receiver   LoggingReceiver
+		processors []LoggingProcessor
+		wantWarn   string
+	}{
+		{
+			name:       "files receiver without parser",
+			receiver:   &LoggingReceiverFiles{IncludePaths: []string{"/var/log/app.log"}},
+			processors: []LoggingProcessor{excludeDebug},
+			wantWarn:   `references jsonPayload.level, but its receivers only set jsonPayload.message`,
+		},
+		{
+			name:       "reference before parser",
+			receiver:   &LoggingReceiverFiles{IncludePaths: []string{"/var/log/app.log"}},
+			processors: []LoggingProcessor{excludeDebug, &LoggingProcessorParseJson{}},
+			wantWarn:   `references jsonPayload.level before it is parsed`,
+		},
+		{
+			name:       "reference after parser",
+			receiver:   &LoggingReceiverFiles{IncludePaths: []string{"/var/log/app.log"}},
+			processors: []LoggingProcessor{&LoggingProcessorParseJson{}, excludeDebug},
+		},
+		{
+			name: "files receiver with parser",
+			receiver: &LoggingReceiverFiles{
+				IncludePaths: []string{"/var/log/app.log"},
+				ParserFile:   "/etc/parsers.conf",
+				Parser:       "app",
+			},
+			processors: []LoggingProcessor{excludeDebug},
+		},
+		{
+			name:       "syslog receiver without format",
+			receiver:   &LoggingReceiverSyslog{TransportProtocol: "tcp", ListenHost: "0.0.0.0", ListenPort: 5140},
+			processors: []LoggingProcessor{excludeDebug},
+			wantWarn:   `references jsonPayload.level, but its receivers only set jsonPayload.message`,
+		},
+		{
+			name:       "syslog receiver with format",
+			receiver:   &LoggingReceiverSyslog{TransportProtocol: "tcp", ListenHost: "0.0.0.0", ListenPort: 5140, Format: "rfc5424"},
+			processors: []LoggingProcessor{excludeDebug},
+		},
+		{
+			name:     "field written before it is read",
+			receiver: &LoggingReceiverFiles{IncludePaths: []string{"/var/log/app.log"}},
+			processors: []LoggingProcessor{
+				&LoggingProcessorModifyFields{Fields: map[string]*ModifyField{"jsonPayload.level": {MoveFrom: "jsonPayload.message"}}},
+				excludeDebug,
+			},
+		},
+		{
+			name:     "empty modify_fields entry",
+			receiver: &LoggingReceiverFiles{IncludePaths: []string{"/var/log/app.log"}},
+			processors: []LoggingProcessor{
+				&LoggingProcessorModifyFields{Fields: map[string]*ModifyField{"jsonPayload.level": nil}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			processors := loggingProcessorMap{}
+			p := &Pipeline{ReceiverIDs: []string{"receiver"}}
+			for i, proc := range tc.processors {
+				id := string(rune('a' + i))
+				processors[id] = proc
+				p.ProcessorIDs = append(p.ProcessorIDs, id)
+			}
+			var out bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&out)
+			warnUnparsedFieldReferences(loggingReceiverMap{"receiver": tc.receiver}, processors, p, "pipeline")
+			got := out.String()
+			if tc.wantWarn == "" && got != "" {
+				t.Errorf("got unexpected warning: %s", got)
+			}
+			if tc.wantWarn != "" && !strings.Contains(got, tc.wantWarn) {
+				t.Errorf("got warning %q, want it to contain %q", got, tc.wantWarn)
+			}
+		})
+	}
+}
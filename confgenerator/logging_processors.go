@@ -16,6 +16,7 @@ package confgenerator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -30,7 +31,7 @@ import (
 // TODO: Add a validation check that will allow only one unique language exceptions that focus in one specific language.
 type ParseMultilineGroup struct {
 	Type     string `yaml:"type" validate:"required,oneof=language_exceptions"`
-	Language string `yaml:"language" validate:"required,oneof=java python go"`
+	Language string `yaml:"language" validate:"required,oneof=java python go csharp ruby node javascript php"`
 }
 
 type ParseMultiline struct {
@@ -68,7 +69,32 @@ var multilineRulesLanguageMap = map[string][]string{
 		`"go_goroutine" "/^goroutine \d+ \[[^\]]+\]:$/" "go_frame_1"`,
 		`"go_frame_1" "/^(?:[^\s.:]+\.)*[^\s.():]+\(|^created by /" "go_frame_2"`,
 		`"go_frame_2" "/^\s/" "go_frame_1"`},
-}
+	// .NET exceptions share the "   at " frame format with Java, but use their own inner exception markers.
+	"csharp": {`"start_state, csharp_start_exception" "/^(?:Unhandled [Ee]xception\. )?(?:[\w.]+\.)?\w*(?:Exception|Error)(?: \(0x[0-9A-Fa-f]+\))?:/" "csharp_after_exception"`,
+		`"csharp_after_exception" "/^[\r\n]*$/" "csharp_after_exception"`,
+		`"csharp_after_exception" "/^[\t ]+at /" "csharp_after_exception"`,
+		`"csharp_after_exception" "/^[\t ]*---> (?:[\w.]+\.)?\w*(?:Exception|Error)/" "csharp_after_exception"`,
+		`"csharp_after_exception" "/^[\t ]+--- End of inner exception stack trace ---$/" "csharp_after_exception"`,
+		`"csharp_after_exception" "/^--- End of stack trace from previous (?x:)location(?: where exception was thrown)? ---$/" "csharp_after_exception"`},
+	"ruby": {`"start_state, ruby_start_exception" "/^.+:\d+:in /" "ruby_after_exception"`,
+		`"start_state, ruby_start_exception" "/Error \(.*\):$/" "ruby_before_rails_trace"`,
+		`"ruby_before_rails_trace" "/^  $/" "ruby_after_exception"`,
+		`"ruby_before_rails_trace" "/^[\t ]+.*?\.rb:\d+:in /" "ruby_after_exception"`,
+		`"ruby_after_exception" "/^[\t ]+from .+:\d+:in /" "ruby_after_exception"`,
+		`"ruby_after_exception" "/^[\t ]+.*?\.rb:\d+:in /" "ruby_after_exception"`},
+	"node": nodeMultilineRules,
+	// "javascript" is an alias of "node".
+	"javascript": nodeMultilineRules,
+	"php": {`"start_state, php_start_exception" "/(?:PHP (?:Notice|Parse error|Fatal error|Warning):)|(?:exception '[^']+' with message ')/" "php_stack_begin"`,
+		`"php_stack_begin" "/^Stack trace:/" "php_stack_frames"`,
+		`"php_stack_frames" "/^#\d/" "php_stack_frames"`,
+		`"php_stack_frames" "/^\s+thrown in /" "start_state"`},
+}
+
+var nodeMultilineRules = []string{`"start_state, node_start_exception" "/^(?:Uncaught )?(?:\w+\.)*\w*(?:Error|Exception)(?: \[\w+\])?:|V8 errors stack trace/" "node_after_exception"`,
+	`"node_after_exception" "/^[\t ]+at /" "node_after_exception"`,
+	`"node_after_exception" "/^[\t ]+\.\.\. \d+ lines matching cause stack trace \.\.\./" "node_after_exception"`,
+	`"node_after_exception" "/^[\t ]*\[cause\]: /" "node_after_exception"`}
 
 func (p ParseMultiline) Components(ctx context.Context, tag, uid string) []fluentbit.Component {
 	var components []fluentbit.Component
@@ -201,7 +227,72 @@ type LoggingProcessorParseJson struct {
 	ConfigComponent `yaml:",inline"`
 	ParserShared    `yaml:",inline"`
 	Field           string `yaml:"field,omitempty" validate:"omitempty,fieldlegacy"`
-}
+	// JSONStreaming splits a field that holds a top-level JSON array, or several JSON objects
+	// concatenated on one line, into one log per object before parsing it.
+	JSONStreaming bool `yaml:"json_streaming,omitempty"`
+}
+
+// splitJSONLuaScript splits the field %q into one record per JSON object of a top-level array or
+// of concatenated objects. The objects are found by tracking the nesting outside of strings, and
+// are then parsed by the JSON parser.
+const splitJSONLuaScript = `
+function split_json(tag, timestamp, record)
+  local key = %q
+  local s = record[key]
+  if type(s) ~= "string" then
+    return 0, 0, 0
+  end
+  local array = string.match(s, "^%%s*%%[") ~= nil
+  local base = 0
+  if array then
+    base = 1
+  end
+  local docs = {}
+  local depth = 0
+  local in_string = false
+  local escaped = false
+  local start = nil
+  for i = 1, #s do
+    local c = string.sub(s, i, i)
+    if in_string then
+      if escaped then
+        escaped = false
+      elseif c == "\\" then
+        escaped = true
+      elseif c == '"' then
+        in_string = false
+      end
+    elseif c == '"' then
+      in_string = true
+    elseif c == "{" or c == "[" then
+      if depth == base and c == "{" then
+        start = i
+      end
+      depth = depth + 1
+    elseif c == "}" or c == "]" then
+      depth = depth - 1
+      if depth == base and start ~= nil then
+        table.insert(docs, string.sub(s, start, i))
+        start = nil
+      end
+    end
+  end
+  -- Leave a single object, or anything that isn't objects, to the parser.
+  if #docs == 0 or (#docs == 1 and not array) then
+    return 0, 0, 0
+  end
+  local records = {}
+  for _, doc in ipairs(docs) do
+    local r = {}
+    for k, v in pairs(record) do
+      r[k] = v
+    end
+    r[key] = doc
+    table.insert(records, r)
+  end
+  return 2, timestamp, records
+end
+`
 
 func (r LoggingProcessorParseJson) Type() string {
 	return "parse_json"
@@ -212,12 +303,22 @@ func (p LoggingProcessorParseJson) Components(ctx context.Context, tag, uid stri
 	parser.Config["Format"] = "json"
 
 	parserFilters := []fluentbit.Component{}
+	if p.JSONStreaming {
+		field := p.Field
+		if field == "" {
+			field = "message"
+		}
+		parserFilters = append(parserFilters, fluentbit.LuaFilterComponents(tag, "split_json", fmt.Sprintf(splitJSONLuaScript, field))...)
+	}
 	parserFilters = append(parserFilters, fluentbit.ParserFilterComponents(tag, p.Field, []string{parserName}, false)...)
 	parserFilters = append(parserFilters, parser)
 	return parserFilters
 }
 
 func (p LoggingProcessorParseJson) Processors(ctx context.Context) ([]otel.Component, error) {
+	if p.JSONStreaming {
+		return nil, errors.New(`"json_streaming" is not supported with the OTel logging backend`)
+	}
 	from := p.Field
 	if from == "" {
 		from = "jsonPayload.message"
@@ -16,7 +16,9 @@ package confgenerator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"path"
 	"strconv"
 	"strings"
@@ -27,6 +29,7 @@ import (
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/secret"
 )
 
 // DBPath returns the database path for the given log tag
@@ -36,14 +39,58 @@ func DBPath(tag string) string {
 	return path.Join("${buffers_dir}", dir)
 }
 
+const (
+	// sourceAddressKey is the label of the address of the peer that sent a log.
+	sourceAddressKey = "agent.googleapis.com/source_address"
+	// forwardTagKey is the label of the tag that a log was sent with to the fluent_forward receiver.
+	forwardTagKey = "agent.googleapis.com/forward_tag"
+
+	addForwardTagLuaFunction       = "add_forward_tag"
+	addForwardTagLuaScriptContents = `
+function add_forward_tag(tag, timestamp, record)
+  -- The tag is formatted like <hash_string>.<pipeline_id>.<receiver_id>.<existing_tag>
+  record["agent.googleapis.com/forward_tag"] = string.match(tag, "^[^.]*%.[^.]*%.[^.]*%.(.*)$")
+  return 2, timestamp, record
+end
+`
+)
+
+// sourceMetadataComponents moves the source metadata that an input recorded in key to the label
+// with the same name, like the file path of the files receiver.
+func sourceMetadataComponents(ctx context.Context, tag, key string) []fluentbit.Component {
+	return LoggingProcessorModifyFields{
+		Fields: map[string]*ModifyField{
+			fmt.Sprintf(`labels."%s"`, key): {
+				MoveFrom: fmt.Sprintf(`jsonPayload."%s"`, key),
+			},
+		},
+	}.Components(ctx, tag, "record_source_metadata")
+}
+
 // A LoggingReceiverFiles represents the user configuration for a file receiver (fluentbit's tail plugin).
 type LoggingReceiverFiles struct {
 	ConfigComponent `yaml:",inline"`
 	// TODO: Use LoggingReceiverFilesMixin after figuring out the validation story.
-	IncludePaths            []string       `yaml:"include_paths" validate:"required,min=1"`
+	// IncludePaths are the glob patterns of the files to tail.
+	IncludePaths []string `yaml:"include_paths" validate:"required,min=1"`
+	// ExcludePaths are glob patterns of files matched by IncludePaths that are not tailed,
+	// e.g. /var/log/*.gz to skip rotated archives.
 	ExcludePaths            []string       `yaml:"exclude_paths,omitempty"`
 	WildcardRefreshInterval *time.Duration `yaml:"wildcard_refresh_interval,omitempty" validate:"omitempty,min=1s,multipleof_time=1s"`
-	RecordLogFilePath       *bool          `yaml:"record_log_file_path,omitempty"`
+	// IgnoreOlderThan skips files that were last modified longer ago than this, so that stale
+	// files in large directories are neither scanned nor ingested.
+	IgnoreOlderThan   *time.Duration `yaml:"ignore_older_than,omitempty" validate:"omitempty,min=1s,multipleof_time=1s"`
+	RecordLogFilePath *bool          `yaml:"record_log_file_path,omitempty"`
+	// RecordSourceMetadata is the same as RecordLogFilePath, for consistency with the other receivers.
+	RecordSourceMetadata bool `yaml:"record_source_metadata,omitempty"`
+	TailShards           int  `yaml:"tail_shards,omitempty" validate:"omitempty,min=1,max=32"`
+	// StalenessAlert reports a file that received new lines and then didn't for this long, with an
+	// ops-agent-health entry and the agent/ops_agent/logging/stale_files metric.
+	StalenessAlert *time.Duration `yaml:"staleness_alert,omitempty" validate:"omitempty,min=1m"`
+	// ParserFile is a fluent-bit parsers file maintained outside of the agent, which must define
+	// Parser. The logs are parsed with Parser as they are read.
+	ParserFile string `yaml:"parser_file,omitempty" validate:"required_with=Parser"`
+	Parser     string `yaml:"parser,omitempty" validate:"required_with=ParserFile"`
 }
 
 func (r LoggingReceiverFiles) Type() string {
@@ -51,19 +98,32 @@ func (r LoggingReceiverFiles) Type() string {
 }
 
 func (r LoggingReceiverFiles) mixin() LoggingReceiverFilesMixin {
+	if r.RecordSourceMetadata {
+		r.RecordLogFilePath = &r.RecordSourceMetadata
+	}
 	return LoggingReceiverFilesMixin{
 		IncludePaths:            r.IncludePaths,
 		ExcludePaths:            r.ExcludePaths,
 		WildcardRefreshInterval: r.WildcardRefreshInterval,
+		IgnoreOlderThan:         r.IgnoreOlderThan,
 		RecordLogFilePath:       r.RecordLogFilePath,
+		TailShards:              r.TailShards,
 	}
 }
 
 func (r LoggingReceiverFiles) Components(ctx context.Context, tag string) []fluentbit.Component {
-	return r.mixin().Components(ctx, tag)
+	c := r.mixin().Components(ctx, tag)
+	if r.Parser != "" {
+		// The parsers file is loaded by the SERVICE section.
+		c = append(c, fluentbit.ParserFilterComponents(tag, "", []string{r.Parser}, false)...)
+	}
+	return c
 }
 
 func (r LoggingReceiverFiles) Pipelines(ctx context.Context) ([]otel.ReceiverPipeline, error) {
+	if r.ParserFile != "" {
+		return nil, errors.New(`"parser_file" is not supported with the OTel logging backend`)
+	}
 	return r.mixin().Pipelines(ctx)
 }
 
@@ -71,9 +131,78 @@ type LoggingReceiverFilesMixin struct {
 	IncludePaths            []string        `yaml:"include_paths,omitempty"`
 	ExcludePaths            []string        `yaml:"exclude_paths,omitempty"`
 	WildcardRefreshInterval *time.Duration  `yaml:"wildcard_refresh_interval,omitempty" validate:"omitempty,min=1s,multipleof_time=1s"`
+	IgnoreOlderThan         *time.Duration  `yaml:"ignore_older_than,omitempty" validate:"omitempty,min=1s,multipleof_time=1s"`
 	MultilineRules          []MultilineRule `yaml:"-"`
 	BufferInMemory          bool            `yaml:"-"`
 	RecordLogFilePath       *bool           `yaml:"record_log_file_path,omitempty"`
+	// TailShards splits the files matching IncludePaths across this many tail inputs, so that
+	// they are scanned in parallel. It is chosen automatically on Linux when unset.
+	TailShards int `yaml:"tail_shards,omitempty" validate:"omitempty,min=1,max=32"`
+}
+
+const (
+	// tailShardThreshold is the number of files matching include_paths above which the tail input
+	// is sharded automatically, since a single input scans its files sequentially.
+	tailShardThreshold = 10000
+	defaultTailShards  = 4
+	// tailShardChars are split into the character classes that shard the files by the first
+	// character that include_paths match in their name. Letters match either case.
+	tailShardChars = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+// tailShards returns how many tail inputs the files are split across. Sharding relies on glob
+// character classes, so it is only supported on Linux.
+func (r LoggingReceiverFilesMixin) tailShards(ctx context.Context) int {
+	if platform.FromContext(ctx).Type != platform.Linux {
+		return 1
+	}
+	if r.TailShards > 0 {
+		return r.TailShards
+	}
+	matched := 0
+	for _, p := range r.IncludePaths {
+		m, _ := globInput(ctx, p)
+		matched += len(m)
+		if matched > tailShardThreshold {
+			return defaultTailShards
+		}
+	}
+	return 1
+}
+
+// shardPaths returns the patterns of shard i out of n. The first wildcard of the file name is
+// restricted to a class of characters per shard, e.g. /var/log/*.log becomes
+// /var/log/[012345678]*.log in the first of 4 shards, so that every file is matched by exactly one
+// shard. Patterns without a wildcard in the file name belong to the first shard.
+func shardPaths(paths []string, i, n int) []string {
+	var chars string
+	for j, c := range tailShardChars {
+		if j*n/len(tailShardChars) != i {
+			continue
+		}
+		chars += string(c)
+		if u := strings.ToUpper(string(c)); u != string(c) {
+			chars += u
+		}
+	}
+	var out []string
+	for _, p := range paths {
+		dir, name := path.Split(p)
+		star := strings.Index(name, "*")
+		if star < 0 {
+			if i == 0 {
+				out = append(out, p)
+			}
+			continue
+		}
+		out = append(out, dir+name[:star]+"["+chars+"]"+name[star:])
+		if i == n-1 {
+			// The rest of the characters, and the wildcard matching nothing.
+			out = append(out, dir+name[:star]+"[!0-9a-zA-Z]"+name[star:])
+			out = append(out, dir+name[:star]+name[star+1:])
+		}
+	}
+	return out
 }
 
 func (r LoggingReceiverFilesMixin) Components(ctx context.Context, tag string) []fluentbit.Component {
@@ -81,6 +210,7 @@ func (r LoggingReceiverFilesMixin) Components(ctx context.Context, tag string) [
 		// No files -> no input.
 		return nil
 	}
+	profile := performanceProfileFromContext(ctx)
 	config := map[string]string{
 		// https://docs.fluentbit.io/manual/pipeline/inputs/tail#config
 		"Name": "tail",
@@ -94,9 +224,9 @@ func (r LoggingReceiverFilesMixin) Components(ctx context.Context, tag string) [
 		"DB.locking":     "true",
 		"Read_from_Head": "True",
 		// Set the chunk limit conservatively to avoid exceeding the recommended chunk size of 5MB per write request.
-		"Buffer_Chunk_Size": "512k",
+		"Buffer_Chunk_Size": profile.TailBufferChunkSize,
 		// Set the max size a bit larger to accommodate for long log lines.
-		"Buffer_Max_Size": "2M",
+		"Buffer_Max_Size": profile.TailBufferMaxSize,
 		// When a message is unstructured (no parser applied), append it under a key named "message".
 		"Key": "message",
 		// Increase this to 30 seconds so log rotations are handled more gracefully.
@@ -113,7 +243,18 @@ func (r LoggingReceiverFilesMixin) Components(ctx context.Context, tag string) [
 		// This is used to deal with backpressure scenarios (e.g: cannot flush data for some reason).
 		// When the input plugin hits "mem_buf_limit", because we have enabled filesystem storage type, mem_buf_limit acts
 		// as a hint to set "how much data can be up in memory", once the limit is reached it continues writing to disk.
-		"Mem_Buf_Limit": "10M",
+		"Mem_Buf_Limit": profile.TailMemBufLimit,
+	}
+	if profile.TailStatWatcher && platform.FromContext(ctx).Type == platform.Linux {
+		// https://docs.fluentbit.io/manual/pipeline/inputs/tail#config
+		config["Inotify_Watcher"] = "false"
+	}
+	if platform.FromContext(ctx).Type == platform.Windows {
+		// Windows programs, e.g. PowerShell transcripts and some IIS configurations, write UTF-16
+		// files with a byte order mark, which are read as garbage as UTF-8. Files with a UTF-16
+		// byte order mark are transcoded to UTF-8, and other files are read as UTF-8.
+		// https://docs.fluentbit.io/manual/pipeline/inputs/tail#config
+		config["Unicode.Encoding"] = "auto"
 	}
 	if len(r.ExcludePaths) > 0 {
 		// TODO: Escaping?
@@ -123,6 +264,10 @@ func (r LoggingReceiverFilesMixin) Components(ctx context.Context, tag string) [
 		refreshIntervalSeconds := int(r.WildcardRefreshInterval.Seconds())
 		config["Refresh_Interval"] = strconv.Itoa(refreshIntervalSeconds)
 	}
+	if r.IgnoreOlderThan != nil {
+		// Files that haven't been modified in this long are skipped during the scan.
+		config["Ignore_Older"] = fmt.Sprintf("%ds", int(r.IgnoreOlderThan.Seconds()))
+	}
 
 	if r.RecordLogFilePath != nil && *r.RecordLogFilePath == true {
 		config["Path_Key"] = "agent.googleapis.com/log_file_path"
@@ -161,10 +306,27 @@ func (r LoggingReceiverFilesMixin) Components(ctx context.Context, tag string) [
 		c = append(c, modify.NewRenameOptions("log", "message").Component(tag))
 	}
 
-	c = append(c, fluentbit.Component{
-		Kind:   "INPUT",
-		Config: config,
-	})
+	n := r.tailShards(ctx)
+	for i := 0; i < n; i++ {
+		shard := config
+		if n > 1 {
+			paths := shardPaths(r.IncludePaths, i, n)
+			if len(paths) == 0 {
+				continue
+			}
+			shard = maps.Clone(config)
+			shard["Path"] = strings.Join(paths, ",")
+			if i > 0 {
+				// The first shard keeps the DB of the unsharded input, so that it doesn't re-read
+				// its files when sharding starts.
+				shard["DB"] = fmt.Sprintf("%s_shard%d", DBPath(tag), i)
+			}
+		}
+		c = append(c, fluentbit.Component{
+			Kind:   "INPUT",
+			Config: shard,
+		})
+	}
 
 	return c
 }
@@ -180,6 +342,9 @@ func (r LoggingReceiverFilesMixin) Pipelines(ctx context.Context) ([]otel.Receiv
 	if i := r.WildcardRefreshInterval; i != nil {
 		receiver_config["poll_interval"] = i.String()
 	}
+	if i := r.IgnoreOlderThan; i != nil {
+		receiver_config["exclude_older_than"] = i.String()
+	}
 	// TODO: Configure `storage` to store file checkpoints
 	// TODO: Configure multiline rules
 	// TODO: Support BufferInMemory
@@ -225,6 +390,184 @@ type LoggingReceiverSyslog struct {
 	TransportProtocol string `yaml:"transport_protocol,omitempty" validate:"oneof=tcp udp"`
 	ListenHost        string `yaml:"listen_host,omitempty" validate:"required,ip"`
 	ListenPort        uint16 `yaml:"listen_port,omitempty" validate:"required"`
+	// RecordSourceMetadata records the address of the sender of each log in the
+	// "agent.googleapis.com/source_address" label.
+	RecordSourceMetadata bool `yaml:"record_source_metadata,omitempty"`
+	// Format parses the syslog header of the messages, as "rfc3164", "rfc5424" or "auto" to try
+	// both. The messages are not parsed if unset.
+	Format string `yaml:"format,omitempty" validate:"omitempty,oneof=rfc3164 rfc5424 auto"`
+	// Framing is how messages are delimited. With "non_transparent" (the default), every
+	// message ends with a newline or NUL trailer. With "octet_counting", every message is prefixed
+	// with its length as in RFC 6587, and the length is removed from the message. Frames that were
+	// received together are split by their lengths, but they must still be followed by a trailer
+	// before they are read.
+	Framing string `yaml:"framing,omitempty" validate:"omitempty,oneof=non_transparent octet_counting"`
+	// MaxMessageSize is the size of the longest message that is accepted, in kilobytes. Longer
+	// messages are truncated or dropped. Defaults to 32.
+	MaxMessageSize int `yaml:"max_message_size_kilobytes,omitempty" validate:"omitempty,min=1,max=65536"`
+}
+
+const (
+	syslogRFC5424Regex = `^\<(?<pri>[0-9]{1,3})\>1 (?<time>[^ ]+) (?<host>[^ ]+) (?<ident>[^ ]+) (?<pid>[^ ]+) (?<msgid>[^ ]+) (?<structured_data>-|(?:\[.*?\])+)(?: (?<message>.*))?$`
+	syslogRFC3164Regex = `^\<(?<pri>[0-9]{1,3})\>(?<time>[^ ]* {1,2}[^ ]* [^ ]*) (?<host>[^ ]*) (?<ident>[a-zA-Z0-9_\/\.\-]*)(?:\[(?<pid>[0-9]+)\])?(?:[^\:]*\:)? *(?<message>.*)$`
+
+	syslogHeaderLuaFunction = "syslog_header"
+	// syslogHeaderLuaScript maps the priority of a parsed message to its facility and severity, and
+	// the structured data of RFC 5424 messages to a map from SD-ID to parameters.
+	syslogHeaderLuaScript = `
+local facilities = {
+  "kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+  "uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+  "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+local severities = {
+  "EMERGENCY", "ALERT", "CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO", "DEBUG",
+}
+
+function parse_structured_data(sd)
+  local out = {}
+  local i = 1
+  local n = #sd
+  while string.sub(sd, i, i) == "[" do
+    local id_end = string.find(sd, "[ %]]", i + 1)
+    if id_end == nil then
+      return out
+    end
+    local id = string.sub(sd, i + 1, id_end - 1)
+    local params = {}
+    i = id_end
+    while string.sub(sd, i, i) == " " do
+      local eq = string.find(sd, "=", i + 1, true)
+      if eq == nil or string.sub(sd, eq + 1, eq + 1) ~= '"' then
+        return out
+      end
+      local name = string.sub(sd, i + 1, eq - 1)
+      local value = {}
+      local j = eq + 2
+      while j <= n do
+        local c = string.sub(sd, j, j)
+        local following = string.sub(sd, j + 1, j + 1)
+        if c == "\\" and (following == '"' or following == "\\" or following == "]") then
+          table.insert(value, following)
+          j = j + 2
+        elseif c == '"' then
+          break
+        else
+          table.insert(value, c)
+          j = j + 1
+        end
+      end
+      params[name] = table.concat(value)
+      i = j + 1
+    end
+    if string.sub(sd, i, i) ~= "]" then
+      return out
+    end
+    out[id] = params
+    i = i + 1
+  end
+  return out
+end
+
+function syslog_header(tag, timestamp, record)
+  local pri = tonumber(record["pri"])
+  if pri == nil then
+    return 0, 0, 0
+  end
+  record["pri"] = nil
+  record["facility"] = facilities[math.floor(pri / 8) + 1]
+  record["logging.googleapis.com/severity"] = severities[(pri % 8) + 1]
+  local sd = record["structured_data"]
+  if sd == "-" then
+    record["structured_data"] = nil
+  elseif type(sd) == "string" then
+    record["structured_data"] = parse_structured_data(sd)
+  end
+  for _, k in ipairs({"host", "ident", "pid", "msgid"}) do
+    if record[k] == "-" then
+      record[k] = nil
+    end
+  end
+  return 2, timestamp, record
+end
+`
+)
+
+const (
+	syslogOctetCountingLuaFunction = "syslog_octet_counting"
+	// syslogOctetCountingLuaScript removes the lengths of RFC 6587 octet-counted frames from the
+	// messages, and splits the messages that hold several frames.
+	syslogOctetCountingLuaScript = `
+function syslog_octet_counting(tag, timestamp, record)
+  local message = record["message"]
+  if type(message) ~= "string" then
+    return 0, timestamp, record
+  end
+  local records = {}
+  local pos = 1
+  while pos <= #message do
+    local length = string.match(message, "^(%d+) ", pos)
+    if length == nil then
+      break
+    end
+    local start = pos + #length + 1
+    local frame = {}
+    for k, v in pairs(record) do
+      frame[k] = v
+    end
+    frame["message"] = string.sub(message, start, start + tonumber(length) - 1)
+    table.insert(records, frame)
+    pos = start + tonumber(length)
+    -- Skip the trailer of frames that were sent with both a length and a trailer.
+    while string.sub(message, pos, pos) == "\n" or string.sub(message, pos, pos) == "\0" do
+      pos = pos + 1
+    end
+  end
+  if #records == 0 then
+    return 0, timestamp, record
+  end
+  if pos <= #message then
+    -- Keep what follows the last frame, rather than dropping it.
+    local rest = {}
+    for k, v in pairs(record) do
+      rest[k] = v
+    end
+    rest["message"] = string.sub(message, pos)
+    table.insert(records, rest)
+  end
+  return 2, timestamp, records
+end
+`
+)
+
+// headerComponents parses the syslog header of the messages according to r.Format.
+func (r LoggingReceiverSyslog) headerComponents(ctx context.Context, tag string) []fluentbit.Component {
+	rfc5424 := RegexParser{
+		Regex: syslogRFC5424Regex,
+		Parser: ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%Y-%m-%dT%H:%M:%S.%L%z",
+		},
+	}
+	rfc3164 := RegexParser{
+		Regex: syslogRFC3164Regex,
+		Parser: ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%b %d %H:%M:%S",
+		},
+	}
+	var parsers []RegexParser
+	switch r.Format {
+	case "rfc5424":
+		parsers = []RegexParser{rfc5424}
+	case "rfc3164":
+		parsers = []RegexParser{rfc3164}
+	default:
+		parsers = []RegexParser{rfc5424, rfc3164}
+	}
+	c := LoggingProcessorParseRegexComplex{Parsers: parsers}.Components(ctx, tag, "syslog_header")
+	c = append(c, fluentbit.LuaFilterComponents(tag, syslogHeaderLuaFunction, syslogHeaderLuaScript)...)
+	return c
 }
 
 func (r LoggingReceiverSyslog) Type() string {
@@ -235,8 +578,12 @@ func (r LoggingReceiverSyslog) GetListenPort() uint16 {
 	return r.ListenPort
 }
 
+func (r LoggingReceiverSyslog) ListenAddress() (string, string, uint16) {
+	return r.TransportProtocol, r.ListenHost, r.GetListenPort()
+}
+
 func (r LoggingReceiverSyslog) Components(ctx context.Context, tag string) []fluentbit.Component {
-	return []fluentbit.Component{{
+	c := []fluentbit.Component{{
 		Kind: "INPUT",
 		Config: map[string]string{
 			// https://docs.fluentbit.io/manual/pipeline/inputs/syslog
@@ -266,19 +613,71 @@ func (r LoggingReceiverSyslog) Components(ctx context.Context, tag string) []flu
 			"Regex":  `^(?<message>.*)$`,
 		},
 	}}
+	if r.MaxMessageSize != 0 {
+		size := fmt.Sprintf("%dk", r.MaxMessageSize)
+		// UDP messages are read into a single chunk, while TCP connections grow their buffer by
+		// chunks up to the max size.
+		c[0].Config["Buffer_Max_Size"] = size
+		if r.TransportProtocol == "udp" || r.MaxMessageSize < 32 {
+			c[0].Config["Buffer_Chunk_Size"] = size
+		}
+	}
+	if r.Framing == "octet_counting" {
+		c = append(c, fluentbit.LuaFilterComponents(tag, syslogOctetCountingLuaFunction, syslogOctetCountingLuaScript)...)
+	}
+	if r.Format != "" {
+		c = append(c, r.headerComponents(ctx, tag)...)
+	}
+	if r.RecordSourceMetadata {
+		c[0].Config["Source_Address_Key"] = sourceAddressKey
+		c = append(c, sourceMetadataComponents(ctx, tag, sourceAddressKey)...)
+	}
+	return c
 }
 
 func init() {
 	LoggingReceiverTypes.RegisterType(func() LoggingReceiver { return &LoggingReceiverSyslog{} })
 }
 
+// LoggingReceiverListenerMixin holds the concurrency and buffer settings of the receivers that
+// accept connections from other processes.
+type LoggingReceiverListenerMixin struct {
+	// Workers reads the connections on a dedicated thread, instead of the thread that also runs
+	// the filters and outputs of Fluent Bit. Fluent Bit reads each port from a single thread, so
+	// only 1 is supported.
+	Workers int `yaml:"workers,omitempty" validate:"omitempty,min=1,max=1"`
+	// BufferChunkSize is how much the buffer of a connection grows by at a time, e.g. "512k".
+	BufferChunkSize string `yaml:"buffer_chunk_size,omitempty" validate:"omitempty,size"`
+	// BufferMaxSize is the size of the largest message that a connection can send, e.g. "2M".
+	BufferMaxSize string `yaml:"buffer_max_size,omitempty" validate:"omitempty,size"`
+}
+
+// apply sets the settings of r on the config of a Fluent Bit input, whose buffer sizes are
+// named chunkSizeKey and maxSizeKey.
+func (r LoggingReceiverListenerMixin) apply(config map[string]string, chunkSizeKey, maxSizeKey string) {
+	if r.Workers > 0 {
+		config["threaded"] = "true"
+	}
+	// Some inputs read the sizes as a number of kilobytes, so always use that unit.
+	if kb, err := sizeKilobytes(r.BufferChunkSize); err == nil {
+		config[chunkSizeKey] = fmt.Sprintf("%dk", kb)
+	}
+	if kb, err := sizeKilobytes(r.BufferMaxSize); err == nil {
+		config[maxSizeKey] = fmt.Sprintf("%dk", kb)
+	}
+}
+
 // A LoggingReceiverTCP represents the configuration for a TCP receiver.
 type LoggingReceiverTCP struct {
-	ConfigComponent `yaml:",inline"`
+	ConfigComponent              `yaml:",inline"`
+	LoggingReceiverListenerMixin `yaml:",inline"`
 
 	Format     string `yaml:"format,omitempty" validate:"required,oneof=json"`
 	ListenHost string `yaml:"listen_host,omitempty" validate:"omitempty,ip"`
 	ListenPort uint16 `yaml:"listen_port,omitempty"`
+	// RecordSourceMetadata records the address of the sender of each log in the
+	// "agent.googleapis.com/source_address" label.
+	RecordSourceMetadata bool `yaml:"record_source_metadata,omitempty"`
 }
 
 func (r LoggingReceiverTCP) Type() string {
@@ -292,18 +691,25 @@ func (r LoggingReceiverTCP) GetListenPort() uint16 {
 	return r.ListenPort
 }
 
-func (r LoggingReceiverTCP) Components(ctx context.Context, tag string) []fluentbit.Component {
+func (r LoggingReceiverTCP) GetListenHost() string {
 	if r.ListenHost == "" {
 		r.ListenHost = "127.0.0.1"
 	}
+	return r.ListenHost
+}
+
+func (r LoggingReceiverTCP) ListenAddress() (string, string, uint16) {
+	return "tcp", r.GetListenHost(), r.GetListenPort()
+}
 
-	return []fluentbit.Component{{
+func (r LoggingReceiverTCP) Components(ctx context.Context, tag string) []fluentbit.Component {
+	c := []fluentbit.Component{{
 		Kind: "INPUT",
 		Config: map[string]string{
 			// https://docs.fluentbit.io/manual/pipeline/inputs/tcp
 			"Name":   "tcp",
 			"Tag":    tag,
-			"Listen": r.ListenHost,
+			"Listen": r.GetListenHost(),
 			"Port":   fmt.Sprintf("%d", r.GetListenPort()),
 			"Format": r.Format,
 			// https://docs.fluentbit.io/manual/administration/buffering-and-storage#input-section-configuration
@@ -322,6 +728,12 @@ func (r LoggingReceiverTCP) Components(ctx context.Context, tag string) []fluent
 			"Chunk_Size": "512k",
 		},
 	}}
+	r.apply(c[0].Config, "Chunk_Size", "Buffer_Size")
+	if r.RecordSourceMetadata {
+		c[0].Config["Source_Address_Key"] = sourceAddressKey
+		c = append(c, sourceMetadataComponents(ctx, tag, sourceAddressKey)...)
+	}
+	return c
 }
 
 func init() {
@@ -330,10 +742,28 @@ func init() {
 
 // A LoggingReceiverFluentForward represents the configuration for a Forward Protocol receiver.
 type LoggingReceiverFluentForward struct {
-	ConfigComponent `yaml:",inline"`
+	ConfigComponent              `yaml:",inline"`
+	LoggingReceiverListenerMixin `yaml:",inline"`
 
 	ListenHost string `yaml:"listen_host,omitempty" validate:"omitempty,ip"`
 	ListenPort uint16 `yaml:"listen_port,omitempty"`
+	// RecordSourceMetadata records the tag that each log was sent with in the
+	// "agent.googleapis.com/forward_tag" label.
+	RecordSourceMetadata bool `yaml:"record_source_metadata,omitempty"`
+	// SharedKey only accepts the forwarders that authenticate with this key in the handshake of
+	// the forward protocol.
+	SharedKey secret.String `yaml:"shared_key,omitempty"`
+	// TLS accepts the connections over TLS.
+	TLS *LoggingReceiverFluentForwardTLS `yaml:"tls,omitempty"`
+}
+
+type LoggingReceiverFluentForwardTLS struct {
+	// CertFile and KeyFile are the certificate and private key of the receiver.
+	CertFile string `yaml:"cert_file" validate:"required"`
+	KeyFile  string `yaml:"key_file" validate:"required"`
+	// ClientCAFile only accepts the forwarders with a client certificate signed by this CA.
+	// Any client is accepted if unset.
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
 }
 
 func (r LoggingReceiverFluentForward) Type() string {
@@ -347,18 +777,25 @@ func (r LoggingReceiverFluentForward) GetListenPort() uint16 {
 	return r.ListenPort
 }
 
-func (r LoggingReceiverFluentForward) Components(ctx context.Context, tag string) []fluentbit.Component {
+func (r LoggingReceiverFluentForward) GetListenHost() string {
 	if r.ListenHost == "" {
 		r.ListenHost = "127.0.0.1"
 	}
+	return r.ListenHost
+}
+
+func (r LoggingReceiverFluentForward) ListenAddress() (string, string, uint16) {
+	return "tcp", r.GetListenHost(), r.GetListenPort()
+}
 
-	return []fluentbit.Component{{
+func (r LoggingReceiverFluentForward) Components(ctx context.Context, tag string) []fluentbit.Component {
+	c := []fluentbit.Component{{
 		Kind: "INPUT",
 		Config: map[string]string{
 			// https://docs.fluentbit.io/manual/pipeline/inputs/forward
 			"Name":       "forward",
 			"Tag_Prefix": tag + ".",
-			"Listen":     r.ListenHost,
+			"Listen":     r.GetListenHost(),
 			"Port":       fmt.Sprintf("%d", r.GetListenPort()),
 			// https://docs.fluentbit.io/manual/administration/buffering-and-storage#input-section-configuration
 			// Buffer in disk to improve reliability.
@@ -372,6 +809,27 @@ func (r LoggingReceiverFluentForward) Components(ctx context.Context, tag string
 			"Mem_Buf_Limit": "10M",
 		},
 	}}
+	r.apply(c[0].Config, "Buffer_Chunk_Size", "Buffer_Max_Size")
+	if key := r.SharedKey.SecretValue(); key != "" {
+		c[0].Config["Shared_Key"] = key
+		c[0].Config["Self_Hostname"] = "google-cloud-ops-agent"
+	}
+	if r.TLS != nil {
+		c[0].Config["tls"] = "On"
+		c[0].Config["tls.crt_file"] = r.TLS.CertFile
+		c[0].Config["tls.key_file"] = r.TLS.KeyFile
+		c[0].Config["tls.verify"] = "Off"
+		if r.TLS.ClientCAFile != "" {
+			c[0].Config["tls.verify"] = "On"
+			c[0].Config["tls.ca_file"] = r.TLS.ClientCAFile
+		}
+	}
+	if r.RecordSourceMetadata {
+		// The records are tagged with the tag they were sent with after Tag_Prefix.
+		c = append(c, fluentbit.LuaFilterComponents(tag+".*", addForwardTagLuaFunction, addForwardTagLuaScriptContents)...)
+		c = append(c, sourceMetadataComponents(ctx, tag+".*", forwardTagKey)...)
+	}
+	return c
 }
 
 func init() {
@@ -385,6 +843,10 @@ type LoggingReceiverWindowsEventLog struct {
 	Channels        []string `yaml:"channels,omitempty,flow" validate:"required,winlogchannels"`
 	ReceiverVersion string   `yaml:"receiver_version,omitempty" validate:"omitempty,oneof=1 2" tracking:""`
 	RenderAsXML     bool     `yaml:"render_as_xml,omitempty" tracking:""`
+	// ReplayOnFirstStart reads the events from this far back when the receiver starts for the
+	// first time. After that, the receiver resumes from the bookmark stored in its DB, so the
+	// events that happen while the agent is stopped are still collected.
+	ReplayOnFirstStart *time.Duration `yaml:"replay_on_first_start,omitempty" validate:"omitempty,min=1s"`
 }
 
 const eventLogV2SeverityParserLua = `
@@ -449,6 +911,13 @@ func (r LoggingReceiverWindowsEventLog) Components(ctx context.Context, tag stri
 		input[0].Config["Use_ANSI"] = "True"
 	}
 
+	if r.ReplayOnFirstStart != nil {
+		// Existing events are only read when there is no bookmark yet, and the query limits them to
+		// the replay window. New events always pass the query.
+		input[0].Config["Read_Existing_Events"] = "True"
+		input[0].Config["Event_Query"] = fmt.Sprintf("*[System[TimeCreated[timediff(@SystemTime) <= %d]]]", r.ReplayOnFirstStart.Milliseconds())
+	}
+
 	if r.RenderAsXML {
 		input[0].Config["Render_Event_As_XML"] = "True"
 		// By default, fluent-bit puts the rendered XML into a field named "System"
@@ -509,14 +978,15 @@ func (r LoggingReceiverWindowsEventLog) Pipelines(ctx context.Context) ([]otel.R
 			// TODO: Rename to `jsonPayload.raw_xml`
 		}
 		var p []otel.Component
+		var err error
 		if r.IsDefaultVersion() {
-			var err error
 			p, err = windowsEventLogV1Processors(ctx)
-			if err != nil {
-				return nil, err
-			}
+		} else {
+			p, err = windowsEventLogV2Processors(ctx)
+		}
+		if err != nil {
+			return nil, err
 		}
-		// TODO: Add processors for fluent-bit's V2 format.
 		out = append(out, otel.ReceiverPipeline{
 			Receiver: otel.Component{
 				Type:   "windowseventlog",
@@ -606,6 +1076,74 @@ func windowsEventLogV1Processors(ctx context.Context) ([]otel.Component, error)
 	return p.Processors(ctx)
 }
 
+func windowsEventLogV2Processors(ctx context.Context) ([]otel.Component, error) {
+	// Convert the OTel format into the format of fluent-bit's winevtlog input.
+	var empty string
+	levels := map[string]string{
+		"Critical":    "1",
+		"Error":       "2",
+		"Warning":     "3",
+		"Information": "4",
+		"Verbose":     "5",
+	}
+	p := &LoggingProcessorModifyFields{
+		EmptyBody: true,
+		Fields: map[string]*ModifyField{
+			"jsonPayload.ActivityID": {
+				CopyFrom:     "jsonPayload.correlation.activity_id",
+				DefaultValue: &empty,
+			},
+			"jsonPayload.Channel":       {CopyFrom: "jsonPayload.channel"},
+			"jsonPayload.Computer":      {CopyFrom: "jsonPayload.computer"},
+			"jsonPayload.EventID":       {CopyFrom: "jsonPayload.event_id.id"},
+			"jsonPayload.EventRecordID": {CopyFrom: "jsonPayload.record_id"},
+			// TODO: fluent-bit renders keywords as a hex bitmask, but OTel provides their names.
+			"jsonPayload.Keywords": {CopyFrom: "jsonPayload.keywords"},
+			// Level is an integer in fluent-bit, but OTel only provides its rendered name.
+			"jsonPayload.Level": {
+				CopyFrom:           "jsonPayload.level",
+				MapValues:          levels,
+				MapValuesExclusive: true,
+				Type:               "integer",
+			},
+			// TODO: Fix OTel receiver to provide raw non-parsed messages.
+			"jsonPayload.Message": {CopyFrom: "jsonPayload.message"},
+			// TODO: OTel puts the human-readable opcode and task at jsonPayload.opcode and jsonPayload.task, but fluent-bit has the integer versions.
+			"jsonPayload.Opcode":       {CopyFrom: "jsonPayload.opcode"},
+			"jsonPayload.ProcessID":    {CopyFrom: "jsonPayload.execution.process_id"},
+			"jsonPayload.ProviderGuid": {CopyFrom: "jsonPayload.provider.guid"},
+			"jsonPayload.ProviderName": {CopyFrom: "jsonPayload.provider.name"},
+			"jsonPayload.Qualifiers":   {CopyFrom: "jsonPayload.event_id.qualifiers"},
+			"jsonPayload.RelatedActivityID": {
+				CopyFrom:     "jsonPayload.correlation.related_activity_id",
+				DefaultValue: &empty,
+			},
+			// TODO: Convert from array of maps to array of strings
+			"jsonPayload.StringInserts": {CopyFrom: "jsonPayload.event_data.data"},
+			"jsonPayload.Task":          {CopyFrom: "jsonPayload.task"},
+			"jsonPayload.ThreadID":      {CopyFrom: "jsonPayload.execution.thread_id"},
+			// TODO: Reformat? (v2 was "YYYY-MM-DD hh:mm:ss +0000", OTel is "YYYY-MM-DDThh:mm:ssZ")
+			"jsonPayload.TimeCreated": {CopyFrom: "jsonPayload.system_time"},
+			"jsonPayload.UserID": {
+				CopyFrom:     "jsonPayload.security.user_id",
+				DefaultValue: &empty,
+			},
+			// Matches eventLogV2SeverityParserLua.
+			"severity": {
+				CopyFrom: "jsonPayload.level",
+				MapValues: map[string]string{
+					"Critical":    "CRITICAL",
+					"Error":       "ERROR",
+					"Warning":     "WARNING",
+					"Information": "INFO",
+					"Verbose":     "NOTICE",
+				},
+				MapValuesExclusive: true,
+			},
+		}}
+	return p.Processors(ctx)
+}
+
 func init() {
 	LoggingReceiverTypes.RegisterType(func() LoggingReceiver { return &LoggingReceiverWindowsEventLog{} }, platform.Windows)
 }
@@ -613,8 +1151,25 @@ func init() {
 // A LoggingReceiverSystemd represents the user configuration for a Systemd/journald receiver.
 type LoggingReceiverSystemd struct {
 	ConfigComponent `yaml:",inline"`
+
+	// Replay is how far back the receiver reads the journal when it has no cursor yet, e.g. on the
+	// first start after install. The receiver starts at the end of the journal when it is unset.
+	Replay string `yaml:"replay,omitempty" validate:"omitempty,duration=1s"` // time.Duration format
 }
 
+// journaldReplayLua drops the journal entries that are older than the replay window. The window is
+// computed once when fluent-bit starts, so entries read from a persisted cursor are only dropped
+// if the agent was stopped for longer than the window.
+const journaldReplayLua = `
+local cutoff = os.time() - %d
+function journald_replay(tag, timestamp, record)
+    if timestamp < cutoff then
+        return -1, timestamp, record
+    end
+    return 0, timestamp, record
+end
+`
+
 func (r LoggingReceiverSystemd) Type() string {
 	return "systemd_journald"
 }
@@ -626,9 +1181,19 @@ func (r LoggingReceiverSystemd) Components(ctx context.Context, tag string) []fl
 			// https://docs.fluentbit.io/manual/pipeline/inputs/systemd
 			"Name": "systemd",
 			"Tag":  tag,
-			"DB":   DBPath(tag),
+			// The cursor is persisted in the DB under the state directory, and synced on every
+			// update so that restarts neither skip nor duplicate entries.
+			"DB":      DBPath(tag),
+			"DB.Sync": "Full",
+			// Without a cursor, start at the end of the journal instead of replaying all of it.
+			"Read_From_Tail": "On",
 		},
 	}}
+	if r.Replay != "" {
+		replay, _ := time.ParseDuration(r.Replay)
+		input[0].Config["Read_From_Tail"] = "Off"
+		input = append(input, fluentbit.LuaFilterComponents(tag, "journald_replay", fmt.Sprintf(journaldReplayLua, int64(replay.Seconds())))...)
+	}
 	filters := fluentbit.TranslationComponents(tag, "PRIORITY", "logging.googleapis.com/severity", false,
 		[]struct{ SrcVal, DestVal string }{
 			{"7", "DEBUG"},
@@ -685,3 +1250,95 @@ func (r LoggingReceiverSystemd) Components(ctx context.Context, tag string) []fl
 func init() {
 	LoggingReceiverTypes.RegisterType(func() LoggingReceiver { return &LoggingReceiverSystemd{} }, platform.Linux)
 }
+
+// A LoggingReceiverProcessEvents represents the user configuration for a receiver of process exec
+// and exit events. The events are collected through the kernel proc connector by the diagnostics
+// service, which writes them to a file.
+type LoggingReceiverProcessEvents struct {
+	ConfigComponent `yaml:",inline"`
+
+	// IncludeNames are regular expressions for the names of the processes to report. All processes
+	// are reported when it is empty.
+	IncludeNames []string `yaml:"include_names,omitempty" validate:"dive,regexp"`
+	// ExcludeNames are regular expressions for the names of the processes not to report.
+	ExcludeNames []string `yaml:"exclude_names,omitempty" validate:"dive,regexp"`
+	// MaxEventsPerSecond is the maximum number of events reported per second.
+	MaxEventsPerSecond int `yaml:"max_events_per_second,omitempty" validate:"omitempty,min=1"`
+}
+
+const defaultProcessEventsPerSecond = 100
+
+func (r LoggingReceiverProcessEvents) Type() string {
+	return "process_events"
+}
+
+// ProcessEventsLogsPath returns the path of the file process events are written to.
+func ProcessEventsLogsPath(logsDir string) string {
+	return path.Join(logsDir, "process-events.log")
+}
+
+func (r LoggingReceiverProcessEvents) EventsPerSecond() int {
+	if r.MaxEventsPerSecond == 0 {
+		return defaultProcessEventsPerSecond
+	}
+	return r.MaxEventsPerSecond
+}
+
+func (r LoggingReceiverProcessEvents) Components(ctx context.Context, tag string) []fluentbit.Component {
+	c := LoggingReceiverFilesMixin{
+		IncludePaths: []string{ProcessEventsLogsPath("${logs_dir}")},
+	}.Components(ctx, tag)
+	c = append(c, LoggingProcessorParseJson{
+		ParserShared: ParserShared{
+			TimeKey:    "time",
+			TimeFormat: "%Y-%m-%dT%H:%M:%S.%L%z",
+		},
+	}.Components(ctx, tag, "process_events")...)
+
+	// The diagnostics service writes the events wanted by any process_events receiver, so each
+	// receiver applies its own filters and rate limit again.
+	if len(r.IncludeNames) > 0 {
+		var patterns []string
+		for _, p := range r.IncludeNames {
+			patterns = append(patterns, fmt.Sprintf("(?:%s)", p))
+		}
+		c = append(c, fluentbit.Component{
+			Kind: "FILTER",
+			Config: map[string]string{
+				"Name":  "grep",
+				"Match": tag,
+				"Regex": fmt.Sprintf("name %s", strings.Join(patterns, "|")),
+			},
+		})
+	}
+	if len(r.ExcludeNames) > 0 {
+		exclude := fluentbit.Component{
+			Kind: "FILTER",
+			OrderedConfig: [][2]string{
+				{"Name", "grep"},
+				{"Match", tag},
+			},
+		}
+		for _, p := range r.ExcludeNames {
+			exclude.OrderedConfig = append(exclude.OrderedConfig, [2]string{"Exclude", fmt.Sprintf("name %s", p)})
+		}
+		c = append(c, exclude)
+	}
+	c = append(c, fluentbit.Component{
+		Kind: "FILTER",
+		Config: map[string]string{
+			// https://docs.fluentbit.io/manual/pipeline/filters/throttle
+			"Name":         "throttle",
+			"Match":        tag,
+			"Rate":         strconv.Itoa(r.EventsPerSecond()),
+			"Window":       "5",
+			"Interval":     "1s",
+			"Print_Status": "false",
+		},
+	})
+	return c
+}
+
+func init() {
+	LoggingReceiverTypes.RegisterType(func() LoggingReceiver { return &LoggingReceiverProcessEvents{} }, platform.Linux)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/filter"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// logTemplateLabel holds the ID of the template of a log.
+const logTemplateLabel = "labels.log_template_id"
+
+// A LoggingProcessorLabelLogTemplate labels the logs with the ID of the template of their
+// message, so that the logs of noisy unstructured applications can be grouped by template. The
+// template is the message with the UUIDs, hexadecimal values and numbers masked, so that e.g.
+// "took 15ms for user 42" and "took 230ms for user 7" have the same template ID.
+type LoggingProcessorLabelLogTemplate struct {
+	ConfigComponent `yaml:",inline"`
+
+	// Field is the field with the message. Defaults to jsonPayload.message.
+	Field string `yaml:"field,omitempty" validate:"omitempty,field"`
+}
+
+func (p LoggingProcessorLabelLogTemplate) Type() string {
+	return "label_log_template"
+}
+
+// logTemplateUUIDPattern is the Lua pattern of a UUID.
+var logTemplateUUIDPattern = strings.Join([]string{
+	strings.Repeat("%x", 8),
+	strings.Repeat("%x", 4),
+	strings.Repeat("%x", 4),
+	strings.Repeat("%x", 4),
+	strings.Repeat("%x", 12),
+}, "%-")
+
+const labelLogTemplateLuaScript = `
+-- mask_hex masks the words of at least 8 hexadecimal digits, like hashes and IDs.
+function mask_hex(word)
+  if #word >= 8 and string.find(word, "%%d") then
+    return "<hex>"
+  end
+end
+
+function process(tag, timestamp, record)
+  local message = %s
+  if type(message) ~= "string" then
+    return 0, timestamp, record
+  end
+  local template = string.gsub(message, "%s", "<uuid>")
+  template = string.gsub(template, "0[xX]%%x+", "<hex>")
+  template = string.gsub(template, "%%x+", mask_hex)
+  template = string.gsub(template, "%%d+", "<num>")
+  -- djb2, kept within 32 bits so that the arithmetic stays exact.
+  local hash = 5381
+  for i = 1, #template do
+    hash = (hash * 33 + string.byte(template, i)) %% 4294967296
+  end
+  %s(string.format("%%08x", hash))
+  return 2, timestamp, record
+end
+`
+
+func (p LoggingProcessorLabelLogTemplate) Components(ctx context.Context, tag, uid string) []fluentbit.Component {
+	c, err := p.components(tag)
+	if err != nil {
+		// It shouldn't be possible to get here if the input validation is working, so treat this as a code bug.
+		panic(err)
+	}
+	return c
+}
+
+func (p LoggingProcessorLabelLogTemplate) components(tag string) ([]fluentbit.Component, error) {
+	field := p.Field
+	if field == "" {
+		field = "jsonPayload.message"
+	}
+	m, err := filter.NewMember(field)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse field %q: %w", field, err)
+	}
+	accessor, err := m.LuaAccessor(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert field %q to Lua accessor: %w", field, err)
+	}
+	label, err := filter.NewMember(logTemplateLabel)
+	if err != nil {
+		return nil, err
+	}
+	setLabel, err := label.LuaAccessor(true)
+	if err != nil {
+		return nil, err
+	}
+	script := fmt.Sprintf(labelLogTemplateLuaScript, accessor, logTemplateUUIDPattern, setLabel)
+	return fluentbit.LuaFilterComponents(tag, "process", script), nil
+}
+
+func (p LoggingProcessorLabelLogTemplate) Processors(ctx context.Context) ([]otel.Component, error) {
+	return nil, errors.New(`"label_log_template" is not supported with the OTel logging backend`)
+}
+
+func init() {
+	LoggingProcessorTypes.RegisterType(func() LoggingProcessor { return &LoggingProcessorLabelLogTemplate{} })
+}
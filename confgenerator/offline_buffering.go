@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+// OfflineBuffering is for agents that are expected to be disconnected from Google Cloud for long
+// periods, e.g. on ships or air-gapped sites that connect once a day.
+//
+// The logs are kept in the buffers of the logging subagent on disk until they are sent or older
+// than Retention, instead of being dropped after 3 retries. The diagnostics service deletes the
+// expired chunks and reports them in the BufferedLogsDropped log and the
+// agent/ops_agent/logging/dropped_buffered_bytes metric.
+//
+// Metrics are out of scope: the metrics subagent only keeps them in memory, so they keep the
+// retries of metrics.service.retry and are lost when the subagent restarts.
+type OfflineBuffering struct {
+	// Retention is how long the logs are kept while they can't be sent.
+	Retention *time.Duration `yaml:"retention" validate:"required,min=1h,multipleof_time=1m"`
+	// MaxBufferSizeMiB is the disk space of the logs buffer. Once it is full, the oldest logs are
+	// dropped. Defaults to 2048.
+	MaxBufferSizeMiB int `yaml:"max_buffer_size_mib,omitempty" validate:"omitempty,min=100"`
+	// ReplayBackoff is the maximum backoff between the retries of the buffered logs once the agent
+	// reconnects. With "short", they are retried within a minute, along with the new logs. With
+	// "long", they are retried with a backoff of up to 30 minutes, so that the retries take less of
+	// the bandwidth of the new logs. Fluent Bit doesn't order the chunks, so neither sends the
+	// new logs strictly first. Defaults to "short".
+	ReplayBackoff string `yaml:"replay_backoff,omitempty" validate:"omitempty,oneof=short long"`
+}
+
+func (o *OfflineBuffering) GetReplayBackoff() string {
+	if o.ReplayBackoff != "" {
+		return o.ReplayBackoff
+	}
+	return "short"
+}
+
+// storageLimitSize returns the storage.total_limit_size of the Cloud Logging outputs.
+func (o *OfflineBuffering) storageLimitSize() string {
+	if o.MaxBufferSizeMiB > 0 {
+		return fmt.Sprintf("%dM", o.MaxBufferSizeMiB)
+	}
+	return "2G"
+}
+
+// withService returns the Fluent Bit service section with the retry scheduler of the replay
+// backoff.
+// https://docs.fluentbit.io/manual/administration/scheduling-and-retries
+func (o *OfflineBuffering) withService(service fluentbit.Component) fluentbit.Component {
+	service.Config["scheduler.base"] = "5"
+	service.Config["scheduler.cap"] = "60"
+	if o.GetReplayBackoff() == "long" {
+		service.Config["scheduler.cap"] = "1800"
+	}
+	return service
+}
+
+// withOutput returns the Cloud Logging output that retries the chunks until the diagnostics
+// service deletes them after the retention.
+func (o *OfflineBuffering) withOutput(output fluentbit.Component) fluentbit.Component {
+	output.Config["Retry_Limit"] = "no_limits"
+	output.Config["storage.total_limit_size"] = o.storageLimitSize()
+	return output
+}
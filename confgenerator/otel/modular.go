@@ -74,6 +74,11 @@ type Pipeline struct {
 	Type                 string
 	ReceiverPipelineName string
 	Processors           []Component
+	// Exporter, if set, is used instead of the exporter of the receiver pipeline's ExporterType.
+	Exporter *Component
+	// SharedExporter names Exporter after its type only, so that all the pipelines with an
+	// Exporter of that type share one instance of it.
+	SharedExporter bool
 }
 
 // Component represents a single OT component (receiver, processor, exporter, etc.)
@@ -114,6 +119,13 @@ type ModularConfig struct {
 	Pipelines         map[string]Pipeline
 
 	Exporters map[ExporterType]Component
+	// MemoryLimiter, if set, is a memory_limiter processor at the start of every pipeline.
+	MemoryLimiter *Component
+	// Batch, if set, is a batch processor at the end of every pipeline.
+	Batch *Component
+	// ResourceAttributes, if set, is a processor at the end of every metrics pipeline that exports
+	// workload metrics with the OTel exporter.
+	ResourceAttributes *Component
 
 	// Test-only options:
 	// Don't generate any self-metrics
@@ -205,29 +217,54 @@ func (c ModularConfig) Generate(ctx context.Context) (string, error) {
 
 		// Everything else in the pipeline is specific to this Type.
 		var processorNames []string
+		if c.MemoryLimiter != nil {
+			name := c.MemoryLimiter.name("_global_4")
+			processorNames = append(processorNames, name)
+			processors[name] = c.MemoryLimiter.Config
+		}
 		processorNames = append(processorNames, receiverProcessorNames...)
 		for i, processor := range pipeline.Processors {
 			name := processor.name(fmt.Sprintf("%s_%d", prefix, i))
 			processorNames = append(processorNames, name)
 			processors[name] = processor.Config
 		}
+		exporterType := receiverPipeline.ExporterTypes[pipeline.Type]
+		if c.ResourceAttributes != nil && pipeline.Type == "metrics" && exporterType == OTel {
+			name := c.ResourceAttributes.name("_global_3")
+			processorNames = append(processorNames, name)
+			processors[name] = c.ResourceAttributes.Config
+		}
 		rdm := receiverPipeline.ResourceDetectionModes[pipeline.Type]
 		if name, ok := resourceDetectionProcessorNames[rdm]; ok {
 			processorNames = append(processorNames, name)
 			processors[name] = resourceDetectionProcessors[rdm].Config
 		}
-		exporterType := receiverPipeline.ExporterTypes[pipeline.Type]
-		if _, ok := exporterNames[exporterType]; !ok {
-			exporter := c.Exporters[exporterType]
-			name := exporter.name(exporterType.Name())
-			exporterNames[exporterType] = name
-			exporters[name] = exporter.Config
+		if c.Batch != nil {
+			name := c.Batch.name("_global_2")
+			processorNames = append(processorNames, name)
+			processors[name] = c.Batch.Config
+		}
+		var exporterName string
+		if pipeline.Exporter != nil {
+			exporterName = pipeline.Exporter.name(prefix)
+			if pipeline.SharedExporter {
+				exporterName = pipeline.Exporter.name("")
+			}
+			exporters[exporterName] = pipeline.Exporter.Config
+		} else {
+			if _, ok := exporterNames[exporterType]; !ok {
+				exporter := c.Exporters[exporterType]
+				name := exporter.name(exporterType.Name())
+				exporterNames[exporterType] = name
+				exporters[name] = exporter.Config
+			}
+			exporterName = exporterNames[exporterType]
 		}
 
 		pipelines[pipeline.Type+"/"+prefix] = map[string]interface{}{
 			"receivers":  []string{receiverName},
 			"processors": processorNames,
-			"exporters":  []string{exporterNames[exporterType]},
+			"exporters":  []string{exporterName},
 		}
 	}
 
@@ -150,6 +150,15 @@ func ToString(a Value) Value {
 	return valuef(`Concat([%s], "")`, a)
 }
 
+// Concat joins the string representations of values with delimiter.
+func Concat(values []Value, delimiter string) Value {
+	var out []string
+	for _, v := range values {
+		out = append(out, v.String())
+	}
+	return valuef(`Concat([%s], %q)`, strings.Join(out, ", "), delimiter)
+}
+
 func ToInt(a Value) Value {
 	return valuef(`Int(%s)`, a)
 }
@@ -245,6 +254,11 @@ func (a LValue) DeleteIf(cond Value) Statements {
 	}
 }
 
+// ReplacePattern replaces the parts of a that match the regular expression pattern with replacement.
+func (a LValue) ReplacePattern(pattern, replacement string) Statements {
+	return statementsf(`replace_pattern(%s, %q, %q)`, a, pattern, replacement)
+}
+
 func (a LValue) KeepKeys(keys ...string) Statements {
 	var quotedKeys []string
 	for _, k := range keys {
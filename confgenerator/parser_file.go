@@ -0,0 +1,82 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ReadParserFile reads the fluent-bit parsers files that files receivers refer to with
+// parser_file. Tests replace it to read the files from testdata.
+var ReadParserFile = os.ReadFile
+
+// parserFileNames returns the names of the parsers defined by a fluent-bit parsers file.
+func parserFileNames(contents []byte) []string {
+	var names []string
+	var inParser bool
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section := strings.ToUpper(line)
+			inParser = section == "[PARSER]" || section == "[MULTILINE_PARSER]"
+			continue
+		}
+		fields := strings.Fields(line)
+		if inParser && len(fields) == 2 && strings.EqualFold(fields[0], "Name") {
+			names = append(names, fields[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateParserFile checks that the parsers file at path exists and defines parser.
+func validateParserFile(path, parser string) error {
+	contents, err := ReadParserFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read parser_file %q: %w", path, err)
+	}
+	names := parserFileNames(contents)
+	for _, name := range names {
+		if name == parser {
+			return nil
+		}
+	}
+	return fmt.Errorf("parser_file %q does not define a parser named %q. Defined parsers: [%s]", path, parser, strings.Join(names, ", "))
+}
+
+// parserFiles returns the parsers files referenced by the files receivers of l. The generated
+// config only refers to the files by path, but it is only valid while they define the parsers that
+// the receivers use, so they are recorded as inputs of the generation.
+func (l *Logging) parserFiles(ctx context.Context) []string {
+	files := map[string]bool{}
+	for _, r := range l.Receivers {
+		if f, ok := r.(*LoggingReceiverFiles); ok && f.ParserFile != "" {
+			files[f.ParserFile] = true
+		}
+	}
+	paths := sortedKeys(files)
+	for _, path := range paths {
+		_, _ = ReadInputFile(ctx, path)
+	}
+	return paths
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParserFilesAreGenerationInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "parsers.conf")
+	if err := os.WriteFile(path, []byte("[PARSER]\n    Name app\n    Format json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := &Logging{Receivers: loggingReceiverMap{
+		"app": &LoggingReceiverFiles{IncludePaths: []string{"/var/log/app.log"}, ParserFile: path, Parser: "app"},
+	}}
+	inputs := &generationInputs{}
+	l.parserFiles(contextWithGenerationInputs(context.Background(), inputs))
+	if len(inputs.inputs) != 1 || inputs.inputs[0].Name != path {
+		t.Fatalf("got inputs %+v, want the parsers file %q", inputs.inputs, path)
+	}
+	input := inputs.inputs[0]
+	if got := inputDigest(input.Kind, input.Name); got != input.Digest {
+		t.Errorf("digest of the unchanged parsers file = %q, want %q", got, input.Digest)
+	}
+	if err := os.WriteFile(path, []byte("[PARSER]\n    Name other\n    Format json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := inputDigest(input.Kind, input.Name); got == input.Digest {
+		t.Errorf("digest of the changed parsers file is unchanged")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// performanceProfile is a bundle of subagent settings selected with global.performance_profile.
+type performanceProfile struct {
+	// MinCPUs is the smallest number of CPUs the profile can run on.
+	MinCPUs int
+
+	// OutputWorkers is the number of workers of the fluent-bit stackdriver outputs.
+	OutputWorkers int
+	// The buffer settings of the fluent-bit tail inputs.
+	TailBufferChunkSize string
+	TailBufferMaxSize   string
+	TailMemBufLimit     string
+	// TailStatWatcher makes the fluent-bit tail inputs poll files instead of using inotify.
+	TailStatWatcher bool
+
+	// ExporterQueue is the sending_queue of the OTel exporters. If nil, the exporter defaults are used.
+	ExporterQueue map[string]interface{}
+	// Batch is the config of a batch processor at the end of every OTel pipeline. If nil, there is no batch processor.
+	Batch map[string]interface{}
+	// GoMaxProcs limits the number of CPUs used by the OTel collector. If zero, all CPUs are used.
+	GoMaxProcs int
+}
+
+var performanceProfiles = map[string]performanceProfile{
+	"low": {
+		OutputWorkers:       2,
+		TailBufferChunkSize: "128k",
+		TailBufferMaxSize:   "1M",
+		TailMemBufLimit:     "5M",
+		ExporterQueue: map[string]interface{}{
+			"num_consumers": 2,
+			"queue_size":    500,
+		},
+		// Fewer, larger requests use less CPU at the cost of latency.
+		Batch: map[string]interface{}{
+			"send_batch_size": 200,
+			"timeout":         "10s",
+		},
+		GoMaxProcs: 1,
+	},
+	// The default profile must keep the settings of configs without a profile.
+	"default": {
+		OutputWorkers:       8,
+		TailBufferChunkSize: "512k",
+		TailBufferMaxSize:   "2M",
+		TailMemBufLimit:     "10M",
+	},
+	"high_throughput": {
+		MinCPUs:       4,
+		OutputWorkers: 16,
+		// Stay well under the recommended chunk size of 5MB per write request.
+		TailBufferChunkSize: "1M",
+		TailBufferMaxSize:   "4M",
+		TailMemBufLimit:     "50M",
+		// Tailing many files can exhaust the inotify watch and instance limits.
+		TailStatWatcher: true,
+		ExporterQueue: map[string]interface{}{
+			"num_consumers": 20,
+			"queue_size":    5000,
+		},
+		Batch: map[string]interface{}{
+			"send_batch_size":     1000,
+			"send_batch_max_size": 2000,
+			"timeout":             "200ms",
+		},
+	},
+}
+
+type performanceProfileKeyType struct{}
+
+var performanceProfileKey = performanceProfileKeyType{}
+
+func contextWithPerformanceProfile(ctx context.Context, g *Global) context.Context {
+	return context.WithValue(ctx, performanceProfileKey, performanceProfiles[g.GetPerformanceProfile()])
+}
+
+func performanceProfileFromContext(ctx context.Context) performanceProfile {
+	if p := ctx.Value(performanceProfileKey); p != nil {
+		return p.(performanceProfile)
+	}
+	return performanceProfiles["default"]
+}
+
+// withExporterQueue returns exporter with the sending_queue of the profile.
+func (p performanceProfile) withExporterQueue(exporter otel.Component) otel.Component {
+	if p.ExporterQueue == nil {
+		return exporter
+	}
+	config := map[string]interface{}{}
+	for k, v := range exporter.Config.(map[string]interface{}) {
+		config[k] = v
+	}
+	config["sending_queue"] = p.ExporterQueue
+	return otel.Component{Type: exporter.Type, Config: config}
+}
+
+// batchProcessor returns the batch processor of the profile with the settings of overrides, if
+// any.
+func (p performanceProfile) batchProcessor(overrides *MetricsBatch) *otel.Component {
+	if p.Batch == nil && overrides == nil {
+		return nil
+	}
+	config := map[string]interface{}{}
+	for k, v := range p.Batch {
+		config[k] = v
+	}
+	if overrides != nil {
+		if overrides.SendBatchSize > 0 {
+			config["send_batch_size"] = overrides.SendBatchSize
+		}
+		if overrides.SendBatchMaxSize > 0 {
+			config["send_batch_max_size"] = overrides.SendBatchMaxSize
+		}
+		if overrides.Timeout != "" {
+			config["timeout"] = overrides.Timeout
+		}
+	}
+	return &otel.Component{Type: "batch", Config: config}
+}
+
+// memoryLimiterProcessor returns the memory_limiter processor of l, if any.
+func memoryLimiterProcessor(l *MetricsMemoryLimiter) *otel.Component {
+	if l == nil {
+		return nil
+	}
+	config := map[string]interface{}{
+		"check_interval": "1s",
+		"limit_mib":      l.LimitMiB,
+	}
+	if l.SpikeLimitMiB > 0 {
+		config["spike_limit_mib"] = l.SpikeLimitMiB
+	}
+	return &otel.Component{Type: "memory_limiter", Config: config}
+}
+
+const (
+	defaultRetryInitialInterval = 5 * time.Second
+	defaultRetryMaxInterval     = 30 * time.Second
+)
+
+func (r *MetricsRetry) initialInterval() time.Duration {
+	if d, err := time.ParseDuration(r.InitialInterval); err == nil {
+		return d
+	}
+	return defaultRetryInitialInterval
+}
+
+func (r *MetricsRetry) maxInterval() time.Duration {
+	if d, err := time.ParseDuration(r.MaxInterval); err == nil {
+		return d
+	}
+	return max(defaultRetryMaxInterval, r.initialInterval())
+}
+
+// maxElapsedTime returns how long the exporters retry a request. The exporters have no limit on
+// the number of retries, so the limit is the sum of the backoffs of RetryLimit retries. Half of
+// the initial interval is added for the time spent on the requests, which is less than the
+// backoff of another retry.
+func (r *MetricsRetry) maxElapsedTime() time.Duration {
+	backoff, total := r.initialInterval(), time.Duration(0)
+	for i := 0; i < *r.RetryLimit; i++ {
+		total += min(backoff, r.maxInterval())
+		backoff *= 2
+	}
+	return total + r.initialInterval()/2
+}
+
+// withRetry returns exporter with the retry_on_failure of r. If r is nil, the exporter defaults
+// are used.
+func withRetry(exporter otel.Component, r *MetricsRetry) otel.Component {
+	if r == nil {
+		return exporter
+	}
+	config := map[string]interface{}{}
+	for k, v := range exporter.Config.(map[string]interface{}) {
+		config[k] = v
+	}
+	if *r.RetryLimit == 0 {
+		config["retry_on_failure"] = map[string]interface{}{"enabled": false}
+	} else {
+		config["retry_on_failure"] = map[string]interface{}{
+			"enabled":          true,
+			"initial_interval": r.initialInterval().String(),
+			"max_interval":     r.maxInterval().String(),
+			"max_elapsed_time": r.maxElapsedTime().String(),
+			// Without randomization, the backoffs and so the number of retries are predictable.
+			"multiplier":           2,
+			"randomization_factor": 0,
+		}
+	}
+	return otel.Component{Type: exporter.Type, Config: config}
+}
+
+// validatePerformanceProfile checks that the profile fits the machine the agent runs on.
+func (g *Global) validatePerformanceProfile(p platform.Platform) error {
+	name := g.GetPerformanceProfile()
+	profile := performanceProfiles[name]
+	if p.NumCPU > 0 && p.NumCPU < profile.MinCPUs {
+		return fmt.Errorf(`global.performance_profile %q requires at least %d CPUs, but this machine has %d. Use "default" or "low" instead`, name, profile.MinCPUs, p.NumCPU)
+	}
+	return nil
+}
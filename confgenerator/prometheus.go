@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
 	"github.com/go-playground/validator/v10"
 	yaml "github.com/goccy/go-yaml"
@@ -39,6 +40,14 @@ import (
 
 const minScrapeInterval = model.Duration(10 * time.Second)
 
+// ScrapeStatusPort is the local port on which the collector exposes the scrape status of the
+// targets of the prometheus receivers, i.e. their up series, for the diagnostics service.
+const ScrapeStatusPort = 20204
+
+// scrapeStatusMetrics are the series that the prometheus receiver reports for every target.
+// scrape_sample_limit is only reported for the receivers with limits.
+var scrapeStatusMetrics = []string{"up", "scrape_samples_scraped", "scrape_samples_post_metric_relabeling", "scrape_sample_limit"}
+
 type PrometheusMetrics struct {
 	ConfigComponent `yaml:",inline"`
 
@@ -51,6 +60,28 @@ type PrometheusMetrics struct {
 	// variables.  If you want to use $ characters in your prometheus configuration,
 	// you must escape them using `$$`.
 	PromConfig promconfig.Config `yaml:"config"`
+
+	// SampleLimit, LabelLimit and LabelValueLengthLimit guard against runaway exporters exhausting
+	// the Cloud Monitoring quota. They apply to every scrape config that doesn't set its own limit.
+	// A scrape that exceeds a limit is dropped as a whole and its target is reported as down.
+	SampleLimit           uint `yaml:"sample_limit,omitempty"`
+	LabelLimit            uint `yaml:"label_limit,omitempty"`
+	LabelValueLengthLimit uint `yaml:"label_value_length_limit,omitempty"`
+
+	// UntypedHandling controls how untyped metrics are written to Google Cloud Managed Service
+	// for Prometheus. Metric names never get Prometheus-style suffixes like _total; instead the
+	// exporter appends the metric kind, e.g. prometheus.googleapis.com/<name>/gauge.
+	// With "unknown", the default, every untyped metric is double-written as both a
+	// prometheus.googleapis.com/<name>/unknown gauge and a
+	// prometheus.googleapis.com/<name>/unknown:counter cumulative, so it can be queried either way.
+	// With "gauge", untyped metrics are only written as prometheus.googleapis.com/<name>/gauge.
+	// That halves their number of time series and keeps the names that older versions of the
+	// agent wrote, which saved MQL queries may rely on.
+	UntypedHandling string `yaml:"untyped_handling,omitempty" validate:"omitempty,oneof=unknown gauge"`
+}
+
+func (r PrometheusMetrics) hasLimits() bool {
+	return r.SampleLimit > 0 || r.LabelLimit > 0 || r.LabelValueLengthLimit > 0
 }
 
 func (r PrometheusMetrics) Type() string {
@@ -90,10 +121,11 @@ func (r PrometheusMetrics) Pipelines(ctx context.Context) ([]otel.ReceiverPipeli
 	return []otel.ReceiverPipeline{{
 		Receiver: prometheusToOtelComponent(r),
 		Processors: map[string][]otel.Component{
-			// Expect metrics, without any additional processing.
-			"metrics": {
-				otel.GroupByGMPAttrs_OTTL(),
-			},
+			// Expect metrics, without any additional processing besides untyped_handling.
+			// This includes the up, scrape_duration_seconds and scrape_samples_scraped series
+			// that the receiver reports for every target, so that failing scrapes are visible
+			// in Cloud Monitoring.
+			"metrics": r.processors(),
 		},
 		ExporterTypes: map[string]otel.ExporterType{
 			"metrics": otel.GMP,
@@ -116,6 +148,18 @@ func prometheusToOtelComponent(m PrometheusMetrics) otel.Component {
 		panic(fmt.Errorf("failed to deep copy prometheus config: %w", err))
 	}
 
+	for _, sc := range copyPromConfig.ScrapeConfigs {
+		if sc.SampleLimit == 0 {
+			sc.SampleLimit = m.SampleLimit
+		}
+		if sc.LabelLimit == 0 {
+			sc.LabelLimit = m.LabelLimit
+		}
+		if sc.LabelValueLengthLimit == 0 {
+			sc.LabelValueLengthLimit = m.LabelValueLengthLimit
+		}
+	}
+
 	// Escape the $ characters in the regexes.
 	for i := range copyPromConfig.ScrapeConfigs {
 		for j := range copyPromConfig.ScrapeConfigs[i].RelabelConfigs {
@@ -128,12 +172,56 @@ func prometheusToOtelComponent(m PrometheusMetrics) otel.Component {
 		}
 	}
 
+	config := map[string]interface{}{"config": copyPromConfig}
+	if m.hasLimits() {
+		// Report the limits per target as scrape_sample_limit etc, so that truncated scrapes
+		// can be told apart from unreachable targets.
+		config["report_extra_scrape_metrics"] = true
+	}
 	return otel.Component{
 		Type:   "prometheus",
-		Config: map[string]interface{}{"config": copyPromConfig},
+		Config: config,
 	}
 }
 
+// scrapeStatusPipeline returns a pipeline that exposes the scrape status series of the receiver
+// pipeline on ScrapeStatusPort.
+func scrapeStatusPipeline(receiverPipelineName string) otel.Pipeline {
+	return otel.Pipeline{
+		Type:                 "metrics",
+		ReceiverPipelineName: receiverPipelineName,
+		Processors: []otel.Component{
+			otel.MetricsFilter("include", "strict", scrapeStatusMetrics...),
+		},
+		Exporter: &otel.Component{
+			Type: "prometheus",
+			Config: map[string]interface{}{
+				"endpoint": fmt.Sprintf("127.0.0.1:%d", ScrapeStatusPort),
+				// Forget the targets that are no longer scraped, e.g. after a config change.
+				"metric_expiration": "5m",
+			},
+		},
+		SharedExporter: true,
+	}
+}
+
+// processors returns the processors of the metrics pipeline, which only apply untyped_handling
+// besides grouping the GMP attributes.
+func (r PrometheusMetrics) processors() []otel.Component {
+	processors := []otel.Component{
+		otel.GroupByGMPAttrs_OTTL(),
+	}
+	if r.UntypedHandling == "gauge" {
+		// The exporter only double-writes metrics that the receiver marked as untyped;
+		// without the mark they are exported as plain gauges.
+		promType := ottl.LValue{"metadata", "prometheus.type"}
+		processors = append(processors, otel.Transform("metric", "metric",
+			promType.DeleteIf(ottl.Equals(promType, ottl.StringLiteral("unknown"))),
+		))
+	}
+	return processors
+}
+
 func deepCopy(config promconfig.Config) (promconfig.Config, error) {
 	marshalledBytes, err := yaml.MarshalWithOptions(
 		config,
@@ -286,6 +374,27 @@ func (r PrometheusMetrics) ExtractFeatures() ([]CustomFeature, error) {
 		Value: "true",
 	})
 
+	if r.UntypedHandling != "" {
+		customFeatures = append(customFeatures, CustomFeature{
+			Key:   []string{"untyped_handling"},
+			Value: r.UntypedHandling,
+		})
+	}
+
+	for _, limit := range [][2]string{
+		{"sample_limit", fmt.Sprintf("%d", r.SampleLimit)},
+		{"label_limit", fmt.Sprintf("%d", r.LabelLimit)},
+		{"label_value_length_limit", fmt.Sprintf("%d", r.LabelValueLengthLimit)},
+	} {
+		if limit[1] == "0" {
+			continue
+		}
+		customFeatures = append(customFeatures, CustomFeature{
+			Key:   []string{limit[0]},
+			Value: limit[1],
+		})
+	}
+
 	for i := range r.PromConfig.ScrapeConfigs {
 		sc := r.PromConfig.ScrapeConfigs[i]
 
@@ -334,6 +443,10 @@ func (r PrometheusMetrics) ExtractFeatures() ([]CustomFeature, error) {
 func (r PrometheusMetrics) ListAllFeatures() []string {
 	return []string{
 		"confgenerator.ConfigComponent.Type",
+		"sample_limit",
+		"label_limit",
+		"label_value_length_limit",
+		"untyped_handling",
 		"config.[].scrape_configs.scheme",
 		// The Ops Agent doesn't support honor_labels, so we don't need to track it.
 		// "config.[].scrape_configs.honor_labels",
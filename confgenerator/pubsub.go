@@ -0,0 +1,294 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// PubSubForwardPort is the port that Fluent Bit forwards the logs of the pipelines with a pubsub
+// exporter to, so that the OpenTelemetry Collector can publish them. It only listens on 127.0.0.1,
+// next to the other local ports of the agent: otel.MetricsPort (20201), fluentbit.MetricsPort
+// (20202) and ScrapeStatusPort (20204). Logging receivers can not listen on it when a pipeline
+// has a pubsub exporter.
+//
+// The pubsub exporter is behind the "pubsub_exporter" feature gate, since it needs components
+// that the collector build has to include: the fluentforward receiver, the groupbyattrs
+// processor, and the googlecloudpubsub exporter with ordering.from_resource_attribute and
+// attributes.from_resource_attributes_with_prefix.
+const PubSubForwardPort = 20203
+
+const (
+	pubsubOutputAlias = "pubsub"
+	// pubsubOrderingKeyAttribute holds the ordering key of a log until the exporter removes it.
+	pubsubOrderingKeyAttribute = "gcp.pubsub.ordering_key"
+	// pubsubAttributePrefix is the prefix of the resource attributes that hold the message
+	// attributes of a log until the exporter removes them.
+	pubsubAttributePrefix = "gcp.pubsub.attribute."
+)
+
+var (
+	pubsubTopicRegex     = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+	pubsubTemplateField  = regexp.MustCompile(`\$\{([^}]*)\}`)
+	pubsubAttributeRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,256}$`)
+	// The exporter sets the CloudEvents attributes, and attributes with the goog prefix are
+	// reserved by Pub/Sub.
+	pubsubReservedAttributePrefixes = []string{"ce-", "content-", "goog"}
+)
+
+// PubSub publishes the logs of a logging pipeline to a Pub/Sub topic, in addition to Cloud
+// Logging, so that event-driven processing can consume them directly.
+type PubSub struct {
+	// Topic is the full name of the topic, e.g. "projects/my-project/topics/my-topic".
+	Topic string `yaml:"topic" validate:"required"`
+	// OrderingKey is a template for the ordering key of the messages, where ${jsonPayload.field}
+	// is replaced by the value of the field, e.g. "${jsonPayload.tenant}/${jsonPayload.user}".
+	// Messages are not ordered if unset.
+	OrderingKey string `yaml:"ordering_key,omitempty"`
+	// Attributes are the attributes of the messages, with templates for their values like
+	// OrderingKey, e.g. {"tenant": "${jsonPayload.tenant}"}. Subscriptions can filter on them.
+	Attributes map[string]string `yaml:"attributes,omitempty" validate:"omitempty,max=50"`
+}
+
+func (s *PubSub) validate(field string) error {
+	if !pubsubTopicRegex.MatchString(s.Topic) {
+		return fmt.Errorf(`%q: topic %q must have the format "projects/PROJECT_ID/topics/TOPIC_ID"`, field, s.Topic)
+	}
+	if _, err := s.orderingKey(); err != nil {
+		return fmt.Errorf("%q: %w", field, err)
+	}
+	for _, name := range sortedKeys(s.Attributes) {
+		if !pubsubAttributeRegex.MatchString(name) {
+			return fmt.Errorf("%q: attribute name %q must have 1 to 256 letters, digits, underscores, periods or hyphens", field, name)
+		}
+		for _, prefix := range pubsubReservedAttributePrefixes {
+			if strings.HasPrefix(strings.ToLower(name), prefix) {
+				return fmt.Errorf("%q: attribute name %q must not start with the reserved prefix %q", field, name, prefix)
+			}
+		}
+		if _, err := pubsubTemplate("attributes."+name, s.Attributes[name]); err != nil {
+			return fmt.Errorf("%q: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// orderingKey returns the OTTL value of the ordering key template, or nil if it is unset.
+func (s *PubSub) orderingKey() (ottl.Value, error) {
+	if s.OrderingKey == "" {
+		return nil, nil
+	}
+	return pubsubTemplate("ordering_key", s.OrderingKey)
+}
+
+// pubsubTemplate returns the OTTL value of the template of the setting name, where
+// ${jsonPayload.field} is replaced by the value of the field.
+func pubsubTemplate(name, template string) (ottl.Value, error) {
+	var values []ottl.Value
+	last := 0
+	for _, m := range pubsubTemplateField.FindAllStringSubmatchIndex(template, -1) {
+		if m[0] > last {
+			values = append(values, ottl.StringLiteral(template[last:m[0]]))
+		}
+		field := template[m[2]:m[3]]
+		// Fluent Bit forwards the fields of jsonPayload as the attributes of the log.
+		path, ok := strings.CutPrefix(field, "jsonPayload.")
+		if !ok || path == "" {
+			return nil, fmt.Errorf(`%s: field %q is not supported; only fields of jsonPayload can be used`, name, field)
+		}
+		values = append(values, ottl.LValue(append([]string{"attributes"}, strings.Split(path, ".")...)))
+		last = m[1]
+	}
+	if last < len(template) {
+		values = append(values, ottl.StringLiteral(template[last:]))
+	}
+	return ottl.Concat(values, ""), nil
+}
+
+// pubsubForwardOutputComponent forwards the records whose tag matches match to the
+// OpenTelemetry Collector.
+func pubsubForwardOutputComponent(match string) fluentbit.Component {
+	return fluentbit.Component{
+		Kind: "OUTPUT",
+		Config: map[string]string{
+			// https://docs.fluentbit.io/manual/pipeline/outputs/forward
+			"Name":        "forward",
+			"Match_Regex": fmt.Sprintf("^(%s)$", match),
+			"Alias":       pubsubOutputAlias,
+			"Host":        "127.0.0.1",
+			"Port":        fmt.Sprintf("%d", PubSubForwardPort),
+			"Retry_Limit": "3",
+		},
+	}
+}
+
+// pubsubReceiverPipeline receives the records that Fluent Bit forwards for Pub/Sub.
+func pubsubReceiverPipeline() otel.ReceiverPipeline {
+	return otel.ReceiverPipeline{
+		Receiver: otel.Component{
+			Type: "fluentforward",
+			Config: map[string]interface{}{
+				"endpoint": fmt.Sprintf("127.0.0.1:%d", PubSubForwardPort),
+			},
+		},
+		Processors: map[string][]otel.Component{
+			"logs": nil,
+		},
+		ExporterTypes: map[string]otel.ExporterType{
+			"logs": otel.OTel,
+		},
+	}
+}
+
+// pubsubPipeline publishes the forwarded records whose Fluent Bit tag matches tagRegex to the
+// topic of s.
+func pubsubPipeline(ctx context.Context, receiverPipelineName, tagRegex string, s *PubSub) (otel.Pipeline, error) {
+	processors := []otel.Component{
+		// All the pipelines share the receiver, so only keep the records of this pipeline.
+		otel.Filter("logs", "log_record", []ottl.Value{
+			ottl.Not(ottl.IsMatch(ottl.LValue{"attributes", "fluent.tag"}, fmt.Sprintf("^(%s)$", tagRegex))),
+		}),
+	}
+	exporter := pubsubExporter(ctx, s)
+	orderingKey, err := s.orderingKey()
+	if err != nil {
+		return otel.Pipeline{}, err
+	}
+	// The exporter takes the ordering key and the message attributes from resource attributes,
+	// so the logs are grouped by their values.
+	var statements ottl.Statements
+	var groupKeys []string
+	if orderingKey != nil {
+		statements = append(statements, ottl.LValue{"attributes", pubsubOrderingKeyAttribute}.Set(orderingKey)...)
+		groupKeys = append(groupKeys, pubsubOrderingKeyAttribute)
+		exporter.Config.(map[string]interface{})["ordering"] = map[string]interface{}{
+			"enabled":                   true,
+			"from_resource_attribute":   pubsubOrderingKeyAttribute,
+			"remove_resource_attribute": true,
+		}
+	}
+	for _, name := range sortedKeys(s.Attributes) {
+		value, err := pubsubTemplate("attributes."+name, s.Attributes[name])
+		if err != nil {
+			return otel.Pipeline{}, err
+		}
+		statements = append(statements, ottl.LValue{"attributes", pubsubAttributePrefix + name}.Set(value)...)
+		groupKeys = append(groupKeys, pubsubAttributePrefix+name)
+	}
+	if len(s.Attributes) > 0 {
+		exporter.Config.(map[string]interface{})["attributes"] = map[string]interface{}{
+			"from_resource_attributes_with_prefix": pubsubAttributePrefix,
+			"remove_resource_attributes":           true,
+		}
+	}
+	if len(groupKeys) > 0 {
+		processors = append(processors,
+			otel.Transform("log", "log", statements),
+			otel.Component{
+				Type: "groupbyattrs",
+				Config: map[string]interface{}{
+					"keys": groupKeys,
+				},
+			},
+		)
+	}
+	processors = append(processors, otel.Component{
+		Type: "batch",
+		Config: map[string]interface{}{
+			// Stay well below the limit of 1000 messages and 10MB per publish request.
+			"send_batch_size":     500,
+			"send_batch_max_size": 1000,
+			"timeout":             "1s",
+		},
+	})
+	return otel.Pipeline{
+		Type:                 "logs",
+		ReceiverPipelineName: receiverPipelineName,
+		Processors:           processors,
+		Exporter:             &exporter,
+	}, nil
+}
+
+func pubsubExporter(ctx context.Context, s *PubSub) otel.Component {
+	userAgent, _ := platform.FromContext(ctx).UserAgent("Google-Cloud-Ops-Agent-Logging")
+	return otel.Component{
+		Type: "googlecloudpubsub",
+		Config: map[string]interface{}{
+			"topic":      s.Topic,
+			"user_agent": userAgent,
+			"timeout":    "12s",
+			// Back off when the topic is out of publish quota.
+			"retry_on_failure": map[string]interface{}{
+				"enabled":          true,
+				"initial_interval": "5s",
+				"max_interval":     "30s",
+				"max_elapsed_time": "300s",
+			},
+			"sending_queue": map[string]interface{}{
+				"enabled":    true,
+				"queue_size": 1000,
+			},
+		},
+	}
+}
+
+// generatePubSubPipelines generates the OTel pipelines that publish the logs of the Fluent Bit
+// logging pipelines with a pubsub exporter.
+func (uc *UnifiedConfig) generatePubSubPipelines(ctx context.Context) (map[string]otel.ReceiverPipeline, map[string]otel.Pipeline, error) {
+	outR := map[string]otel.ReceiverPipeline{}
+	outP := map[string]otel.Pipeline{}
+	if uc.Logging == nil || uc.Logging.Service == nil || uc.Logging.Service.OTelLogging {
+		return outR, outP, nil
+	}
+	pipelines, err := uc.Pipelines(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags := map[string][]string{}
+	topics := map[string]*PubSub{}
+	for _, p := range pipelines {
+		if p.backend != backendFluentBit || p.pubsub == nil {
+			continue
+		}
+		source, err := p.fluentBitComponents(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		tags[p.pID] = append(tags[p.pID], source.tagRegex)
+		topics[p.pID] = p.pubsub
+	}
+	if len(tags) == 0 {
+		return outR, outP, nil
+	}
+	outR[pubsubOutputAlias] = pubsubReceiverPipeline()
+	for _, pID := range sortedKeys(tags) {
+		sort.Strings(tags[pID])
+		pipeline, err := pubsubPipeline(ctx, pubsubOutputAlias, strings.Join(tags[pID], "|"), topics[pID])
+		if err != nil {
+			return nil, nil, err
+		}
+		outP[fmt.Sprintf("pubsub_%s", strings.ReplaceAll(pID, "_", "__"))] = pipeline
+	}
+	return outR, outP, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+func TestPubSubValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{
+			name:   "topic",
+			output: `pubsub: {topic: projects/my-project/topics/events}`,
+		},
+		{
+			name:   "ordering key and attributes",
+			output: `pubsub: {topic: projects/my-project/topics/events, ordering_key: "${jsonPayload.tenant}", attributes: {tenant: "${jsonPayload.tenant}", type: "order.${jsonPayload.type}"}}`,
+		},
+		{
+			name:    "missing topic",
+			output:  `pubsub: {ordering_key: "${jsonPayload.tenant}"}`,
+			wantErr: "topic",
+		},
+		{
+			name:    "topic without project",
+			output:  `pubsub: {topic: events}`,
+			wantErr: `topic "events" must have the format "projects/PROJECT_ID/topics/TOPIC_ID"`,
+		},
+		{
+			name:    "ordering key with field outside of jsonPayload",
+			output:  `pubsub: {topic: projects/my-project/topics/events, ordering_key: "${severity}"}`,
+			wantErr: `ordering_key: field "severity" is not supported`,
+		},
+		{
+			name:    "attribute with field outside of jsonPayload",
+			output:  `pubsub: {topic: projects/my-project/topics/events, attributes: {level: "${severity}"}}`,
+			wantErr: `attributes.level: field "severity" is not supported`,
+		},
+		{
+			name:    "attribute with invalid name",
+			output:  `pubsub: {topic: projects/my-project/topics/events, attributes: {"my tenant": "${jsonPayload.tenant}"}}`,
+			wantErr: `attribute name "my tenant" must have 1 to 256 letters`,
+		},
+		{
+			name:    "attribute with reserved prefix",
+			output:  `pubsub: {topic: projects/my-project/topics/events, attributes: {ce-type: "${jsonPayload.type}"}}`,
+			wantErr: `attribute name "ce-type" must not start with the reserved prefix "ce-"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := confgenerator.ContextWithExperiments(context.Background(), map[string]bool{"pubsub_exporter": true})
+			checkPipelineOutputErr(t, validateWithPipelineOutputContext(ctx, tc.output), tc.wantErr)
+		})
+	}
+}
+
+func TestPubSubRequiresFeatureGate(t *testing.T) {
+	err := validateWithPipelineOutput(`pubsub: {topic: projects/my-project/topics/events}`)
+	checkPipelineOutputErr(t, err, `requires the experimental feature "pubsub_exporter"`)
+}
+
+func TestPubSubForwardPortIsNotTaken(t *testing.T) {
+	ports := map[int]string{}
+	for _, p := range []struct {
+		name string
+		port int
+	}{
+		{"otel.MetricsPort", otel.MetricsPort},
+		{"fluentbit.MetricsPort", fluentbit.MetricsPort},
+		{"confgenerator.ScrapeStatusPort", confgenerator.ScrapeStatusPort},
+		{"confgenerator.PubSubForwardPort", confgenerator.PubSubForwardPort},
+	} {
+		if other, ok := ports[p.port]; ok {
+			t.Errorf("%s and %s both use port %d", other, p.name, p.port)
+		}
+		ports[p.port] = p.name
+	}
+}
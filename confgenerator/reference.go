@@ -0,0 +1,185 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// ComponentReference describes the configuration of a component type, as derived from its struct.
+type ComponentReference struct {
+	Subagent  string           `yaml:"subagent"`
+	Kind      string           `yaml:"kind"`
+	Type      string           `yaml:"type"`
+	Platforms []string         `yaml:"platforms,flow"`
+	Fields    []FieldReference `yaml:"fields"`
+}
+
+// FieldReference describes a configuration field of a component type. Nested fields are named
+// by their path, e.g. tls.ca_file or series[].labels.
+type FieldReference struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Required bool   `yaml:"required,omitempty"`
+	// Default and Description are not in the struct, and are left for callers to fill in.
+	Default     string `yaml:"default,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Validation is the validate tag of the field.
+	Validation string `yaml:"validation,omitempty"`
+}
+
+// Reference returns the references of all the component types of r, sorted by type.
+func (r *componentTypeRegistry[CI, M]) Reference() []ComponentReference {
+	var out []ComponentReference
+	for _, name := range sortedKeys(r.TypeMap) {
+		ct := r.TypeMap[name]
+		var platforms []string
+		for _, p := range []struct {
+			name string
+			t    platform.Type
+		}{
+			{"linux", platform.Linux},
+			{"windows", platform.Windows},
+		} {
+			if ct.platforms&p.t != 0 {
+				platforms = append(platforms, p.name)
+			}
+		}
+		var fields []FieldReference
+		for _, f := range fieldReferences(reflect.TypeOf(ct.constructor()), "") {
+			// These fields of ConfigComponent are rejected on all other components.
+			if (f.Name == "labels" || f.Name == "default_severity") && !(r.Subagent == "logging" && r.Kind == "receiver") {
+				continue
+			}
+			fields = append(fields, f)
+		}
+		out = append(out, ComponentReference{
+			Subagent:  r.Subagent,
+			Kind:      r.Kind,
+			Type:      name,
+			Platforms: platforms,
+			Fields:    fields,
+		})
+	}
+	return out
+}
+
+// Reference returns the references of all the registered component types.
+func Reference() []ComponentReference {
+	var out []ComponentReference
+	out = append(out, LoggingReceiverTypes.Reference()...)
+	out = append(out, LoggingProcessorTypes.Reference()...)
+	out = append(out, MetricsReceiverTypes.Reference()...)
+	out = append(out, MetricsProcessorTypes.Reference()...)
+	out = append(out, CombinedReceiverTypes.Reference()...)
+	out = append(out, TracesProcessorTypes.Reference()...)
+	return out
+}
+
+// fieldReferences returns the references of the fields of the struct t, prefixing their names
+// with prefix.
+func fieldReferences(t reflect.Type, prefix string) []FieldReference {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []FieldReference
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tags := strings.Split(f.Tag.Get("yaml"), ",")
+		name := tags[0]
+		if name == "-" {
+			continue
+		}
+		if contains(tags[1:], "inline") {
+			out = append(out, fieldReferences(f.Type, prefix)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		validate := f.Tag.Get("validate")
+		ref := FieldReference{
+			Name:       prefix + name,
+			Type:       typeName(f.Type),
+			Required:   contains(strings.Split(strings.SplitN(validate, ",dive", 2)[0], ","), "required"),
+			Validation: validate,
+		}
+		out = append(out, ref)
+		if nested := nestedStruct(f.Type); nested != nil {
+			path := ref.Name
+			if k := indirect(f.Type).Kind(); k == reflect.Slice || k == reflect.Map {
+				path += "[]"
+			}
+			out = append(out, fieldReferences(nested, path+".")...)
+		}
+	}
+	return out
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// nestedStruct returns the struct type of the values of t, if they are a struct of this module
+// whose fields should be listed. Structs of other modules (e.g. the Prometheus config) are
+// described as objects instead.
+func nestedStruct(t reflect.Type) reflect.Type {
+	t = indirect(t)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = indirect(t.Elem())
+	}
+	if t.Kind() != reflect.Struct || !strings.HasPrefix(t.PkgPath(), "github.com/GoogleCloudPlatform/ops-agent/") {
+		return nil
+	}
+	return t
+}
+
+// typeName returns the name of the YAML type of the values of t.
+func typeName(t reflect.Type) string {
+	t = indirect(t)
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprintf("list of %s", typeName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map of %s to %s", typeName(t.Key()), typeName(t.Elem()))
+	case reflect.Struct:
+		return "object"
+	default:
+		return "any"
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type referenceTestTLS struct {
+	CAFile string `yaml:"ca_file" validate:"required"`
+}
+
+type referenceTestMatcher struct {
+	Labels map[string]string `yaml:"labels" validate:"required,min=1"`
+}
+
+type referenceTestComponent struct {
+	ConfigComponent `yaml:",inline"`
+
+	Paths    []string               `yaml:"paths,flow" validate:"required,dive,required"`
+	Interval *time.Duration         `yaml:"interval,omitempty" validate:"omitempty,min=1s"`
+	Workers  int                    `yaml:"workers,omitempty"`
+	TLS      *referenceTestTLS      `yaml:"tls,omitempty"`
+	Series   []referenceTestMatcher `yaml:"series,omitempty" validate:"dive"`
+	internal string
+}
+
+func TestFieldReferences(t *testing.T) {
+	got := fieldReferences(reflect.TypeOf(&referenceTestComponent{}), "")
+	want := []FieldReference{
+		{Name: "type", Type: "string", Required: true, Validation: "required"},
+		{Name: "labels", Type: "map of string to string", Validation: "omitempty,dive,keys,required,endkeys"},
+		{Name: "default_severity", Type: "string", Validation: "omitempty,oneof=DEFAULT DEBUG INFO NOTICE WARNING ERROR CRITICAL ALERT EMERGENCY"},
+		{Name: "paths", Type: "list of string", Required: true, Validation: "required,dive,required"},
+		{Name: "interval", Type: "duration", Validation: "omitempty,min=1s"},
+		{Name: "workers", Type: "integer"},
+		{Name: "tls", Type: "object"},
+		{Name: "tls.ca_file", Type: "string", Required: true, Validation: "required"},
+		{Name: "series", Type: "list of object", Validation: "dive"},
+		{Name: "series[].labels", Type: "map of string to string", Required: true, Validation: "required,min=1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("fieldReferences() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
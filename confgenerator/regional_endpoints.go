@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// locationRegexp matches the Google Cloud regions that logs and metrics can be sent to,
+// e.g. europe-west1.
+var locationRegexp = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+
+// LoggingEndpoint returns the host of the regional Cloud Logging endpoint of location, which
+// keeps the logs in that location for data residency.
+func LoggingEndpoint(location string) string {
+	return fmt.Sprintf("logging.%s.rep.googleapis.com", location)
+}
+
+// MonitoringEndpoint returns the host of the regional Cloud Monitoring endpoint of location.
+func MonitoringEndpoint(location string) string {
+	return fmt.Sprintf("monitoring.%s.rep.googleapis.com", location)
+}
+
+type loggingLocationKeyType struct{}
+
+var loggingLocationKey = loggingLocationKeyType{}
+
+// contextWithLoggingLocation sets the location that the fluent-bit outputs send logs to.
+func contextWithLoggingLocation(ctx context.Context, location string) context.Context {
+	return context.WithValue(ctx, loggingLocationKey, location)
+}
+
+func loggingLocationFromContext(ctx context.Context) string {
+	if l, ok := ctx.Value(loggingLocationKey).(string); ok {
+		return l
+	}
+	return ""
+}
+
+// withRegionalEndpoints returns a googlecloud or googlemanagedprometheus exporter that sends
+// metrics to metricsLocation and logs to loggingLocation. Empty locations keep the global
+// endpoints.
+func withRegionalEndpoints(exporter otel.Component, metricsLocation, loggingLocation string) otel.Component {
+	config := exporter.Config.(map[string]interface{})
+	if metricsLocation != "" {
+		config["metric"].(map[string]interface{})["endpoint"] = MonitoringEndpoint(metricsLocation) + ":443"
+	}
+	if loggingLocation != "" && exporter.Type == "googlecloud" {
+		config["log"] = map[string]interface{}{
+			"endpoint": LoggingEndpoint(loggingLocation) + ":443",
+		}
+	}
+	return exporter
+}
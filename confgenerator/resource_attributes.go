@@ -0,0 +1,73 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel/ottl"
+)
+
+// resourceAttributeLabels maps the resource attributes that metrics.service.resource_attributes
+// accepts to the metric labels they are promoted to. The label names are those that the googlecloud
+// exporter uses for service resource labels.
+var resourceAttributeLabels = map[string]string{
+	"service.name":           "service_name",
+	"service.namespace":      "service_namespace",
+	"deployment.environment": "deployment_environment",
+}
+
+func validateResourceAttributes(attributes map[string]string) error {
+	for _, k := range sortedKeys(attributes) {
+		if _, ok := resourceAttributeLabels[k]; !ok {
+			return fmt.Errorf(`"metrics.service.resource_attributes" does not support %q; it must be one of [%s]`, k, strings.Join(sortedKeys(resourceAttributeLabels), ", "))
+		}
+	}
+	return nil
+}
+
+// resourceAttributesProcessor returns a processor that sets attributes on the resource and promotes
+// them to metric labels, or nil if there are no attributes. Neither overwrites a value that is
+// already set, so the attributes cannot collide with those sent by an application (e.g. over OTLP)
+// or with the labels of a receiver.
+func resourceAttributesProcessor(attributes map[string]string) *otel.Component {
+	if len(attributes) == 0 {
+		return nil
+	}
+	var resource, datapoint ottl.Statements
+	for _, k := range sortedKeys(attributes) {
+		resource = resource.Append(ottl.LValue{"attributes", k}.SetIfNil(ottl.StringLiteral(attributes[k])))
+		label := ottl.LValue{"attributes", resourceAttributeLabels[k]}
+		datapoint = datapoint.Append(label.SetIfNil(ottl.LValue{"resource.attributes", k}))
+	}
+	return &otel.Component{
+		Type: "transform",
+		Config: map[string]any{
+			"error_mode": "ignore",
+			"metric_statements": []map[string]any{
+				{
+					"context":    "resource",
+					"statements": resource,
+				},
+				{
+					"context":    "datapoint",
+					"statements": datapoint,
+				},
+			},
+		},
+	}
+}
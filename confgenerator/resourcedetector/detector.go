@@ -15,7 +15,10 @@
 package resourcedetector
 
 import (
-	gcp_metadata "cloud.google.com/go/compute/metadata"
+	"fmt"
+	"sort"
+	"sync"
+
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
@@ -33,13 +36,20 @@ type resourceCache struct {
 	Error    error
 }
 
-var cachedResourceAndError *resourceCache
+var (
+	// cachedResourceMu guards cachedResourceAndError, since the pipelines are generated
+	// concurrently.
+	cachedResourceMu       sync.Mutex
+	cachedResourceAndError *resourceCache
+)
 
 // Get a resource instance for the current environment;
 // In order to access the attributes of a specific type of resource,
 // needs to cast the returned Resource instance to its underlying type:
 // actual, ok := resource.(GCEResource)
 func GetResource() (Resource, error) {
+	cachedResourceMu.Lock()
+	defer cachedResourceMu.Unlock()
 	if cachedResourceAndError != nil {
 		return cachedResourceAndError.Resource, cachedResourceAndError.Error
 	}
@@ -51,13 +61,44 @@ func GetResource() (Resource, error) {
 	return r, err
 }
 
+// A Detector recognizes an environment that the agent can run in.
+type Detector interface {
+	// Applies reports whether the agent runs in the environment of the detector.
+	Applies() bool
+	// Resource returns the resource of the environment.
+	Resource() (Resource, error)
+}
+
+type registeredDetector struct {
+	name     string
+	priority int
+	detector Detector
+}
+
+var detectors []registeredDetector
+
+// RegisterDetector adds a detector for an environment. Detectors are tried from the highest to
+// the lowest priority, and the resource of the first one that applies is used.
+// It panics if the name is taken.
+func RegisterDetector(name string, priority int, d Detector) {
+	for _, r := range detectors {
+		if r.name == name {
+			panic(fmt.Sprintf("resource detector %q is already registered", name))
+		}
+	}
+	detectors = append(detectors, registeredDetector{name, priority, d})
+	sort.SliceStable(detectors, func(i, j int) bool {
+		return detectors[i].priority > detectors[j].priority
+	})
+}
+
 func getUncachedResource() (Resource, error) {
-	switch {
-	case gcp_metadata.OnGCE():
-		return GetGCEResource()
-	default:
-		return GetUnrecognizedPlatformResource()
+	for _, r := range detectors {
+		if r.detector.Applies() {
+			return r.detector.Resource()
+		}
 	}
+	return GetUnrecognizedPlatformResource()
 }
 
 // UnrecognizedPlatformResource that returns an empty resource instance without any attributes
@@ -0,0 +1,68 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetector
+
+import (
+	"testing"
+)
+
+type fakeDetector struct {
+	applies  bool
+	resource Resource
+}
+
+func (d fakeDetector) Applies() bool {
+	return d.applies
+}
+
+func (d fakeDetector) Resource() (Resource, error) {
+	return d.resource, nil
+}
+
+func TestDetectorPriority(t *testing.T) {
+	saved := detectors
+	defer func() { detectors = saved }()
+	detectors = nil
+
+	low := OverrideResource{Type: "generic_node"}
+	high := OverrideResource{Type: "generic_task"}
+	RegisterDetector("low", 1, fakeDetector{applies: true, resource: low})
+	RegisterDetector("skipped", 3, fakeDetector{applies: false, resource: GCEResource{}})
+	RegisterDetector("high", 2, fakeDetector{applies: true, resource: high})
+
+	actual, err := getUncachedResource()
+	if err != nil {
+		t.Fatalf("should not have error: %v", err)
+	}
+	if r, ok := actual.(OverrideResource); !ok || r.Type != "generic_task" {
+		t.Errorf("should have used the applicable detector with the highest priority, got %v", actual)
+	}
+}
+
+func TestNoDetectorApplies(t *testing.T) {
+	saved := detectors
+	defer func() { detectors = saved }()
+	detectors = nil
+
+	RegisterDetector("skipped", 1, fakeDetector{applies: false, resource: GCEResource{}})
+
+	actual, err := getUncachedResource()
+	if err != nil {
+		t.Fatalf("should not have error: %v", err)
+	}
+	if _, ok := actual.(UnrecognizedPlatformResource); !ok {
+		t.Errorf("should have returned UnrecognizedPlatformResource, got %T", actual)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	gcp_metadata "cloud.google.com/go/compute/metadata"
 	"github.com/prometheus/prometheus/util/strutil"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
@@ -47,6 +48,21 @@ func GetGCEResource() (Resource, error) {
 	return dt.GetResource()
 }
 
+// gceDetector detects GCE VMs from the metadata server.
+type gceDetector struct{}
+
+func (gceDetector) Applies() bool {
+	return gcp_metadata.OnGCE()
+}
+
+func (gceDetector) Resource() (Resource, error) {
+	return GetGCEResource()
+}
+
+func init() {
+	RegisterDetector("gce", 0, gceDetector{})
+}
+
 // The data provider interface for GCE environment
 // Implementation of this provider can use either the metadata server on VM,
 // or the cloud API
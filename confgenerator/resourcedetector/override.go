@@ -0,0 +1,94 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetector
+
+import (
+	"regexp"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// OverrideLabels maps the monitored resource types that can be forced with an OverrideResource
+// to the OTel resource attributes of their labels. All the labels are required.
+var OverrideLabels = map[string]map[string]string{
+	"gce_instance": {
+		"instance_id": "host.id",
+		"zone":        "cloud.availability_zone",
+	},
+	"generic_node": {
+		"location":  "cloud.availability_zone",
+		"namespace": "service.namespace",
+		"node_id":   "host.id",
+	},
+	"generic_task": {
+		"location":  "cloud.availability_zone",
+		"namespace": "service.namespace",
+		"job":       "service.name",
+		"task_id":   "service.instance.id",
+	},
+}
+
+// OverrideResource is a resource that is set explicitly instead of being detected, for
+// environments where the detection picks the wrong resource.
+type OverrideResource struct {
+	Type   string
+	Labels map[string]string
+	// Project is the project that the resource belongs to. It is empty if it is not known, in
+	// which case the telemetry is written to the project of the credentials.
+	Project string
+}
+
+func (r OverrideResource) ProjectName() string {
+	return r.Project
+}
+
+func (r OverrideResource) MonitoredResource() *monitoredres.MonitoredResource {
+	return &monitoredres.MonitoredResource{
+		Type:   r.Type,
+		Labels: r.Labels,
+	}
+}
+
+func (r OverrideResource) OTelResourceAttributes() map[string]string {
+	attributes := map[string]string{}
+	if r.Type == "gce_instance" {
+		attributes["cloud.platform"] = "gcp_compute_engine"
+	}
+	for label, attribute := range OverrideLabels[r.Type] {
+		attributes[attribute] = r.Labels[label]
+	}
+	if r.Project != "" {
+		attributes["cloud.project"] = r.Project
+	}
+	if location, ok := attributes["cloud.availability_zone"]; ok {
+		attributes["cloud.region"] = regionOf(location)
+	}
+	return attributes
+}
+
+var zonePattern = regexp.MustCompile(`^([a-z]+-[a-z]+[0-9]+)-[a-z]$`)
+
+// regionOf returns the region of a location, which is either a zone like "us-central1-a" or
+// already a region, e.g. the location of a generic_node.
+func regionOf(location string) string {
+	if m := zonePattern.FindStringSubmatch(location); m != nil {
+		return m[1]
+	}
+	return location
+}
+
+func (OverrideResource) PrometheusStyleMetadata() map[string]string {
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resourcedetector
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOverrideResourceOTelResourceAttributes(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		resource OverrideResource
+		want     map[string]string
+	}{
+		{
+			name: "gce_instance",
+			resource: OverrideResource{
+				Type:    "gce_instance",
+				Labels:  map[string]string{"instance_id": "1234", "zone": "us-central1-a"},
+				Project: "my-project",
+			},
+			want: map[string]string{
+				"cloud.platform":          "gcp_compute_engine",
+				"cloud.project":           "my-project",
+				"cloud.availability_zone": "us-central1-a",
+				"cloud.region":            "us-central1",
+				"host.id":                 "1234",
+			},
+		},
+		{
+			name: "generic_node in a region",
+			resource: OverrideResource{
+				Type:   "generic_node",
+				Labels: map[string]string{"location": "europe-west4", "namespace": "edge", "node_id": "node-1"},
+			},
+			want: map[string]string{
+				"cloud.availability_zone": "europe-west4",
+				"cloud.region":            "europe-west4",
+				"service.namespace":       "edge",
+				"host.id":                 "node-1",
+			},
+		},
+		{
+			name: "generic_task in a custom location",
+			resource: OverrideResource{
+				Type:   "generic_task",
+				Labels: map[string]string{"location": "store-42", "namespace": "edge", "job": "pos", "task_id": "1"},
+			},
+			want: map[string]string{
+				"cloud.availability_zone": "store-42",
+				"cloud.region":            "store-42",
+				"service.namespace":       "edge",
+				"service.name":            "pos",
+				"service.instance.id":     "1",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.resource.OTelResourceAttributes()); diff != "" {
+				t.Errorf("OTelResourceAttributes() mismatch (-want +got):\n%s", diff)
+			}
+			if got := tc.resource.ProjectName(); got != tc.resource.Project {
+				t.Errorf("ProjectName() = %q, want %q", got, tc.resource.Project)
+			}
+		})
+	}
+}
@@ -18,7 +18,9 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
@@ -53,7 +55,7 @@ func healthChecksLogsPath() string {
 	return path.Join("${logs_dir}", "health-checks.log")
 }
 
-func generateInputHealthLoggingPingComponent(ctx context.Context) []fluentbit.Component {
+func generateInputHealthLoggingPingComponent(ctx context.Context, interval time.Duration) []fluentbit.Component {
 	return []fluentbit.Component{
 		{
 			Kind: "INPUT",
@@ -61,7 +63,7 @@ func generateInputHealthLoggingPingComponent(ctx context.Context) []fluentbit.Co
 				"Name":          "dummy",
 				"Tag":           healthLogsTag,
 				"Dummy":         `{"code": "LogPingOpsAgent", "severity": "DEBUG"}`,
-				"Interval_Sec":  "600",
+				"Interval_Sec":  strconv.FormatInt(int64(interval.Seconds()), 10),
 				"Interval_NSec": "0",
 			},
 		},
@@ -204,6 +206,49 @@ func generateFilterMapSeverityFieldComponent(ctx context.Context) []fluentbit.Co
 	}.Components(ctx, opsAgentLogsMatch, "self-logs-processing")
 }
 
+// selfLogDedupLua drops the error and warning self logs that repeat a message already seen
+// within the window, and records how many were dropped on the next occurrence after the window.
+// Fluent-bit self logs start with their own timestamp, which is ignored when comparing them.
+const selfLogDedupLua = `
+local window = %d
+local entries = {}
+function self_log_dedup(tag, timestamp, record)
+    local severity = record["logging.googleapis.com/severity"]
+    local message = record["message"]
+    if (severity ~= "ERROR" and severity ~= "WARNING") or message == nil then
+        return 0, timestamp, record
+    end
+    local key = tag .. "\n" .. (string.gsub(tostring(message), "^%%[[^%%]]*%%]", ""))
+    local now = os.time()
+    local entry = entries[key]
+    if entry ~= nil and now - entry.start < window then
+        entry.count = entry.count + 1
+        return -1, timestamp, record
+    end
+    -- Forget the messages whose window ended, so that the table doesn't grow forever.
+    for k, e in pairs(entries) do
+        if now - e.start >= window then
+            entries[k] = nil
+        end
+    end
+    entries[key] = {start = now, count = 0}
+    if entry ~= nil and entry.count > 0 then
+        record["suppressed_count"] = entry.count
+        return 2, timestamp, record
+    end
+    return 0, timestamp, record
+end
+`
+
+// This method creates a component that collapses repeated self logs, so that the agent's own
+// error spam doesn't consume the Logging quota. It returns no components if window is 0.
+func generateFilterSelfLogsDedupComponents(ctx context.Context, window time.Duration) []fluentbit.Component {
+	if window == 0 {
+		return nil
+	}
+	return fluentbit.LuaFilterComponents(opsAgentLogsMatch, "self_log_dedup", fmt.Sprintf(selfLogDedupLua, int64(window.Seconds())))
+}
+
 // This method creates a component that outputs all ops-agent self logs to Cloud Logging.
 func generateOutputSelfLogsComponent(ctx context.Context, userAgent string, ingestSelfLogs bool) fluentbit.Component {
 	outputLogNames := []string{healthLogsTag}
@@ -211,17 +256,18 @@ func generateOutputSelfLogsComponent(ctx context.Context, userAgent string, inge
 		// Ingest fluent-bit logs to Cloud Logging if enabled.
 		outputLogNames = append(outputLogNames, fluentBitSelfLogsTag)
 	}
-	return stackdriverOutputComponent(ctx, strings.Join(outputLogNames, "|"), userAgent, "", "")
+	return stackdriverOutputComponent(ctx, strings.Join(outputLogNames, "|"), agentLogsOutputAlias, userAgent, "", "")
 }
 
 func (uc *UnifiedConfig) generateSelfLogsComponents(ctx context.Context, userAgent string) []fluentbit.Component {
 	out := make([]fluentbit.Component, 0)
-	out = append(out, generateInputHealthLoggingPingComponent(ctx)...)
+	out = append(out, generateInputHealthLoggingPingComponent(ctx, uc.Global.GetHeartbeatInterval())...)
 	out = append(out, generateInputFluentBitSelfLogsComponents(ctx, uc.Logging.Service.LogLevel)...)
 	out = append(out, generateInputHealthChecksLogsComponents(ctx)...)
 	out = append(out, generateFilterSelfLogsSamplingComponents(ctx)...)
 	out = append(out, generateFilterStructuredHealthLogsComponents(ctx)...)
 	out = append(out, generateFilterMapSeverityFieldComponent(ctx)...)
+	out = append(out, generateFilterSelfLogsDedupComponents(ctx, uc.Global.GetSelfLogDedupWindow())...)
 	out = append(out, generateOutputSelfLogsComponent(ctx, userAgent, uc.Global.GetDefaultSelfLogFileCollection()))
 
 	return out
@@ -0,0 +1,90 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+)
+
+// SyslogForward sends the logs of a logging pipeline to a syslog server as RFC 5424 messages,
+// in addition to Cloud Logging, e.g. to feed an on-prem SIEM without a second agent.
+type SyslogForward struct {
+	// Endpoint is the host:port of the syslog server.
+	Endpoint string `yaml:"endpoint" validate:"required,hostname_port"`
+	// Mode is the transport to the syslog server. Defaults to tcp if unset.
+	Mode string `yaml:"mode,omitempty" validate:"omitempty,oneof=tcp tls"`
+	// CAFile verifies the certificate of the syslog server in tls mode. The system
+	// certificates are used if unset.
+	CAFile string `yaml:"ca_file,omitempty"`
+	// InsecureSkipVerify disables the verification of the certificate of the syslog server.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// validate checks the settings that are only supported in tls mode.
+func (s *SyslogForward) validate(field string) error {
+	if s.Mode != "tls" && (s.CAFile != "" || s.InsecureSkipVerify) {
+		return fmt.Errorf(`%q: "ca_file" and "insecure_skip_verify" are only supported with "mode: tls"`, field)
+	}
+	return nil
+}
+
+// syslogForwardOutputAlias is the alias of the syslog output of a logging pipeline, which
+// becomes the pipeline label of the logging self metrics.
+func syslogForwardOutputAlias(pipelineID string) string {
+	return "syslog_forward." + pipelineID
+}
+
+// syslogForwardOutputComponent sends the records whose tag matches match to the syslog server.
+func syslogForwardOutputComponent(match, pipelineID string, s *SyslogForward) fluentbit.Component {
+	host, port, _ := net.SplitHostPort(s.Endpoint)
+	mode := s.Mode
+	if mode == "" {
+		mode = "tcp"
+	}
+	config := map[string]string{
+		// https://docs.fluentbit.io/manual/pipeline/outputs/syslog
+		"Name":        "syslog",
+		"Match_Regex": fmt.Sprintf("^(%s)$", match),
+		"Alias":       syslogForwardOutputAlias(pipelineID),
+		"Host":        host,
+		"Port":        port,
+		"Mode":        mode,
+
+		"Syslog_Format":      "rfc5424",
+		"Syslog_Message_Key": "message",
+		// The log name identifies the receiver of the record, like the APP-NAME of syslog.
+		"Syslog_Appname_Key": "logging.googleapis.com/logName",
+
+		// Like the stackdriver outputs, give up on a chunk after 3 retries.
+		"Retry_Limit": "3",
+	}
+	if mode == "tls" {
+		config["tls"] = "On"
+		config["tls.verify"] = "On"
+		if s.InsecureSkipVerify {
+			config["tls.verify"] = "Off"
+		}
+		if s.CAFile != "" {
+			config["tls.ca_file"] = s.CAFile
+		}
+	}
+	return fluentbit.Component{
+		Kind:   "OUTPUT",
+		Config: config,
+	}
+}
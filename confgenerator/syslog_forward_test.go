@@ -0,0 +1,110 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+// validateWithPipelineOutput unmarshals and validates a config with a logging pipeline that has
+// output, a pipeline setting in flow style, e.g. "syslog_forward: {endpoint: host:514}".
+func validateWithPipelineOutput(output string) error {
+	return validateWithPipelineOutputContext(context.Background(), output)
+}
+
+// validateWithPipelineOutputContext is like validateWithPipelineOutput, with the experiments of ctx.
+func validateWithPipelineOutputContext(ctx context.Context, output string) error {
+	ctx = platform.Platform{Type: platform.Linux}.TestContext(ctx)
+	uc, err := confgenerator.UnmarshalYamlToUnifiedConfig(ctx, []byte(`
+logging:
+  receivers:
+    app:
+      type: files
+      include_paths: [/var/log/app.log]
+  service:
+    pipelines:
+      p:
+        receivers: [app]
+        `+output))
+	if err != nil {
+		return err
+	}
+	return uc.Validate(ctx)
+}
+
+// checkPipelineOutputErr checks that err contains wantErr, or is nil if wantErr is empty.
+func checkPipelineOutputErr(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	if wantErr == "" {
+		if err != nil {
+			t.Errorf("got error %v, want nil", err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("got error %v, want an error containing %q", err, wantErr)
+	}
+}
+
+func TestSyslogForwardValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{
+			name:   "tcp",
+			output: `syslog_forward: {endpoint: "siem.example.com:514"}`,
+		},
+		{
+			name:   "tls with ca file",
+			output: `syslog_forward: {endpoint: "siem.example.com:6514", mode: tls, ca_file: /etc/ssl/siem.pem}`,
+		},
+		{
+			name:    "missing endpoint",
+			output:  `syslog_forward: {mode: tcp}`,
+			wantErr: "endpoint",
+		},
+		{
+			name:    "endpoint without port",
+			output:  `syslog_forward: {endpoint: siem.example.com}`,
+			wantErr: "endpoint",
+		},
+		{
+			name:    "unsupported mode",
+			output:  `syslog_forward: {endpoint: "siem.example.com:514", mode: udp}`,
+			wantErr: "mode",
+		},
+		{
+			name:    "ca file without tls",
+			output:  `syslog_forward: {endpoint: "siem.example.com:514", ca_file: /etc/ssl/siem.pem}`,
+			wantErr: `"ca_file" and "insecure_skip_verify" are only supported with "mode: tls"`,
+		},
+		{
+			name:    "insecure skip verify without tls",
+			output:  `syslog_forward: {endpoint: "siem.example.com:514", insecure_skip_verify: true}`,
+			wantErr: `"ca_file" and "insecure_skip_verify" are only supported with "mode: tls"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checkPipelineOutputErr(t, validateWithPipelineOutput(tc.output), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// templateParameterRegex matches the references to template parameters, e.g. ${app}.
+var templateParameterRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// pipelineTemplate is a parameterized set of receivers and processors.
+type pipelineTemplate struct {
+	receivers  []*ast.MappingValueNode
+	processors []*ast.MappingValueNode
+}
+
+// expandTemplates parses a config and expands its pipeline templates before it is unmarshaled.
+// Templates are defined in the top-level "templates" section:
+//
+//	templates:
+//	  app:
+//	    receivers:
+//	      log:
+//	        type: files
+//	        include_paths:
+//	        - /var/log/${app}/*.log
+//	    processors:
+//	      json:
+//	        type: parse_json
+//
+// and instantiated by logging or metrics pipelines that set "template" and "parameters"
+// instead of "receivers" and "processors":
+//
+//	logging:
+//	  service:
+//	    pipelines:
+//	      payments:
+//	        template: app
+//	        parameters:
+//	          app: payments
+//
+// Each instance adds the receivers and processors of the template to its section, with
+// their IDs prefixed by the pipeline ID (e.g. payments_log), and lists them in the pipeline.
+// Processors run in the order they are defined. ${name} is replaced with the value of the
+// parameter name in all keys and strings of the template; a string that consists of a
+// single reference takes the value as is, so that parameters can also be numbers or lists.
+//
+// The templates are expanded in the syntax tree of the config, so that the errors of the
+// unmarshaling still point to the lines of the config. The components of an instance keep the
+// positions of the template. expandTemplates returns the root node of the config, or nil if
+// the config is empty.
+func expandTemplates(input []byte) (ast.Node, error) {
+	file, err := parser.ParseBytes(input, 0)
+	if err != nil {
+		return nil, err
+	}
+	var doc ast.Node
+	for _, d := range file.Docs {
+		if d.Body != nil {
+			doc = d.Body
+			break
+		}
+	}
+	if mappingGet(doc, "templates") == nil {
+		return doc, nil
+	}
+	// Entries are added to the mappings of the config, which the parser returns as
+	// *ast.MappingValueNode if they have a single entry.
+	root := normalizeMappings(doc).(*ast.MappingNode)
+	i := mappingIndex(root, "templates")
+	templates, err := parseTemplates(root.Values[i].Value)
+	if err != nil {
+		return nil, err
+	}
+	root.Values = append(root.Values[:i:i], root.Values[i+1:]...)
+	used := map[string]bool{}
+	for _, subagent := range []string{"logging", "metrics"} {
+		if err := expandSectionTemplates(root, subagent, templates, used); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(templates) {
+		if !used[name] {
+			return nil, fmt.Errorf("template %q is not used by any pipeline", name)
+		}
+	}
+	return root, nil
+}
+
+func parseTemplates(n ast.Node) (map[string]pipelineTemplate, error) {
+	m, ok := n.(*ast.MappingNode)
+	if !ok {
+		return nil, fmt.Errorf(`"templates" must be a map of template names to templates`)
+	}
+	templates := map[string]pipelineTemplate{}
+	for _, item := range m.Values {
+		name := keyString(item)
+		body, ok := item.Value.(*ast.MappingNode)
+		if !ok {
+			return nil, fmt.Errorf("template %q must be a map", name)
+		}
+		var t pipelineTemplate
+		for _, field := range body.Values {
+			components, ok := field.Value.(*ast.MappingNode)
+			if !ok {
+				return nil, fmt.Errorf("%q of template %q must be a map of IDs to components", keyString(field), name)
+			}
+			switch keyString(field) {
+			case "receivers":
+				t.receivers = components.Values
+			case "processors":
+				t.processors = components.Values
+			default:
+				return nil, fmt.Errorf("template %q has unsupported field %q; supported fields are [receivers processors]", name, keyString(field))
+			}
+		}
+		if len(t.receivers) == 0 {
+			return nil, fmt.Errorf("template %q must have at least one receiver", name)
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+// expandSectionTemplates expands the templates instantiated by the pipelines of the
+// logging or metrics section of root.
+func expandSectionTemplates(root *ast.MappingNode, subagent string, templates map[string]pipelineTemplate, used map[string]bool) error {
+	section, _ := mappingGet(root, subagent).(*ast.MappingNode)
+	service, _ := mappingGet(section, "service").(*ast.MappingNode)
+	pipelines, _ := mappingGet(service, "pipelines").(*ast.MappingNode)
+	if pipelines == nil {
+		return nil
+	}
+	for _, item := range pipelines.Values {
+		pID := keyString(item)
+		pipeline, _ := item.Value.(*ast.MappingNode)
+		templateNode, ok := mappingGet(pipeline, "template").(*ast.StringNode)
+		if !ok {
+			continue
+		}
+		name := templateNode.Value
+		t, ok := templates[name]
+		if !ok {
+			return fmt.Errorf("%s pipeline %q uses template %q, which is not defined", subagent, pID, name)
+		}
+		used[name] = true
+		if mappingIndex(pipeline, "receivers") >= 0 || mappingIndex(pipeline, "processors") >= 0 {
+			return fmt.Errorf("%s pipeline %q must not set receivers or processors together with a template", subagent, pID)
+		}
+		params := map[string]ast.Node{}
+		if p, ok := mappingGet(pipeline, "parameters").(*ast.MappingNode); ok {
+			for _, param := range p.Values {
+				params[keyString(param)] = param.Value
+			}
+		}
+		referenced := map[string]bool{}
+		var receiverIDs, processorIDs []string
+		for _, c := range []struct {
+			kind       string
+			components []*ast.MappingValueNode
+			ids        *[]string
+		}{
+			{"receivers", t.receivers, &receiverIDs},
+			{"processors", t.processors, &processorIDs},
+		} {
+			for _, component := range c.components {
+				id, err := substituteString(fmt.Sprintf("%s_%s", pID, keyString(component)), params, referenced)
+				if err != nil {
+					return fmt.Errorf("%s pipeline %q: %w", subagent, pID, err)
+				}
+				value, err := substituteParameters(component.Value, params, referenced)
+				if err != nil {
+					return fmt.Errorf("%s pipeline %q: %w", subagent, pID, err)
+				}
+				components := mappingGetOrAdd(section, c.kind, item.Key)
+				if mappingIndex(components, id) >= 0 {
+					return fmt.Errorf("%s pipeline %q: template %q defines %s %q, which already exists", subagent, pID, name, c.kind, id)
+				}
+				components.Values = append(components.Values, ast.MappingValue(component.Start, newString(component.Key.GetToken(), id), value))
+				*c.ids = append(*c.ids, id)
+			}
+		}
+		for _, p := range sortedKeys(params) {
+			if !referenced[p] {
+				return fmt.Errorf("%s pipeline %q sets parameter %q, which is not used by template %q", subagent, pID, p, name)
+			}
+		}
+		var fields []*ast.MappingValueNode
+		for _, field := range pipeline.Values {
+			if k := keyString(field); k != "template" && k != "parameters" {
+				fields = append(fields, field)
+			}
+		}
+		// The lists of the instance take the position of the template reference.
+		fields = append(fields, ast.MappingValue(item.Start, newString(templateNode.Token, "receivers"), newStringSequence(templateNode.Token, receiverIDs)))
+		if len(processorIDs) > 0 {
+			fields = append(fields, ast.MappingValue(item.Start, newString(templateNode.Token, "processors"), newStringSequence(templateNode.Token, processorIDs)))
+		}
+		pipeline.Values = fields
+	}
+	return nil
+}
+
+// substituteParameters returns a copy of n with the parameter references replaced, and
+// records the parameters that were referenced.
+func substituteParameters(n ast.Node, params map[string]ast.Node, referenced map[string]bool) (ast.Node, error) {
+	switch n := n.(type) {
+	case *ast.StringNode:
+		if m := templateParameterRegex.FindStringSubmatch(n.Value); m != nil && m[0] == n.Value {
+			value, ok := params[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("parameter %q is not set", m[1])
+			}
+			referenced[m[1]] = true
+			return value, nil
+		}
+		s, err := substituteString(n.Value, params, referenced)
+		if err != nil {
+			return nil, err
+		}
+		return newString(n.Token, s), nil
+	case *ast.LiteralNode:
+		s, err := substituteString(n.Value.Value, params, referenced)
+		if err != nil {
+			return nil, err
+		}
+		out := *n
+		out.Value = newString(n.Value.Token, s)
+		return &out, nil
+	case *ast.AnchorNode:
+		value, err := substituteParameters(n.Value, params, referenced)
+		if err != nil {
+			return nil, err
+		}
+		out := *n
+		out.Value = value
+		return &out, nil
+	case *ast.MappingNode:
+		out := ast.Mapping(n.Start, n.IsFlowStyle)
+		for _, item := range n.Values {
+			key, err := substituteString(keyString(item), params, referenced)
+			if err != nil {
+				return nil, err
+			}
+			value, err := substituteParameters(item.Value, params, referenced)
+			if err != nil {
+				return nil, err
+			}
+			out.Values = append(out.Values, ast.MappingValue(item.Start, newString(item.Key.GetToken(), key), value))
+		}
+		return out, nil
+	case *ast.SequenceNode:
+		out := ast.Sequence(n.Start, n.IsFlowStyle)
+		for _, item := range n.Values {
+			value, err := substituteParameters(item, params, referenced)
+			if err != nil {
+				return nil, err
+			}
+			out.Values = append(out.Values, value)
+		}
+		return out, nil
+	default:
+		return n, nil
+	}
+}
+
+// substituteString replaces the parameter references in s with the values of the parameters.
+func substituteString(s string, params map[string]ast.Node, referenced map[string]bool) (string, error) {
+	var err error
+	out := templateParameterRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := templateParameterRegex.FindStringSubmatch(ref)[1]
+		value, ok := params[name]
+		if !ok {
+			err = fmt.Errorf("parameter %q is not set", name)
+			return ref
+		}
+		referenced[name] = true
+		if scalar, ok := value.(ast.ScalarNode); ok {
+			return fmt.Sprint(scalar.GetValue())
+		}
+		return value.String()
+	})
+	return out, err
+}
+
+// normalizeMappings replaces the mappings with a single entry in the tree of n with
+// *ast.MappingNode, so that entries can be added to all mappings.
+func normalizeMappings(n ast.Node) ast.Node {
+	switch n := n.(type) {
+	case *ast.MappingValueNode:
+		n.Value = normalizeMappings(n.Value)
+		return ast.Mapping(n.Start, false, n)
+	case *ast.MappingNode:
+		for _, item := range n.Values {
+			item.Value = normalizeMappings(item.Value)
+		}
+	case *ast.SequenceNode:
+		for i, item := range n.Values {
+			n.Values[i] = normalizeMappings(item)
+		}
+	case *ast.AnchorNode:
+		n.Value = normalizeMappings(n.Value)
+	case *ast.TagNode:
+		n.Value = normalizeMappings(n.Value)
+	}
+	return n
+}
+
+// newString returns a string node with the value s at the position of tk.
+func newString(tk *token.Token, s string) *ast.StringNode {
+	out := *tk
+	out.Type = token.StringType
+	out.Value = s
+	out.Origin = s
+	return ast.String(&out)
+}
+
+// newStringSequence returns a flow sequence of the values at the position of tk.
+func newStringSequence(tk *token.Token, values []string) *ast.SequenceNode {
+	out := ast.Sequence(tk, true)
+	for _, v := range values {
+		out.Values = append(out.Values, newString(tk, v))
+	}
+	return out
+}
+
+// keyString returns the key of item as a string.
+func keyString(item *ast.MappingValueNode) string {
+	if scalar, ok := item.Key.(ast.ScalarNode); ok {
+		return fmt.Sprint(scalar.GetValue())
+	}
+	return item.Key.String()
+}
+
+func mappingIndex(m *ast.MappingNode, key string) int {
+	if m == nil {
+		return -1
+	}
+	for i, item := range m.Values {
+		if keyString(item) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingGet returns the value of key in n, which may be a mapping with a single entry.
+func mappingGet(n ast.Node, key string) ast.Node {
+	switch n := n.(type) {
+	case *ast.MappingNode:
+		if i := mappingIndex(n, key); i >= 0 {
+			return n.Values[i].Value
+		}
+	case *ast.MappingValueNode:
+		if keyString(n) == key {
+			return n.Value
+		}
+	}
+	return nil
+}
+
+// mappingGetOrAdd returns the mapping that is the value of key in m, adding an empty one at
+// the position of pos if it doesn't exist yet.
+func mappingGetOrAdd(m *ast.MappingNode, key string, pos ast.MapKeyNode) *ast.MappingNode {
+	if v, ok := mappingGet(m, key).(*ast.MappingNode); ok {
+		return v
+	}
+	v := ast.Mapping(pos.GetToken(), false)
+	if i := mappingIndex(m, key); i >= 0 {
+		// An empty value, e.g. "receivers:", is replaced.
+		m.Values[i].Value = v
+	} else {
+		m.Values = append(m.Values, ast.MappingValue(pos.GetToken(), newString(pos.GetToken(), key), v))
+	}
+	return v
+}
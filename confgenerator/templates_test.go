@@ -0,0 +1,341 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	yaml "github.com/goccy/go-yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExpandTemplates(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "no templates",
+			input: `
+logging:
+  service:
+    pipelines: {}
+`,
+			want: `
+logging:
+  service:
+    pipelines: {}
+`,
+		},
+		{
+			name: "logging instances",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+        include_paths:
+        - /var/log/${app}/*.log
+    processors:
+      json:
+        type: parse_json
+      severity:
+        type: modify_fields
+        fields:
+          labels.app:
+            static_value: ${app}
+logging:
+  receivers:
+    syslog:
+      type: files
+      include_paths: [/var/log/syslog]
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [syslog]
+      payments:
+        template: app
+        parameters:
+          app: payments
+      billing:
+        template: app
+        parameters:
+          app: billing
+`,
+			want: `
+logging:
+  receivers:
+    syslog:
+      type: files
+      include_paths: [/var/log/syslog]
+    payments_log:
+      type: files
+      include_paths: [/var/log/payments/*.log]
+    billing_log:
+      type: files
+      include_paths: [/var/log/billing/*.log]
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: [syslog]
+      payments:
+        receivers: [payments_log]
+        processors: [payments_json, payments_severity]
+      billing:
+        receivers: [billing_log]
+        processors: [billing_json, billing_severity]
+  processors:
+    payments_json:
+      type: parse_json
+    payments_severity:
+      type: modify_fields
+      fields:
+        labels.app:
+          static_value: payments
+    billing_json:
+      type: parse_json
+    billing_severity:
+      type: modify_fields
+      fields:
+        labels.app:
+          static_value: billing
+`,
+		},
+		{
+			name: "metrics parameters keep their type",
+			input: `
+templates:
+  redis:
+    receivers:
+      redis:
+        type: redis
+        address: localhost:${port}
+        collection_interval: ${interval}
+metrics:
+  service:
+    pipelines:
+      cache:
+        template: redis
+        parameters:
+          port: 6379
+          interval: 30s
+`,
+			want: `
+metrics:
+  service:
+    pipelines:
+      cache:
+        receivers: [cache_redis]
+  receivers:
+    cache_redis:
+      type: redis
+      address: localhost:6379
+      collection_interval: 30s
+`,
+		},
+		{
+			name: "undefined template",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+logging:
+  service:
+    pipelines:
+      payments:
+        template: ap
+`,
+			wantErr: `logging pipeline "payments" uses template "ap", which is not defined`,
+		},
+		{
+			name: "missing parameter",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+        include_paths:
+        - /var/log/${app}/*.log
+logging:
+  service:
+    pipelines:
+      payments:
+        template: app
+`,
+			wantErr: `logging pipeline "payments": parameter "app" is not set`,
+		},
+		{
+			name: "unused parameter",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+logging:
+  service:
+    pipelines:
+      payments:
+        template: app
+        parameters:
+          ap: payments
+`,
+			wantErr: `logging pipeline "payments" sets parameter "ap", which is not used by template "app"`,
+		},
+		{
+			name: "receivers and template",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+logging:
+  service:
+    pipelines:
+      payments:
+        template: app
+        receivers: [syslog]
+`,
+			wantErr: `logging pipeline "payments" must not set receivers or processors together with a template`,
+		},
+		{
+			name: "conflicting ID",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+logging:
+  receivers:
+    payments_log:
+      type: files
+  service:
+    pipelines:
+      payments:
+        template: app
+`,
+			wantErr: `logging pipeline "payments": template "app" defines receivers "payments_log", which already exists`,
+		},
+		{
+			name: "unused template",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+`,
+			wantErr: `template "app" is not used by any pipeline`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandTemplates([]byte(strings.TrimSpace(tc.input)))
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("expandTemplates() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandTemplates() failed: %v", err)
+			}
+			var gotDoc, wantDoc yaml.MapSlice
+			if err := yaml.NodeToValue(got, &gotDoc, yaml.UseOrderedMap()); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.UnmarshalWithOptions([]byte(tc.want), &wantDoc, yaml.UseOrderedMap()); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wantDoc, gotDoc); diff != "" {
+				t.Errorf("expandTemplates() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExpandTemplatesKeepsPositions(t *testing.T) {
+	ctx := platform.Platform{Type: platform.Linux}.TestContext(context.Background())
+	for _, tc := range []struct {
+		name    string
+		input   string
+		wantPos string
+	}{
+		{
+			name: "error in template",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+        include_paths:
+        - /var/log/${app}/*.log
+        unknown_option: true
+logging:
+  service:
+    pipelines:
+      payments:
+        template: app
+        parameters:
+          app: payments
+`,
+			wantPos: "[8:9]",
+		},
+		{
+			name: "error after templates",
+			input: `
+templates:
+  app:
+    receivers:
+      log:
+        type: files
+        include_paths:
+        - /var/log/${app}/*.log
+logging:
+  receivers:
+    syslog:
+      type: files
+      include_paths:
+      - /var/log/syslog
+      unknown_option: true
+  service:
+    pipelines:
+      payments:
+        template: app
+        parameters:
+          app: payments
+`,
+			wantPos: "[14:7]",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := UnmarshalYamlToUnifiedConfig(ctx, []byte(strings.TrimSpace(tc.input)))
+			if err == nil || !strings.HasPrefix(err.Error(), tc.wantPos) {
+				t.Errorf("UnmarshalYamlToUnifiedConfig() error = %v, want an error at %s", err, tc.wantPos)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+
+local window = 60
+local entries = {}
+function self_log_dedup(tag, timestamp, record)
+    local severity = record["logging.googleapis.com/severity"]
+    local message = record["message"]
+    if (severity ~= "ERROR" and severity ~= "WARNING") or message == nil then
+        return 0, timestamp, record
+    end
+    local key = tag .. "\n" .. (string.gsub(tostring(message), "^%[[^%]]*%]", ""))
+    local now = os.time()
+    local entry = entries[key]
+    if entry ~= nil and now - entry.start < window then
+        entry.count = entry.count + 1
+        return -1, timestamp, record
+    end
+    -- Forget the messages whose window ended, so that the table doesn't grow forever.
+    for k, e in pairs(entries) do
+        if now - e.start >= window then
+            entries[k] = nil
+        end
+    end
+    entries[key] = {start = now, count = 0}
+    if entry ~= nil and entry.count > 0 then
+        record["suppressed_count"] = entry.count
+        return 2, timestamp, record
+    end
+    return 0, timestamp, record
+end
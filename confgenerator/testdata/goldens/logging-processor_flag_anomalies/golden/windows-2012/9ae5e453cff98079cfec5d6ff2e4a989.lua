@@ -0,0 +1,52 @@
+
+local stats = {}
+
+-- update adds v to the statistics of field i, and returns whether v is anomalous.
+function update(i, v)
+  local s = stats[i]
+  if s == nil then
+    stats[i] = {n = 1, mean = v, var = 0}
+    return false
+  end
+  local anomalous = false
+  if s.n >= 100 and s.var > 0 then
+    anomalous = math.abs(v - s.mean) / math.sqrt(s.var) > 4
+  end
+  local diff = v - s.mean
+  local incr = 0.1 * diff
+  s.mean = s.mean + incr
+  s.var = (1 - 0.1) * (s.var + diff * incr)
+  s.n = s.n + 1
+  return anomalous
+end
+
+function process(tag, timestamp, record)
+  local anomalous = false
+  local v0 = tonumber((function()
+return record["latency_ms"]
+end)())
+  if v0 ~= nil and update(0, v0) then
+    anomalous = true
+  end
+  local v1 = tonumber((function()
+if record["response"] == nil
+then
+return nil
+end
+return record["response"]["bytes"]
+end)())
+  if v1 ~= nil and update(1, v1) then
+    anomalous = true
+  end
+  if not anomalous then
+    return 0, timestamp, record
+  end
+  (function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["anomaly"] = value
+end)("true")
+  return 2, timestamp, record
+end
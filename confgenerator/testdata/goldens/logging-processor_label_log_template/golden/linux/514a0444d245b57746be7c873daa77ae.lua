@@ -0,0 +1,33 @@
+
+-- mask_hex masks the words of at least 8 hexadecimal digits, like hashes and IDs.
+function mask_hex(word)
+  if #word >= 8 and string.find(word, "%d") then
+    return "<hex>"
+  end
+end
+
+function process(tag, timestamp, record)
+  local message = (function()
+return record["message"]
+end)()
+  if type(message) ~= "string" then
+    return 0, timestamp, record
+  end
+  local template = string.gsub(message, "%x%x%x%x%x%x%x%x%-%x%x%x%x%-%x%x%x%x%-%x%x%x%x%-%x%x%x%x%x%x%x%x%x%x%x%x", "<uuid>")
+  template = string.gsub(template, "0[xX]%x+", "<hex>")
+  template = string.gsub(template, "%x+", mask_hex)
+  template = string.gsub(template, "%d+", "<num>")
+  -- djb2, kept within 32 bits so that the arithmetic stays exact.
+  local hash = 5381
+  for i = 1, #template do
+    hash = (hash * 33 + string.byte(template, i)) % 4294967296
+  end
+  (function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["log_template_id"] = value
+end)(string.format("%08x", hash))
+  return 2, timestamp, record
+end
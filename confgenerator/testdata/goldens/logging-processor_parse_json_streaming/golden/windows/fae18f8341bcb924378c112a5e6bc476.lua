@@ -0,0 +1,57 @@
+
+function split_json(tag, timestamp, record)
+  local key = "message"
+  local s = record[key]
+  if type(s) ~= "string" then
+    return 0, 0, 0
+  end
+  local array = string.match(s, "^%s*%[") ~= nil
+  local base = 0
+  if array then
+    base = 1
+  end
+  local docs = {}
+  local depth = 0
+  local in_string = false
+  local escaped = false
+  local start = nil
+  for i = 1, #s do
+    local c = string.sub(s, i, i)
+    if in_string then
+      if escaped then
+        escaped = false
+      elseif c == "\\" then
+        escaped = true
+      elseif c == '"' then
+        in_string = false
+      end
+    elseif c == '"' then
+      in_string = true
+    elseif c == "{" or c == "[" then
+      if depth == base and c == "{" then
+        start = i
+      end
+      depth = depth + 1
+    elseif c == "}" or c == "]" then
+      depth = depth - 1
+      if depth == base and start ~= nil then
+        table.insert(docs, string.sub(s, start, i))
+        start = nil
+      end
+    end
+  end
+  -- Leave a single object, or anything that isn't objects, to the parser.
+  if #docs == 0 or (#docs == 1 and not array) then
+    return 0, 0, 0
+  end
+  local records = {}
+  for _, doc in ipairs(docs) do
+    local r = {}
+    for k, v in pairs(record) do
+      r[k] = v
+    end
+    r[key] = doc
+    table.insert(records, r)
+  end
+  return 2, timestamp, records
+end
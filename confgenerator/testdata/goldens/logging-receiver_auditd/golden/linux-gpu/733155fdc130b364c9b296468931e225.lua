@@ -0,0 +1,99 @@
+
+	local pending = nil
+	local pending_id = nil
+	local pending_ts = nil
+
+	-- auditd hex-encodes the value of these fields when it contains spaces, quotes or control
+	-- characters. Encoded values are written without quotes.
+	local auditd_encoded_fields = {
+	  acct = true, cmd = true, comm = true, cwd = true, data = true, exe = true,
+	  name = true, new = true, old = true, path = true, proctitle = true,
+	}
+
+	local function auditd_decode_hex(v)
+	  if (v:len() % 2 ~= 0 or not v:match("^%x+$")) then
+	    return v
+	  end
+	  local decoded = v:gsub("%x%x", function(h) return string.char(tonumber(h, 16)) end)
+	  -- The arguments in proctitle are separated by NUL characters.
+	  return (decoded:gsub("%z", " "))
+	end
+
+	local function auditd_parse_fields(s, fields, decode_args)
+	  local i = 1
+	  while true do
+	    local _, e, k = s:find("([%w_-]+)=", i)
+	    if (e == nil) then
+	      break
+	    end
+	    local q = s:sub(e + 1, e + 1)
+	    local v = nil
+	    if (q == '"' or q == "'") then
+	      local close = s:find(q, e + 2, true) or (s:len() + 1)
+	      v = s:sub(e + 2, close - 1)
+	      i = close + 1
+	      if (q == "'") then
+	        -- User space records (e.g. USER_LOGIN) wrap their own fields in msg='...'.
+	        auditd_parse_fields(v, fields, false)
+	        v = nil
+	      end
+	    else
+	      -- Enriched logs (log_format = ENRICHED) separate the translated fields with 0x1D.
+	      local close = s:find("[ \029]", e + 1) or (s:len() + 1)
+	      v = s:sub(e + 1, close - 1)
+	      i = close + 1
+	      if (auditd_encoded_fields[k] or (decode_args and k:match("^a%d+$"))) then
+	        v = auditd_decode_hex(v)
+	      end
+	    end
+	    if (v ~= nil) then
+	      fields[k] = v
+	    end
+	  end
+	  return fields
+	end
+
+	function auditd_merge_records(tag, timestamp, record)
+	  local id = record["audit_id"]
+	  if (id == nil) then
+	    return 0, timestamp, record
+	  end
+	  local rtype = record["type"]
+	  local ts = tonumber(record["audit_time"]) or timestamp
+	  -- Only the arguments of EXECVE records are strings; SYSCALL arguments are register values.
+	  local fields = auditd_parse_fields(record["message"] or "", {}, rtype == "EXECVE")
+	  record["audit_time"] = nil
+	  record["message"] = nil
+
+	  local out = nil
+	  local out_ts = nil
+	  if (pending ~= nil and (pending_id ~= id or rtype == "EOE")) then
+	    out, out_ts = pending, pending_ts
+	    pending = nil
+	  end
+
+	  if (rtype == "EOE") then
+	    -- Nothing to keep.
+	  elseif (pending == nil) then
+	    for k, v in pairs(fields) do
+	      record[k] = v
+	    end
+	    pending, pending_id, pending_ts = record, id, ts
+	  else
+	    local key = string.lower(rtype)
+	    if (key == "path") then
+	      pending["paths"] = pending["paths"] or {}
+	      table.insert(pending["paths"], fields)
+	    elseif (fields[key] ~= nil and next(fields, next(fields)) == nil) then
+	      pending[key] = fields[key]
+	    else
+	      pending[key] = fields
+	    end
+	  end
+
+	  if (out == nil) then
+	    return -1, 0, 0
+	  end
+	  return 1, out_ts, out
+	end
+	
\ No newline at end of file
@@ -0,0 +1,44 @@
+
+function process(tag, timestamp, record)
+local __field_0 = (function()
+return record["event"]
+end)();
+local __field_2 = (function()
+return record["severity"]
+end)();
+(function(value)
+record["event"] = value
+end)(nil);
+(function(value)
+record["severity"] = value
+end)(nil);
+local v = __field_0;
+if v == "Accepted connection" then v = "accepted"
+elseif v == "client closed the connection" then v = "client_closed"
+elseif v == "failed to connect to instance" then v = "failed"
+elseif v == "instance closed the connection" then v = "instance_closed"
+else v = nil
+end
+(function(value)
+record["connection_event"] = value
+end)(v)
+local v = "agent.googleapis.com/cloudsql_proxy";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["logging.googleapis.com/instrumentation_source"] = value
+end)(v)
+local v = __field_2;
+if v == "DEBUG" then v = "DEBUG"
+elseif v == "ERROR" then v = "ERROR"
+elseif v == "INFO" then v = "INFO"
+elseif v == "WARNING" then v = "WARNING"
+else v = nil
+end
+(function(value)
+record["logging.googleapis.com/severity"] = value
+end)(v)
+return 2, timestamp, record
+end
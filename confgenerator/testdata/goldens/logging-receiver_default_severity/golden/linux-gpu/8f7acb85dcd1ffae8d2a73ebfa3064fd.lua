@@ -0,0 +1,12 @@
+
+function process(tag, timestamp, record)
+local __field_0 = (function()
+return record["logging.googleapis.com/severity"]
+end)();
+local v = __field_0;
+if v == nil then v = "INFO" end;
+(function(value)
+record["logging.googleapis.com/severity"] = value
+end)(v)
+return 2, timestamp, record
+end
@@ -0,0 +1,29 @@
+
+function process(tag, timestamp, record)
+local __field_1 = (function()
+return record["level"]
+end)();
+local v = "agent.googleapis.com/dirsrv_error";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["logging.googleapis.com/instrumentation_source"] = value
+end)(v)
+local v = __field_1;
+if v == "ALERT" then v = "ALERT"
+elseif v == "CRIT" then v = "CRITICAL"
+elseif v == "DEBUG" then v = "DEBUG"
+elseif v == "EMERG" then v = "EMERGENCY"
+elseif v == "ERR" then v = "ERROR"
+elseif v == "INFO" then v = "INFO"
+elseif v == "NOTICE" then v = "NOTICE"
+elseif v == "WARNING" then v = "WARNING"
+else v = nil
+end
+(function(value)
+record["logging.googleapis.com/severity"] = value
+end)(v)
+return 2, timestamp, record
+end
@@ -0,0 +1,107 @@
+
+local slowlog_keys = {"took", "took_millis", "total_hits", "types", "stats", "search_type", "total_shards", "source", "id", "type", "routing"}
+
+-- parse_utc returns the seconds since the epoch of an ISO 8601 time in UTC.
+local function parse_utc(s)
+  local y, mo, d, h, mi, sec, frac = string.match(s, "^(%d+)-(%d+)-(%d+)T(%d+):(%d+):(%d+)[.,]?(%d*)Z$")
+  if y == nil then
+    return nil
+  end
+  -- Days since the epoch of the date, see http://howardhinnant.github.io/date_algorithms.html.
+  y, mo, d = tonumber(y), tonumber(mo), tonumber(d)
+  if mo <= 2 then
+    y = y - 1
+  end
+  local era = math.floor(y / 400)
+  local yoe = y - era * 400
+  local doy = math.floor((153 * ((mo + 9) % 12) + 2) / 5) + d - 1
+  local doe = yoe * 365 + math.floor(yoe / 4) - math.floor(yoe / 100) + doy
+  local t = (era * 146097 + doe - 719468) * 86400 + tonumber(h) * 3600 + tonumber(mi) * 60 + tonumber(sec)
+  if frac ~= "" then
+    t = t + tonumber("0." .. frac)
+  end
+  return t
+end
+
+-- text_field returns the value of key in a message of the plain text layout. The values end
+-- with "], " and the next key, or with "]" at the end of the message.
+local function text_field(msg, key)
+  local v = string.match(msg, "[%s,]" .. key .. "%[(.-)%],%s+[%w_]+%[")
+  if v == nil then
+    v = string.match(msg, "[%s,]" .. key .. "%[(.*)%],?%s*$")
+  end
+  return v
+end
+
+function process(tag, timestamp, record)
+  local code = 2
+  local renames = {}
+  for k, _ in pairs(record) do
+    local name = string.match(k, "^elasticsearch%.slowlog%.(.+)$")
+    if name == nil then
+      local field = string.match(k, "^elasticsearch%.(.+)$")
+      if field ~= nil and (string.match(field, "^cluster%.") or string.match(field, "^node%.")) then
+        name = field
+      end
+    end
+    if k == "log.level" then
+      name = "level"
+    elseif k == "log.logger" then
+      name = "component"
+    elseif k == "node_name" then
+      name = "node.name"
+    end
+    if name ~= nil then
+      renames[k] = name
+    end
+  end
+  for k, name in pairs(renames) do
+    record[name] = record[k]
+    record[k] = nil
+  end
+  if type(record["@timestamp"]) == "string" then
+    local t = parse_utc(record["@timestamp"])
+    if t ~= nil then
+      timestamp = t
+      code = 1
+      record["@timestamp"] = nil
+    end
+  end
+
+  local msg = record["message"]
+  if type(msg) == "string" then
+    -- Search slow logs start with "[index][shard]", indexing slow logs with "[index/uuid]".
+    local index, rest = string.match(msg, "^%[([^%]]+)%](.*)$")
+    if index ~= nil then
+      local name, uuid = string.match(index, "^(.-)/(.+)$")
+      if name ~= nil then
+        index = name
+        record["index_uuid"] = uuid
+      end
+      record["index"] = index
+      local shard = string.match(rest, "^%[(%d+)%]")
+      if shard ~= nil then
+        record["shard"] = tonumber(shard)
+      end
+    end
+    if record["took"] == nil then
+      for _, key in ipairs(slowlog_keys) do
+        local v = text_field(msg, key)
+        if v ~= nil then
+          -- The JSON layout names the type of the document doc_type.
+          if key == "type" then
+            key = "doc_type"
+          end
+          record[key] = v
+        end
+      end
+    end
+  end
+  for _, key in ipairs({"took_millis", "total_shards"}) do
+    local n = tonumber(record[key])
+    if n ~= nil then
+      record[key] = n
+    end
+  end
+  return code, timestamp, record
+end
@@ -0,0 +1,20 @@
+
+function process(tag, timestamp, record)
+local v = "payments";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["app"] = value
+end)(v)
+local v = "backend";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["tier"] = value
+end)(v)
+return 2, timestamp, record
+end
@@ -0,0 +1,44 @@
+
+function process(tag, timestamp, record)
+local __field_1 = (function()
+return record["queue_id"]
+end)();
+local __field_3 = (function()
+return record["level"]
+end)();
+local v = "agent.googleapis.com/postfix";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["logging.googleapis.com/instrumentation_source"] = value
+end)(v)
+local v = __field_1;
+(function(value)
+if record["logging.googleapis.com/operation"] == nil
+then
+record["logging.googleapis.com/operation"] = {}
+end
+record["logging.googleapis.com/operation"]["id"] = value
+end)(v)
+local v = "postfix";
+(function(value)
+if record["logging.googleapis.com/operation"] == nil
+then
+record["logging.googleapis.com/operation"] = {}
+end
+record["logging.googleapis.com/operation"]["producer"] = value
+end)(v)
+local v = __field_3;
+if v == "error" then v = "ERROR"
+elseif v == "fatal" then v = "CRITICAL"
+elseif v == "panic" then v = "EMERGENCY"
+elseif v == "warning" then v = "WARNING"
+else v = nil
+end
+(function(value)
+record["logging.googleapis.com/severity"] = value
+end)(v)
+return 2, timestamp, record
+end
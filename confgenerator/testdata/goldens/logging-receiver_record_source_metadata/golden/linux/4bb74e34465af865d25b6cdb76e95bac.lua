@@ -0,0 +1,18 @@
+
+function process(tag, timestamp, record)
+local __field_0 = (function()
+return record["agent.googleapis.com/forward_tag"]
+end)();
+(function(value)
+record["agent.googleapis.com/forward_tag"] = value
+end)(nil);
+local v = __field_0;
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["agent.googleapis.com/forward_tag"] = value
+end)(v)
+return 2, timestamp, record
+end
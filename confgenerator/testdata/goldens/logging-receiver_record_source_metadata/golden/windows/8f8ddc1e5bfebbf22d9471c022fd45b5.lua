@@ -0,0 +1,6 @@
+
+function add_forward_tag(tag, timestamp, record)
+  -- The tag is formatted like <hash_string>.<pipeline_id>.<receiver_id>.<existing_tag>
+  record["agent.googleapis.com/forward_tag"] = string.match(tag, "^[^.]*%.[^.]*%.[^.]*%.(.*)$")
+  return 2, timestamp, record
+end
@@ -0,0 +1,76 @@
+
+local facilities = {
+  "kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+  "uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+  "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+local severities = {
+  "EMERGENCY", "ALERT", "CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO", "DEBUG",
+}
+
+function parse_structured_data(sd)
+  local out = {}
+  local i = 1
+  local n = #sd
+  while string.sub(sd, i, i) == "[" do
+    local id_end = string.find(sd, "[ %]]", i + 1)
+    if id_end == nil then
+      return out
+    end
+    local id = string.sub(sd, i + 1, id_end - 1)
+    local params = {}
+    i = id_end
+    while string.sub(sd, i, i) == " " do
+      local eq = string.find(sd, "=", i + 1, true)
+      if eq == nil or string.sub(sd, eq + 1, eq + 1) ~= '"' then
+        return out
+      end
+      local name = string.sub(sd, i + 1, eq - 1)
+      local value = {}
+      local j = eq + 2
+      while j <= n do
+        local c = string.sub(sd, j, j)
+        local following = string.sub(sd, j + 1, j + 1)
+        if c == "\\" and (following == '"' or following == "\\" or following == "]") then
+          table.insert(value, following)
+          j = j + 2
+        elseif c == '"' then
+          break
+        else
+          table.insert(value, c)
+          j = j + 1
+        end
+      end
+      params[name] = table.concat(value)
+      i = j + 1
+    end
+    if string.sub(sd, i, i) ~= "]" then
+      return out
+    end
+    out[id] = params
+    i = i + 1
+  end
+  return out
+end
+
+function syslog_header(tag, timestamp, record)
+  local pri = tonumber(record["pri"])
+  if pri == nil then
+    return 0, 0, 0
+  end
+  record["pri"] = nil
+  record["facility"] = facilities[math.floor(pri / 8) + 1]
+  record["logging.googleapis.com/severity"] = severities[(pri % 8) + 1]
+  local sd = record["structured_data"]
+  if sd == "-" then
+    record["structured_data"] = nil
+  elseif type(sd) == "string" then
+    record["structured_data"] = parse_structured_data(sd)
+  end
+  for _, k in ipairs({"host", "ident", "pid", "msgid"}) do
+    if record[k] == "-" then
+      record[k] = nil
+    end
+  end
+  return 2, timestamp, record
+end
@@ -0,0 +1,40 @@
+
+function syslog_octet_counting(tag, timestamp, record)
+  local message = record["message"]
+  if type(message) ~= "string" then
+    return 0, timestamp, record
+  end
+  local records = {}
+  local pos = 1
+  while pos <= #message do
+    local length = string.match(message, "^(%d+) ", pos)
+    if length == nil then
+      break
+    end
+    local start = pos + #length + 1
+    local frame = {}
+    for k, v in pairs(record) do
+      frame[k] = v
+    end
+    frame["message"] = string.sub(message, start, start + tonumber(length) - 1)
+    table.insert(records, frame)
+    pos = start + tonumber(length)
+    -- Skip the trailer of frames that were sent with both a length and a trailer.
+    while string.sub(message, pos, pos) == "\n" or string.sub(message, pos, pos) == "\0" do
+      pos = pos + 1
+    end
+  end
+  if #records == 0 then
+    return 0, timestamp, record
+  end
+  if pos <= #message then
+    -- Keep what follows the last frame, rather than dropping it.
+    local rest = {}
+    for k, v in pairs(record) do
+      rest[k] = v
+    end
+    rest["message"] = string.sub(message, pos)
+    table.insert(records, rest)
+  end
+  return 2, timestamp, records
+end
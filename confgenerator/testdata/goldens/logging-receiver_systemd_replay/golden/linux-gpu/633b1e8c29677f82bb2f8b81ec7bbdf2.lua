@@ -0,0 +1,8 @@
+
+local cutoff = os.time() - 3600
+function journald_replay(tag, timestamp, record)
+    if timestamp < cutoff then
+        return -1, timestamp, record
+    end
+    return 0, timestamp, record
+end
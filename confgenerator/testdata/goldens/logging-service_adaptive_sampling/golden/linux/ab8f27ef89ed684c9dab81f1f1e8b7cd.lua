@@ -0,0 +1,25 @@
+
+local rate_path = "/var/lib/google-cloud-ops-agent/fluent-bit/adaptive_sampling_rate"
+local min_rate = 0.1
+local rate = 1
+local checked = 0
+
+function sample(tag, timestamp, record)
+  local now = os.time()
+  if now - checked >= 10 then
+    checked = now
+    rate = 1
+    local f = io.open(rate_path, "r")
+    if f ~= nil then
+      local r = tonumber(f:read("*l"))
+      f:close()
+      if r ~= nil and r < 1 then
+        rate = math.max(r, min_rate)
+      end
+    end
+  end
+  if rate < 1 and math.random() >= rate then
+    return -1, timestamp, record
+  end
+  return 0, timestamp, record
+end
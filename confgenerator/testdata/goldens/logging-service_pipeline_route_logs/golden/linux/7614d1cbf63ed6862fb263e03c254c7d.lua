@@ -0,0 +1,30 @@
+
+function process(tag, timestamp, record)
+local route0 = ((function(v) if v == nil then return false end return (string.lower(tostring(v)) == string.lower("ERROR")) end)((function()
+return record["logging.googleapis.com/severity"]
+end)()) or (function(v) if v == nil then return false end return (string.lower(tostring(v)) == string.lower("CRITICAL")) end)((function()
+return record["logging.googleapis.com/severity"]
+end)()));
+local route1 = (function(v) if v == nil then return false end return (string.lower(tostring(v)) == string.lower("true")) end)((function()
+return record["audit"]
+end)());
+if route0 then
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["agent.googleapis.com/log_bucket"] = value
+end)("paged");
+(function(value)
+record["logging.googleapis.com/logName"] = value
+end)("app-paged");
+elseif route1 then
+(function(value)
+record["logging.googleapis.com/logName"] = value
+end)("app-audit");
+else
+  return 0, timestamp, record
+end
+return 2, timestamp, record
+end
@@ -0,0 +1,12 @@
+
+function process(tag, timestamp, record)
+local v = "archive";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["agent.googleapis.com/log_bucket"] = value
+end)(v)
+return 2, timestamp, record
+end
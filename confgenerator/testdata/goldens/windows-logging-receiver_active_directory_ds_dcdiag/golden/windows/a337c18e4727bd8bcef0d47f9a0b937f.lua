@@ -0,0 +1,16 @@
+
+function process(tag, timestamp, record)
+local v = "agent.googleapis.com/active_directory_ds_dcdiag";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["logging.googleapis.com/instrumentation_source"] = value
+end)(v)
+local v = "ERROR";
+(function(value)
+record["logging.googleapis.com/severity"] = value
+end)(v)
+return 2, timestamp, record
+end
@@ -0,0 +1,58 @@
+
+function process(tag, timestamp, record)
+local __field_0 = (function()
+return record["http_request_requestMethod"]
+end)();
+local __field_1 = (function()
+return record["http_request_requestUrl"]
+end)();
+local __field_2 = (function()
+return record["http_request_status"]
+end)();
+(function(value)
+record["http_request_requestMethod"] = value
+end)(nil);
+(function(value)
+record["http_request_requestUrl"] = value
+end)(nil);
+(function(value)
+record["http_request_status"] = value
+end)(nil);
+local v = __field_0;
+(function(value)
+if record["logging.googleapis.com/httpRequest"] == nil
+then
+record["logging.googleapis.com/httpRequest"] = {}
+end
+record["logging.googleapis.com/httpRequest"]["requestMethod"] = value
+end)(v)
+local v = __field_1;
+(function(value)
+if record["logging.googleapis.com/httpRequest"] == nil
+then
+record["logging.googleapis.com/httpRequest"] = {}
+end
+record["logging.googleapis.com/httpRequest"]["requestUrl"] = value
+end)(v)
+local v = __field_2;
+(function(value)
+if record["logging.googleapis.com/httpRequest"] == nil
+then
+record["logging.googleapis.com/httpRequest"] = {}
+end
+record["logging.googleapis.com/httpRequest"]["status"] = value
+end)(v)
+local v = "agent.googleapis.com/windows_iis_request_tracing";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["logging.googleapis.com/instrumentation_source"] = value
+end)(v)
+local v = "WARNING";
+(function(value)
+record["logging.googleapis.com/severity"] = value
+end)(v)
+return 2, timestamp, record
+end
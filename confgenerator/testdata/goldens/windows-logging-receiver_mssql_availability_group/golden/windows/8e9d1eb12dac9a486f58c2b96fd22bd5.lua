@@ -0,0 +1,12 @@
+
+function process(tag, timestamp, record)
+local v = "agent.googleapis.com/mssql_availability_group";
+(function(value)
+if record["logging.googleapis.com/labels"] == nil
+then
+record["logging.googleapis.com/labels"] = {}
+end
+record["logging.googleapis.com/labels"]["logging.googleapis.com/instrumentation_source"] = value
+end)(v)
+return 2, timestamp, record
+end
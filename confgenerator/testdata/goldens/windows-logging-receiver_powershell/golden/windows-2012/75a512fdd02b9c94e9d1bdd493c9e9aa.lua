@@ -0,0 +1,26 @@
+
+function process(tag, timestamp, record)
+  local inserts = record["StringInserts"]
+  if type(inserts) ~= "table" then
+    return 0, timestamp, record
+  end
+  if record["EventID"] == 4104 then
+    -- Creating Scriptblock text (%1 of %2): %3 ScriptBlock ID: %4 Path: %5
+    record["command"] = inserts[3]
+    record["script_block_id"] = inserts[4]
+    if inserts[5] ~= nil and inserts[5] ~= "" then
+      record["script_path"] = inserts[5]
+    end
+    record["user"] = record["UserID"]
+  elseif record["EventID"] == 4103 then
+    -- The context info holds "Key = Value" lines, e.g. "Command Name = Get-Process".
+    local context = inserts[1]
+    if type(context) == "string" then
+      record["command"] = context:match("Command Name = ([^\r\n]*)")
+      record["user"] = context:match("\n%s*User = ([^\r\n]*)")
+    end
+  else
+    return 0, timestamp, record
+  end
+  return 2, timestamp, record
+end
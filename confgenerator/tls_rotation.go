@@ -0,0 +1,54 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator
+
+import (
+	"sort"
+)
+
+// TLSReloadInterval is how often the OTel components reload their certificate, key and CA
+// files, so that short-lived certificates are rotated without restarting the pipelines. The
+// Prometheus receiver reads them for every scrape, and the diagnostics service hot reloads the
+// logging subagent when the files of LoggingTLSFiles change.
+const TLSReloadInterval = "1m"
+
+// LoggingTLSFiles returns the certificate, key and CA files that the logging subagent reads.
+func (uc *UnifiedConfig) LoggingTLSFiles() []string {
+	if uc.Logging == nil {
+		return nil
+	}
+	files := map[string]bool{}
+	for _, r := range uc.Logging.Receivers {
+		if r, ok := r.(*LoggingReceiverFluentForward); ok && r.TLS != nil {
+			files[r.TLS.CertFile] = true
+			files[r.TLS.KeyFile] = true
+			files[r.TLS.ClientCAFile] = true
+		}
+	}
+	if uc.Logging.Service != nil {
+		for _, p := range uc.Logging.Service.Pipelines {
+			if p.SyslogForward != nil && p.SyslogForward.Mode == "tls" {
+				files[p.SyslogForward.CAFile] = true
+			}
+		}
+	}
+	delete(files, "")
+	var out []string
+	for f := range files {
+		out = append(out, f)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confgenerator_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoggingTLSFiles(t *testing.T) {
+	uc := mustParseConfig(t, `
+logging:
+  receivers:
+    forward:
+      type: fluent_forward
+      tls:
+        cert_file: /etc/certs/forward.crt
+        key_file: /etc/certs/forward.key
+    forward_mtls:
+      type: fluent_forward
+      listen_port: 24225
+      tls:
+        cert_file: /etc/certs/forward.crt
+        key_file: /etc/certs/forward.key
+        client_ca_file: /etc/certs/ca.crt
+  service:
+    pipelines:
+      forward:
+        receivers: [forward, forward_mtls]
+        syslog_forward:
+          endpoint: siem.example.com:6514
+          mode: tls
+          ca_file: /etc/certs/siem-ca.crt
+`)
+	want := []string{"/etc/certs/ca.crt", "/etc/certs/forward.crt", "/etc/certs/forward.key", "/etc/certs/siem-ca.crt"}
+	if got := uc.LoggingTLSFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LoggingTLSFiles() = %v, want %v", got, want)
+	}
+}
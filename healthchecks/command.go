@@ -0,0 +1,182 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A check can also be shipped as a program instead of being built into the agent, so that
+// images can add checks to the agent that they install from the packages. The program is
+// installed in the health checks directory of the agent, e.g.
+// /etc/google-cloud-ops-agent/healthchecks.d on Linux, and is named after the check. The agent
+// runs it with the other registered checks, and reads the result that Main writes:
+//
+//	func main() {
+//		healthchecks.Main(licenseServerCheck{})
+//	}
+//
+// The result is a JSON object on stdout with the errors of the check, which have the fields of
+// Error in snake case:
+//
+//	{"errors": [{"code": "LicenseServerConnErr", "class": "CONNECTION", "message": "...", "is_fatal": true}]}
+//
+// An error without a code gets the ERROR result. A program that exits with a non-zero status or
+// writes no result fails with the ERROR result.
+
+// commandResult is the result that a check program writes to stdout.
+type commandResult struct {
+	Errors []commandError `json:"errors,omitempty"`
+}
+
+type commandError struct {
+	Code         string `json:"code,omitempty"`
+	Class        string `json:"class,omitempty"`
+	Message      string `json:"message"`
+	Action       string `json:"action,omitempty"`
+	ResourceLink string `json:"resource_link,omitempty"`
+	IsFatal      bool   `json:"is_fatal,omitempty"`
+}
+
+// Main runs c as a check program and exits. The logs of c are written to stderr.
+func Main(c Check) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	err := c.Run(ctx, stderrLogger{os.Stderr})
+	cancel()
+	if err := writeCommandResult(os.Stdout, err); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the result of %q: %v\n", c.Name(), err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func writeCommandResult(w io.Writer, err error) error {
+	var result commandResult
+	for _, e := range splitErrors(err) {
+		var checkErr Error
+		if errors.As(e, &checkErr) {
+			result.Errors = append(result.Errors, commandError(checkErr))
+		} else {
+			result.Errors = append(result.Errors, commandError{Message: e.Error()})
+		}
+	}
+	return json.NewEncoder(w).Encode(result)
+}
+
+// splitErrors returns the errors that were joined with errors.Join into err.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// readCommandResult returns the error of the check whose program wrote output.
+func readCommandResult(output []byte) error {
+	var result commandResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		return fmt.Errorf("invalid result %q: %w", output, err)
+	}
+	var errs []error
+	for _, e := range result.Errors {
+		if e.Code == "" {
+			errs = append(errs, errors.New(e.Message))
+		} else {
+			errs = append(errs, Error(e))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type stderrLogger struct {
+	w io.Writer
+}
+
+func (l stderrLogger) log(level, msg string, keysAndValues ...any) {
+	fmt.Fprintf(l.w, "%s: %s", level, msg)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fmt.Fprintf(l.w, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	fmt.Fprintln(l.w)
+}
+
+func (l stderrLogger) Infow(msg string, keysAndValues ...any) {
+	l.log("INFO", msg, keysAndValues...)
+}
+
+func (l stderrLogger) Warnw(msg string, keysAndValues ...any) {
+	l.log("WARNING", msg, keysAndValues...)
+}
+
+func (l stderrLogger) Errorw(msg string, keysAndValues ...any) {
+	l.log("ERROR", msg, keysAndValues...)
+}
+
+// commandCheck runs a check program.
+type commandCheck struct {
+	path string
+}
+
+func (c commandCheck) Name() string {
+	return strings.TrimSuffix(filepath.Base(c.path), filepath.Ext(c.path))
+}
+
+func (c commandCheck) Run(ctx context.Context, logger Logger) error {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.path)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if s := strings.TrimSpace(stderr.String()); s != "" {
+		logger.Infow(s, "check", c.Name())
+	}
+	if err != nil {
+		return fmt.Errorf("health check program %q failed: %w", c.path, err)
+	}
+	return readCommandResult(stdout.Bytes())
+}
+
+// CommandChecks returns the checks of the programs in dir, sorted by name. It returns no checks
+// if dir doesn't exist.
+func CommandChecks(dir string) ([]Check, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checks []Check
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		checks = append(checks, commandCheck{path: filepath.Join(dir, e.Name())})
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name() < checks[j].Name() })
+	return checks, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package healthchecks is the API for adding health checks to the Ops Agent, e.g. for images
+// that need the agent to verify that their license server is reachable. The registered checks
+// run after the built-in checks, and their results are written to the ops-agent-health log and
+// to the health checks results file with the same schema as those of the built-in checks.
+//
+// Checks are registered by the init functions of packages that are built into the agent:
+//
+//	func init() {
+//		healthchecks.Register(licenseServerCheck{})
+//	}
+//
+// Images that install the agent from the packages can't rebuild it. They ship their checks as
+// programs instead, which run the check with Main and are installed in the health checks
+// directory of the agent. See CommandChecks.
+//
+// This package is kept backwards compatible: identifiers may be added, but the existing ones
+// don't change their meaning.
+package healthchecks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// The classes of errors, which group the errors in the ops-agent-health log.
+const (
+	ClassAPI        = "API"
+	ClassConnection = "CONNECTION"
+	ClassGeneric    = "GENERIC"
+	ClassPort       = "PORT"
+	ClassPermission = "PERMISSION"
+	ClassRuntime    = "RUNTIME"
+)
+
+// Error is a problem that a check found.
+type Error struct {
+	// Code identifies the problem in the ops-agent-health log, e.g. "LicenseServerConnErr".
+	// Codes are shared by all the checks, so the codes of checks that are not part of the agent
+	// should start with the name of their product.
+	Code string
+	// Class is one of the Class constants.
+	Class string
+	// Message describes the problem.
+	Message string
+	// Action tells the user how to fix the problem.
+	Action string
+	// ResourceLink is a link to the documentation of the problem.
+	ResourceLink string
+	// IsFatal is set when the agent can't work because of the problem. The check then has the
+	// FAIL result, and otherwise the WARNING result.
+	IsFatal bool
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// Logger writes to the ops-agent-health log. The key and value pairs are added to the entry
+// as structured fields.
+type Logger interface {
+	Infow(msg string, keysAndValues ...any)
+	Warnw(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+}
+
+// Check is a health check.
+type Check interface {
+	// Name is the name of the check in the results, e.g. "License Server Check".
+	Name() string
+	// Run runs the check. It returns nil when the check passes, an Error, or several Errors
+	// joined with errors.Join. Any other error is reported with the ERROR result. ctx is
+	// canceled after Timeout.
+	Run(ctx context.Context, logger Logger) error
+}
+
+// Timeout is how long a registered check may run.
+const Timeout = 30 * time.Second
+
+var (
+	mu         sync.Mutex
+	registered []Check
+)
+
+// Register adds a check that runs after the built-in checks. It panics if a check with the same
+// name is already registered.
+func Register(c Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, r := range registered {
+		if r.Name() == c.Name() {
+			panic(fmt.Sprintf("health check %q is already registered", c.Name()))
+		}
+	}
+	registered = append(registered, c)
+}
+
+// Registered returns the registered checks, in the order in which they were registered.
+func Registered() []Check {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Check(nil), registered...)
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+type namedCheck string
+
+func (c namedCheck) Name() string {
+	return string(c)
+}
+
+func (c namedCheck) Run(ctx context.Context, logger Logger) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	defer func() { registered = nil }()
+	Register(namedCheck("B Check"))
+	Register(namedCheck("A Check"))
+
+	got := Registered()
+	if len(got) != 2 || got[0].Name() != "B Check" || got[1].Name() != "A Check" {
+		t.Errorf("Registered() = %v, want the checks in the order in which they were registered", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a check with the same name twice didn't panic")
+		}
+	}()
+	Register(namedCheck("A Check"))
+}
+
+func TestCommandResult(t *testing.T) {
+	licenseErr := Error{
+		Code:    "PartnerLicenseErr",
+		Class:   ClassConnection,
+		Message: "The license server is unreachable.",
+		IsFatal: true,
+	}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "pass", want: "{}\n"},
+		{name: "error", err: licenseErr, want: `{"errors":[{"code":"PartnerLicenseErr","class":"CONNECTION","message":"The license server is unreachable.","is_fatal":true}]}` + "\n"},
+		{name: "unstructured error", err: errors.New("boom"), want: `{"errors":[{"message":"boom"}]}` + "\n"},
+		{name: "joined errors", err: errors.Join(licenseErr, errors.New("boom")), want: `{"errors":[{"code":"PartnerLicenseErr","class":"CONNECTION","message":"The license server is unreachable.","is_fatal":true},{"message":"boom"}]}` + "\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeCommandResult(&out, tc.err); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("writeCommandResult() wrote %s, want %s", out.String(), tc.want)
+			}
+			got := readCommandResult(out.Bytes())
+			if (got == nil) != (tc.err == nil) || (got != nil && got.Error() != tc.err.Error()) {
+				t.Errorf("readCommandResult() = %v, want %v", got, tc.err)
+			}
+			if tc.err != nil && errors.As(tc.err, new(Error)) && !errors.As(got, new(Error)) {
+				t.Errorf("readCommandResult() = %#v, want it to contain an Error", got)
+			}
+		})
+	}
+}
+
+func TestReadCommandResultInvalid(t *testing.T) {
+	if err := readCommandResult([]byte("not json")); err == nil {
+		t.Error("readCommandResult() succeeded, want an error for an invalid result")
+	}
+}
@@ -2327,6 +2327,10 @@ func testDefaultMetrics(ctx context.Context, t *testing.T, logger *log.Logger, v
 		if err != nil {
 			t.Fatalf("Failed to enable swap file: %v", err)
 		}
+		// The conntrack metrics are only reported while the connection tracking module is loaded.
+		if _, err := gce.RunRemotely(ctx, logger, vm, "sudo modprobe nf_conntrack || sudo /sbin/modprobe nf_conntrack"); err != nil {
+			t.Fatalf("Failed to load the nf_conntrack module: %v", err)
+		}
 	}
 
 	agentMetricsMetadata := path.Join("agent_metrics", "metadata.yaml")
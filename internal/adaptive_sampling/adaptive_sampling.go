@@ -0,0 +1,151 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package adaptive_sampling sets the sampling rate of the logs sent to Cloud Logging while the
+// Logging API keeps rejecting requests because of the quota. The rate is written to a file that
+// the sampling filter of the Fluent Bit config reads, so that it changes without a reload.
+package adaptive_sampling
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/readiness"
+)
+
+// SustainedIntervals is for how many checks in a row requests must be rejected before the logs
+// are sampled, so that short bursts of rejected requests are retried as usual.
+const SustainedIntervals = 3
+
+// RejectedRequests counts the requests of the Fluent Bit stackdriver outputs that the Logging API
+// rejected because of the quota.
+var RejectedRequests = readiness.Probe{
+	URL:    fmt.Sprintf("http://127.0.0.1:%d/metrics", fluentbit.MetricsPort),
+	Metric: "fluentbit_stackdriver_requests_total",
+	Labels: []string{`status="429"`},
+}
+
+type Options struct {
+	// Path is the file that the sampling rate is written to.
+	Path string
+	// MinRate is the smallest sampling rate.
+	MinRate float64
+	// Interval is how often the rejected requests are counted.
+	Interval time.Duration
+	// Count returns the number of rejected requests since Fluent Bit started.
+	Count func(ctx context.Context) (float64, error)
+}
+
+// sampler halves the sampling rate, down to minRate, after every check with rejected requests
+// once they were rejected for SustainedIntervals checks in a row, and doubles it after every
+// check without rejected requests.
+type sampler struct {
+	minRate float64
+	rate    float64
+	// last is the number of rejected requests at the previous check, or -1 before the first.
+	last     float64
+	rejected int
+}
+
+func newSampler(minRate float64) *sampler {
+	return &sampler{minRate: minRate, rate: 1, last: -1}
+}
+
+// check updates the rate with the number of rejected requests, and logs when the sampling starts
+// and stops. It returns whether the rate changed.
+func (s *sampler) check(count float64, logger logs.StructuredLogger) bool {
+	last := s.last
+	s.last = count
+	if last < 0 {
+		return false
+	}
+	// A lower count means that Fluent Bit restarted.
+	if count > last || (count < last && count > 0) {
+		s.rejected++
+	} else {
+		s.rejected = 0
+	}
+	previous := s.rate
+	switch {
+	case s.rejected >= SustainedIntervals:
+		s.rate = max(s.rate/2, s.minRate)
+	case s.rejected == 0:
+		s.rate = min(s.rate*2, 1)
+	}
+	if s.rate == previous {
+		return false
+	}
+	if previous == 1 {
+		logger.Warnw("Sampling the logs sent to Cloud Logging because the Logging API keeps rejecting requests with 429 RESOURCE_EXHAUSTED",
+			"code", "LogSamplingStarted",
+			"sampling_rate", s.rate,
+			"min_sampling_rate", s.minRate)
+	} else if s.rate == 1 {
+		logger.Infow("Stopped sampling the logs sent to Cloud Logging",
+			"code", "LogSamplingStopped")
+	}
+	return true
+}
+
+// writeRate replaces the file at path with rate, so that the filter never reads a partial file.
+func writeRate(path string, rate float64) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatFloat(rate, 'g', -1, 64)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Watch counts the rejected requests every opts.Interval and writes the sampling rate to
+// opts.Path until ctx is done. The rate starts at 1, and is reset to 1 when ctx is done.
+func Watch(ctx context.Context, opts Options, logger logs.StructuredLogger) {
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0755); err != nil {
+		logger.Errorf("Failed to create the directory of the sampling rate: %v", err)
+		return
+	}
+	s := newSampler(opts.MinRate)
+	if err := writeRate(opts.Path, s.rate); err != nil {
+		logger.Errorf("Failed to write the sampling rate: %v", err)
+	}
+	defer writeRate(opts.Path, 1)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+		count, err := opts.Count(ctx)
+		if err != nil {
+			// Fluent Bit is not running; the rate is checked again once it is.
+			continue
+		}
+		if s.check(count, logger) {
+			if err := writeRate(opts.Path, s.rate); err != nil {
+				logger.Errorf("Failed to write the sampling rate: %v", err)
+			}
+		}
+	}
+}
+
+// CountRejectedRequests counts the rejected requests with RejectedRequests.
+func CountRejectedRequests(ctx context.Context) (float64, error) {
+	return RejectedRequests.Sum(ctx, &http.Client{Timeout: 10 * time.Second})
+}
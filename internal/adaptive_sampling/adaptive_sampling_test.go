@@ -0,0 +1,95 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive_sampling
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"go.uber.org/zap"
+)
+
+func TestSampler(t *testing.T) {
+	logger, observed := logs.DiscardLogger()
+	s := newSampler(0.2)
+	// The counts of rejected requests at every check, and the rate after it.
+	for i, step := range []struct {
+		count float64
+		rate  float64
+	}{
+		{0, 1},
+		{5, 1},
+		{10, 1},
+		// Requests were rejected for SustainedIntervals checks in a row.
+		{15, 0.5},
+		{20, 0.25},
+		{25, 0.2},
+		{30, 0.2},
+		{30, 0.4},
+		{30, 0.8},
+		{30, 1},
+		// Fluent Bit restarted.
+		{2, 1},
+	} {
+		s.check(step.count, logger)
+		if s.rate != step.rate {
+			t.Fatalf("rate after check %d = %g, want %g", i, s.rate, step.rate)
+		}
+	}
+	if n := observed.FilterField(zap.String("code", "LogSamplingStarted")).Len(); n != 1 {
+		t.Errorf("got %d LogSamplingStarted entries, want 1", n)
+	}
+	if n := observed.FilterField(zap.String("code", "LogSamplingStopped")).Len(); n != 1 {
+		t.Errorf("got %d LogSamplingStopped entries, want 1", n)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fluent-bit", "adaptive_sampling_rate")
+	ctx, cancel := context.WithCancel(context.Background())
+	var count float64
+	checks := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Watch(ctx, Options{
+			Path:     path,
+			MinRate:  0.1,
+			Interval: time.Millisecond,
+			Count: func(context.Context) (float64, error) {
+				checks++
+				if checks == 1+SustainedIntervals {
+					cancel()
+				}
+				count += 10
+				return count, nil
+			},
+		}, logs.Default())
+	}()
+	<-done
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The rate is reset when the watch stops.
+	if got := strings.TrimSpace(string(data)); got != "1" {
+		t.Errorf("rate after the watch stopped = %q, want 1", got)
+	}
+}
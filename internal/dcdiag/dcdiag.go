@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dcdiag periodically runs dcdiag on an Active Directory domain controller and writes
+// the failed tests to a file as JSON lines, which is then read by the active_directory_ds_dcdiag
+// logging receiver.
+package dcdiag
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timeFormat matches the time format of the other agent logs.
+const timeFormat = "2006-01-02T15:04:05.000Z0700"
+
+var (
+	// Sample line:    Starting test: Replications
+	startRegex = regexp.MustCompile(`^\s*Starting test: (\S+)`)
+	// Sample line:    ......................... DC1 failed test Replications
+	resultRegex = regexp.MustCompile(`^\s*\.+ (\S+) (passed|failed) test (\S+)`)
+)
+
+type Result struct {
+	Time   string `json:"time"`
+	Test   string `json:"test"`
+	Target string `json:"target"`
+	Result string `json:"result"`
+	// Message is the output of the test.
+	Message string `json:"message,omitempty"`
+}
+
+// Parse returns the result of each test in the output of dcdiag.
+func Parse(r io.Reader) ([]Result, error) {
+	var results []Result
+	var details []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if startRegex.MatchString(line) {
+			details = nil
+			continue
+		}
+		if m := resultRegex.FindStringSubmatch(line); m != nil {
+			results = append(results, Result{
+				Test:    m[3],
+				Target:  m[1],
+				Result:  m[2],
+				Message: strings.Join(details, "\n"),
+			})
+			details = nil
+			continue
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			details = append(details, line)
+		}
+	}
+	return results, s.Err()
+}
+
+// Run runs dcdiag every interval and appends the failed tests to path, until ctx is done.
+func Run(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := runOnce(ctx, path); err != nil {
+			log.Printf("failed to run dcdiag: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, path string) error {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "dcdiag.exe")
+	cmd.Stdout = &out
+	// dcdiag exits with a non-zero code when a test fails; the output is parsed regardless.
+	if err := cmd.Run(); err != nil && out.Len() == 0 {
+		return err
+	}
+	results, err := Parse(&out)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	now := time.Now().Format(timeFormat)
+	enc := json.NewEncoder(f)
+	for _, r := range results {
+		if r.Result != "failed" {
+			continue
+		}
+		r.Time = now
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dcdiag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const sampleOutput = "\r\n" +
+	"Directory Server Diagnosis\r\n" +
+	"\r\n" +
+	"Performing initial setup:\r\n" +
+	"   Trying to find home server...\r\n" +
+	"   Home Server = DC1\r\n" +
+	"   * Identified AD Forest. \r\n" +
+	"   Done gathering initial info.\r\n" +
+	"\r\n" +
+	"Doing primary tests\r\n" +
+	"\r\n" +
+	"   Testing server: Default-First-Site-Name\\DC1\r\n" +
+	"      Starting test: Connectivity\r\n" +
+	"         ......................... DC1 passed test Connectivity\r\n" +
+	"      Starting test: Replications\r\n" +
+	"         [Replications Check,DC1] A recent replication attempt failed:\r\n" +
+	"            From DC2 to DC1\r\n" +
+	"            The replication generated an error (1722):\r\n" +
+	"            The RPC server is unavailable.\r\n" +
+	"         ......................... DC1 failed test Replications\r\n" +
+	"      Starting test: SysVolCheck\r\n" +
+	"         ......................... DC1 passed test SysVolCheck\r\n" +
+	"\r\n" +
+	"   Running enterprise tests on : example.com\r\n" +
+	"      Starting test: LocatorCheck\r\n" +
+	"         ......................... example.com passed test LocatorCheck\r\n"
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader(sampleOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Result{
+		{Test: "Connectivity", Target: "DC1", Result: "passed"},
+		{
+			Test:    "Replications",
+			Target:  "DC1",
+			Result:  "failed",
+			Message: "[Replications Check,DC1] A recent replication attempt failed:\nFrom DC2 to DC1\nThe replication generated an error (1722):\nThe RPC server is unavailable.",
+		},
+		{Test: "SysVolCheck", Target: "DC1", Result: "passed"},
+		{Test: "LocatorCheck", Target: "example.com", Result: "passed"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -18,8 +18,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
+	"cloud.google.com/go/compute/metadata"
 	"cloud.google.com/go/logging"
 	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
 	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
@@ -27,6 +29,7 @@ import (
 	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
 	"github.com/cenkalti/backoff/v4"
 	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/option"
 	metricpb "google.golang.org/genproto/googleapis/api/metric"
 	"google.golang.org/grpc/codes"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
@@ -37,6 +40,9 @@ const (
 	AccessTokenScopeInsufficient = "ACCESS_TOKEN_SCOPE_INSUFFICIENT"
 	IamPermissionDenied          = "IAM_PERMISSION_DENIED"
 	MaxMonitoringPingRetries     = 1
+
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	traceAppendScope   = "https://www.googleapis.com/auth/trace.append"
 )
 
 func createMonitoringPingRequest(resource resourcedetector.Resource) *monitoringpb.CreateTimeSeriesRequest {
@@ -84,11 +90,20 @@ func monitoringPing(ctx context.Context, client monitoring.MetricClient, resourc
 	return backoff.Retry(pingOperation, pingBackoff)
 }
 
-func runLoggingCheck(logger logs.StructuredLogger, resource resourcedetector.Resource) error {
+// clientOptions returns the options of an API client that sends requests to endpoint, or to
+// the global endpoint of the API if endpoint is empty.
+func clientOptions(endpoint string) []option.ClientOption {
+	if endpoint == "" {
+		return nil
+	}
+	return []option.ClientOption{option.WithEndpoint(endpoint + ":443")}
+}
+
+func runLoggingCheck(logger logs.StructuredLogger, resource resourcedetector.Resource, endpoint string) error {
 	ctx := context.Background()
 
 	// New Logging Client
-	logClient, err := logging.NewClient(ctx, resource.ProjectName())
+	logClient, err := logging.NewClient(ctx, resource.ProjectName(), clientOptions(endpoint)...)
 	if err != nil {
 		return err
 	}
@@ -128,11 +143,11 @@ func runLoggingCheck(logger logs.StructuredLogger, resource resourcedetector.Res
 	return nil
 }
 
-func runMonitoringCheck(logger logs.StructuredLogger, resource resourcedetector.Resource) error {
+func runMonitoringCheck(logger logs.StructuredLogger, resource resourcedetector.Resource, endpoint string) error {
 	ctx := context.Background()
 
 	// New Monitoring Client
-	monClient, err := monitoring.NewMetricClient(ctx)
+	monClient, err := monitoring.NewMetricClient(ctx, clientOptions(endpoint)...)
 	if err != nil {
 		return err
 	}
@@ -172,7 +187,35 @@ func runMonitoringCheck(logger logs.StructuredLogger, resource resourcedetector.
 	return nil
 }
 
-type APICheck struct{}
+// runTraceScopeCheck verifies that the VM's default service account has a scope that
+// allows writing to the Trace API. There is no cheap way to ping the Trace API itself,
+// so this only catches the most common misconfiguration on GCE.
+func runTraceScopeCheck(logger logs.StructuredLogger) error {
+	if _, ok := os.LookupEnv("GOOGLE_APPLICATION_CREDENTIALS"); ok || !metadata.OnGCE() {
+		// Scopes only apply to the credentials of the VM's service account.
+		return nil
+	}
+	scopes, err := metadata.Scopes("default")
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		if scope == cloudPlatformScope || scope == traceAppendScope {
+			logger.Infof("found scope %s needed by the Trace API", scope)
+			return nil
+		}
+	}
+	return TraceApiScopeErr
+}
+
+type APICheck struct {
+	// Traces is set when the config has traces pipelines, which send data to the Trace API.
+	Traces bool
+	// LoggingEndpoint and MonitoringEndpoint are the regional endpoints that the config sends
+	// logs and metrics to, so that the checks write to the same endpoints as the subagents.
+	LoggingEndpoint    string
+	MonitoringEndpoint string
+}
 
 func (c APICheck) Name() string {
 	return "API Check"
@@ -183,7 +226,11 @@ func (c APICheck) RunCheck(logger logs.StructuredLogger) error {
 	if err != nil {
 		return fmt.Errorf("failed to detect the resource: %v", err)
 	}
-	monErr := runMonitoringCheck(logger, resource)
-	logErr := runLoggingCheck(logger, resource)
-	return errors.Join(monErr, logErr)
+	monErr := runMonitoringCheck(logger, resource, c.MonitoringEndpoint)
+	logErr := runLoggingCheck(logger, resource, c.LoggingEndpoint)
+	var traceErr error
+	if c.Traces {
+		traceErr = runTraceScopeCheck(logger)
+	}
+	return errors.Join(monErr, logErr, traceErr)
 }
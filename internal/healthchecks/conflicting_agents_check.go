@@ -0,0 +1,149 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+// conflictingAgent is an agent that collects the same telemetry as the Ops Agent.
+type conflictingAgent struct {
+	name string
+	// processes are the names of the executables of the agent.
+	processes []string
+	// executableDirs are the directories of the executables of the agent, for agents whose
+	// processes have generic names like "agent".
+	executableDirs []string
+	// logging and metrics are set when the agent conflicts with the logging or metrics section
+	// of the Ops Agent config.
+	logging, metrics bool
+}
+
+var conflictingAgents = []conflictingAgent{
+	{name: "the legacy Logging agent (google-fluentd)", processes: []string{"google-fluentd"}, executableDirs: []string{"/opt/google-fluentd"}, logging: true},
+	{name: "the legacy Monitoring agent (stackdriver-agent)", processes: []string{"stackdriver-collectd"}, executableDirs: []string{"/opt/stackdriver/collectd"}, metrics: true},
+	{name: "fluentd", processes: []string{"fluentd"}, logging: true},
+	{name: "td-agent", processes: []string{"td-agent"}, executableDirs: []string{"/opt/td-agent"}, logging: true},
+	{name: "collectd", processes: []string{"collectd"}, metrics: true},
+	{name: "the Datadog Agent", executableDirs: []string{"/opt/datadog-agent"}, logging: true, metrics: true},
+	{name: "the OpenTelemetry Collector", processes: []string{"otelcol", "otelcol-contrib"}, logging: true, metrics: true},
+}
+
+// ConflictingAgentsCheck warns about other agents that run next to the Ops Agent and collect the
+// same telemetry, e.g. after the Ops Agent was installed without uninstalling the legacy agents.
+type ConflictingAgentsCheck struct {
+	// Logging and Metrics are set when the config has a logging or metrics section.
+	Logging bool
+	Metrics bool
+}
+
+func (c ConflictingAgentsCheck) Name() string {
+	return "Conflicting Agents Check"
+}
+
+func (c ConflictingAgentsCheck) RunCheck(logger logs.StructuredLogger) error {
+	var found []string
+	for _, a := range findConflictingAgents() {
+		if (a.logging && c.Logging) || (a.metrics && c.Metrics) {
+			found = append(found, a.name)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	healthCheckError := ConflictingAgentsErr
+	healthCheckError.Message = fmt.Sprintf(healthCheckError.Message, strings.Join(found, ", "))
+	return healthCheckError
+}
+
+// findConflictingAgentsIn returns the agents that have a process in procDir. Agents that are
+// installed but stopped don't collect anything, so they are not returned.
+func findConflictingAgentsIn(procDir string) []conflictingAgent {
+	running := runningProcesses(procDir)
+	var found []conflictingAgent
+	for _, a := range conflictingAgents {
+		if a.isRunning(running) {
+			found = append(found, a)
+		}
+	}
+	return found
+}
+
+func (a conflictingAgent) isRunning(running processes) bool {
+	for _, p := range a.processes {
+		if running.names[p] {
+			return true
+		}
+	}
+	for _, dir := range a.executableDirs {
+		for _, exe := range running.executables {
+			if strings.HasPrefix(exe, dir+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// processes are the running processes.
+type processes struct {
+	// names are the names of the processes. Interpreted agents like fluentd run as e.g.
+	// "ruby /usr/sbin/fluentd", so the names of the first two arguments count as well as the
+	// name of the command.
+	names map[string]bool
+	// executables are the paths of the executables of the processes.
+	executables []string
+}
+
+// runningProcesses returns the processes in procDir.
+func runningProcesses(procDir string) processes {
+	running := processes{names: map[string]bool{}}
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return running
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		if comm, err := os.ReadFile(filepath.Join(procDir, e.Name(), "comm")); err == nil {
+			running.names[strings.TrimSpace(string(comm))] = true
+		}
+		// The executables of the processes of other users can't be read without root, which the
+		// health checks run as.
+		if exe, err := os.Readlink(filepath.Join(procDir, e.Name(), "exe")); err == nil {
+			running.executables = append(running.executables, exe)
+		}
+		cmdline, err := os.ReadFile(filepath.Join(procDir, e.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		args := bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0})
+		for i := 0; i < len(args) && i < 2; i++ {
+			if len(args[i]) > 0 {
+				running.names[path.Base(string(args[i]))] = true
+			}
+		}
+	}
+	return running
+}
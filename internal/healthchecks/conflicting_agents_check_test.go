@@ -0,0 +1,60 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindConflictingAgentsIn(t *testing.T) {
+	procDir := t.TempDir()
+	writeProcess := func(pid, comm, cmdline, exe string) {
+		t.Helper()
+		dir := filepath.Join(procDir, pid)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(exe, filepath.Join(dir, "exe")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeProcess("1", "systemd", "/sbin/init\x00", "/usr/lib/systemd/systemd")
+	writeProcess("20", "ruby", "/usr/bin/ruby\x00/usr/local/bin/fluentd\x00-c\x00/etc/fluent/fluent.conf\x00", "/usr/bin/ruby")
+	writeProcess("30", "otelopscol", "/opt/google-cloud-ops-agent/subagents/opentelemetry-collector/otelopscol\x00", "/opt/google-cloud-ops-agent/subagents/opentelemetry-collector/otelopscol")
+	writeProcess("40", "collectd", "", "/usr/sbin/collectd")
+	// The Datadog Agent runs as "agent", which is only told apart by its executable.
+	writeProcess("50", "agent", "agent\x00run\x00", "/opt/datadog-agent/bin/agent/agent")
+	writeProcess("60", "agent", "agent\x00", "/opt/datadog-agent-extras/agent")
+
+	var got []string
+	for _, a := range findConflictingAgentsIn(procDir) {
+		got = append(got, a.name)
+	}
+	// google-fluentd is not running, so it is not returned even if it is installed.
+	want := []string{"fluentd", "collectd", "the Datadog Agent"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findConflictingAgentsIn() returned unexpected agents (-want +got):\n%s", diff)
+	}
+}
@@ -14,28 +14,21 @@
 
 package healthchecks
 
+import checks "github.com/GoogleCloudPlatform/ops-agent/healthchecks"
+
 // Error classification
 const (
-	Api        = "API"
-	Connection = "CONNECTION"
-	Generic    = "GENERIC"
-	Port       = "PORT"
-	Permission = "PERMISSION"
-	Runtime    = "RUNTIME"
+	Api        = checks.ClassAPI
+	Connection = checks.ClassConnection
+	Generic    = checks.ClassGeneric
+	Port       = checks.ClassPort
+	Permission = checks.ClassPermission
+	Runtime    = checks.ClassRuntime
 )
 
-type HealthCheckError struct {
-	Code         string
-	Class        string
-	Message      string
-	Action       string
-	ResourceLink string
-	IsFatal      bool
-}
-
-func (e HealthCheckError) Error() string {
-	return e.Message
-}
+// HealthCheckError is the error of the public API, so that the results of the registered checks
+// are reported like those of the built-in checks.
+type HealthCheckError = checks.Error
 
 // Interface used to verify if an error implements `Unwrap() []error`.
 // The resulting error from `errors.Join(errs ...error)` implements this interface.
@@ -61,6 +54,34 @@ var (
 		ResourceLink: "https://cloud.google.com/monitoring/agent/ops-agent/troubleshooting",
 		IsFatal:      true,
 	}
+	// ReceiverPortErr is formatted with the port and the ID of the receiver that needs it.
+	ReceiverPortErr = HealthCheckError{
+		Code:         "ReceiverPortErr",
+		Class:        Port,
+		Message:      "Port %d needed by receiver %q is unavailable.",
+		Action:       "Verify that port %d is open, or configure receiver %q to listen on another port.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/troubleshoot-run-ingest",
+		IsFatal:      true,
+	}
+	// PrometheusTargetsDownErr is formatted with the job name and the ID of the receiver that scrapes it.
+	PrometheusTargetsDownErr = HealthCheckError{
+		Code:         "PrometheusTargetsDownErr",
+		Class:        Connection,
+		Message:      "All targets of Prometheus job %q in receiver %q are unreachable.",
+		Action:       "Verify that the targets of job %q in receiver %q are running and that their addresses are correct. The up metric of the job reports the state of each target.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/prometheus",
+		IsFatal:      false,
+	}
+	// PrometheusScrapeTruncatedErr is formatted with the target, the job name and the ID of the
+	// receiver that scrapes it, and then with the sample limit.
+	PrometheusScrapeTruncatedErr = HealthCheckError{
+		Code:         "PrometheusScrapeTruncatedErr",
+		Class:        Runtime,
+		Message:      "Target %q of Prometheus job %q in receiver %q exposes more samples than the sample limit of %d, so its scrapes are dropped.",
+		Action:       "Reduce the series that the target exposes, drop some of them with metric_relabel_configs, or raise the sample_limit of the receiver.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/prometheus",
+		IsFatal:      false,
+	}
 	LogApiConnErr = HealthCheckError{
 		Code:         "LogApiConnErr",
 		Class:        Connection,
@@ -77,6 +98,14 @@ var (
 		ResourceLink: "https://cloud.google.com/monitoring/agent/ops-agent/troubleshooting",
 		IsFatal:      true,
 	}
+	TraceApiConnErr = HealthCheckError{
+		Code:         "TraceApiConnErr",
+		Class:        Connection,
+		Message:      "Request to Trace API failed.",
+		Action:       "Check your internet connection and firewall rules.",
+		ResourceLink: "https://cloud.google.com/trace/docs/troubleshooting",
+		IsFatal:      true,
+	}
 	PacApiConnErr = HealthCheckError{
 		Code:         "PacApiConnErr",
 		Class:        Connection,
@@ -101,6 +130,38 @@ var (
 		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/troubleshoot-run-ingest#network-issues",
 		IsFatal:      true,
 	}
+	MetaServerBlockedErr = HealthCheckError{
+		Code:         "MetaServerBlockedErr",
+		Class:        Connection,
+		Message:      "The GCE Metadata server is unreachable, so the agent can't get credentials.",
+		Action:       "Verify that firewall rules, routes and proxy settings allow requests to metadata.google.internal (169.254.169.254).",
+		ResourceLink: "https://cloud.google.com/compute/docs/troubleshooting/troubleshoot-metadata-server",
+		IsFatal:      true,
+	}
+	MetaNoServiceAccountErr = HealthCheckError{
+		Code:         "MetaNoServiceAccountErr",
+		Class:        Permission,
+		Message:      "The VM has no service account attached.",
+		Action:       "Attach a service account to the Compute Engine VM, or provide credentials with GOOGLE_APPLICATION_CREDENTIALS.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/authorization",
+		IsFatal:      true,
+	}
+	MetaServiceAccountDisabledErr = HealthCheckError{
+		Code:         "MetaServiceAccountDisabledErr",
+		Class:        Permission,
+		Message:      "The GCE Metadata server couldn't issue a valid access token. The VM's service account may be disabled or deleted.",
+		Action:       "Verify that the service account attached to the Compute Engine VM exists and is enabled.",
+		ResourceLink: "https://cloud.google.com/iam/docs/service-accounts-disable-enable",
+		IsFatal:      true,
+	}
+	MetaScopesErr = HealthCheckError{
+		Code:         "MetaScopesErr",
+		Class:        Permission,
+		Message:      "The access token of the VM's service account is missing the logging.write or monitoring.write scope.",
+		Action:       "Add the https://www.googleapis.com/auth/logging.write and https://www.googleapis.com/auth/monitoring.write scopes to the Compute Engine VM.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/authorization",
+		IsFatal:      true,
+	}
 	LogApiScopeErr = HealthCheckError{
 		Code:         "LogApiScopeErr",
 		Class:        Permission,
@@ -117,6 +178,14 @@ var (
 		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/authorization",
 		IsFatal:      true,
 	}
+	TraceApiScopeErr = HealthCheckError{
+		Code:         "TraceApiScopeErr",
+		Class:        Permission,
+		Message:      "VM is missing the https://www.googleapis.com/auth/trace.append scope.",
+		Action:       "Add the https://www.googleapis.com/auth/trace.append scope to the Compute Engine VM.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/authorization",
+		IsFatal:      true,
+	}
 	LogApiPermissionErr = HealthCheckError{
 		Code:         "LogApiPermissionErr",
 		Class:        Permission,
@@ -181,6 +250,30 @@ var (
 		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/troubleshoot-find-info",
 		IsFatal:      false,
 	}
+	TimeSyncNotRunningErr = HealthCheckError{
+		Code:         "TimeSyncNotRunningErr",
+		Class:        Runtime,
+		Message:      "No time synchronization service is running, so the clock of the VM may drift. Cloud Monitoring rejects points with timestamps too far from the current time.",
+		Action:       "Enable and start chrony or systemd-timesyncd on Linux, or the Windows Time service (w32time) on Windows.",
+		ResourceLink: "https://cloud.google.com/compute/docs/instances/configure-ntp",
+		IsFatal:      false,
+	}
+	TimeSyncNotSynchronizedErr = HealthCheckError{
+		Code:         "TimeSyncNotSynchronizedErr",
+		Class:        Runtime,
+		Message:      "The time synchronization service is running, but the clock of the VM is not synchronized. Cloud Monitoring rejects points with timestamps too far from the current time.",
+		Action:       "Verify that the VM can reach its NTP servers, e.g. metadata.google.internal on Compute Engine.",
+		ResourceLink: "https://cloud.google.com/compute/docs/instances/configure-ntp",
+		IsFatal:      false,
+	}
+	ConflictingAgentsErr = HealthCheckError{
+		Code:         "ConflictingAgentsErr",
+		Class:        Runtime,
+		Message:      "Detected other agents that collect the same telemetry as the Ops Agent: %s. The telemetry is then ingested twice, which duplicates its cost.",
+		Action:       "Uninstall or stop the other agents, or remove the conflicting sections from the Ops Agent configuration.",
+		ResourceLink: "https://cloud.google.com/stackdriver/docs/solutions/agents/ops-agent/installation",
+		IsFatal:      false,
+	}
 	HcFailureErr = HealthCheckError{
 		Code:         "HcFailureErr",
 		Class:        Generic,
@@ -0,0 +1,29 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"testing"
+
+	checks "github.com/GoogleCloudPlatform/ops-agent/healthchecks"
+)
+
+// SetRegisteredChecks makes HealthCheckRegistryFactory use registered instead of the checks
+// registered with the public healthchecks API until the end of the test.
+func SetRegisteredChecks(t *testing.T, registered ...checks.Check) {
+	old := registeredChecks
+	registeredChecks = func() []checks.Check { return registered }
+	t.Cleanup(func() { registeredChecks = old })
+}
@@ -15,16 +15,24 @@
 package healthchecks
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
+	checks "github.com/GoogleCloudPlatform/ops-agent/healthchecks"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
 )
 
 var healthChecksLogFile = "health-checks.log"
 
+// HealthChecksResultsFile is the file under the state directory that holds the results of the
+// last health checks run as JSON.
+const HealthChecksResultsFile = "health-checks.json"
+
 type HealthCheck interface {
 	Name() string
 	RunCheck(logger logs.StructuredLogger) error
@@ -76,12 +84,106 @@ func (r HealthCheckResult) ErrorSlice() []error {
 	return []error{r.Err}
 }
 
+// Result returns the result of the most severe error of the check: PASS, WARNING, ERROR or FAIL.
+func (r HealthCheckResult) Result() string {
+	result := "PASS"
+	for _, e := range r.ErrorSlice() {
+		if resultSeverity[errorResult(e)] > resultSeverity[result] {
+			result = errorResult(e)
+		}
+	}
+	return result
+}
+
 func LogHealthCheckResults(healthCheckResults []HealthCheckResult, logger logs.StructuredLogger) {
 	for _, result := range healthCheckResults {
 		result.LogResult(logger)
 	}
 }
 
+// errorResult returns the result of a health check that returned e, as printed by LogResult.
+func errorResult(e error) string {
+	if e == nil {
+		return "PASS"
+	}
+	if healthError, ok := e.(HealthCheckError); ok {
+		if healthError.IsFatal {
+			return "FAIL"
+		}
+		return "WARNING"
+	}
+	return "ERROR"
+}
+
+// resultSeverity orders results, so that a check reports its most severe error.
+var resultSeverity = map[string]int{"PASS": 0, "WARNING": 1, "ERROR": 2, "FAIL": 3}
+
+type errorJSON struct {
+	Result       string `json:"result"`
+	Code         string `json:"code,omitempty"`
+	Class        string `json:"class,omitempty"`
+	Message      string `json:"message"`
+	Action       string `json:"action,omitempty"`
+	ResourceLink string `json:"resource_link,omitempty"`
+}
+
+type resultJSON struct {
+	Name   string      `json:"name"`
+	Result string      `json:"result"`
+	Errors []errorJSON `json:"errors,omitempty"`
+}
+
+type resultsJSON struct {
+	Time    time.Time    `json:"time"`
+	Results []resultJSON `json:"results"`
+}
+
+// MarshalHealthCheckResults encodes the results as JSON, for tools that consume them
+// programmatically. Each check has the result of its most severe error.
+func MarshalHealthCheckResults(healthCheckResults []HealthCheckResult, now time.Time) ([]byte, error) {
+	out := resultsJSON{Time: now.UTC(), Results: []resultJSON{}}
+	for _, r := range healthCheckResults {
+		res := resultJSON{Name: r.Name, Result: "PASS"}
+		for _, e := range r.ErrorSlice() {
+			if e == nil {
+				continue
+			}
+			ej := errorJSON{Result: errorResult(e), Message: e.Error()}
+			if healthError, ok := e.(HealthCheckError); ok {
+				ej.Code = healthError.Code
+				ej.Class = healthError.Class
+				ej.Action = healthError.Action
+				ej.ResourceLink = healthError.ResourceLink
+			}
+			if resultSeverity[ej.Result] > resultSeverity[res.Result] {
+				res.Result = ej.Result
+			}
+			res.Errors = append(res.Errors, ej)
+		}
+		out.Results = append(out.Results, res)
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// WriteHealthCheckResults writes the results as JSON to HealthChecksResultsFile under stateDir,
+// replacing those of the previous run.
+func WriteHealthCheckResults(healthCheckResults []HealthCheckResult, stateDir string) error {
+	data, err := MarshalHealthCheckResults(healthCheckResults, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(stateDir, HealthChecksResultsFile)
+	// Write to a temporary file first, so that readers never see a partial file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 func CreateHealthChecksLogger(logDir string) logs.StructuredLogger {
 	path := filepath.Join(logDir, healthChecksLogFile)
 	// Make sure the directory exists before writing the file.
@@ -99,14 +201,87 @@ func CreateHealthChecksLogger(logDir string) logs.StructuredLogger {
 	return logs.New(path)
 }
 
+// ConfigRequirements describes what an Ops Agent config needs from the environment
+// beyond what every config needs. It allows the health checks to evaluate a candidate
+// config before it is applied.
+type ConfigRequirements struct {
+	// Traces is set when the config has traces pipelines, which need the Cloud Trace API.
+	Traces bool
+	// Logging and Metrics are set when the config has a logging or metrics section, which
+	// conflict with other agents that collect the same telemetry.
+	Logging bool
+	Metrics bool
+	// Listeners are the network addresses that the configured receivers listen on.
+	Listeners []Listener
+	// AppliedListeners are the network addresses of the config that was applied before, which
+	// the subagents hold if they are running.
+	AppliedListeners []Listener
+	// LoggingEndpoint and MonitoringEndpoint are the hosts of the regional endpoints that the
+	// config sends logs and metrics to. They are empty for the global endpoints.
+	LoggingEndpoint    string
+	MonitoringEndpoint string
+}
+
 type HealthCheckRegistry []HealthCheck
 
-func HealthCheckRegistryFactory() HealthCheckRegistry {
-	return HealthCheckRegistry{
-		PortsCheck{},
-		NetworkCheck{},
-		APICheck{},
+func HealthCheckRegistryFactory(req ConfigRequirements) HealthCheckRegistry {
+	r := HealthCheckRegistry{
+		PortsCheck{Listeners: req.Listeners, AppliedListeners: req.AppliedListeners},
+		NetworkCheck{Traces: req.Traces, LoggingEndpoint: req.LoggingEndpoint, MonitoringEndpoint: req.MonitoringEndpoint},
+		MetadataCheck{},
+		APICheck{Traces: req.Traces, LoggingEndpoint: req.LoggingEndpoint, MonitoringEndpoint: req.MonitoringEndpoint},
+		TimeSyncCheck{},
+		ConflictingAgentsCheck{Logging: req.Logging, Metrics: req.Metrics},
+	}
+	for _, c := range registeredChecks() {
+		r = append(r, registeredCheck{c})
 	}
+	return r
+}
+
+// registeredChecks returns the checks that were registered with the public healthchecks API.
+// Tests replace it, so that their checks don't stay registered.
+var registeredChecks = checks.Registered
+
+// CommandChecksDir returns the directory of the health check programs of the agent whose user
+// config is userConfPath.
+func CommandChecksDir(userConfPath string) string {
+	return filepath.Join(filepath.Dir(userConfPath), "healthchecks.d")
+}
+
+// WithCommandChecks returns r with the health check programs in dir, which images install next
+// to the agent without rebuilding it.
+func (r HealthCheckRegistry) WithCommandChecks(dir string) HealthCheckRegistry {
+	commandChecks, err := checks.CommandChecks(dir)
+	if err != nil {
+		log.Printf("failed to list the health check programs in %q: %v", dir, err)
+	}
+	for _, c := range commandChecks {
+		r = append(r, registeredCheck{c})
+	}
+	return r
+}
+
+// registeredCheck runs a check that was registered with the public healthchecks API.
+type registeredCheck struct {
+	check checks.Check
+}
+
+func (c registeredCheck) Name() string {
+	return c.check.Name()
+}
+
+// RunCheck runs the check with a context that is canceled after checks.Timeout. The check is
+// not part of the agent, so a panic is reported as its error instead of stopping the agent.
+func (c registeredCheck) RunCheck(logger logs.StructuredLogger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("health check panicked: %v", r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), checks.Timeout)
+	defer cancel()
+	return c.check.Run(ctx, logger)
 }
 
 func (r HealthCheckRegistry) RunAllHealthChecks(logger logs.StructuredLogger) []HealthCheckResult {
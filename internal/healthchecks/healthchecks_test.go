@@ -15,13 +15,20 @@
 package healthchecks_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	checks "github.com/GoogleCloudPlatform/ops-agent/healthchecks"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
 	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"go.uber.org/zap"
 	"gotest.tools/v3/assert"
 )
 
@@ -201,6 +208,7 @@ func TestMultipleFailureResultCheck(t *testing.T) {
 	assert.Equal(t, observedLogs.All()[1].Entry.Level.String(), "warn")
 	assert.Check(t, strings.Contains(observedLogs.All()[2].Entry.Message, expectedFailure))
 	assert.Equal(t, observedLogs.All()[2].Entry.Level.String(), "error")
+	assert.Equal(t, result.Result(), "FAIL")
 }
 
 type MultipleSuccessResultCheck struct{}
@@ -226,4 +234,127 @@ func TestMultipleSuccessResultCheck(t *testing.T) {
 
 	assert.Check(t, strings.Contains(observedLogs.All()[0].Entry.Message, expectedSuccess))
 	assert.Equal(t, observedLogs.All()[0].Entry.Level.String(), "info")
+	assert.Equal(t, result.Result(), "PASS")
+}
+
+func TestWriteHealthCheckResults(t *testing.T) {
+	allHealthChecks := healthchecks.HealthCheckRegistry{FailureCheck{}, SuccessCheck{}, MultipleFailureResultCheck{}}
+	testLogger, _ := logs.DiscardLogger()
+	stateDir := t.TempDir()
+
+	err := healthchecks.WriteHealthCheckResults(allHealthChecks.RunAllHealthChecks(testLogger), stateDir)
+	assert.NilError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(stateDir, healthchecks.HealthChecksResultsFile))
+	assert.NilError(t, err)
+	var got struct {
+		Results []struct {
+			Name   string
+			Result string
+			Errors []struct {
+				Result  string
+				Code    string
+				Message string
+			}
+		}
+	}
+	assert.NilError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, len(got.Results), 3)
+
+	assert.Equal(t, got.Results[0].Name, "Failure Check")
+	assert.Equal(t, got.Results[0].Result, "FAIL")
+	assert.Equal(t, got.Results[0].Errors[0].Code, "TestFailure")
+
+	assert.Equal(t, got.Results[1].Name, "Success Check")
+	assert.Equal(t, got.Results[1].Result, "PASS")
+	assert.Equal(t, len(got.Results[1].Errors), 0)
+
+	// The check reports its most severe error.
+	assert.Equal(t, got.Results[2].Result, "FAIL")
+	assert.Equal(t, len(got.Results[2].Errors), 3)
+	assert.Equal(t, got.Results[2].Errors[0].Result, "ERROR")
+	assert.Equal(t, got.Results[2].Errors[0].Message, "Test error.")
+	assert.Equal(t, got.Results[2].Errors[1].Result, "WARNING")
+}
+
+var TestPartnerError = checks.Error{
+	Code:    "TestPartnerLicenseErr",
+	Class:   checks.ClassConnection,
+	Message: "The license server is unreachable.",
+	IsFatal: true,
+}
+
+type PartnerCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (c PartnerCheck) Name() string {
+	return c.name
+}
+
+func (c PartnerCheck) Run(ctx context.Context, logger checks.Logger) error {
+	return c.run(ctx)
+}
+
+func TestRegisteredChecks(t *testing.T) {
+	healthchecks.SetRegisteredChecks(t,
+		PartnerCheck{name: "Partner License Check", run: func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); !ok {
+				return errors.New("context without deadline")
+			}
+			return TestPartnerError
+		}},
+		PartnerCheck{name: "Partner Panic Check", run: func(ctx context.Context) error {
+			panic("boom")
+		}},
+	)
+
+	registry := healthchecks.HealthCheckRegistryFactory(healthchecks.ConfigRequirements{})
+	registered := registry[len(registry)-2:]
+	testLogger, observedLogs := logs.DiscardLogger()
+	results := registered.RunAllHealthChecks(testLogger)
+
+	assert.Equal(t, results[0].Name, "Partner License Check")
+	assert.Equal(t, results[0].Result(), "FAIL")
+	assert.Equal(t, observedLogs.FilterField(zap.String("code", "TestPartnerLicenseErr")).Len(), 1)
+	assert.Equal(t, results[1].Name, "Partner Panic Check")
+	assert.Equal(t, results[1].Result(), "ERROR")
+	assert.ErrorContains(t, results[1].Err, "boom")
+}
+
+func TestCommandChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the check programs are shell scripts")
+	}
+	dir := t.TempDir()
+	programs := map[string]string{
+		"license_server_check": `echo '{"errors":[{"code":"PartnerLicenseErr","class":"CONNECTION","message":"The license server is unreachable.","is_fatal":true}]}'`,
+		"passing_check":        `echo "checked the license" >&2; echo '{}'`,
+		"crashing_check":       `exit 3`,
+	}
+	for name, script := range programs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := healthchecks.HealthCheckRegistry{}.WithCommandChecks(dir)
+	testLogger, observedLogs := logs.DiscardLogger()
+	results := registry.RunAllHealthChecks(testLogger)
+
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, results[0].Name, "crashing_check")
+	assert.Equal(t, results[0].Result(), "ERROR")
+	assert.Equal(t, results[1].Name, "license_server_check")
+	assert.Equal(t, results[1].Result(), "FAIL")
+	assert.Equal(t, observedLogs.FilterField(zap.String("code", "PartnerLicenseErr")).Len(), 1)
+	assert.Equal(t, results[2].Name, "passing_check")
+	assert.Equal(t, results[2].Result(), "PASS")
+	assert.Equal(t, observedLogs.FilterMessage("checked the license").Len(), 1)
+}
+
+func TestCommandChecksMissingDir(t *testing.T) {
+	registry := healthchecks.HealthCheckRegistry{}.WithCommandChecks(filepath.Join(t.TempDir(), "healthchecks.d"))
+	assert.Equal(t, len(registry), 0)
 }
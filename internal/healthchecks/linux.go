@@ -54,3 +54,21 @@ func isTimeoutError(err error) bool {
 func isConnectionRefusedError(err error) bool {
 	return errors.Is(err, syscall.ECONNREFUSED)
 }
+
+// getTimeSyncStatus asks chronyd about the synchronization of the clock, and falls back to
+// timedated, which knows about systemd-timesyncd and the kernel's synchronization flag.
+func getTimeSyncStatus() (timeSyncStatus, string) {
+	if output, err := runCommand("chronyc", "-n", "tracking"); err == nil {
+		if status := parseChronyTracking(output); status != timeSyncUnknown {
+			return status, "chronyc"
+		}
+	}
+	if output, err := runCommand("timedatectl", "show"); err == nil {
+		return parseTimedatectl(output), "timedatectl"
+	}
+	return timeSyncUnknown, ""
+}
+
+func findConflictingAgents() []conflictingAgent {
+	return findConflictingAgentsIn("/proc")
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+)
+
+const (
+	metadataRequestTimeout = 10 * time.Second
+
+	loggingWriteScope    = "https://www.googleapis.com/auth/logging.write"
+	monitoringWriteScope = "https://www.googleapis.com/auth/monitoring.write"
+)
+
+// tokenInfoURL is the endpoint that validates access tokens. Tests replace it.
+var tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+type accessToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+type tokenInfo struct {
+	Scope     string `json:"scope"`
+	ExpiresIn string `json:"expires_in"`
+	Error     string `json:"error"`
+}
+
+// MetadataCheck verifies that the GCE metadata server is reachable and issues a valid access
+// token for the VM's service account. Each way this can fail has its own error, since they all
+// look alike in the API errors that the subagents report.
+type MetadataCheck struct{}
+
+func (c MetadataCheck) Name() string {
+	return "Metadata Check"
+}
+
+func (c MetadataCheck) RunCheck(logger logs.StructuredLogger) error {
+	p := platform.FromContext(context.TODO())
+	if p.ResourceOverride != nil && p.ResourceOverride.MonitoredResource().Type != "gce_instance" {
+		return nil
+	}
+	if _, ok := os.LookupEnv("GOOGLE_APPLICATION_CREDENTIALS"); ok {
+		// The agent doesn't use the credentials of the VM's service account.
+		return nil
+	}
+	client := metadata.NewClient(&http.Client{Timeout: metadataRequestTimeout})
+	return runMetadataCheck(context.Background(), logger, client)
+}
+
+func runMetadataCheck(ctx context.Context, logger logs.StructuredLogger, client *metadata.Client) error {
+	email, err := client.EmailWithContext(ctx, "default")
+	if err != nil {
+		logger.Infof(err.Error())
+		var notDefined metadata.NotDefinedError
+		if errors.As(err, &notDefined) {
+			return MetaNoServiceAccountErr
+		}
+		var metaErr *metadata.Error
+		if errors.As(err, &metaErr) {
+			return err
+		}
+		return MetaServerBlockedErr
+	}
+	logger.Infof("found service account %s", email)
+
+	data, err := client.GetWithContext(ctx, "instance/service-accounts/default/token")
+	if err != nil {
+		logger.Infof(err.Error())
+		var metaErr *metadata.Error
+		if errors.As(err, &metaErr) {
+			return MetaServiceAccountDisabledErr
+		}
+		return MetaServerBlockedErr
+	}
+	var token accessToken
+	if err := json.Unmarshal([]byte(data), &token); err != nil || token.AccessToken == "" {
+		logger.Infof("invalid access token response: %v", err)
+		return MetaServiceAccountDisabledErr
+	}
+	logger.Infof("metadata server issued a %s token that expires in %ds", token.TokenType, token.ExpiresIn)
+
+	info, err := validateAccessToken(ctx, token.AccessToken)
+	if err != nil {
+		return err
+	}
+	if info.Error != "" {
+		logger.Infof("access token was rejected: %s", info.Error)
+		return MetaServiceAccountDisabledErr
+	}
+	scopes := strings.Fields(info.Scope)
+	if !hasScope(scopes, loggingWriteScope) || !hasScope(scopes, monitoringWriteScope) {
+		logger.Infof("access token has scopes %v", scopes)
+		return MetaScopesErr
+	}
+	return nil
+}
+
+// validateAccessToken returns the scopes and expiration of an access token, or the reason it
+// was rejected.
+func validateAccessToken(ctx context.Context, token string) (tokenInfo, error) {
+	var info tokenInfo
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenInfoURL+"?"+url.Values{"access_token": {token}}.Encode(), nil)
+	if err != nil {
+		return info, err
+	}
+	resp, err := (&http.Client{Timeout: metadataRequestTimeout}).Do(req)
+	if err != nil {
+		return info, fmt.Errorf("failed to validate the access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, fmt.Errorf("failed to validate the access token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && info.Error == "" {
+		info.Error = resp.Status
+	}
+	return info, nil
+}
+
+// hasScope returns whether scopes grants scope, either directly or through the cloud-platform scope.
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == cloudPlatformScope {
+			return true
+		}
+	}
+	return false
+}
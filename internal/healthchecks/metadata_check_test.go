@@ -0,0 +1,107 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+func TestRunMetadataCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		emailStatus   int
+		tokenStatus   int
+		tokenInfo     string
+		tokenInfoCode int
+		want          error
+	}{
+		{
+			name:          "valid token",
+			emailStatus:   http.StatusOK,
+			tokenStatus:   http.StatusOK,
+			tokenInfo:     `{"scope": "https://www.googleapis.com/auth/logging.write https://www.googleapis.com/auth/monitoring.write", "expires_in": "3599"}`,
+			tokenInfoCode: http.StatusOK,
+		},
+		{
+			name:          "cloud-platform scope",
+			emailStatus:   http.StatusOK,
+			tokenStatus:   http.StatusOK,
+			tokenInfo:     `{"scope": "https://www.googleapis.com/auth/cloud-platform", "expires_in": "3599"}`,
+			tokenInfoCode: http.StatusOK,
+		},
+		{
+			name:        "no service account",
+			emailStatus: http.StatusNotFound,
+			want:        MetaNoServiceAccountErr,
+		},
+		{
+			name:        "disabled service account",
+			emailStatus: http.StatusOK,
+			tokenStatus: http.StatusForbidden,
+			want:        MetaServiceAccountDisabledErr,
+		},
+		{
+			name:          "rejected token",
+			emailStatus:   http.StatusOK,
+			tokenStatus:   http.StatusOK,
+			tokenInfo:     `{"error": "invalid_token"}`,
+			tokenInfoCode: http.StatusBadRequest,
+			want:          MetaServiceAccountDisabledErr,
+		},
+		{
+			name:          "missing scopes",
+			emailStatus:   http.StatusOK,
+			tokenStatus:   http.StatusOK,
+			tokenInfo:     `{"scope": "https://www.googleapis.com/auth/logging.write", "expires_in": "3599"}`,
+			tokenInfoCode: http.StatusOK,
+			want:          MetaScopesErr,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/service-accounts/default/email"):
+					w.WriteHeader(test.emailStatus)
+					w.Write([]byte("agent@project.iam.gserviceaccount.com"))
+				case strings.HasSuffix(r.URL.Path, "/service-accounts/default/token"):
+					w.WriteHeader(test.tokenStatus)
+					w.Write([]byte(`{"access_token": "token", "expires_in": 3599, "token_type": "Bearer"}`))
+				case r.URL.Path == "/tokeninfo":
+					w.WriteHeader(test.tokenInfoCode)
+					w.Write([]byte(test.tokenInfo))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+			defer func(u string) { tokenInfoURL = u }(tokenInfoURL)
+			tokenInfoURL = server.URL + "/tokeninfo"
+
+			got := runMetadataCheck(context.Background(), logs.Default(), metadata.NewClient(server.Client()))
+			if got != test.want {
+				t.Errorf("runMetadataCheck() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -64,6 +64,14 @@ var (
 			healthCheckError: DLApiConnErr,
 		},
 	}
+	traceRequests = []networkRequest{
+		{
+			name:             "Trace API",
+			url:              "https://cloudtrace.googleapis.com/$discovery/rest",
+			successMessage:   "Request to the Trace API was successful.",
+			healthCheckError: TraceApiConnErr,
+		},
+	}
 	gceRequests = []networkRequest{
 		{
 			name:             "GCE Metadata Server",
@@ -104,7 +112,31 @@ func (r networkRequest) SendRequest(logger logs.StructuredLogger) error {
 	return nil
 }
 
-type NetworkCheck struct{}
+type NetworkCheck struct {
+	// Traces is set when the config has traces pipelines, which send data to the Trace API.
+	Traces bool
+	// LoggingEndpoint and MonitoringEndpoint are the regional endpoints that the config sends
+	// logs and metrics to. They replace the global endpoints of the Logging and Monitoring API
+	// requests.
+	LoggingEndpoint    string
+	MonitoringEndpoint string
+}
+
+// regionalRequest returns r with its host replaced by the regional endpoint of its API, if the
+// config sends to one.
+func (c NetworkCheck) regionalRequest(r networkRequest) networkRequest {
+	endpoint := ""
+	switch r.healthCheckError {
+	case LogApiConnErr:
+		endpoint = c.LoggingEndpoint
+	case MonApiConnErr:
+		endpoint = c.MonitoringEndpoint
+	}
+	if endpoint != "" {
+		r.url = "https://" + endpoint + "/$discovery/rest"
+	}
+	return r
+}
 
 func (c NetworkCheck) Name() string {
 	return "Network Check"
@@ -115,7 +147,12 @@ func (c NetworkCheck) RunCheck(logger logs.StructuredLogger) error {
 	ctx := context.TODO()
 	p := platform.FromContext(ctx)
 	for _, r := range commonRequests {
-		networkErrors = append(networkErrors, r.SendRequest(logger))
+		networkErrors = append(networkErrors, c.regionalRequest(r).SendRequest(logger))
+	}
+	if c.Traces {
+		for _, r := range traceRequests {
+			networkErrors = append(networkErrors, r.SendRequest(logger))
+		}
 	}
 	if p.ResourceOverride == nil || p.ResourceOverride.MonitoredResource().Type == "gce_instance" {
 		for _, r := range gceRequests {
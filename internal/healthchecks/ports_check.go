@@ -17,8 +17,10 @@ package healthchecks
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
@@ -28,9 +30,29 @@ import (
 const (
 	tcpHost  = "0.0.0.0"
 	tcp6Host = "::"
+
+	FluentBitSubagent = "google-cloud-ops-agent-fluent-bit"
+	OtelSubagent      = "google-cloud-ops-agent-opentelemetry-collector"
 )
 
-type PortsCheck struct{}
+// Listener is a network address that a user-configured receiver listens on.
+type Listener struct {
+	ReceiverID string
+	// Subagent is the service that opens the listener.
+	Subagent string
+	// Network is the transport protocol, "tcp" or "udp".
+	Network string
+	Host    string
+	Port    uint16
+}
+
+type PortsCheck struct {
+	// Listeners are the user-configured addresses to check, in addition to the fixed agent ports.
+	Listeners []Listener
+	// AppliedListeners are the user-configured addresses of the config that the running
+	// subagents were started with, and that they hold themselves.
+	AppliedListeners []Listener
+}
 
 func (c PortsCheck) Name() string {
 	return "Ports Check"
@@ -39,7 +61,13 @@ func (c PortsCheck) Name() string {
 // checkIfPortAvailable listens in the provided socket and local provided network (tcp4, tcp6, ...)
 // and handles the errors if the port is already being used by another process.
 func checkIfPortAvailable(host string, port string, network string) (bool, error) {
-	lsnr, err := net.Listen(network, net.JoinHostPort(host, port))
+	var lsnr io.Closer
+	var err error
+	if strings.HasPrefix(network, "udp") {
+		lsnr, err = net.ListenPacket(network, net.JoinHostPort(host, port))
+	} else {
+		lsnr, err = net.Listen(network, net.JoinHostPort(host, port))
+	}
 	if err != nil {
 		if isPortUnavailableError(err) {
 			return false, nil
@@ -53,11 +81,56 @@ func checkIfPortAvailable(host string, port string, network string) (bool, error
 func (c PortsCheck) RunCheck(logger logs.StructuredLogger) error {
 	fbErr := runFluentBitCheck(logger)
 	otelErr := runOtelCollectorCheck(logger)
-	return errors.Join(fbErr, otelErr)
+	listenersErr := runListenersCheck(logger, c.Listeners, c.AppliedListeners, isSubagentActive)
+	return errors.Join(fbErr, otelErr, listenersErr)
+}
+
+// listenerAddress identifies the socket of a listener, regardless of the receiver that opens it.
+type listenerAddress struct {
+	subagent, network, host string
+	port                    uint16
+}
+
+func (l Listener) address() listenerAddress {
+	return listenerAddress{l.Subagent, l.Network, l.Host, l.Port}
+}
+
+// runListenersCheck verifies that the addresses of user-configured receivers are available.
+// A subagent that is already running holds the addresses of the config it was started with, so
+// those are skipped. Addresses that only the new config has are checked even then.
+func runListenersCheck(logger logs.StructuredLogger, listeners, applied []Listener, subagentActive func(string) (bool, error)) error {
+	held := map[listenerAddress]bool{}
+	for _, l := range applied {
+		held[l.address()] = true
+	}
+	active := map[string]bool{}
+	var errs []error
+	for _, l := range listeners {
+		if held[l.address()] {
+			isActive, ok := active[l.Subagent]
+			if !ok {
+				var err error
+				isActive, err = subagentActive(l.Subagent)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				active[l.Subagent] = isActive
+			}
+			if isActive {
+				continue
+			}
+		}
+		healthCheckError := ReceiverPortErr
+		healthCheckError.Message = fmt.Sprintf(healthCheckError.Message, l.Port, l.ReceiverID)
+		healthCheckError.Action = fmt.Sprintf(healthCheckError.Action, l.Port, l.ReceiverID)
+		errs = append(errs, runPortCheck(logger, int(l.Port), l.Host, l.Network, healthCheckError))
+	}
+	return errors.Join(errs...)
 }
 
 func runFluentBitCheck(logger logs.StructuredLogger) error {
-	fbActive, err := isSubagentActive("google-cloud-ops-agent-fluent-bit")
+	fbActive, err := isSubagentActive(FluentBitSubagent)
 	if err != nil {
 		return err
 	}
@@ -74,7 +147,7 @@ func runFluentBitCheck(logger logs.StructuredLogger) error {
 }
 
 func runOtelCollectorCheck(logger logs.StructuredLogger) error {
-	ocActive, err := isSubagentActive("google-cloud-ops-agent-opentelemetry-collector")
+	ocActive, err := isSubagentActive(OtelSubagent)
 	if err != nil {
 		return err
 	}
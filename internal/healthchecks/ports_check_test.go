@@ -0,0 +1,100 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+func TestRunListenersCheck(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	takenPort := uint16(taken.Addr().(*net.TCPAddr).Port)
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	freePort := uint16(free.Addr().(*net.TCPAddr).Port)
+	free.Close()
+
+	listener := func(port uint16) Listener {
+		return Listener{ReceiverID: "receiver", Subagent: FluentBitSubagent, Network: "tcp", Host: "127.0.0.1", Port: port}
+	}
+	for _, tc := range []struct {
+		name     string
+		listener Listener
+		applied  []Listener
+		active   bool
+		wantErr  bool
+	}{
+		{
+			name:     "free port",
+			listener: listener(freePort),
+		},
+		{
+			name:     "taken port",
+			listener: listener(takenPort),
+			wantErr:  true,
+		},
+		{
+			name:     "port held by the running subagent",
+			listener: listener(takenPort),
+			applied:  []Listener{listener(takenPort)},
+			active:   true,
+		},
+		{
+			// The receiver is only renamed, and the running subagent still holds its port.
+			name:     "port held by the running subagent for another receiver",
+			listener: listener(takenPort),
+			applied:  []Listener{{ReceiverID: "old", Subagent: FluentBitSubagent, Network: "tcp", Host: "127.0.0.1", Port: takenPort}},
+			active:   true,
+		},
+		{
+			name:     "new port of the running subagent",
+			listener: listener(takenPort),
+			active:   true,
+			wantErr:  true,
+		},
+		{
+			name:     "applied port of a stopped subagent",
+			listener: listener(takenPort),
+			applied:  []Listener{listener(takenPort)},
+			wantErr:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, _ := logs.DiscardLogger()
+			subagentActive := func(string) (bool, error) { return tc.active, nil }
+			err := runListenersCheck(logger, []Listener{tc.listener}, tc.applied, subagentActive)
+			if !tc.wantErr {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			var healthErr HealthCheckError
+			if !errors.As(err, &healthErr) || healthErr.Code != ReceiverPortErr.Code {
+				t.Errorf("got error %v, want %s", err, ReceiverPortErr.Code)
+			}
+		})
+	}
+}
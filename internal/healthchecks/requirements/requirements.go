@@ -0,0 +1,91 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package requirements determines what an Ops Agent config needs from the environment, so that
+// the health checks can evaluate a config before it is applied. It lives outside of confgenerator
+// so that the config generation doesn't depend on the health checks.
+package requirements
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+)
+
+var subagentServices = map[string]string{
+	"fluentbit": healthchecks.FluentBitSubagent,
+	"otel":      healthchecks.OtelSubagent,
+}
+
+// FromConfig returns the requirements of the merged config uc. applied is the config that was
+// applied before, if any, whose listeners the running subagents hold.
+func FromConfig(ctx context.Context, uc, applied *confgenerator.UnifiedConfig) (healthchecks.ConfigRequirements, error) {
+	req := healthchecks.ConfigRequirements{}
+	// The merged config always has the logging and metrics sections of the built-in config, so
+	// only pipelines with receivers tell whether it collects logs or metrics.
+	if uc.Logging != nil && uc.Logging.Service != nil {
+		req.Logging = hasReceivers(uc.Logging.Service.Pipelines)
+	}
+	if uc.Metrics != nil && uc.Metrics.Service != nil {
+		req.Metrics = hasReceivers(uc.Metrics.Service.Pipelines)
+	}
+	if uc.Traces != nil && uc.Traces.Service != nil {
+		req.Traces = hasReceivers(uc.Traces.Service.Pipelines)
+	}
+	if uc.Logging != nil && uc.Logging.Service != nil && uc.Logging.Service.Location != "" {
+		req.LoggingEndpoint = confgenerator.LoggingEndpoint(uc.Logging.Service.Location)
+	}
+	if uc.Metrics != nil && uc.Metrics.Service != nil && uc.Metrics.Service.Location != "" {
+		req.MonitoringEndpoint = confgenerator.MonitoringEndpoint(uc.Metrics.Service.Location)
+	}
+	var err error
+	if req.Listeners, err = listeners(ctx, uc); err != nil {
+		return req, err
+	}
+	if applied != nil {
+		// The applied config generated fine before, so an error only means that it can't be
+		// generated by this version of the agent anymore. Its listeners are then unknown.
+		req.AppliedListeners, _ = listeners(ctx, applied)
+	}
+	return req, nil
+}
+
+// hasReceivers returns whether any of pipelines has a receiver.
+func hasReceivers(pipelines map[string]*confgenerator.Pipeline) bool {
+	for _, p := range pipelines {
+		if p != nil && len(p.ReceiverIDs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func listeners(ctx context.Context, uc *confgenerator.UnifiedConfig) ([]healthchecks.Listener, error) {
+	receiverListeners, err := uc.ReceiverListeners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []healthchecks.Listener
+	for _, l := range receiverListeners {
+		out = append(out, healthchecks.Listener{
+			ReceiverID: l.ReceiverID,
+			Subagent:   subagentServices[l.Subagent],
+			Network:    l.Network,
+			Host:       l.Host,
+			Port:       l.Port,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package requirements
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/GoogleCloudPlatform/ops-agent/apps"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/platform"
+	"github.com/google/go-cmp/cmp"
+	"github.com/shirou/gopsutil/host"
+)
+
+func TestFromConfig(t *testing.T) {
+	ctx := platform.Platform{
+		Type:     platform.Linux,
+		HostInfo: &host.InfoStat{Hostname: "hostname"},
+	}.TestContext(context.Background())
+	uc, err := confgenerator.UnmarshalYamlToUnifiedConfig(ctx, []byte(`
+logging:
+  receivers:
+    syslog_tcp:
+      type: syslog
+      transport_protocol: tcp
+      listen_host: 0.0.0.0
+      listen_port: 5140
+  service:
+    pipelines:
+      first:
+        receivers: [syslog_tcp]
+      second:
+        receivers: [syslog_tcp]
+metrics:
+  receivers:
+    remote_write:
+      type: prometheus_remote_write
+      endpoint: 0.0.0.0:9091
+  service:
+    pipelines:
+      remote_write:
+        receivers: [remote_write]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromConfig(ctx, uc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := healthchecks.ConfigRequirements{
+		Logging: true,
+		Metrics: true,
+		// The receiver is used in two pipelines, but only listens once.
+		Listeners: []healthchecks.Listener{
+			{
+				ReceiverID: "remote_write",
+				Subagent:   healthchecks.OtelSubagent,
+				Network:    "tcp",
+				Host:       "0.0.0.0",
+				Port:       9091,
+			},
+			{
+				ReceiverID: "syslog_tcp",
+				Subagent:   healthchecks.FluentBitSubagent,
+				Network:    "tcp",
+				Host:       "0.0.0.0",
+				Port:       5140,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FromConfig() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFromConfigWithoutPipelines(t *testing.T) {
+	ctx := platform.Platform{
+		Type:     platform.Linux,
+		HostInfo: &host.InfoStat{Hostname: "hostname"},
+	}.TestContext(context.Background())
+	// The built-in pipelines are disabled by overriding them with pipelines without receivers.
+	uc, err := confgenerator.UnmarshalYamlToUnifiedConfig(ctx, []byte(`
+logging:
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: []
+metrics:
+  service:
+    pipelines:
+      default_pipeline:
+        receivers: []
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromConfig(ctx, uc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Logging || got.Metrics || got.Traces {
+		t.Errorf("FromConfig() = %+v, want no logging, metrics or traces", got)
+	}
+}
+
+func TestFromConfigWithLocation(t *testing.T) {
+	ctx := platform.Platform{
+		Type:     platform.Linux,
+		HostInfo: &host.InfoStat{Hostname: "hostname"},
+	}.TestContext(context.Background())
+	uc, err := confgenerator.UnmarshalYamlToUnifiedConfig(ctx, []byte(`
+logging:
+  service:
+    location: europe-west1
+metrics:
+  service:
+    location: us-central1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromConfig(ctx, uc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "logging.europe-west1.rep.googleapis.com"; got.LoggingEndpoint != want {
+		t.Errorf("FromConfig().LoggingEndpoint = %q, want %q", got.LoggingEndpoint, want)
+	}
+	if want := "monitoring.us-central1.rep.googleapis.com"; got.MonitoringEndpoint != want {
+		t.Errorf("FromConfig().MonitoringEndpoint = %q, want %q", got.MonitoringEndpoint, want)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+const timeSyncCommandTimeout = 10 * time.Second
+
+type timeSyncStatus int
+
+const (
+	// timeSyncUnknown is the status when no tool can tell whether the clock is synchronized.
+	timeSyncUnknown timeSyncStatus = iota
+	timeSyncNotRunning
+	timeSyncNotSynchronized
+	timeSyncSynchronized
+)
+
+// runCommand runs a command and returns its standard output, which is also returned when the
+// command fails.
+var runCommand = func(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeSyncCommandTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// TimeSyncCheck verifies that a time synchronization service is running and that the clock is
+// synchronized. Points with timestamps too far from the current time are rejected by Cloud
+// Monitoring, and a drifting clock is a common cause of rejected metrics.
+type TimeSyncCheck struct{}
+
+func (c TimeSyncCheck) Name() string {
+	return "Time Sync Check"
+}
+
+func (c TimeSyncCheck) RunCheck(logger logs.StructuredLogger) error {
+	status, tool := getTimeSyncStatus()
+	switch status {
+	case timeSyncNotRunning:
+		return TimeSyncNotRunningErr
+	case timeSyncNotSynchronized:
+		return TimeSyncNotSynchronizedErr
+	case timeSyncSynchronized:
+		logger.Infof("The clock is synchronized according to %s", tool)
+	default:
+		logger.Infof("Could not determine whether the clock is synchronized")
+	}
+	return nil
+}
+
+// keyValues parses the "key<sep>value" lines of the output of a command.
+func keyValues(output []byte, sep string) map[string]string {
+	out := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), sep)
+		if ok {
+			out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
+// parseChronyTracking parses the output of "chronyc tracking".
+func parseChronyTracking(output []byte) timeSyncStatus {
+	switch keyValues(output, ":")["Leap status"] {
+	case "":
+		return timeSyncUnknown
+	case "Not synchronised":
+		return timeSyncNotSynchronized
+	}
+	return timeSyncSynchronized
+}
+
+// parseTimedatectl parses the output of "timedatectl show". NTPSynchronized is the kernel's
+// synchronization flag, which is set by any NTP daemon, e.g. ntpd, and NTP is set when
+// timedated manages the service, e.g. systemd-timesyncd or chronyd.
+func parseTimedatectl(output []byte) timeSyncStatus {
+	values := keyValues(output, "=")
+	switch {
+	case values["NTPSynchronized"] == "":
+		return timeSyncUnknown
+	case values["NTPSynchronized"] == "yes":
+		return timeSyncSynchronized
+	case values["NTP"] == "yes":
+		return timeSyncNotSynchronized
+	}
+	return timeSyncNotRunning
+}
+
+// parseW32tmStatus parses the output of "w32tm /query /status". The clock is not synchronized
+// when the leap indicator is 3, or when the source is the local clock.
+func parseW32tmStatus(output []byte) timeSyncStatus {
+	values := keyValues(output, ":")
+	leap, source := values["Leap Indicator"], values["Source"]
+	switch {
+	case leap == "" && source == "":
+		return timeSyncUnknown
+	case strings.HasPrefix(leap, "3"),
+		strings.HasPrefix(source, "Local CMOS Clock"),
+		strings.HasPrefix(source, "Free-running System Clock"):
+		return timeSyncNotSynchronized
+	}
+	return timeSyncSynchronized
+}
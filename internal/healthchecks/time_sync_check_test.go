@@ -0,0 +1,111 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthchecks
+
+import (
+	"testing"
+)
+
+const chronyTrackingSynchronized = `Reference ID    : A9FEA9FE (169.254.169.254)
+Stratum         : 3
+Ref time (UTC)  : Mon Jun 03 12:00:00 2024
+System time     : 0.000001234 seconds fast of NTP time
+Last offset     : +0.000000567 seconds
+Leap status     : Normal
+`
+
+const chronyTrackingNotSynchronized = `Reference ID    : 00000000 ()
+Stratum         : 0
+Ref time (UTC)  : Thu Jan 01 00:00:00 1970
+Leap status     : Not synchronised
+`
+
+func TestParseChronyTracking(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   timeSyncStatus
+	}{
+		{"synchronized", chronyTrackingSynchronized, timeSyncSynchronized},
+		{"not synchronized", chronyTrackingNotSynchronized, timeSyncNotSynchronized},
+		{"insert second", "Leap status     : Insert second\n", timeSyncSynchronized},
+		{"empty", "", timeSyncUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseChronyTracking([]byte(tc.output)); got != tc.want {
+				t.Errorf("parseChronyTracking() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTimedatectl(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   timeSyncStatus
+	}{
+		{"synchronized", "Timezone=UTC\nNTP=yes\nNTPSynchronized=yes\n", timeSyncSynchronized},
+		{"synchronized by another daemon", "NTP=no\nNTPSynchronized=yes\n", timeSyncSynchronized},
+		{"not synchronized", "NTP=yes\nNTPSynchronized=no\n", timeSyncNotSynchronized},
+		{"not running", "NTP=no\nNTPSynchronized=no\n", timeSyncNotRunning},
+		{"empty", "", timeSyncUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseTimedatectl([]byte(tc.output)); got != tc.want {
+				t.Errorf("parseTimedatectl() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseW32tmStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   timeSyncStatus
+	}{
+		{
+			name:   "synchronized",
+			output: "Leap Indicator: 0(no warning)\r\nStratum: 2 (secondary reference - syncd by (S)NTP)\r\nSource: metadata.google.internal\r\n",
+			want:   timeSyncSynchronized,
+		},
+		{
+			name:   "leap indicator",
+			output: "Leap Indicator: 3(not synchronized)\r\nStratum: 0 (unspecified)\r\nSource: metadata.google.internal\r\n",
+			want:   timeSyncNotSynchronized,
+		},
+		{
+			name:   "local clock",
+			output: "Leap Indicator: 0(no warning)\r\nSource: Local CMOS Clock\r\n",
+			want:   timeSyncNotSynchronized,
+		},
+		{
+			name:   "free-running clock",
+			output: "Leap Indicator: 0(no warning)\r\nSource: Free-running System Clock\r\n",
+			want:   timeSyncNotSynchronized,
+		},
+		{
+			name: "empty",
+			want: timeSyncUnknown,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseW32tmStatus([]byte(tc.output)); got != tc.want {
+				t.Errorf("parseW32tmStatus() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
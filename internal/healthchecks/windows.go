@@ -53,3 +53,23 @@ func isTimeoutError(err error) bool {
 func isConnectionRefusedError(err error) bool {
 	return errors.Is(err, windows.WSAECONNREFUSED)
 }
+
+// w32timeNotStarted is the error that w32tm prints when the Windows Time service is stopped.
+const w32timeNotStarted = "0x80070426"
+
+func getTimeSyncStatus() (timeSyncStatus, string) {
+	output, err := runCommand("w32tm", "/query", "/status")
+	if err != nil {
+		if strings.Contains(string(output), w32timeNotStarted) {
+			return timeSyncNotRunning, "w32tm"
+		}
+		return timeSyncUnknown, ""
+	}
+	return parseW32tmStatus(output), "w32tm"
+}
+
+// findConflictingAgents returns no agents on Windows, where the service refuses to start next to
+// the legacy agents.
+func findConflictingAgents() []conflictingAgent {
+	return nil
+}
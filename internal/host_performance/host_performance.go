@@ -0,0 +1,236 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package host_performance reads the Linux host metrics that the hostmetrics receiver of the OTel
+// collector has no scraper for: the pressure stall information (PSI), the hugepages, the memory of
+// the NUMA nodes and the usage of the ephemeral ports.
+package host_performance
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PressureResources are the resources of /proc/pressure.
+var PressureResources = []string{"cpu", "memory", "io"}
+
+// Pressure is the total time during which some or all of the non-idle tasks were stalled on a
+// resource.
+type Pressure struct {
+	// Level is "some" or "full".
+	Level string
+	Total time.Duration
+}
+
+// ReadPressure reads /proc/pressure/<resource> under procDir. It fails if the kernel was built
+// without PSI, or it is disabled with psi=0.
+func ReadPressure(procDir, resource string) ([]Pressure, error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "pressure", resource))
+	if err != nil {
+		return nil, err
+	}
+	// Sample line: some avg10=0.00 avg60=0.00 avg300=0.00 total=1234
+	var out []Pressure
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, f := range fields[1:] {
+			total, ok := strings.CutPrefix(f, "total=")
+			if !ok {
+				continue
+			}
+			us, err := strconv.ParseInt(total, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pressure line %q: %w", line, err)
+			}
+			out = append(out, Pressure{Level: fields[0], Total: time.Duration(us) * time.Microsecond})
+		}
+	}
+	return out, nil
+}
+
+// Hugepages is the usage of the hugepages of the default size.
+type Hugepages struct {
+	Total    int64
+	Free     int64
+	Reserved int64
+	Surplus  int64
+	// PageSize is in bytes.
+	PageSize int64
+}
+
+// ReadHugepages reads the hugepages of /proc/meminfo under procDir.
+func ReadHugepages(procDir string) (Hugepages, error) {
+	values, err := readMeminfo(filepath.Join(procDir, "meminfo"), "")
+	if err != nil {
+		return Hugepages{}, err
+	}
+	return Hugepages{
+		Total:    values["HugePages_Total"],
+		Free:     values["HugePages_Free"],
+		Reserved: values["HugePages_Rsvd"],
+		Surplus:  values["HugePages_Surp"],
+		PageSize: values["Hugepagesize"],
+	}, nil
+}
+
+// NUMANode is the memory of a NUMA node.
+type NUMANode struct {
+	Node string
+	// Total and Free are in bytes.
+	Total int64
+	Free  int64
+	// Allocations are the page allocations of /sys/devices/system/node/node<N>/numastat by type,
+	// e.g. "numa_hit" or "numa_miss".
+	Allocations map[string]int64
+}
+
+// ReadNUMANodes reads the NUMA nodes under sysDir, sorted by node number. A machine without NUMA
+// has a single node.
+func ReadNUMANodes(sysDir string) ([]NUMANode, error) {
+	dirs, err := filepath.Glob(filepath.Join(sysDir, "devices", "system", "node", "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	var out []NUMANode
+	for _, dir := range dirs {
+		node := strings.TrimPrefix(filepath.Base(dir), "node")
+		// Sample line: Node 0 MemTotal:       16305492 kB
+		values, err := readMeminfo(filepath.Join(dir, "meminfo"), "Node "+node+" ")
+		if err != nil {
+			return nil, err
+		}
+		n := NUMANode{
+			Node:        node,
+			Total:       values["MemTotal"],
+			Free:        values["MemFree"],
+			Allocations: map[string]int64{},
+		}
+		// Sample line: numa_hit 123456
+		data, err := os.ReadFile(filepath.Join(dir, "numastat"))
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			if v, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				n.Allocations[fields[0]] = v
+			}
+		}
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := strconv.Atoi(out[i].Node)
+		b, _ := strconv.Atoi(out[j].Node)
+		return a < b
+	})
+	return out, nil
+}
+
+// readMeminfo reads the "Key: value [kB]" lines of a meminfo file whose lines start with prefix.
+// The values in kB are returned in bytes.
+func readMeminfo(path, prefix string) (map[string]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]int64{}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		key, value, ok := strings.Cut(strings.TrimPrefix(s.Text(), prefix), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		v, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			v *= 1024
+		}
+		values[strings.TrimSpace(key)] = v
+	}
+	return values, s.Err()
+}
+
+// EphemeralPortRange reads the range of the local ports that the kernel picks for outgoing
+// connections from /proc/sys/net/ipv4/ip_local_port_range under procDir.
+func EphemeralPortRange(procDir string) (low, high int, err error) {
+	data, err := os.ReadFile(filepath.Join(procDir, "sys", "net", "ipv4", "ip_local_port_range"))
+	if err != nil {
+		return 0, 0, err
+	}
+	// Sample content: 32768	60999
+	if _, err := fmt.Sscan(string(data), &low, &high); err != nil {
+		return 0, 0, fmt.Errorf("invalid port range %q: %w", data, err)
+	}
+	return low, high, nil
+}
+
+// tcpListen is the state of the listening TCP sockets in /proc/net/tcp.
+const tcpListen = "0A"
+
+// EphemeralPortsUsed counts the distinct local ports between low and high of the sockets of
+// protocol, i.e. "tcp" or "udp", over IPv4 and IPv6. The listening TCP sockets are not counted,
+// but the ones in TIME_WAIT are, since they hold their port until they expire.
+func EphemeralPortsUsed(procDir, protocol string, low, high int) (int64, error) {
+	ports := map[int]bool{}
+	for _, file := range []string{protocol, protocol + "6"} {
+		data, err := os.ReadFile(filepath.Join(procDir, "net", file))
+		if os.IsNotExist(err) {
+			// IPv6 is disabled.
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		// Sample line:
+		//   0: 0100007F:A8F2 0100007F:1F90 06 00000000:00000000 03:00000F3C 00000000     0        0 0 ...
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || (protocol == "tcp" && fields[3] == tcpListen) {
+				continue
+			}
+			i := strings.LastIndex(fields[1], ":")
+			if i < 0 {
+				continue
+			}
+			port, err := strconv.ParseInt(fields[1][i+1:], 16, 32)
+			if err != nil {
+				continue
+			}
+			if int(port) >= low && int(port) <= high {
+				ports[int(port)] = true
+			}
+		}
+	}
+	return int64(len(ports)), nil
+}
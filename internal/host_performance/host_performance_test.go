@@ -0,0 +1,131 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host_performance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPressure(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pressure", "memory"), `some avg10=0.00 avg60=0.12 avg300=0.05 total=1500
+full avg10=0.00 avg60=0.00 avg300=0.00 total=250
+`)
+	got, err := ReadPressure(dir, "memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pressure{
+		{Level: "some", Total: 1500 * time.Microsecond},
+		{Level: "full", Total: 250 * time.Microsecond},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadPressure() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := ReadPressure(dir, "cpu"); err == nil {
+		t.Error("ReadPressure() succeeded without the pressure file")
+	}
+}
+
+func TestReadHugepages(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "meminfo"), `MemTotal:       16305492 kB
+HugePages_Total:     512
+HugePages_Free:      128
+HugePages_Rsvd:       64
+HugePages_Surp:        0
+Hugepagesize:       2048 kB
+`)
+	got, err := ReadHugepages(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Hugepages{Total: 512, Free: 128, Reserved: 64, PageSize: 2 << 20}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadHugepages() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadNUMANodes(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []struct{ node, total, free, hit string }{
+		{"10", "2048", "1024", "7"},
+		{"2", "4096", "512", "42"},
+	} {
+		nodeDir := filepath.Join(dir, "devices", "system", "node", "node"+n.node)
+		writeFile(t, filepath.Join(nodeDir, "meminfo"), "Node "+n.node+" MemTotal:       "+n.total+" kB\nNode "+n.node+" MemFree:        "+n.free+" kB\n")
+		writeFile(t, filepath.Join(nodeDir, "numastat"), "numa_hit "+n.hit+"\nnuma_miss 3\n")
+	}
+	writeFile(t, filepath.Join(dir, "devices", "system", "node", "possible"), "0-10\n")
+	got, err := ReadNUMANodes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NUMANode{
+		{Node: "2", Total: 4096 << 10, Free: 512 << 10, Allocations: map[string]int64{"numa_hit": 42, "numa_miss": 3}},
+		{Node: "10", Total: 2048 << 10, Free: 1024 << 10, Allocations: map[string]int64{"numa_hit": 7, "numa_miss": 3}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadNUMANodes() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEphemeralPorts(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "sys", "net", "ipv4", "ip_local_port_range"), "32768\t60999\n")
+	writeFile(t, filepath.Join(dir, "net", "tcp"), `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:8000 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:8001 0100007F:1F90 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:8002 0100007F:1F90 06 00000000:00000000 03:00000F3C 00000000     0        0 0 3 0000000000000000
+   3: 0100007F:1F90 0100007F:8001 01 00000000:00000000 00:00000000 00000000     0        0 3 1 0000000000000000 20 4 30 10 -1
+`)
+	writeFile(t, filepath.Join(dir, "net", "tcp6"), `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:8001 00000000000000000000000001000000:01BB 01 00000000:00000000 00:00000000 00000000     0        0 4 1 0000000000000000 20 4 30 10 -1
+   1: 00000000000000000000000001000000:8003 00000000000000000000000001000000:01BB 01 00000000:00000000 00:00000000 00000000     0        0 5 1 0000000000000000 20 4 30 10 -1
+`)
+	writeFile(t, filepath.Join(dir, "net", "udp"), `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:8000 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 6 2 0000000000000000 0
+`)
+	low, high, err := EphemeralPortRange(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if low != 32768 || high != 60999 {
+		t.Errorf("EphemeralPortRange() = %d, %d, want 32768, 60999", low, high)
+	}
+	// The listening socket on 0x8000 and the server side on 0x1F90 are not counted, and 0x8001 is
+	// counted once for IPv4 and IPv6.
+	if got, err := EphemeralPortsUsed(dir, "tcp", low, high); err != nil || got != 3 {
+		t.Errorf("EphemeralPortsUsed(tcp) = %d, %v, want 3", got, err)
+	}
+	if got, err := EphemeralPortsUsed(dir, "udp", low, high); err != nil || got != 1 {
+		t.Errorf("EphemeralPortsUsed(udp) = %d, %v, want 1", got, err)
+	}
+}
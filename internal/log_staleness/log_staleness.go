@@ -0,0 +1,171 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package log_staleness reports the files of the files receivers that stopped receiving new lines,
+// which is often the first symptom of a wedged application. Only files that received new lines
+// while they were watched are reported, so that old files matching the same patterns, e.g. rotated
+// logs, are ignored.
+package log_staleness
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+// Receiver is a files receiver with a staleness alert.
+type Receiver struct {
+	ID           string
+	IncludePaths []string
+	ExcludePaths []string
+	// Threshold is how long a file can go without new lines before it is reported.
+	Threshold time.Duration
+}
+
+// StaleFile is a file that has not received new lines for longer than the threshold of its
+// receiver.
+type StaleFile struct {
+	Receiver  string
+	Path      string
+	LastWrite time.Time
+}
+
+type Options struct {
+	Receivers []Receiver
+	// Interval is how often the files are checked.
+	Interval time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// Record is called with the stale files after every check.
+	Record func([]StaleFile)
+}
+
+type fileKey struct {
+	receiver, path string
+}
+
+// files returns the last modification time of the files matched by r.
+func (r Receiver) files() map[string]time.Time {
+	out := map[string]time.Time{}
+	for _, include := range r.IncludePaths {
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			if excluded(m, r.ExcludePaths) {
+				continue
+			}
+			fi, err := os.Stat(m)
+			if err != nil || fi.IsDir() {
+				continue
+			}
+			out[m] = fi.ModTime()
+		}
+	}
+	return out
+}
+
+func excluded(path string, excludePaths []string) bool {
+	for _, exclude := range excludePaths {
+		if ok, _ := filepath.Match(exclude, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// watcher keeps the files that received new lines while they were watched, and whether they are
+// stale now.
+type watcher struct {
+	stale map[fileKey]bool
+}
+
+// check logs the files that went stale or received new lines again since the previous check, and
+// returns the stale files.
+func (w *watcher) check(receivers []Receiver, now time.Time, logger logs.StructuredLogger) []StaleFile {
+	seen := map[fileKey]bool{}
+	var out []StaleFile
+	for _, r := range receivers {
+		files := r.files()
+		paths := make([]string, 0, len(files))
+		for p := range files {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			lastWrite := files[p]
+			k := fileKey{r.ID, p}
+			seen[k] = true
+			wasStale, watched := w.stale[k]
+			isStale := now.Sub(lastWrite) >= r.Threshold
+			if !isStale {
+				if wasStale {
+					logger.Infow("Log file is receiving new lines again",
+						"code", "LogFileResumed",
+						"receiver", r.ID,
+						"path", p)
+				}
+				w.stale[k] = false
+				continue
+			}
+			if !watched {
+				continue
+			}
+			if !wasStale {
+				logger.Warnw("Log file has not received new lines for longer than its staleness alert",
+					"code", "LogFileStale",
+					"receiver", r.ID,
+					"path", p,
+					"last_write", lastWrite.Format(time.RFC3339),
+					"staleness_alert", r.Threshold.String())
+				w.stale[k] = true
+			}
+			out = append(out, StaleFile{Receiver: r.ID, Path: p, LastWrite: lastWrite})
+		}
+	}
+	// Forget the files that were removed.
+	for k := range w.stale {
+		if !seen[k] {
+			delete(w.stale, k)
+		}
+	}
+	return out
+}
+
+// Watch checks the files of opts.Receivers every opts.Interval until ctx is done. It logs a
+// LogFileStale entry to logger when a file goes stale, and a LogFileResumed entry when it receives
+// new lines again.
+func Watch(ctx context.Context, opts Options, logger logs.StructuredLogger) {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	w := &watcher{stale: map[fileKey]bool{}}
+	for {
+		stale := w.check(opts.Receivers, now(), logger)
+		if opts.Record != nil {
+			opts.Record(stale)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log_staleness
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"go.uber.org/zap"
+)
+
+func writeFile(t *testing.T, path string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now()
+	active := filepath.Join(dir, "app.log")
+	rotated := filepath.Join(dir, "app.1.log")
+	excludedFile := filepath.Join(dir, "app.log.gz")
+	writeFile(t, active, start)
+	writeFile(t, rotated, start.Add(-24*time.Hour))
+	writeFile(t, excludedFile, start)
+
+	receivers := []Receiver{{
+		ID:           "app",
+		IncludePaths: []string{filepath.Join(dir, "app*")},
+		ExcludePaths: []string{filepath.Join(dir, "*.gz")},
+		Threshold:    30 * time.Minute,
+	}}
+	logger, observed := logs.DiscardLogger()
+	w := &watcher{stale: map[fileKey]bool{}}
+
+	// The rotated file was already stale when it was first seen, so it is not reported.
+	if stale := w.check(receivers, start, logger); len(stale) != 0 {
+		t.Errorf("got stale files %v, want none", stale)
+	}
+
+	later := start.Add(time.Hour)
+	stale := w.check(receivers, later, logger)
+	if len(stale) != 1 || stale[0].Path != active || stale[0].Receiver != "app" {
+		t.Errorf("got stale files %v, want only %s", stale, active)
+	}
+	// The file is only logged once while it stays stale.
+	w.check(receivers, later.Add(time.Minute), logger)
+	if entries := observed.FilterField(zap.String("code", "LogFileStale")).All(); len(entries) != 1 {
+		t.Errorf("got %d LogFileStale entries, want 1", len(entries))
+	}
+
+	writeFile(t, active, later)
+	if stale := w.check(receivers, later, logger); len(stale) != 0 {
+		t.Errorf("got stale files %v after new lines, want none", stale)
+	}
+	if entries := observed.FilterField(zap.String("code", "LogFileResumed")).All(); len(entries) != 1 {
+		t.Errorf("got %d LogFileResumed entries, want 1", len(entries))
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatOperationalEvent appends the fields of an event to its message, one "key=value" per
+// line, so that they can be extracted from the event data.
+func formatOperationalEvent(msg string, keysAndValues []any) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	if len(keysAndValues) > 0 {
+		b.WriteString("\r\n")
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fmt.Fprintf(&b, "\r\n%v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	return b.String()
+}
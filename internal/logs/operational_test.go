@@ -0,0 +1,50 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logs
+
+import "testing"
+
+func TestFormatOperationalEvent(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		msg           string
+		keysAndValues []any
+		want          string
+	}{
+		{
+			name: "message only",
+			msg:  "Ops Agent started.",
+			want: "Ops Agent started.",
+		},
+		{
+			name:          "fields",
+			msg:           "Subagent restarted.",
+			keysAndValues: []any{"subagent", "fluent-bit", "restarts", 2},
+			want:          "Subagent restarted.\r\n\r\nsubagent=fluent-bit\r\nrestarts=2",
+		},
+		{
+			name:          "key without value",
+			msg:           "Config applied.",
+			keysAndValues: []any{"config", "config.yaml", "dangling"},
+			want:          "Config applied.\r\n\r\nconfig=config.yaml",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatOperationalEvent(tc.msg, tc.keysAndValues); got != tc.want {
+				t.Errorf("formatOperationalEvent(%q, %v) = %q, want %q", tc.msg, tc.keysAndValues, got, tc.want)
+			}
+		})
+	}
+}
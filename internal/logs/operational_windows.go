@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package logs
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+const (
+	// OperationalLogName is the event log that the lifecycle events of the agent are written to.
+	// Channels like "Google Cloud Ops Agent/Operational" need a compiled instrumentation
+	// manifest, so this is a classic event log under "Applications and Services Logs".
+	OperationalLogName = "Google Cloud Ops Agent"
+	operationalSource  = "google-cloud-ops-agent-operational"
+	eventLogKey        = `SYSTEM\CurrentControlSet\Services\EventLog`
+)
+
+// The event IDs of the operational events. They are stable, so that alerts can be based on
+// them. EventCreate.exe, which formats the events, only supports IDs up to 1000.
+const (
+	AgentStartedEventID       uint32 = 100
+	AgentStoppedEventID       uint32 = 101
+	ConfigAppliedEventID      uint32 = 110
+	ConfigInvalidEventID      uint32 = 111
+	HealthCheckPassedEventID  uint32 = 120
+	HealthCheckWarningEventID uint32 = 121
+	HealthCheckFailedEventID  uint32 = 122
+	SubagentRestartedEventID  uint32 = 130
+)
+
+// InstallOperationalLog creates the operational event log and registers its event source.
+func InstallOperationalLog() error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, eventLogKey+`\`+OperationalLogName+`\`+operationalSource, registry.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.SetExpandStringValue("EventMessageFile", `%SystemRoot%\System32\EventCreate.exe`); err != nil {
+		return err
+	}
+	if err := k.SetDWordValue("CustomSource", 1); err != nil {
+		return err
+	}
+	return k.SetDWordValue("TypesSupported", eventlog.Error|eventlog.Warning|eventlog.Info)
+}
+
+// UninstallOperationalLog removes the event source and the operational event log. The events that
+// were written to the log are removed with it.
+func UninstallOperationalLog() error {
+	logKey := eventLogKey + `\` + OperationalLogName
+	for _, key := range []string{logKey + `\` + operationalSource, logKey} {
+		if err := registry.DeleteKey(registry.LOCAL_MACHINE, key); err != nil && !errors.Is(err, registry.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// OperationalLog writes structured events to the operational event log.
+// The methods of a nil OperationalLog do nothing, so that callers don't need to check
+// whether the log could be opened.
+type OperationalLog struct {
+	log *eventlog.Log
+}
+
+func OpenOperationalLog() (*OperationalLog, error) {
+	l, err := eventlog.Open(operationalSource)
+	if err != nil {
+		return nil, err
+	}
+	return &OperationalLog{log: l}, nil
+}
+
+func (l *OperationalLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.log.Close()
+}
+
+func (l *OperationalLog) Info(eventID uint32, msg string, keysAndValues ...any) error {
+	if l == nil {
+		return nil
+	}
+	return l.log.Info(eventID, formatOperationalEvent(msg, keysAndValues))
+}
+
+func (l *OperationalLog) Warning(eventID uint32, msg string, keysAndValues ...any) error {
+	if l == nil {
+		return nil
+	}
+	return l.log.Warning(eventID, formatOperationalEvent(msg, keysAndValues))
+}
+
+func (l *OperationalLog) Error(eventID uint32, msg string, keysAndValues ...any) error {
+	if l == nil {
+		return nil
+	}
+	return l.log.Error(eventID, formatOperationalEvent(msg, keysAndValues))
+}
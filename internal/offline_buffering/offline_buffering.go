@@ -0,0 +1,89 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package offline_buffering enforces the retention of the logs that the logging subagent buffers
+// while the agent is offline. The Cloud Logging outputs retry the buffered chunks without a
+// limit, so the chunks that are older than the retention are deleted here and reported.
+package offline_buffering
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+type Options struct {
+	// BuffersDir is the storage path of the logging subagent.
+	BuffersDir string
+	// Retention is how long the chunks are kept after they were last written.
+	Retention time.Duration
+	// Interval is how often the chunks are checked.
+	Interval time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// Record is called with the total number of bytes dropped since the watch started.
+	Record func(droppedBytes int64)
+}
+
+// dropExpired deletes the chunks under dir that were last written before deadline, and returns
+// how many chunks and bytes it deleted.
+func dropExpired(dir string, deadline time.Time) (chunks int, bytes int64) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".flb") {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil || !fi.ModTime().Before(deadline) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return nil
+		}
+		chunks++
+		bytes += fi.Size()
+		return nil
+	})
+	return chunks, bytes
+}
+
+// Watch deletes the expired chunks every opts.Interval until ctx is done.
+func Watch(ctx context.Context, opts Options, logger logs.StructuredLogger) {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	var total int64
+	for {
+		chunks, bytes := dropExpired(opts.BuffersDir, now().Add(-opts.Retention))
+		if chunks > 0 {
+			total += bytes
+			logger.Warnw("Dropped buffered logs that could not be sent within the offline buffering retention",
+				"code", "BufferedLogsDropped",
+				"chunks", chunks,
+				"bytes", bytes,
+				"retention", opts.Retention.String())
+		}
+		opts.Record(total)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offline_buffering
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDropExpired(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for _, f := range []struct {
+		name string
+		age  time.Duration
+	}{
+		{"tail.0/1-1700000000.1.flb", 50 * time.Hour},
+		{"tail.0/1-1700100000.1.flb", time.Hour},
+		{"forward.1/1-1700000000.2.flb", 49 * time.Hour},
+		// Only chunks are deleted.
+		{"tail.0/state.db", 100 * time.Hour},
+	} {
+		path := filepath.Join(dir, f.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("chunk"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chunks, bytes := dropExpired(dir, now.Add(-48*time.Hour))
+	if chunks != 2 || bytes != 10 {
+		t.Errorf("dropExpired() = %d chunks, %d bytes, want 2 chunks, 10 bytes", chunks, bytes)
+	}
+	for _, name := range []string{"tail.0/1-1700100000.1.flb", "tail.0/state.db"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was deleted: %v", name, err)
+		}
+	}
+}
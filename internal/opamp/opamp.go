@@ -0,0 +1,379 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package opamp is a client of the Open Agent Management Protocol over its HTTP transport. It
+// reports the agent's version, effective config and health to an OpAMP server, and applies the
+// configs that the server pushes once their signature is verified.
+// https://github.com/open-telemetry/opamp-spec
+package opamp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+const (
+	// ConfigFile is the name of the agent config in the remote config map.
+	ConfigFile = "config.yaml"
+	// ClaimsFile is the name of the claims of ConfigFile in the remote config map, in JSON. See
+	// claims.
+	ClaimsFile = "config.yaml.claims"
+	// SignatureFile is the name of the base64-encoded ed25519 signature of ClaimsFile in the remote
+	// config map.
+	SignatureFile = "config.yaml.sig"
+)
+
+// claims bind a remote config to one agent and one use. They are signed instead of the config, so
+// that a signed config can't be replayed to another agent, or to the same agent once it expired
+// or was applied.
+type claims struct {
+	// InstanceUID is the hex encoded instance UID of the agent that the config is for.
+	InstanceUID string `json:"instance_uid"`
+	// Nonce is unique to each signed config.
+	Nonce string `json:"nonce"`
+	// Expires is when the config stops being accepted.
+	Expires time.Time `json:"expires"`
+	// ConfigSHA256 is the hex encoded SHA-256 digest of ConfigFile.
+	ConfigSHA256 string `json:"config_sha256"`
+}
+
+type Options struct {
+	// Endpoint is the URL of the OpAMP server.
+	Endpoint string
+	// PollInterval is how often the client polls the server.
+	PollInterval time.Duration
+	// InstanceUID identifies the agent to the server. It should be stable across restarts. Remote
+	// configs are only accepted if their claims are for this UID, even if the server assigns
+	// another one to the agent.
+	InstanceUID []byte
+	// IdentifyingAttributes and NonIdentifyingAttributes describe the agent, using the OpenTelemetry
+	// semantic conventions, e.g. service.version.
+	IdentifyingAttributes    map[string]string
+	NonIdentifyingAttributes map[string]string
+	// EffectiveConfig returns the config files the agent runs with, by name.
+	EffectiveConfig func() (map[string][]byte, error)
+	// Health returns the health of the subagents, by name. A nil error means healthy.
+	Health func(ctx context.Context) map[string]error
+	// PublicKey verifies the signature of remote configs. If it is nil, the client does not accept
+	// remote configs.
+	PublicKey ed25519.PublicKey
+	// Apply validates and installs a remote config. It returns whether the config changed.
+	Apply func(ctx context.Context, config []byte) (bool, error)
+	// Restart is called once a changed config was applied and reported, to start using it.
+	Restart func()
+	// NoncesPath is the file that records the nonces of the applied remote configs until they
+	// expire, so that they can't be applied again after a restart. If it is empty, the nonces are
+	// only recorded in memory.
+	NoncesPath string
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// ReadPublicKey reads a PEM encoded ed25519 public key.
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ed25519 public key", path)
+	}
+	return edKey, nil
+}
+
+// InstanceUID derives a 16 byte instance UID from a stable identifier of the machine, such as the
+// GCE instance ID.
+func InstanceUID(id string) []byte {
+	sum := sha256.Sum256([]byte("google-cloud-ops-agent/" + id))
+	uid := sum[:16]
+	// Mark it as a name-based UUID (version 5, RFC 4122 variant).
+	uid[6] = uid[6]&0x0f | 0x50
+	uid[8] = uid[8]&0x3f | 0x80
+	return uid
+}
+
+// verify returns the agent config of files and its claims if the signature of the claims is
+// valid, and the claims are for the agent identified by instanceUID and not expired at now.
+func verify(files map[string][]byte, key ed25519.PublicKey, instanceUID []byte, now time.Time) ([]byte, *claims, error) {
+	if key == nil {
+		return nil, nil, errors.New("remote configs are not accepted without a public key")
+	}
+	for _, name := range []string{ConfigFile, ClaimsFile, SignatureFile} {
+		if _, ok := files[name]; !ok {
+			return nil, nil, fmt.Errorf("the remote config has no %s", name)
+		}
+	}
+	config, encodedClaims := files[ConfigFile], files[ClaimsFile]
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(files[SignatureFile])))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s: %w", SignatureFile, err)
+	}
+	if !ed25519.Verify(key, encodedClaims, signature) {
+		return nil, nil, fmt.Errorf("the signature of %s is not valid", ClaimsFile)
+	}
+	var c claims
+	if err := json.Unmarshal(encodedClaims, &c); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s: %w", ClaimsFile, err)
+	}
+	sum := sha256.Sum256(config)
+	switch {
+	case c.InstanceUID != hex.EncodeToString(instanceUID):
+		return nil, nil, fmt.Errorf("the remote config is for the instance %q", c.InstanceUID)
+	case c.Nonce == "":
+		return nil, nil, fmt.Errorf("%s has no nonce", ClaimsFile)
+	case !now.Before(c.Expires):
+		return nil, nil, fmt.Errorf("the remote config expired at %s", c.Expires.Format(time.RFC3339))
+	case c.ConfigSHA256 != hex.EncodeToString(sum[:]):
+		return nil, nil, fmt.Errorf("the digest of %s does not match its claims", ConfigFile)
+	}
+	return config, &c, nil
+}
+
+// nonces records the nonces of the applied remote configs, by nonce, with their expiry.
+type nonces map[string]time.Time
+
+// readNonces reads the nonces recorded in path that did not expire at now.
+func readNonces(path string, now time.Time) (nonces, error) {
+	n := nonces{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return n, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for nonce, expires := range n {
+		if !now.Before(expires) {
+			delete(n, nonce)
+		}
+	}
+	return n, nil
+}
+
+func (n nonces) write(path string) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+type client struct {
+	opts        Options
+	http        *http.Client
+	logger      logs.StructuredLogger
+	instanceUID []byte
+	sequenceNum uint64
+	start       time.Time
+	status      *remoteConfigStatus
+	nonces      nonces
+}
+
+func (c *client) now() time.Time {
+	if c.opts.Now != nil {
+		return c.opts.Now()
+	}
+	return time.Now()
+}
+
+func (c *client) message(ctx context.Context) agentToServer {
+	m := agentToServer{
+		instanceUID:              c.instanceUID,
+		sequenceNum:              c.sequenceNum,
+		identifyingAttributes:    c.opts.IdentifyingAttributes,
+		nonIdentifyingAttributes: c.opts.NonIdentifyingAttributes,
+		capabilities:             capabilityReportsStatus | capabilityReportsHealth,
+		remoteConfigStatus:       c.status,
+	}
+	c.sequenceNum++
+	if c.opts.PublicKey != nil && c.opts.Apply != nil {
+		m.capabilities |= capabilityAcceptsRemoteConfig | capabilityReportsRemoteConfig
+	}
+	if c.opts.EffectiveConfig != nil {
+		m.capabilities |= capabilityReportsEffectiveConfig
+		files, err := c.opts.EffectiveConfig()
+		if err != nil {
+			c.logger.Warnf("Failed to read the effective config for OpAMP: %v", err)
+		} else {
+			m.effectiveConfig = files
+		}
+	}
+	now := uint64(time.Now().UnixNano())
+	health := &componentHealth{
+		healthy:    true,
+		startTime:  uint64(c.start.UnixNano()),
+		status:     "running",
+		statusTime: now,
+		components: map[string]componentHealth{},
+	}
+	if c.opts.Health != nil {
+		for name, err := range c.opts.Health(ctx) {
+			h := componentHealth{healthy: err == nil, status: "running", statusTime: now}
+			if err != nil {
+				health.healthy = false
+				h.status = "unhealthy"
+				h.lastError = err.Error()
+			}
+			health.components[name] = h
+		}
+	}
+	if !health.healthy {
+		health.status = "degraded"
+	}
+	m.health = health
+	return m
+}
+
+func (c *client) exchange(ctx context.Context, m agentToServer) (*serverToAgent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.Endpoint, bytes.NewReader(m.marshal()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", c.opts.Endpoint, resp.Status)
+	}
+	var reply serverToAgent
+	if err := reply.unmarshal(body); err != nil {
+		return nil, fmt.Errorf("failed to decode the reply of %s: %w", c.opts.Endpoint, err)
+	}
+	return &reply, nil
+}
+
+// handle processes a reply, and returns whether the agent must be restarted once the resulting
+// status has been reported.
+func (c *client) handle(ctx context.Context, reply *serverToAgent) bool {
+	if reply.errorMessage != "" {
+		c.logger.Warnf("The OpAMP server returned an error: %s", reply.errorMessage)
+	}
+	if len(reply.newInstanceUID) > 0 {
+		// The new instance UID only identifies the agent in its messages. Remote configs stay bound
+		// to the UID derived from the machine, so that a server can't make the agent accept a
+		// config signed for another instance by assigning it that instance's UID.
+		c.instanceUID = reply.newInstanceUID
+	}
+	if reply.remoteConfig == nil || (c.status != nil && bytes.Equal(c.status.hash, reply.remoteHash)) {
+		return false
+	}
+	c.status = &remoteConfigStatus{hash: reply.remoteHash}
+	now := c.now()
+	config, claims, err := verify(reply.remoteConfig, c.opts.PublicKey, c.opts.InstanceUID, now)
+	if err == nil && c.opts.Apply == nil {
+		err = errors.New("remote configs are not accepted")
+	}
+	if err == nil && c.opts.NoncesPath != "" {
+		c.nonces, err = readNonces(c.opts.NoncesPath, now)
+	} else if c.nonces == nil {
+		c.nonces = nonces{}
+	}
+	if err == nil {
+		if _, ok := c.nonces[claims.Nonce]; ok {
+			err = fmt.Errorf("the remote config with the nonce %q was already applied", claims.Nonce)
+		}
+	}
+	if err != nil {
+		c.logger.Warnf("Rejected the remote config from OpAMP: %v", err)
+		c.status.status = remoteConfigFailed
+		c.status.err = err.Error()
+		return false
+	}
+	changed, err := c.opts.Apply(ctx, config)
+	if err != nil {
+		c.logger.Warnf("Failed to apply the remote config from OpAMP: %v", err)
+		c.status.status = remoteConfigFailed
+		c.status.err = err.Error()
+		return false
+	}
+	c.status.status = remoteConfigApplied
+	c.nonces[claims.Nonce] = claims.Expires
+	if c.opts.NoncesPath != "" {
+		if err := c.nonces.write(c.opts.NoncesPath); err != nil {
+			c.logger.Warnf("Failed to record the nonce of the remote config from OpAMP: %v", err)
+		}
+	}
+	if changed {
+		c.logger.Infof("Applied the remote config from OpAMP")
+	}
+	return changed
+}
+
+// Run polls the OpAMP server until ctx is done.
+func Run(ctx context.Context, httpClient *http.Client, opts Options, logger logs.StructuredLogger) {
+	c := &client{
+		opts:        opts,
+		http:        httpClient,
+		logger:      logger,
+		instanceUID: opts.InstanceUID,
+		start:       time.Now(),
+	}
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		reply, err := c.exchange(ctx, c.message(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warnf("Failed to reach the OpAMP server: %v", err)
+		} else if c.handle(ctx, reply) {
+			// Report the applied config before the restart interrupts the client.
+			if _, err := c.exchange(ctx, c.message(ctx)); err != nil {
+				logger.Warnf("Failed to report the remote config status to OpAMP: %v", err)
+			}
+			if opts.Restart != nil {
+				opts.Restart()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opamp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const testConfig = "logging:\n  receivers:\n    syslog:\n      type: files\n      include_paths: [/var/log/messages]\n"
+
+var (
+	testUID = InstanceUID("test")
+	testNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+)
+
+// signedConfig returns the remote config files of config, with claims for the agent testUID that
+// expire an hour after testNow. modify, if not nil, changes the claims before they are signed.
+func signedConfig(t *testing.T, key ed25519.PrivateKey, config string, modify func(*claims)) map[string][]byte {
+	t.Helper()
+	sum := sha256.Sum256([]byte(config))
+	c := claims{
+		InstanceUID:  hex.EncodeToString(testUID),
+		Nonce:        "nonce-1",
+		Expires:      testNow.Add(time.Hour),
+		ConfigSHA256: hex.EncodeToString(sum[:]),
+	}
+	if modify != nil {
+		modify(&c)
+	}
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := ed25519.Sign(key, encoded)
+	return map[string][]byte{
+		ConfigFile:    []byte(config),
+		ClaimsFile:    encoded,
+		SignatureFile: []byte(base64.StdEncoding.EncodeToString(signature) + "\n"),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPrivate, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		files   map[string][]byte
+		key     ed25519.PublicKey
+		wantErr bool
+	}{
+		{name: "valid", files: signedConfig(t, private, testConfig, nil), key: public},
+		{name: "signed by another key", files: signedConfig(t, otherPrivate, testConfig, nil), key: public, wantErr: true},
+		{name: "no public key", files: signedConfig(t, private, testConfig, nil), wantErr: true},
+		{name: "unsigned", files: map[string][]byte{ConfigFile: []byte(testConfig)}, key: public, wantErr: true},
+		{
+			name: "tampered config",
+			files: func() map[string][]byte {
+				files := signedConfig(t, private, testConfig, nil)
+				files[ConfigFile] = []byte(testConfig + "\n")
+				return files
+			}(),
+			key:     public,
+			wantErr: true,
+		},
+		{
+			name:    "for another instance",
+			files:   signedConfig(t, private, testConfig, func(c *claims) { c.InstanceUID = hex.EncodeToString(InstanceUID("other")) }),
+			key:     public,
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			files:   signedConfig(t, private, testConfig, func(c *claims) { c.Expires = testNow }),
+			key:     public,
+			wantErr: true,
+		},
+		{
+			name:    "without a nonce",
+			files:   signedConfig(t, private, testConfig, func(c *claims) { c.Nonce = "" }),
+			key:     public,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, _, err := verify(tc.files, tc.key, testUID, testNow)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("verify() = %q, want an error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(config) != testConfig {
+				t.Errorf("verify() = %q, want %q", config, testConfig)
+			}
+		})
+	}
+}
+
+func TestInstanceUID(t *testing.T) {
+	uid := InstanceUID("1234567890")
+	if len(uid) != 16 {
+		t.Errorf("len(InstanceUID()) = %d, want 16", len(uid))
+	}
+	if !bytes.Equal(uid, InstanceUID("1234567890")) {
+		t.Errorf("InstanceUID() is not stable")
+	}
+	if bytes.Equal(uid, InstanceUID("0987654321")) {
+		t.Errorf("InstanceUID() is the same for different IDs")
+	}
+}
+
+// reported is what the test server decoded from an AgentToServer message.
+type reported struct {
+	capabilities uint64
+	hasConfig    bool
+	configStatus uint64
+	configHash   []byte
+}
+
+func decodeAgentToServer(t *testing.T, b []byte) reported {
+	t.Helper()
+	var r reported
+	err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 4:
+			r.capabilities, _ = protowire.ConsumeVarint(value)
+		case 6:
+			r.hasConfig = true
+		case 7:
+			status, err := consumeBytes(typ, value)
+			if err != nil {
+				return err
+			}
+			return fields(status, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				switch num {
+				case 1:
+					r.configHash, _ = consumeBytes(typ, value)
+				case 2:
+					r.configStatus, _ = protowire.ConsumeVarint(value)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func remoteConfigReply(files map[string][]byte, hash string) []byte {
+	var remote []byte
+	remote = appendMessage(remote, 1, marshalConfigMap(files))
+	remote = appendMessage(remote, 2, []byte(hash))
+	return appendMessage(nil, 3, remote)
+}
+
+func TestRun(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var messages []reported
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, decodeAgentToServer(t, body))
+		w.Write(remoteConfigReply(signedConfig(t, private, testConfig, nil), "hash-1"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var applied []string
+	logger, _ := logs.DiscardLogger()
+	Run(ctx, server.Client(), Options{
+		Endpoint:        server.URL,
+		PollInterval:    time.Hour,
+		InstanceUID:     testUID,
+		Now:             func() time.Time { return testNow },
+		EffectiveConfig: func() (map[string][]byte, error) { return map[string][]byte{ConfigFile: nil}, nil },
+		Health: func(context.Context) map[string]error {
+			return map[string]error{"fluentbit": nil, "otel": errors.New("not exporting")}
+		},
+		PublicKey: public,
+		Apply: func(ctx context.Context, config []byte) (bool, error) {
+			applied = append(applied, string(config))
+			return true, nil
+		},
+		Restart: cancel,
+	}, logger)
+
+	if len(applied) != 1 || applied[0] != testConfig {
+		t.Errorf("applied %q, want [%q]", applied, testConfig)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	wantCapabilities := uint64(capabilityReportsStatus | capabilityAcceptsRemoteConfig | capabilityReportsEffectiveConfig | capabilityReportsHealth | capabilityReportsRemoteConfig)
+	if messages[0].capabilities != wantCapabilities || !messages[0].hasConfig {
+		t.Errorf("first message = %+v, want capabilities %#x and an effective config", messages[0], wantCapabilities)
+	}
+	if messages[1].configStatus != remoteConfigApplied || string(messages[1].configHash) != "hash-1" {
+		t.Errorf("second message = %+v, want the applied status of hash-1", messages[1])
+	}
+}
+
+func TestRunRejectsUnsignedConfig(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{
+		opts: Options{
+			PublicKey: public,
+			Apply: func(context.Context, []byte) (bool, error) {
+				t.Errorf("Apply() was called for an unsigned config")
+				return true, nil
+			},
+		},
+		logger: logs.NewSimpleLogger(),
+	}
+	reply := &serverToAgent{remoteConfig: map[string][]byte{ConfigFile: []byte(testConfig)}, remoteHash: []byte("hash-1")}
+	if c.handle(context.Background(), reply) {
+		t.Errorf("handle() asked for a restart")
+	}
+	if c.status == nil || c.status.status != remoteConfigFailed || c.status.err == "" {
+		t.Errorf("status = %+v, want a failure", c.status)
+	}
+}
+
+func TestHandleRejectsReplayedConfig(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noncesPath := filepath.Join(t.TempDir(), "nonces.json")
+	applied := 0
+	newClient := func() *client {
+		return &client{
+			opts: Options{
+				PublicKey: public,
+				Apply: func(context.Context, []byte) (bool, error) {
+					applied++
+					return true, nil
+				},
+				NoncesPath:  noncesPath,
+				Now:         func() time.Time { return testNow },
+				InstanceUID: testUID,
+			},
+			instanceUID: testUID,
+			logger:      logs.NewSimpleLogger(),
+		}
+	}
+	files := signedConfig(t, private, testConfig, nil)
+	if !newClient().handle(context.Background(), &serverToAgent{remoteConfig: files, remoteHash: []byte("hash-1")}) {
+		t.Fatalf("handle() did not apply the config")
+	}
+	// The agent restarted, and the same signed config is pushed again.
+	c := newClient()
+	if c.handle(context.Background(), &serverToAgent{remoteConfig: files, remoteHash: []byte("hash-2")}) {
+		t.Errorf("handle() applied a replayed config")
+	}
+	if applied != 1 || c.status.status != remoteConfigFailed {
+		t.Errorf("applied %d configs with the status %+v, want 1 and a failure", applied, c.status)
+	}
+}
+
+func TestHandleRejectsConfigOfReassignedInstance(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherUID := InstanceUID("other")
+	c := &client{
+		opts: Options{
+			PublicKey: public,
+			Apply: func(context.Context, []byte) (bool, error) {
+				t.Errorf("Apply() was called for the config of another instance")
+				return true, nil
+			},
+			InstanceUID: testUID,
+			Now:         func() time.Time { return testNow },
+		},
+		instanceUID: testUID,
+		logger:      logs.NewSimpleLogger(),
+	}
+	// The server assigns the UID of another agent, and replays the config signed for that agent.
+	files := signedConfig(t, private, testConfig, func(c *claims) { c.InstanceUID = hex.EncodeToString(otherUID) })
+	if c.handle(context.Background(), &serverToAgent{newInstanceUID: otherUID, remoteConfig: files, remoteHash: []byte("hash-1")}) {
+		t.Errorf("handle() applied the config of another instance")
+	}
+	if c.status == nil || c.status.status != remoteConfigFailed {
+		t.Errorf("status = %+v, want a failure", c.status)
+	}
+	if !bytes.Equal(c.instanceUID, otherUID) {
+		t.Errorf("instance UID = %x, want the assigned %x in the messages", c.instanceUID, otherUID)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opamp
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// This file encodes and decodes the subset of the OpAMP messages that the client uses. The field
+// numbers are those of
+// https://github.com/open-telemetry/opamp-spec/blob/main/proto/opamp.proto
+
+// Capabilities of the agent, as reported in AgentToServer.capabilities.
+const (
+	capabilityReportsStatus          = 0x1
+	capabilityAcceptsRemoteConfig    = 0x2
+	capabilityReportsEffectiveConfig = 0x4
+	capabilityReportsHealth          = 0x800
+	capabilityReportsRemoteConfig    = 0x1000
+)
+
+// RemoteConfigStatuses values.
+const (
+	remoteConfigApplied = 1
+	remoteConfigFailed  = 3
+)
+
+type componentHealth struct {
+	healthy    bool
+	startTime  uint64
+	lastError  string
+	status     string
+	statusTime uint64
+	components map[string]componentHealth
+}
+
+type remoteConfigStatus struct {
+	hash   []byte
+	status uint64
+	err    string
+}
+
+type agentToServer struct {
+	instanceUID              []byte
+	sequenceNum              uint64
+	identifyingAttributes    map[string]string
+	nonIdentifyingAttributes map[string]string
+	capabilities             uint64
+	health                   *componentHealth
+	effectiveConfig          map[string][]byte
+	remoteConfigStatus       *remoteConfigStatus
+}
+
+type serverToAgent struct {
+	errorMessage   string
+	remoteConfig   map[string][]byte
+	remoteHash     []byte
+	newInstanceUID []byte
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+// appendKeyValues appends attrs as repeated KeyValue messages with string AnyValues.
+func appendKeyValues(b []byte, num protowire.Number, attrs map[string]string) []byte {
+	for _, k := range sortedKeys(attrs) {
+		var value, kv []byte
+		value = appendString(value, 1, attrs[k])
+		kv = appendString(kv, 1, k)
+		kv = appendMessage(kv, 2, value)
+		b = appendMessage(b, num, kv)
+	}
+	return b
+}
+
+func (h componentHealth) marshal() []byte {
+	var b []byte
+	if h.healthy {
+		b = appendVarint(b, 1, 1)
+	}
+	b = appendFixed64(b, 2, h.startTime)
+	b = appendString(b, 3, h.lastError)
+	b = appendString(b, 4, h.status)
+	b = appendFixed64(b, 5, h.statusTime)
+	for _, name := range sortedKeys(h.components) {
+		var entry []byte
+		entry = appendString(entry, 1, name)
+		entry = appendMessage(entry, 2, h.components[name].marshal())
+		b = appendMessage(b, 6, entry)
+	}
+	return b
+}
+
+// marshalConfigMap encodes files as an AgentConfigMap.
+func marshalConfigMap(files map[string][]byte) []byte {
+	var b []byte
+	for _, name := range sortedKeys(files) {
+		var file, entry []byte
+		file = protowire.AppendTag(file, 1, protowire.BytesType)
+		file = protowire.AppendBytes(file, files[name])
+		file = appendString(file, 2, "text/yaml")
+		entry = appendString(entry, 1, name)
+		entry = appendMessage(entry, 2, file)
+		b = appendMessage(b, 1, entry)
+	}
+	return b
+}
+
+func (m agentToServer) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, m.instanceUID)
+	b = appendVarint(b, 2, m.sequenceNum)
+	var description []byte
+	description = appendKeyValues(description, 1, m.identifyingAttributes)
+	description = appendKeyValues(description, 2, m.nonIdentifyingAttributes)
+	b = appendMessage(b, 3, description)
+	b = appendVarint(b, 4, m.capabilities)
+	if m.health != nil {
+		b = appendMessage(b, 5, m.health.marshal())
+	}
+	if m.effectiveConfig != nil {
+		var effective []byte
+		effective = appendMessage(effective, 1, marshalConfigMap(m.effectiveConfig))
+		b = appendMessage(b, 6, effective)
+	}
+	if s := m.remoteConfigStatus; s != nil {
+		var status []byte
+		if len(s.hash) > 0 {
+			status = protowire.AppendTag(status, 1, protowire.BytesType)
+			status = protowire.AppendBytes(status, s.hash)
+		}
+		status = appendVarint(status, 2, s.status)
+		status = appendString(status, 3, s.err)
+		b = appendMessage(b, 7, status)
+	}
+	return b
+}
+
+// fields calls f with each field of the message in b.
+func fields(b []byte, f func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := f(num, typ, b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func consumeBytes(typ protowire.Type, value []byte) ([]byte, error) {
+	if typ != protowire.BytesType {
+		return nil, fmt.Errorf("unexpected wire type %d", typ)
+	}
+	v, n := protowire.ConsumeBytes(value)
+	if n < 0 {
+		return nil, protowire.ParseError(n)
+	}
+	return v, nil
+}
+
+// unmarshalConfigMap decodes an AgentConfigMap into the bodies of its files.
+func unmarshalConfigMap(b []byte) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != 1 {
+			return nil
+		}
+		entry, err := consumeBytes(typ, value)
+		if err != nil {
+			return err
+		}
+		var name string
+		var body []byte
+		err = fields(entry, func(num protowire.Number, typ protowire.Type, value []byte) error {
+			v, err := consumeBytes(typ, value)
+			if err != nil {
+				return err
+			}
+			switch num {
+			case 1:
+				name = string(v)
+			case 2:
+				return fields(v, func(num protowire.Number, typ protowire.Type, value []byte) error {
+					if num != 1 {
+						return nil
+					}
+					body, err = consumeBytes(typ, value)
+					return err
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		files[name] = body
+		return nil
+	})
+	return files, err
+}
+
+func (m *serverToAgent) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 2: // error_response
+			v, err := consumeBytes(typ, value)
+			if err != nil {
+				return err
+			}
+			return fields(v, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				if num != 2 {
+					return nil
+				}
+				msg, err := consumeBytes(typ, value)
+				m.errorMessage = string(msg)
+				return err
+			})
+		case 3: // remote_config
+			v, err := consumeBytes(typ, value)
+			if err != nil {
+				return err
+			}
+			return fields(v, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				v, err := consumeBytes(typ, value)
+				if err != nil {
+					return err
+				}
+				switch num {
+				case 1:
+					m.remoteConfig, err = unmarshalConfigMap(v)
+				case 2:
+					m.remoteHash = v
+				}
+				return err
+			})
+		case 8: // agent_identification
+			v, err := consumeBytes(typ, value)
+			if err != nil {
+				return err
+			}
+			return fields(v, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				if num != 1 {
+					return nil
+				}
+				uid, err := consumeBytes(typ, value)
+				m.newInstanceUID = uid
+				return err
+			})
+		}
+		return nil
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"runtime"
 
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/resourcedetector"
 	"github.com/shirou/gopsutil/host"
@@ -29,7 +30,9 @@ type Platform struct {
 	WinlogV1Channels   []string
 	HostInfo           *host.InfoStat
 	HasNvidiaGpu       bool
-	ResourceOverride   resourcedetector.Resource
+	// NumCPU is the number of CPUs of the machine, or 0 if unknown.
+	NumCPU           int
+	ResourceOverride resourcedetector.Resource
 	// Resource override only for GCE metadata unit testing
 	TestGCEResourceOverride resourcedetector.Resource
 }
@@ -60,6 +63,14 @@ func (p Platform) TestContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, platformKey, p)
 }
 
+// WithResourceOverride returns a context whose platform is the one of ctx, with its detected
+// resource replaced by r.
+func WithResourceOverride(ctx context.Context, r resourcedetector.Resource) context.Context {
+	p := FromContext(ctx)
+	p.ResourceOverride = r
+	return context.WithValue(ctx, platformKey, p)
+}
+
 var detectedPlatform Platform = detect()
 
 func FromContext(ctx context.Context) Platform {
@@ -76,6 +87,7 @@ func detect() Platform {
 	}
 	p := Platform{
 		HostInfo: info,
+		NumCPU:   runtime.NumCPU(),
 	}
 	p.detectPlatform()
 	return p
@@ -0,0 +1,107 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preemption reports when a GCE instance is about to be preempted or terminated, so that
+// users can correlate the gap in its telemetry with the preemption.
+package preemption
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+// The metadata keys that announce the end of the instance, and the values they announce it with.
+// https://cloud.google.com/compute/docs/instances/create-use-preemptible#detecting_if_an_instance_was_preempted
+var notices = map[string]string{
+	"instance/preempted":         "TRUE",
+	"instance/maintenance-event": "TERMINATE_ON_HOST_MAINTENANCE",
+}
+
+var errNotified = errors.New("notice received")
+
+type Options struct {
+	// BuffersDir is the fluent-bit storage directory, whose chunks have not been sent yet.
+	BuffersDir string
+	// Start is when the agent started.
+	Start time.Time
+	// Flush is called when the notice is received, to send the pending telemetry right away.
+	Flush func()
+}
+
+// BufferStats returns the number and total size of the chunks in dir.
+func BufferStats(dir string) (chunks int, bytes int64, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".flb" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		chunks++
+		bytes += info.Size()
+		return nil
+	})
+	return chunks, bytes, err
+}
+
+// Watch waits until the metadata server announces that the instance is being preempted or
+// terminated, then flushes and logs an InstancePreempting entry to logger. It returns once the
+// notice was handled, or when ctx is done.
+func Watch(ctx context.Context, client *metadata.Client, opts Options, logger logs.StructuredLogger) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	noticed := make(chan string, len(notices))
+	for key, want := range notices {
+		key, want := key, want
+		go func() {
+			client.SubscribeWithContext(ctx, key, func(ctx context.Context, v string, ok bool) error {
+				if ok && v == want {
+					noticed <- key
+					return errNotified
+				}
+				return nil
+			})
+		}()
+	}
+
+	var key string
+	select {
+	case <-ctx.Done():
+		return
+	case key = <-noticed:
+	}
+	if opts.Flush != nil {
+		opts.Flush()
+	}
+	chunks, bytes, err := BufferStats(opts.BuffersDir)
+	if err != nil {
+		logger.Warnf("failed to read the buffer stats: %v", err)
+	}
+	logger.Warnw("Instance preempting",
+		"code", "InstancePreempting",
+		"notice", key,
+		"uptime", time.Since(opts.Start).Round(time.Second).String(),
+		"buffered_chunks", chunks,
+		"buffered_bytes", bytes)
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preemption
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+func TestBufferStats(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{
+		"tail.0/1-1707900000.123.flb": 100,
+		"tail.0/1-1707900001.456.flb": 50,
+		"tail.1/1-1707900002.789.flb": 25,
+		"tail.1/notes.txt":            1000,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks, bytes, err := BufferStats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks != 3 || bytes != 175 {
+		t.Errorf("BufferStats() = %d, %d; want 3, 175", chunks, bytes)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/instance/preempted"):
+			w.Header().Set("Etag", "1")
+			if r.URL.Query().Get("wait_for_change") == "true" {
+				w.Write([]byte("TRUE"))
+				return
+			}
+			w.Write([]byte("FALSE"))
+		case strings.HasSuffix(r.URL.Path, "/instance/maintenance-event"):
+			// Block like a metadata server with no pending change.
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	logger, observed := logs.DiscardLogger()
+	flushed := false
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	Watch(ctx, metadata.NewClient(nil), Options{
+		BuffersDir: t.TempDir(),
+		Start:      time.Now().Add(-time.Hour),
+		Flush:      func() { flushed = true },
+	}, logger)
+
+	if !flushed {
+		t.Errorf("the notice did not flush")
+	}
+	entries := observed.FilterMessage("Instance preempting").All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d InstancePreempting entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["code"] != "InstancePreempting" || fields["notice"] != "instance/preempted" || fields["uptime"] != "1h0m0s" {
+		t.Errorf("unexpected entry fields: %v", fields)
+	}
+}
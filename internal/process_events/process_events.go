@@ -0,0 +1,176 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package process_events writes process start and exit events to a file as JSON lines, which is
+// then read by the process_events logging receiver.
+package process_events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+const (
+	// The events file is rotated once it reaches maxFileSize bytes; fluent-bit follows the rotation.
+	maxFileSize = 10 * 1024 * 1024
+	// timeFormat matches the time format of the other agent logs.
+	timeFormat = "2006-01-02T15:04:05.000Z0700"
+)
+
+type Event struct {
+	Time     string `json:"time"`
+	Event    string `json:"event"`
+	PID      int    `json:"pid,omitempty"`
+	PPID     int    `json:"ppid,omitempty"`
+	UID      *int   `json:"uid,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Cmdline  string `json:"cmdline,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Signal   int    `json:"signal,omitempty"`
+	// Dropped is the number of events that were dropped by the rate limit in the previous second.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// A Filter selects processes by name.
+type Filter struct {
+	// Include, when not empty, only selects the processes whose name matches one of the expressions.
+	Include []*regexp.Regexp
+	// Exclude never selects the processes whose name matches one of the expressions.
+	Exclude []*regexp.Regexp
+}
+
+func (f Filter) Match(name string) bool {
+	for _, re := range f.Exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, re := range f.Include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+type Options struct {
+	// Path is the file the events are written to.
+	Path string
+	// An event is written if any of the filters matches it.
+	Filters []Filter
+	// MaxEventsPerSecond is the maximum number of events written per second.
+	MaxEventsPerSecond int
+}
+
+// reporter filters, rate limits and writes events.
+type reporter struct {
+	opts    Options
+	now     func() time.Time
+	w       io.WriteCloser
+	size    int64
+	window  time.Time
+	count   int
+	dropped int
+}
+
+func newReporter(opts Options) *reporter {
+	return &reporter{opts: opts, now: time.Now}
+}
+
+func (r *reporter) match(name string) bool {
+	for _, f := range r.opts.Filters {
+		if f.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report writes e unless it is filtered out or over the rate limit.
+func (r *reporter) Report(e Event) error {
+	if !r.match(e.Name) {
+		return nil
+	}
+	now := r.now()
+	if window := now.Truncate(time.Second); !window.Equal(r.window) {
+		r.window, r.count = window, 0
+		if r.dropped > 0 {
+			dropped := Event{Event: "dropped", Dropped: r.dropped}
+			r.dropped = 0
+			if err := r.write(now, dropped); err != nil {
+				return err
+			}
+		}
+	}
+	if r.count >= r.opts.MaxEventsPerSecond {
+		r.dropped++
+		return nil
+	}
+	r.count++
+	return r.write(now, e)
+}
+
+func (r *reporter) write(now time.Time, e Event) error {
+	e.Time = now.Format(timeFormat)
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if r.w == nil || r.size+int64(len(b)) > maxFileSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := r.w.Write(b)
+	r.size += int64(n)
+	return err
+}
+
+func (r *reporter) rotate() error {
+	if r.w != nil {
+		if err := r.w.Close(); err != nil {
+			return err
+		}
+		r.w = nil
+		if err := os.Rename(r.opts.Path, r.opts.Path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate %s: %w", r.opts.Path, err)
+		}
+	}
+	f, err := os.OpenFile(r.opts.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.w, r.size = f, info.Size()
+	return nil
+}
+
+func (r *reporter) Close() error {
+	if r.w == nil {
+		return nil
+	}
+	return r.w.Close()
+}
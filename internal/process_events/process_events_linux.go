@@ -0,0 +1,211 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package process_events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Constants from linux/connector.h and linux/cn_proc.h.
+const (
+	cnIdxProc         = 0x1
+	cnValProc         = 0x1
+	procCnMcastListen = 1
+	procEventExec     = 0x00000002
+	procEventExit     = 0x80000000
+
+	nlMsgHdrLen = 16
+	cnMsgLen    = 20
+	// what, cpu and timestamp_ns precede the event data in struct proc_event.
+	procEventHdrLen = 16
+)
+
+// The number of exec events whose process information is kept to report the exit events.
+const maxTrackedProcesses = 32768
+
+type processInfo struct {
+	name string
+	ppid int
+	uid  *int
+}
+
+// Run listens to the proc connector and reports process exec and exit events until ctx is done.
+// It requires CAP_NET_ADMIN.
+func Run(ctx context.Context, opts Options) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return fmt.Errorf("failed to open the proc connector: %w", err)
+	}
+	defer unix.Close(fd)
+	// Reads time out periodically so that ctx is checked.
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &unix.Timeval{Sec: 1}); err != nil {
+		return err
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		return fmt.Errorf("failed to bind to the proc connector: %w", err)
+	}
+	if err := unix.Sendto(fd, listenMessage(procCnMcastListen), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to subscribe to process events: %w", err)
+	}
+
+	r := newReporter(opts)
+	defer r.Close()
+	processes := map[int]processInfo{}
+	buf := make([]byte, os.Getpagesize())
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == unix.EAGAIN || err == unix.EINTR {
+			continue
+		}
+		if err == unix.ENOBUFS {
+			// Events were lost because the socket buffer overflowed; keep going.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read process events: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			e, ok := parseEvent(m.Data, processes)
+			if !ok {
+				continue
+			}
+			if err := r.Report(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func listenMessage(op uint32) []byte {
+	b := make([]byte, nlMsgHdrLen+cnMsgLen+4)
+	binary.NativeEndian.PutUint32(b[0:], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:], unix.NLMSG_DONE)
+	binary.NativeEndian.PutUint32(b[12:], uint32(os.Getpid()))
+	binary.NativeEndian.PutUint32(b[16:], cnIdxProc)
+	binary.NativeEndian.PutUint32(b[20:], cnValProc)
+	binary.NativeEndian.PutUint16(b[32:], 4)
+	binary.NativeEndian.PutUint32(b[36:], op)
+	return b
+}
+
+// parseEvent converts the cn_msg in data to an Event. Only processes are reported, not threads.
+func parseEvent(data []byte, processes map[int]processInfo) (Event, bool) {
+	if len(data) < cnMsgLen+procEventHdrLen+8 {
+		return Event{}, false
+	}
+	ev := data[cnMsgLen:]
+	what := binary.NativeEndian.Uint32(ev[0:])
+	pid := int(binary.NativeEndian.Uint32(ev[procEventHdrLen:]))
+	tgid := int(binary.NativeEndian.Uint32(ev[procEventHdrLen+4:]))
+	if pid != tgid {
+		return Event{}, false
+	}
+	switch what {
+	case procEventExec:
+		info := readProcessInfo(pid)
+		if len(processes) >= maxTrackedProcesses {
+			clear(processes)
+		}
+		processes[pid] = info
+		return Event{
+			Event:   "exec",
+			PID:     pid,
+			PPID:    info.ppid,
+			UID:     info.uid,
+			Name:    info.name,
+			Cmdline: readCmdline(pid),
+		}, true
+	case procEventExit:
+		if len(ev) < procEventHdrLen+16 {
+			return Event{}, false
+		}
+		info, ok := processes[pid]
+		if !ok {
+			// The process started before the agent, or was not tracked.
+			return Event{}, false
+		}
+		delete(processes, pid)
+		exitCode := int(binary.NativeEndian.Uint32(ev[procEventHdrLen+8:])) >> 8
+		return Event{
+			Event:    "exit",
+			PID:      pid,
+			PPID:     info.ppid,
+			UID:      info.uid,
+			Name:     info.name,
+			ExitCode: &exitCode,
+			Signal:   int(binary.NativeEndian.Uint32(ev[procEventHdrLen+8:])) & 0x7f,
+		}, true
+	}
+	return Event{}, false
+}
+
+func readCmdline(pid int) string {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return ""
+	}
+	return string(bytes.ReplaceAll(bytes.TrimRight(b, "\x00"), []byte{0}, []byte{' '}))
+}
+
+func readProcessInfo(pid int) processInfo {
+	var info processInfo
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return info
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, value, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		switch key {
+		case "Name":
+			info.name = strings.TrimSpace(value)
+		case "PPid":
+			info.ppid, _ = strconv.Atoi(fields[0])
+		case "Uid":
+			// Real, effective, saved and filesystem UIDs; report the real one.
+			if uid, err := strconv.Atoi(fields[0]); err == nil {
+				info.uid = &uid
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package process_events
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	f := Filter{
+		Include: []*regexp.Regexp{regexp.MustCompile(`^(bash|sh)$`), regexp.MustCompile(`^python`)},
+		Exclude: []*regexp.Regexp{regexp.MustCompile(`^python2`)},
+	}
+	for name, want := range map[string]bool{
+		"bash":      true,
+		"sh":        true,
+		"python3":   true,
+		"python2.7": false,
+		"ls":        false,
+	} {
+		if got := f.Match(name); got != want {
+			t.Errorf("Match(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if !(Filter{}).Match("ls") {
+		t.Errorf("an empty filter should match every process")
+	}
+}
+
+func TestReporterRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "process-events.log")
+	r := newReporter(Options{
+		Path:               path,
+		Filters:            []Filter{{Exclude: []*regexp.Regexp{regexp.MustCompile(`^ls$`)}}},
+		MaxEventsPerSecond: 2,
+	})
+	now := time.Date(2024, 2, 14, 9, 12, 44, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	for _, name := range []string{"bash", "ls", "sh", "curl", "wget"} {
+		if err := r.Report(Event{Event: "exec", Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(time.Second)
+	if err := r.Report(Event{Event: "exec", Name: "bash"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`{"time":"2024-02-14T09:12:44.000Z","event":"exec","name":"bash"}`,
+		`{"time":"2024-02-14T09:12:44.000Z","event":"exec","name":"sh"}`,
+		`{"time":"2024-02-14T09:12:45.000Z","event":"dropped","dropped":2}`,
+		`{"time":"2024-02-14T09:12:45.000Z","event":"exec","name":"bash"}`,
+	}
+	if got := strings.Split(strings.TrimSpace(string(b)), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
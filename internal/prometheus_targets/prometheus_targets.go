@@ -0,0 +1,262 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prometheus_targets reports the scrape jobs of the prometheus receivers whose targets are
+// all down, and the targets whose scrapes are dropped because they exceed the sample limit. It
+// reads the series that the receiver reports for every target, which the collector exposes on a
+// local endpoint, so the targets are not contacted a second time.
+package prometheus_targets
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/healthchecks"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Job is a scrape job of a prometheus receiver. Jobs are told apart by name, which is the job label
+// of their up series.
+type Job struct {
+	ReceiverID string
+	Name       string
+	// ScrapeInterval is how often the targets of the job are scraped.
+	ScrapeInterval time.Duration
+}
+
+type Options struct {
+	// URL is the endpoint that exposes the up series of the targets.
+	URL  string
+	Jobs []Job
+	// Intervals is the number of scrape intervals that all targets of a job need to be down for
+	// before the job is reported.
+	Intervals int
+	// Interval is how often the endpoint is read.
+	Interval time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// Record is called with the truncated targets after every read of the endpoint.
+	Record func([]TruncatedTarget)
+}
+
+// TruncatedTarget is a target whose last scrape exceeded the sample limit of its job. The
+// receiver drops all samples of such a scrape.
+type TruncatedTarget struct {
+	ReceiverID string
+	Job        string
+	Instance   string
+	// Samples is the number of samples that the target exposed after metric relabeling.
+	Samples int
+	Limit   int
+}
+
+// scrapeStatus is the state of the targets of the last scrape.
+type scrapeStatus struct {
+	// up is, by job name, whether any target of the job is up. Jobs without targets are missing.
+	up map[string]bool
+	// truncated are the targets that exceeded the sample limit, without their receiver ID.
+	truncated []TruncatedTarget
+}
+
+type targetKey struct {
+	job, instance string
+}
+
+// readScrapeStatus parses the series of the targets from r.
+func readScrapeStatus(r io.Reader) (scrapeStatus, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return scrapeStatus{}, err
+	}
+	// gauges returns the values of the series called name by target.
+	gauges := func(name string) map[targetKey]float64 {
+		out := map[targetKey]float64{}
+		for _, m := range families[name].GetMetric() {
+			var key targetKey
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "job":
+					key.job = l.GetValue()
+				case "instance":
+					key.instance = l.GetValue()
+				}
+			}
+			if key.job == "" || m.GetGauge() == nil {
+				continue
+			}
+			out[key] = m.GetGauge().GetValue()
+		}
+		return out
+	}
+	status := scrapeStatus{up: map[string]bool{}}
+	for key, v := range gauges("up") {
+		status.up[key.job] = status.up[key.job] || v == 1
+	}
+	// A scrape that exceeds the limit fails, but its samples are still counted.
+	samples := gauges("scrape_samples_post_metric_relabeling")
+	for key, limit := range gauges("scrape_sample_limit") {
+		if limit > 0 && samples[key] > limit {
+			status.truncated = append(status.truncated, TruncatedTarget{
+				Job:      key.job,
+				Instance: key.instance,
+				Samples:  int(samples[key]),
+				Limit:    int(limit),
+			})
+		}
+	}
+	sort.Slice(status.truncated, func(i, j int) bool {
+		a, b := status.truncated[i], status.truncated[j]
+		return a.Job < b.Job || (a.Job == b.Job && a.Instance < b.Instance)
+	})
+	return status, nil
+}
+
+// watcher keeps when the targets of each job went down, whether the job was reported, and the
+// reported truncated targets.
+type watcher struct {
+	downSince map[string]time.Time
+	reported  map[string]bool
+	truncated map[targetKey]bool
+}
+
+// check logs the jobs whose targets have been down for opts.Intervals scrape intervals, and the
+// reported jobs whose targets are up again.
+func (w *watcher) check(opts Options, up map[string]bool, now time.Time, logger logs.StructuredLogger) {
+	for _, job := range opts.Jobs {
+		jobUp, scraped := up[job.Name]
+		if !scraped || jobUp {
+			if w.reported[job.Name] {
+				logger.Infow(fmt.Sprintf("Prometheus job %q in receiver %q has reachable targets again.", job.Name, job.ReceiverID),
+					"code", "PrometheusTargetsUp",
+					"receiver", job.ReceiverID,
+					"job", job.Name)
+			}
+			delete(w.downSince, job.Name)
+			delete(w.reported, job.Name)
+			continue
+		}
+		since, ok := w.downSince[job.Name]
+		if !ok {
+			w.downSince[job.Name] = now
+			continue
+		}
+		if w.reported[job.Name] || now.Sub(since) < time.Duration(opts.Intervals)*job.ScrapeInterval {
+			continue
+		}
+		healthErr := healthchecks.PrometheusTargetsDownErr
+		logger.Warnw(fmt.Sprintf(healthErr.Message, job.Name, job.ReceiverID),
+			"code", healthErr.Code,
+			"receiver", job.ReceiverID,
+			"job", job.Name,
+			"down_since", since.Format(time.RFC3339))
+		w.reported[job.Name] = true
+	}
+}
+
+// checkTruncated logs the targets that started exceeding the sample limit, and the reported
+// targets that are within it again. It returns the truncated targets of opts.Jobs.
+func (w *watcher) checkTruncated(opts Options, truncated []TruncatedTarget, logger logs.StructuredLogger) []TruncatedTarget {
+	receivers := map[string]string{}
+	for _, job := range opts.Jobs {
+		receivers[job.Name] = job.ReceiverID
+	}
+	var out []TruncatedTarget
+	current := map[targetKey]bool{}
+	for _, t := range truncated {
+		receiver, ok := receivers[t.Job]
+		if !ok {
+			continue
+		}
+		t.ReceiverID = receiver
+		out = append(out, t)
+		key := targetKey{t.Job, t.Instance}
+		current[key] = true
+		if w.truncated[key] {
+			continue
+		}
+		healthErr := healthchecks.PrometheusScrapeTruncatedErr
+		logger.Warnw(fmt.Sprintf(healthErr.Message, t.Instance, t.Job, t.ReceiverID, t.Limit),
+			"code", healthErr.Code,
+			"receiver", t.ReceiverID,
+			"job", t.Job,
+			"instance", t.Instance,
+			"samples", t.Samples,
+			"sample_limit", t.Limit)
+		w.truncated[key] = true
+	}
+	for key := range w.truncated {
+		if current[key] {
+			continue
+		}
+		logger.Infow(fmt.Sprintf("Target %q of Prometheus job %q is within the sample limit again.", key.instance, key.job),
+			"code", "PrometheusScrapeWithinLimit",
+			"receiver", receivers[key.job],
+			"job", key.job,
+			"instance", key.instance)
+		delete(w.truncated, key)
+	}
+	return out
+}
+
+// Watch reads the series of the targets every opts.Interval until ctx is done. It logs a
+// PrometheusTargetsDownErr entry to logger when all targets of a job have been down for
+// opts.Intervals scrape intervals, and a PrometheusTargetsUp entry when one of them is up again.
+// It logs a PrometheusScrapeTruncatedErr entry when a target starts exceeding the sample limit,
+// and a PrometheusScrapeWithinLimit entry when it is within the limit again.
+func Watch(ctx context.Context, opts Options, logger logs.StructuredLogger) {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	sort.Slice(opts.Jobs, func(i, j int) bool { return opts.Jobs[i].Name < opts.Jobs[j].Name })
+	client := &http.Client{Timeout: 10 * time.Second}
+	w := &watcher{downSince: map[string]time.Time{}, reported: map[string]bool{}, truncated: map[targetKey]bool{}}
+	for {
+		// The endpoint is missing until the collector has started, which is not worth reporting.
+		if status, err := fetchScrapeStatus(ctx, client, opts.URL); err == nil {
+			w.check(opts, status.up, now(), logger)
+			truncated := w.checkTruncated(opts, status.truncated, logger)
+			if opts.Record != nil {
+				opts.Record(truncated)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+func fetchScrapeStatus(ctx context.Context, client *http.Client, url string) (scrapeStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return scrapeStatus{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return scrapeStatus{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return scrapeStatus{}, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return readScrapeStatus(resp.Body)
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prometheus_targets
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+)
+
+const exposition = `# HELP up The scraping was successful
+# TYPE up gauge
+up{instance="10.0.0.1:9100",job="node"} 1
+up{instance="10.0.0.2:9100",job="node"} 0
+up{instance="10.0.0.3:8080",job="app"} 0
+# HELP scrape_samples_scraped The number of samples the target exposed
+# TYPE scrape_samples_scraped gauge
+scrape_samples_scraped{instance="10.0.0.1:9100",job="node"} 120
+scrape_samples_scraped{instance="10.0.0.2:9100",job="node"} 0
+scrape_samples_scraped{instance="10.0.0.3:8080",job="app"} 5400
+# HELP scrape_samples_post_metric_relabeling The number of samples remaining after metric relabeling was applied
+# TYPE scrape_samples_post_metric_relabeling gauge
+scrape_samples_post_metric_relabeling{instance="10.0.0.1:9100",job="node"} 100
+scrape_samples_post_metric_relabeling{instance="10.0.0.2:9100",job="node"} 0
+scrape_samples_post_metric_relabeling{instance="10.0.0.3:8080",job="app"} 5000
+# HELP scrape_sample_limit The configured sample limit for a target
+# TYPE scrape_sample_limit gauge
+scrape_sample_limit{instance="10.0.0.1:9100",job="node"} 100
+scrape_sample_limit{instance="10.0.0.2:9100",job="node"} 100
+scrape_sample_limit{instance="10.0.0.3:8080",job="app"} 1000
+`
+
+func TestReadScrapeStatus(t *testing.T) {
+	got, err := readScrapeStatus(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := scrapeStatus{
+		up: map[string]bool{"node": true, "app": false},
+		// The node target at the limit is not truncated.
+		truncated: []TruncatedTarget{{Job: "app", Instance: "10.0.0.3:8080", Samples: 5000, Limit: 1000}},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(scrapeStatus{})); diff != "" {
+		t.Errorf("readScrapeStatus() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	opts := Options{
+		Jobs:      []Job{{ReceiverID: "prometheus", Name: "app", ScrapeInterval: time.Minute}},
+		Intervals: 3,
+	}
+	logger, observed := logs.DiscardLogger()
+	w := &watcher{downSince: map[string]time.Time{}, reported: map[string]bool{}, truncated: map[targetKey]bool{}}
+	start := time.Now()
+	down := map[string]bool{"app": false}
+
+	for _, elapsed := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		w.check(opts, down, start.Add(elapsed), logger)
+	}
+	if entries := observed.FilterField(zap.String("code", "PrometheusTargetsDownErr")).All(); len(entries) != 0 {
+		t.Fatalf("got %d PrometheusTargetsDownErr entries before 3 scrape intervals, want 0", len(entries))
+	}
+	// The job is only reported once while its targets stay down.
+	w.check(opts, down, start.Add(3*time.Minute), logger)
+	w.check(opts, down, start.Add(4*time.Minute), logger)
+	if entries := observed.FilterField(zap.String("code", "PrometheusTargetsDownErr")).All(); len(entries) != 1 {
+		t.Errorf("got %d PrometheusTargetsDownErr entries, want 1", len(entries))
+	}
+
+	w.check(opts, map[string]bool{"app": true}, start.Add(5*time.Minute), logger)
+	if entries := observed.FilterField(zap.String("code", "PrometheusTargetsUp")).All(); len(entries) != 1 {
+		t.Errorf("got %d PrometheusTargetsUp entries, want 1", len(entries))
+	}
+	// A job that is not scraped, e.g. because the collector restarted, is not down.
+	w.check(opts, map[string]bool{}, start.Add(6*time.Minute), logger)
+	if len(w.downSince) != 0 {
+		t.Errorf("got jobs down since %v, want none", w.downSince)
+	}
+}
+
+func TestCheckTruncated(t *testing.T) {
+	opts := Options{Jobs: []Job{{ReceiverID: "prometheus", Name: "app", ScrapeInterval: time.Minute}}}
+	logger, observed := logs.DiscardLogger()
+	w := &watcher{downSince: map[string]time.Time{}, reported: map[string]bool{}, truncated: map[targetKey]bool{}}
+	truncated := []TruncatedTarget{
+		{Job: "app", Instance: "10.0.0.3:8080", Samples: 5000, Limit: 1000},
+		// Jobs of other receivers, e.g. of a previous config, are ignored.
+		{Job: "other", Instance: "10.0.0.4:8080", Samples: 5000, Limit: 1000},
+	}
+
+	// The target is only reported once while it exceeds the limit.
+	for i := 0; i < 2; i++ {
+		got := w.checkTruncated(opts, truncated, logger)
+		want := []TruncatedTarget{{ReceiverID: "prometheus", Job: "app", Instance: "10.0.0.3:8080", Samples: 5000, Limit: 1000}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("checkTruncated() mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if entries := observed.FilterField(zap.String("code", "PrometheusScrapeTruncatedErr")).All(); len(entries) != 1 {
+		t.Errorf("got %d PrometheusScrapeTruncatedErr entries, want 1", len(entries))
+	}
+
+	if got := w.checkTruncated(opts, nil, logger); len(got) != 0 {
+		t.Errorf("checkTruncated() = %v, want none", got)
+	}
+	if entries := observed.FilterField(zap.String("code", "PrometheusScrapeWithinLimit")).All(); len(entries) != 1 {
+		t.Errorf("got %d PrometheusScrapeWithinLimit entries, want 1", len(entries))
+	}
+}
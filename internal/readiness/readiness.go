@@ -0,0 +1,200 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package readiness tells when the subagents are actually ingesting, which is once they have
+// exported for the first time, and reports it to systemd.
+package readiness
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
+)
+
+// A Probe checks a counter on the self metrics endpoint of a subagent.
+type Probe struct {
+	URL string
+	// Metric is the name of a counter, e.g. of exported records. The "_total" suffix is optional.
+	Metric string
+	// Labels, when set, are label pairs like `name="stackdriver.user"`, one of which must be a label
+	// of the counted series.
+	Labels []string
+}
+
+// outputNames returns the label pairs of the Fluent Bit outputs with the given names.
+func outputNames(names []string) []string {
+	var labels []string
+	for _, n := range names {
+		labels = append(labels, fmt.Sprintf("name=%q", n))
+	}
+	return labels
+}
+
+// Probes are the probes for each subagent, by the name of the subagent.
+var Probes = map[string]Probe{
+	"fluentbit": {
+		URL:    fmt.Sprintf("http://127.0.0.1:%d/metrics", fluentbit.MetricsPort),
+		Metric: "fluentbit_output_proc_records_total",
+		// Only the outputs of the user pipelines count: the prometheus_exporter output also
+		// processes records but does not export them, and the output of the agent logs exports
+		// before any user log is ingested.
+		Labels: outputNames(confgenerator.UserLogsOutputAliases),
+	},
+	"otel": {
+		URL:    fmt.Sprintf("http://127.0.0.1:%d/metrics", otel.MetricsPort),
+		Metric: "otelcol_exporter_sent_metric_points",
+	},
+}
+
+// Exported returns whether the subagent has exported at least one record.
+func (p Probe) Exported(ctx context.Context, client *http.Client) (bool, error) {
+	v, err := p.Sum(ctx, client)
+	return v > 0, err
+}
+
+// Sum returns the sum of the counted series.
+func (p Probe) Sum(ctx context.Context, client *http.Client) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s returned %s", p.URL, resp.Status)
+	}
+	return p.sum(resp.Body)
+}
+
+// sum scans the Prometheus text exposition format in r.
+func (p Probe) sum(r io.Reader) (float64, error) {
+	var total float64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, value, ok := splitSample(line)
+		if !ok || strings.TrimSuffix(name, "_total") != strings.TrimSuffix(p.Metric, "_total") {
+			continue
+		}
+		if len(p.Labels) > 0 && !hasLabel(labels, p.Labels) {
+			continue
+		}
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			total += v
+		}
+	}
+	return total, scanner.Err()
+}
+
+// hasLabel returns whether one of the label pairs in want is one of the comma separated labels.
+func hasLabel(labels string, want []string) bool {
+	for _, l := range want {
+		if labels == l ||
+			strings.HasPrefix(labels, l+",") ||
+			strings.HasSuffix(labels, ","+l) ||
+			strings.Contains(labels, ","+l+",") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSample splits a sample line like `name{labels} value [timestamp]`.
+func splitSample(line string) (name, labels, value string, ok bool) {
+	rest := line
+	if i := strings.IndexByte(line, '{'); i >= 0 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return "", "", "", false
+		}
+		name, labels, rest = line[:i], line[i+1:j], line[j+1:]
+	} else {
+		name, rest, _ = strings.Cut(line, " ")
+		rest = " " + rest
+	}
+	fields := strings.Fields(rest)
+	if name == "" || len(fields) == 0 {
+		return "", "", "", false
+	}
+	return name, labels, fields[0], true
+}
+
+// Wait polls each probe every interval until all of them have exported, or ctx is done.
+func Wait(ctx context.Context, interval time.Duration, probes ...Probe) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	pending := probes
+	for {
+		var notReady []Probe
+		var lastErr error
+		for _, p := range pending {
+			ok, err := p.Exported(ctx, client)
+			if err != nil {
+				lastErr = err
+			}
+			if !ok {
+				notReady = append(notReady, p)
+			}
+		}
+		pending = notReady
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%w: %v", ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("%w: %s has not exported yet", ctx.Err(), pending[0].URL)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Notify sends state, like "READY=1", to the systemd notification socket.
+// It does nothing when the process was not started by systemd with Type=notify.
+// See sd_notify(3).
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		// An abstract socket.
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package readiness
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fluentBitMetrics = `# HELP fluentbit_output_proc_records_total Number of processed output records.
+# TYPE fluentbit_output_proc_records_total counter
+fluentbit_output_proc_records_total{name="prometheus_exporter.0"} 12 1707900000000
+fluentbit_output_proc_records_total{name="stackdriver.user"} %s 1707900000000
+`
+
+func TestProbeExported(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		probe Probe
+		body  string
+		want  bool
+	}{
+		{
+			name:  "fluent-bit before the first export",
+			probe: Probes["fluentbit"],
+			body:  strings.Replace(fluentBitMetrics, "%s", "0", 1),
+			want:  false,
+		},
+		{
+			name:  "fluent-bit after the first export",
+			probe: Probes["fluentbit"],
+			body:  strings.Replace(fluentBitMetrics, "%s", "3", 1),
+			want:  true,
+		},
+		{
+			name:  "fluent-bit with only agent logs exported",
+			probe: Probes["fluentbit"],
+			body:  `fluentbit_output_proc_records_total{name="stackdriver.agent"} 7` + "\n",
+			want:  false,
+		},
+		{
+			name:  "fluent-bit with compressed user logs exported",
+			probe: Probes["fluentbit"],
+			body:  `fluentbit_output_proc_records_total{name="stackdriver.user.gzip"} 7` + "\n",
+			want:  true,
+		},
+		{
+			name:  "otel without the _total suffix",
+			probe: Probes["otel"],
+			body:  `otelcol_exporter_sent_metric_points{exporter="googlecloud",service_name="google-cloud-metrics-agent"} 42` + "\n",
+			want:  true,
+		},
+		{
+			name:  "otel with the _total suffix",
+			probe: Probes["otel"],
+			body:  `otelcol_exporter_sent_metric_points_total{exporter="googlecloud"} 1.5e+06` + "\n",
+			want:  true,
+		},
+		{
+			name:  "other metrics only",
+			probe: Probes["otel"],
+			body:  "otelcol_process_uptime 12\n",
+			want:  false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sum, err := tc.probe.sum(strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sum > 0; got != tc.want {
+				t.Errorf("sum() = %v, want exported = %v", sum, tc.want)
+			}
+		})
+	}
+}
+
+func TestWait(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Write([]byte("otelcol_exporter_sent_metric_points 0\n"))
+			return
+		}
+		w.Write([]byte("otelcol_exporter_sent_metric_points 10\n"))
+	}))
+	defer server.Close()
+	probe := Probe{URL: server.URL, Metric: "otelcol_exporter_sent_metric_points"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := Wait(ctx, 10*time.Millisecond, probe); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	never := Probe{URL: server.URL, Metric: "fluentbit_output_proc_records_total"}
+	if err := Wait(ctx, 10*time.Millisecond, never); err == nil {
+		t.Errorf("Wait() returned nil for a probe that never exports")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	if err := Notify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("got %q, want %q", got, "READY=1")
+	}
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify("READY=1"); err != nil {
+		t.Errorf("Notify() without a socket = %v", err)
+	}
+}
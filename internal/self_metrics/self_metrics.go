@@ -18,11 +18,18 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	mexporter "github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/metric"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/host_performance"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/log_staleness"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/prometheus_targets"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version_check"
 	"go.opentelemetry.io/contrib/detectors/gcp"
 	"go.opentelemetry.io/otel/attribute"
 	metricapi "go.opentelemetry.io/otel/metric"
@@ -126,6 +133,280 @@ func InstrumentFeatureTrackingMetric(uc *confgenerator.UnifiedConfig, meter metr
 	return nil
 }
 
+var (
+	versionCheckMu sync.Mutex
+	versionCheck   *version_check.Result
+)
+
+// RecordVersionCheck sets the result reported by the version_skew metric.
+func RecordVersionCheck(r version_check.Result) {
+	versionCheckMu.Lock()
+	defer versionCheckMu.Unlock()
+	versionCheck = &r
+}
+
+// InstrumentVersionSkewMetric reports 1 if a newer agent version is available in the configured
+// repository and 0 otherwise, once a version check was recorded.
+func InstrumentVersionSkewMetric(meter metricapi.Meter) error {
+	_, err := meter.Int64ObservableGauge(
+		"agent/ops_agent/version_skew",
+		metricapi.WithInt64Callback(
+			func(ctx context.Context, observer metricapi.Int64Observer) error {
+				versionCheckMu.Lock()
+				defer versionCheckMu.Unlock()
+				if versionCheck == nil {
+					return nil
+				}
+				var behind int64
+				if versionCheck.Behind {
+					behind = 1
+				}
+				labels := []attribute.KeyValue{
+					attribute.String("installed_version", versionCheck.Installed),
+					attribute.String("latest_version", versionCheck.Latest),
+				}
+				observer.Observe(behind, metricapi.WithAttributes(labels...))
+				return nil
+			}),
+	)
+	return err
+}
+
+var (
+	staleFilesMu sync.Mutex
+	staleFiles   []log_staleness.StaleFile
+)
+
+// RecordStaleFiles sets the files reported by the stale_files metric.
+func RecordStaleFiles(files []log_staleness.StaleFile) {
+	staleFilesMu.Lock()
+	defer staleFilesMu.Unlock()
+	staleFiles = files
+}
+
+// InstrumentStaleFilesMetric reports the number of stale files of each files receiver with a
+// staleness alert.
+func InstrumentStaleFilesMetric(meter metricapi.Meter) error {
+	_, err := meter.Int64ObservableGauge(
+		"agent/ops_agent/logging/stale_files",
+		metricapi.WithInt64Callback(
+			func(ctx context.Context, observer metricapi.Int64Observer) error {
+				staleFilesMu.Lock()
+				defer staleFilesMu.Unlock()
+				counts := map[string]int64{}
+				for _, f := range staleFiles {
+					counts[f.Receiver]++
+				}
+				for receiver, count := range counts {
+					observer.Observe(count, metricapi.WithAttributes(attribute.String("receiver_id", receiver)))
+				}
+				return nil
+			}),
+	)
+	return err
+}
+
+var (
+	truncatedScrapesMu sync.Mutex
+	truncatedScrapes   []prometheus_targets.TruncatedTarget
+)
+
+// RecordTruncatedScrapes sets the targets reported by the truncated_scrapes metric.
+func RecordTruncatedScrapes(targets []prometheus_targets.TruncatedTarget) {
+	truncatedScrapesMu.Lock()
+	defer truncatedScrapesMu.Unlock()
+	truncatedScrapes = targets
+}
+
+// InstrumentTruncatedScrapesMetric reports the number of targets of each prometheus receiver job
+// whose scrapes exceed the sample limit.
+func InstrumentTruncatedScrapesMetric(meter metricapi.Meter) error {
+	_, err := meter.Int64ObservableGauge(
+		"agent/ops_agent/metrics/truncated_scrapes",
+		metricapi.WithInt64Callback(
+			func(ctx context.Context, observer metricapi.Int64Observer) error {
+				truncatedScrapesMu.Lock()
+				defer truncatedScrapesMu.Unlock()
+				type job struct{ receiver, name string }
+				counts := map[job]int64{}
+				for _, t := range truncatedScrapes {
+					counts[job{t.ReceiverID, t.Job}]++
+				}
+				for j, count := range counts {
+					observer.Observe(count, metricapi.WithAttributes(
+						attribute.String("receiver_id", j.receiver),
+						attribute.String("job", j.name)))
+				}
+				return nil
+			}),
+	)
+	return err
+}
+
+var (
+	droppedBufferedBytesMu sync.Mutex
+	droppedBufferedBytes   int64
+)
+
+// RecordDroppedBufferedBytes sets the bytes reported by the dropped_buffered_bytes metric.
+func RecordDroppedBufferedBytes(bytes int64) {
+	droppedBufferedBytesMu.Lock()
+	defer droppedBufferedBytesMu.Unlock()
+	droppedBufferedBytes = bytes
+}
+
+// InstrumentDroppedBufferedBytesMetric reports the bytes of the buffered logs that were dropped
+// after the offline buffering retention.
+func InstrumentDroppedBufferedBytesMetric(meter metricapi.Meter) error {
+	_, err := meter.Int64ObservableCounter(
+		"agent/ops_agent/logging/dropped_buffered_bytes",
+		metricapi.WithInt64Callback(
+			func(ctx context.Context, observer metricapi.Int64Observer) error {
+				droppedBufferedBytesMu.Lock()
+				defer droppedBufferedBytesMu.Unlock()
+				observer.Observe(droppedBufferedBytes)
+				return nil
+			}),
+	)
+	return err
+}
+
+// InstrumentHeartbeatMetric reports 1 every time the metrics are collected, so that alerts can
+// find agents that went silent without relying on their logs.
+func InstrumentHeartbeatMetric(meter metricapi.Meter) error {
+	_, err := meter.Int64ObservableGauge(
+		"agent/ops_agent/heartbeat",
+		metricapi.WithInt64Callback(
+			func(ctx context.Context, observer metricapi.Int64Observer) error {
+				labels := []attribute.KeyValue{
+					attribute.String("version", version.Version),
+				}
+				observer.Observe(1, metricapi.WithAttributes(labels...))
+				return nil
+			}),
+	)
+	return err
+}
+
+// InstrumentHostPerformanceMetrics reports the pressure stall information, the hugepages and the
+// memory of the NUMA nodes read from procDir and sysDir. The metrics of the files that the kernel
+// doesn't have, e.g. /proc/pressure without PSI, are not reported.
+func InstrumentHostPerformanceMetrics(meter metricapi.Meter, procDir, sysDir string) error {
+	for _, resource := range host_performance.PressureResources {
+		name := resource
+		if resource == "io" {
+			name = "disk"
+		}
+		_, err := meter.Int64ObservableCounter(
+			fmt.Sprintf("%s/pressure_stall_time", name),
+			metricapi.WithUnit("us"),
+			metricapi.WithInt64Callback(
+				func(ctx context.Context, observer metricapi.Int64Observer) error {
+					pressure, err := host_performance.ReadPressure(procDir, resource)
+					if err != nil {
+						return nil
+					}
+					for _, p := range pressure {
+						observer.Observe(p.Total.Microseconds(), metricapi.WithAttributes(attribute.String("level", p.Level)))
+					}
+					return nil
+				}),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	hugepagesCount, err := meter.Int64ObservableGauge("memory/hugepages/count")
+	if err != nil {
+		return err
+	}
+	hugepagesSize, err := meter.Int64ObservableGauge("memory/hugepages/page_size", metricapi.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, observer metricapi.Observer) error {
+			h, err := host_performance.ReadHugepages(procDir)
+			if err != nil || h.PageSize == 0 {
+				return nil
+			}
+			for state, count := range map[string]int64{
+				"used":     h.Total - h.Free,
+				"free":     h.Free,
+				"reserved": h.Reserved,
+				"surplus":  h.Surplus,
+			} {
+				observer.ObserveInt64(hugepagesCount, count, metricapi.WithAttributes(attribute.String("state", state)))
+			}
+			observer.ObserveInt64(hugepagesSize, h.PageSize)
+			return nil
+		},
+		hugepagesCount, hugepagesSize,
+	)
+	if err != nil {
+		return err
+	}
+	numaBytes, err := meter.Int64ObservableGauge("memory/numa/bytes_used", metricapi.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	numaAllocations, err := meter.Int64ObservableCounter("memory/numa/allocations")
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, observer metricapi.Observer) error {
+			nodes, err := host_performance.ReadNUMANodes(sysDir)
+			if err != nil {
+				return nil
+			}
+			for _, n := range nodes {
+				node := attribute.String("numa_node", n.Node)
+				observer.ObserveInt64(numaBytes, n.Total-n.Free, metricapi.WithAttributes(node, attribute.String("state", "used")))
+				observer.ObserveInt64(numaBytes, n.Free, metricapi.WithAttributes(node, attribute.String("state", "free")))
+				for t, count := range n.Allocations {
+					observer.ObserveInt64(numaAllocations, count, metricapi.WithAttributes(node, attribute.String("type", t)))
+				}
+			}
+			return nil
+		},
+		numaBytes, numaAllocations,
+	)
+	return err
+}
+
+// InstrumentEphemeralPortsMetrics reports the number of ephemeral ports in use by protocol, and
+// the size of the ephemeral port range, read from procDir.
+func InstrumentEphemeralPortsMetrics(meter metricapi.Meter, procDir string) error {
+	used, err := meter.Int64ObservableGauge("network/ephemeral_ports/used")
+	if err != nil {
+		return err
+	}
+	limit, err := meter.Int64ObservableGauge("network/ephemeral_ports/limit")
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(
+		func(ctx context.Context, observer metricapi.Observer) error {
+			low, high, err := host_performance.EphemeralPortRange(procDir)
+			if err != nil {
+				return nil
+			}
+			observer.ObserveInt64(limit, int64(high-low+1))
+			for _, protocol := range []string{"tcp", "udp"} {
+				count, err := host_performance.EphemeralPortsUsed(procDir, protocol, low, high)
+				if err != nil {
+					continue
+				}
+				observer.ObserveInt64(used, count, metricapi.WithAttributes(attribute.String("protocol", protocol)))
+			}
+			return nil
+		},
+		used, limit,
+	)
+	return err
+}
+
 func CreateFeatureTrackingMeterProvider(exporter metricsdk.Exporter, res *resource.Resource) *metricsdk.MeterProvider {
 	provider := metricsdk.NewMeterProvider(
 		metricsdk.WithReader(
@@ -173,6 +454,44 @@ func CreateEnabledReceiversMeterProvider(exporter metricsdk.Exporter, res *resou
 	return provider
 }
 
+func CreateHeartbeatMeterProvider(exporter metricsdk.Exporter, res *resource.Resource, interval time.Duration) *metricsdk.MeterProvider {
+	provider := metricsdk.NewMeterProvider(
+		metricsdk.WithReader(
+			metricsdk.NewPeriodicReader(
+				exporter,
+				metricsdk.WithInterval(interval),
+			),
+		),
+		metricsdk.WithView(
+			metricsdk.NewView(
+				metricsdk.Instrument{
+					Name: "agent/ops_agent/heartbeat",
+					Kind: metricsdk.InstrumentKindObservableGauge,
+				},
+				metricsdk.Stream{
+					Name:        "agent/ops_agent/heartbeat",
+					Aggregation: metricsdk.AggregationDefault{},
+				},
+			)),
+		metricsdk.WithResource(res),
+	)
+	return provider
+}
+
+// CreateHostMetricsMeterProvider returns a provider that exports the host metrics at the
+// collection interval of their hostmetrics receiver.
+func CreateHostMetricsMeterProvider(exporter metricsdk.Exporter, res *resource.Resource, interval time.Duration) *metricsdk.MeterProvider {
+	return metricsdk.NewMeterProvider(
+		metricsdk.WithReader(
+			metricsdk.NewPeriodicReader(
+				exporter,
+				metricsdk.WithInterval(interval),
+			),
+		),
+		metricsdk.WithResource(res),
+	)
+}
+
 func CollectOpsAgentSelfMetrics(ctx context.Context, userUc, mergedUc *confgenerator.UnifiedConfig) (err error) {
 
 	// Resource for GCP and SDK detectors
@@ -204,8 +523,52 @@ func CollectOpsAgentSelfMetrics(ctx context.Context, userUc, mergedUc *confgener
 	if err != nil {
 		return fmt.Errorf("failed to instrument enabled receivers: %w", err)
 	}
+	err = InstrumentVersionSkewMetric(enabledReceiversProvider.Meter("ops_agent/self_metrics"))
+	if err != nil {
+		return fmt.Errorf("failed to instrument version skew: %w", err)
+	}
+	err = InstrumentStaleFilesMetric(enabledReceiversProvider.Meter("ops_agent/self_metrics"))
+	if err != nil {
+		return fmt.Errorf("failed to instrument stale files: %w", err)
+	}
+	err = InstrumentTruncatedScrapesMetric(enabledReceiversProvider.Meter("ops_agent/self_metrics"))
+	if err != nil {
+		return fmt.Errorf("failed to instrument truncated scrapes: %w", err)
+	}
+	if mergedUc.Global != nil && mergedUc.Global.OfflineBuffering != nil {
+		err = InstrumentDroppedBufferedBytesMetric(enabledReceiversProvider.Meter("ops_agent/self_metrics"))
+		if err != nil {
+			return fmt.Errorf("failed to instrument dropped buffered bytes: %w", err)
+		}
+	}
+
+	heartbeatProvider := CreateHeartbeatMeterProvider(exporter, res, mergedUc.Global.GetHeartbeatInterval())
+	err = InstrumentHeartbeatMetric(heartbeatProvider.Meter("ops_agent/self_metrics"))
+	if err != nil {
+		return fmt.Errorf("failed to instrument heartbeat: %w", err)
+	}
+
+	var hostMetricsProvider *metricsdk.MeterProvider
+	if interval, performance, ok := mergedUc.DiagnosticsHostMetrics(); ok && runtime.GOOS == "linux" {
+		hostMetricsProvider = CreateHostMetricsMeterProvider(exporter, res, interval)
+		err = InstrumentEphemeralPortsMetrics(hostMetricsProvider.Meter("ops_agent/host_metrics"), "/proc")
+		if err != nil {
+			return fmt.Errorf("failed to instrument ephemeral ports metrics: %w", err)
+		}
+		if performance {
+			err = InstrumentHostPerformanceMetrics(hostMetricsProvider.Meter("ops_agent/host_metrics"), "/proc", "/sys")
+			if err != nil {
+				return fmt.Errorf("failed to instrument host performance metrics: %w", err)
+			}
+		}
+	}
 
 	defer func() {
+		if hostMetricsProvider != nil {
+			if serr := hostMetricsProvider.Shutdown(ctx); serr != nil && err == nil {
+				err = fmt.Errorf("failed to shutdown meter provider: %w", serr)
+			}
+		}
 		if serr := featureTrackingProvider.Shutdown(ctx); serr != nil {
 			myStatus, ok := status.FromError(serr)
 			if !ok && myStatus.Code() == codes.Unknown {
@@ -222,23 +585,51 @@ func CollectOpsAgentSelfMetrics(ctx context.Context, userUc, mergedUc *confgener
 				err = fmt.Errorf("failed to shutdown meter provider: %w", serr)
 			}
 		}
+		if serr := heartbeatProvider.Shutdown(ctx); serr != nil {
+			myStatus, ok := status.FromError(serr)
+			if !ok && myStatus.Code() == codes.Unknown {
+				log.Print(serr)
+			} else if err == nil {
+				err = fmt.Errorf("failed to shutdown meter provider: %w", serr)
+			}
+		}
 	}()
 
+	flush := func() {
+		err := featureTrackingProvider.ForceFlush(ctx)
+		if err != nil {
+			log.Print(err)
+		}
+		err = enabledReceiversProvider.ForceFlush(ctx)
+		if err != nil {
+			log.Print(err)
+		}
+		err = heartbeatProvider.ForceFlush(ctx)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+
 	timer := time.NewTimer(10 * time.Second)
 
 	for {
 		select {
 		case <-timer.C:
-			err := featureTrackingProvider.ForceFlush(ctx)
-			if err != nil {
-				log.Print(err)
-			}
-			err = enabledReceiversProvider.ForceFlush(ctx)
-			if err != nil {
-				log.Print(err)
-			}
+			flush()
+		case <-flushRequests:
+			flush()
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
+
+var flushRequests = make(chan struct{}, 1)
+
+// Flush asks CollectOpsAgentSelfMetrics to export the self metrics right away.
+func Flush() {
+	select {
+	case flushRequests <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tls_rotation reloads the logging subagent when the certificate, key or CA files of its
+// config change on disk, so that short-lived certificates can be rotated without restarting the
+// agent. On Linux the subagent is hot reloaded. Fluent Bit can't hot reload on Windows, so only
+// the logging subagent is restarted there. The OTel components reload their files themselves, see
+// confgenerator.TLSReloadInterval.
+package tls_rotation
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+)
+
+type Options struct {
+	// Paths are the TLS files of the logging subagent config.
+	Paths []string
+	// Interval is how often the files are checked.
+	Interval time.Duration
+	// Reload reloads the logging subagent.
+	Reload func()
+}
+
+// watcher keeps the digest of the files at the previous check.
+type watcher struct {
+	digests map[string][sha256.Size]byte
+	// changed are the files that changed since the last reload.
+	changed map[string]bool
+}
+
+func newWatcher(paths []string) *watcher {
+	w := &watcher{digests: map[string][sha256.Size]byte{}, changed: map[string]bool{}}
+	w.digest(paths)
+	return w
+}
+
+// digest reads the files and returns the ones that changed since the previous call. Files that
+// can't be read, e.g. while they are replaced, keep their previous digest.
+func (w *watcher) digest(paths []string) []string {
+	var changed []string
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		d := sha256.Sum256(b)
+		if previous, ok := w.digests[p]; ok && previous != d {
+			changed = append(changed, p)
+		}
+		w.digests[p] = d
+	}
+	return changed
+}
+
+// check returns the files to reload the logging subagent for, or nil. The certificate and its key
+// are often written one after the other, so the files are only reloaded once they did not change
+// for a whole check, which avoids loading a certificate with the key of the previous one.
+func (w *watcher) check(paths []string) []string {
+	changed := w.digest(paths)
+	if len(changed) > 0 {
+		for _, p := range changed {
+			w.changed[p] = true
+		}
+		return nil
+	}
+	if len(w.changed) == 0 {
+		return nil
+	}
+	var out []string
+	for _, p := range paths {
+		if w.changed[p] {
+			out = append(out, p)
+		}
+	}
+	w.changed = map[string]bool{}
+	return out
+}
+
+// Watch checks the files every opts.Interval until ctx is done, and reloads the logging subagent
+// once they changed.
+func Watch(ctx context.Context, opts Options, logger logs.StructuredLogger) {
+	w := newWatcher(opts.Paths)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+		if changed := w.check(opts.Paths); len(changed) > 0 {
+			logger.Infow("Reloading the logging subagent because its TLS files changed",
+				"code", "TLSFilesChanged",
+				"paths", changed)
+			opts.Reload()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls_rotation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	key := filepath.Join(dir, "key.pem")
+	missing := filepath.Join(dir, "missing.pem")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(cert, "cert 1")
+	write(key, "key 1")
+	paths := []string{cert, key, missing}
+
+	w := newWatcher(paths)
+	for i, step := range []struct {
+		write func()
+		want  []string
+	}{
+		{func() {}, nil},
+		// The certificate is written first, and the key at the next check.
+		{func() { write(cert, "cert 2") }, nil},
+		{func() { write(key, "key 2") }, nil},
+		// The files are reloaded once they settled.
+		{func() {}, []string{cert, key}},
+		{func() {}, nil},
+		// Rewriting the same content is not a change.
+		{func() { write(cert, "cert 2") }, nil},
+		{func() {}, nil},
+		// The key can't be read while it is replaced.
+		{func() { os.Remove(key) }, nil},
+		{func() { write(key, "key 3") }, nil},
+		{func() {}, []string{key}},
+	} {
+		step.write()
+		if got := w.check(paths); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("check %d = %v, want %v", i, got, step.want)
+		}
+	}
+}
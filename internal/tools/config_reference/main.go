@@ -0,0 +1,118 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// config_reference writes the reference of all the receivers and processors of the Ops Agent
+// config as YAML. The fields, their types, validation and supported platforms come from the Go
+// structs of the components; the defaults and descriptions come from the configuration_options
+// of the metadata.yaml files of the third party apps.
+//
+// Usage:
+//
+//	go run ./internal/tools/config_reference -out config_reference.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	yaml "github.com/goccy/go-yaml"
+
+	_ "github.com/GoogleCloudPlatform/ops-agent/apps"
+	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
+	"github.com/GoogleCloudPlatform/ops-agent/integration_test/metadata"
+)
+
+var (
+	applicationsDir = flag.String("applications", "integration_test/third_party_apps_test/applications", "directory with the metadata.yaml files of the third party apps")
+	out             = flag.String("out", "", "file to write the reference to; stdout if empty")
+)
+
+// fieldKey identifies a field of a component type in the metadata.yaml files.
+type fieldKey struct {
+	subagent, componentType, field string
+}
+
+// readConfigurationOptions returns the configuration options of the metadata.yaml files in dir.
+func readConfigurationOptions(dir string) (map[fieldKey]*metadata.ConfigurationFields, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "metadata.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	options := map[fieldKey]*metadata.ConfigurationFields{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var m metadata.IntegrationMetadata
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if m.ConfigurationOptions == nil {
+			continue
+		}
+		for _, c := range []struct {
+			subagent string
+			inputs   []*metadata.InputConfiguration
+		}{
+			{"logging", m.ConfigurationOptions.LogsConfiguration},
+			{"metrics", m.ConfigurationOptions.MetricsConfiguration},
+		} {
+			for _, input := range c.inputs {
+				for _, f := range input.Fields {
+					options[fieldKey{c.subagent, input.Type, f.Name}] = f
+				}
+			}
+		}
+	}
+	return options, nil
+}
+
+func run() error {
+	options, err := readConfigurationOptions(*applicationsDir)
+	if err != nil {
+		return err
+	}
+	reference := confgenerator.Reference()
+	for i, c := range reference {
+		if c.Kind != "receiver" {
+			continue
+		}
+		for j, f := range c.Fields {
+			if o, ok := options[fieldKey{c.Subagent, c.Type, f.Name}]; ok {
+				reference[i].Fields[j].Default = o.Default
+				reference[i].Fields[j].Description = o.Description
+			}
+		}
+	}
+	data, err := yaml.Marshal(map[string]interface{}{"components": reference})
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0644)
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package version_check compares the installed agent version against the latest version available
+// in the configured package repository, so that fleet owners can find stale agents. It only
+// queries the repository metadata and never installs anything.
+package version_check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
+)
+
+// PackageName is the name of the agent package in the repository.
+const PackageName = "google-cloud-ops-agent"
+
+// ErrUnsupported is returned by LatestVersion when the platform has no supported package manager.
+var ErrUnsupported = errors.New("no supported package manager found")
+
+// Result is the outcome of a version check.
+type Result struct {
+	Installed string
+	Latest    string
+	// Behind is true if Latest is newer than Installed.
+	Behind bool
+}
+
+type Options struct {
+	// Interval is how often the repository is queried.
+	Interval time.Duration
+	// Latest returns the latest version available in the repository. It defaults to LatestVersion.
+	Latest func(ctx context.Context) (string, error)
+	// Record is called with the result of every successful check.
+	Record func(Result)
+}
+
+// parse returns the numeric components of the upstream part of v, ignoring any epoch, package
+// revision or distro suffix, e.g. "1:2.47.0~ubuntu22.04" and "2.47.0-1.el9" both parse as 2.47.0.
+func parse(v string) ([]int, error) {
+	if i := strings.Index(v, ":"); i >= 0 {
+		v = v[i+1:]
+	}
+	if i := strings.IndexAny(v, "~-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", v)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// Compare returns -1, 0 or 1 if a is older than, the same as or newer than b.
+func Compare(a, b string) (int, error) {
+	pa, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x < y {
+			return -1, nil
+		} else if x > y {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// newest returns the newest of versions.
+func newest(versions []string) (string, error) {
+	if len(versions) == 0 {
+		return "", fmt.Errorf("%s not found in the configured repositories", PackageName)
+	}
+	n := versions[0]
+	for _, v := range versions[1:] {
+		c, err := Compare(v, n)
+		if err != nil {
+			return "", err
+		}
+		if c > 0 {
+			n = v
+		}
+	}
+	return n, nil
+}
+
+// Check compares installed against the version returned by latest.
+func Check(ctx context.Context, installed string, latest func(ctx context.Context) (string, error)) (Result, error) {
+	l, err := latest(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	c, err := Compare(installed, l)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Installed: installed, Latest: l, Behind: c < 0}, nil
+}
+
+// Watch checks the installed version once and then every opts.Interval until ctx is done. Whenever
+// the result changes, it logs an AgentVersionCheck entry to logger.
+func Watch(ctx context.Context, opts Options, logger logs.StructuredLogger) {
+	if _, err := parse(version.Version); err != nil {
+		// Development builds have no comparable version.
+		return
+	}
+	latest := opts.Latest
+	if latest == nil {
+		latest = LatestVersion
+	}
+	var last Result
+	for {
+		r, err := Check(ctx, version.Version, latest)
+		if errors.Is(err, ErrUnsupported) {
+			return
+		} else if err != nil {
+			logger.Warnf("failed to check the latest agent version: %v", err)
+		} else {
+			if opts.Record != nil {
+				opts.Record(r)
+			}
+			if r != last {
+				msg := "Agent version is up to date"
+				if r.Behind {
+					msg = "A newer agent version is available in the configured repository"
+				}
+				logger.Infow(msg,
+					"code", "AgentVersionCheck",
+					"installed_version", r.Installed,
+					"latest_version", r.Latest)
+				last = r
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
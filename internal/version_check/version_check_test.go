@@ -0,0 +1,97 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version_check
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/ops-agent/internal/logs"
+	"github.com/GoogleCloudPlatform/ops-agent/internal/version"
+)
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"2.47.0", "2.47.0", 0},
+		{"2.46.1", "2.47.0", -1},
+		{"2.47.0", "2.9.3", 1},
+		{"2.47", "2.47.0", 0},
+		{"2.47.0", "2.47.0~ubuntu22.04", 0},
+		{"2.46.0", "1:2.47.0-1.el9", -1},
+	} {
+		got, err := Compare(tc.a, tc.b)
+		if err != nil {
+			t.Errorf("Compare(%q, %q) failed: %v", tc.a, tc.b, err)
+		} else if got != tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+	if _, err := Compare("latest", "2.47.0"); err == nil {
+		t.Errorf("Compare(\"latest\", ...) succeeded, want error")
+	}
+}
+
+func TestNewest(t *testing.T) {
+	got, err := newest([]string{"2.9.0-1.el9", "2.47.0-1.el9", "2.46.1-1.el9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "2.47.0-1.el9" {
+		t.Errorf("newest() = %q, want %q", got, "2.47.0-1.el9")
+	}
+	if _, err := newest(nil); err == nil {
+		t.Errorf("newest(nil) succeeded, want error")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	installed := version.Version
+	version.Version = "2.46.0"
+	defer func() { version.Version = installed }()
+
+	logger, observed := logs.DiscardLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var results []Result
+	Watch(ctx, Options{
+		Interval: time.Millisecond,
+		Latest: func(ctx context.Context) (string, error) {
+			return "2.47.0~ubuntu22.04", nil
+		},
+		Record: func(r Result) {
+			results = append(results, r)
+			if len(results) == 3 {
+				cancel()
+			}
+		},
+	}, logger)
+
+	want := Result{Installed: "2.46.0", Latest: "2.47.0~ubuntu22.04", Behind: true}
+	if len(results) != 3 || results[0] != want {
+		t.Errorf("got results %v, want 3 of %v", results, want)
+	}
+	entries := observed.FilterFieldKey("installed_version").All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d AgentVersionCheck entries, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["installed_version"] != "2.46.0" || fields["latest_version"] != "2.47.0~ubuntu22.04" {
+		t.Errorf("unexpected entry fields: %v", fields)
+	}
+}
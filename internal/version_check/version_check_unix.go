@@ -0,0 +1,80 @@
+// Copyright 2024 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package version_check
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// queries are the package manager commands that print the versions of PackageName available in
+// the configured repositories, in order of preference. Repositories are not refreshed, so that the
+// check never changes the state of the host.
+var queries = []struct {
+	name  string
+	args  []string
+	parse func(out string) []string
+}{
+	{"apt-cache", []string{"policy", PackageName}, parseAptPolicy},
+	{"dnf", []string{"repoquery", "--quiet", "--cacheonly", "--queryformat", "%{version}\n", PackageName}, strings.Fields},
+	{"repoquery", []string{"--quiet", "--cache-only", "--queryformat", "%{version}", PackageName}, strings.Fields},
+	{"zypper", []string{"--non-interactive", "--no-refresh", "--quiet", "search", "--details", "--match-exact", "--type", "package", PackageName}, parseZypperSearch},
+}
+
+// parseAptPolicy returns the candidate version from the output of "apt-cache policy".
+func parseAptPolicy(out string) []string {
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "Candidate:"); ok {
+			if v = strings.TrimSpace(v); v != "(none)" {
+				return []string{v}
+			}
+		}
+	}
+	return nil
+}
+
+// parseZypperSearch returns the versions column from the output of "zypper search --details".
+func parseZypperSearch(out string) []string {
+	var versions []string
+	for _, line := range strings.Split(out, "\n") {
+		cols := strings.Split(line, "|")
+		if len(cols) < 4 || strings.TrimSpace(cols[1]) != PackageName {
+			continue
+		}
+		versions = append(versions, strings.TrimSpace(cols[3]))
+	}
+	return versions
+}
+
+// LatestVersion returns the latest version of the agent in the repositories configured on the
+// host, using the first package manager that is installed.
+func LatestVersion(ctx context.Context) (string, error) {
+	for _, q := range queries {
+		path, err := exec.LookPath(q.name)
+		if err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, path, q.args...).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s failed: %w", q.name, err)
+		}
+		return newest(q.parse(string(out)))
+	}
+	return "", ErrUnsupported
+}
@@ -33,6 +33,7 @@ import (
 	"time"
 
 	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/GoogleCloudPlatform/ops-agent/apps"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/fluentbit"
 	"github.com/GoogleCloudPlatform/ops-agent/confgenerator/otel"
@@ -49,7 +50,7 @@ import (
 )
 
 const (
-	flbMainConf          = "fluent_bit_main.conf"
+	flbMainConf          = "fluent_bit_main.yaml"
 	flbParserConf        = "fluent_bit_parser.conf"
 	transformationInput  = "input.log"
 	transformationOutput = "output_fluentbit.yaml"
@@ -59,6 +60,10 @@ const (
 var (
 	flbPath        = flag.String("flb", os.Getenv("FLB"), "path to fluent-bit")
 	otelopscolPath = flag.String("otelopscol", os.Getenv("OTELOPSCOL"), "path to otelopscol")
+
+	dryRunConfig   = flag.String("dry_run_config", "", "agent config whose receiver TestReceiverDryRun runs")
+	dryRunReceiver = flag.String("dry_run_receiver", "", "logging receiver whose pipelines TestReceiverDryRun runs")
+	dryRunInput    = flag.String("dry_run_input", "", "log lines that TestReceiverDryRun runs through the pipelines")
 )
 
 type transformationTest []loggingProcessor
@@ -110,6 +115,15 @@ func (transformationConfig transformationTest) runFluentBitTest(t *testing.T, na
 		t.Fatalf("failed to generate config files: %v", err)
 	}
 
+	data := runFluentBit(t, genFiles)
+	checkOutput(t, filepath.Join(name, transformationOutput), data)
+}
+
+// runFluentBit runs Fluent Bit with the generated files, whose output prints the requests that
+// would be sent to Cloud Logging, and returns the requests. The timestamps of the entries that
+// were created while Fluent Bit ran are replaced with "now".
+func runFluentBit(t *testing.T, genFiles map[string]string) []map[string]any {
+	t.Helper()
 	if len(*flbPath) == 0 {
 		t.Skip("--flb not supplied")
 	}
@@ -175,8 +189,62 @@ func (transformationConfig transformationTest) runFluentBitTest(t *testing.T, na
 			}
 		}
 	}
+	return data
+}
 
-	checkOutput(t, filepath.Join(name, transformationOutput), data)
+// TestReceiverDryRun runs the log lines of --dry_run_input through the parsing and processors
+// that the agent config --dry_run_config applies to the logging receiver --dry_run_receiver, and
+// prints the LogEntries that would be sent to Cloud Logging. This allows iterating on parse_regex,
+// modify_fields and similar processors locally:
+//
+//	go test ./transformation_test -run TestReceiverDryRun -v -flb=/path/to/fluent-bit \
+//	  -dry_run_config=config.yaml -dry_run_receiver=my_receiver -dry_run_input=sample.log
+func TestReceiverDryRun(t *testing.T) {
+	if *dryRunConfig == "" {
+		t.Skip("--dry_run_config not supplied")
+	}
+	if *dryRunReceiver == "" || *dryRunInput == "" {
+		t.Fatal("--dry_run_receiver and --dry_run_input are required with --dry_run_config")
+	}
+	pl := platform.Platform{
+		Type: platform.Linux,
+		HostInfo: &host.InfoStat{
+			Hostname:        "hostname",
+			OS:              "linux",
+			Platform:        "linux_platform",
+			PlatformVersion: "linux_platform_version",
+		},
+		// Don't look up the resource from the metadata server, so that this works outside of GCE.
+		ResourceOverride: resourcedetector.GCEResource{
+			Project:    "my-project",
+			Zone:       "test-zone",
+			InstanceID: "test-instance-id",
+		},
+	}
+	ctx := pl.TestContext(context.Background())
+	uc, err := confgenerator.MergeConfFiles(ctx, *dryRunConfig, apps.BuiltInConfStructs)
+	if err != nil {
+		t.Fatalf("the agent config file is not valid: %v", err)
+	}
+	input, err := filepath.Abs(*dryRunInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genFiles, err := uc.GenerateFluentBitDryRunConfigs(ctx, *dryRunReceiver, input, "my-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []any
+	for _, req := range runFluentBit(t, genFiles) {
+		if val, ok := req["entries"].([]any); ok {
+			entries = append(entries, val...)
+		}
+	}
+	out, err := yaml.MarshalWithOptions(entries, yaml.UseLiteralStyleIfMultiline(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("%s", out)
 }
 
 func checkOutput(t *testing.T, name string, got []map[string]any) {